@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// A single --delta cannot fit both a wedding shot continuously and a hike with an hour between viewpoints (v2.10.0+)
+// --group-by adaptive computes a threshold per day from the distribution of its gaps: a gap far above the typical
+// gap of the day (log-gap outlier) starts a new event. --delta is the floor of the threshold, --adaptive-max-gap its ceiling
+
+// DefaultAdaptiveMaxGap is the adaptive threshold ceiling used when Config.AdaptiveMaxGap is zero
+const DefaultAdaptiveMaxGap = 6 * time.Hour
+
+const (
+	adaptiveMinGaps       = 5      // Days with fewer gaps use --delta
+	adaptiveOutlierFactor = 2.5    // Deviations above the median log gap that make a gap an outlier
+	madScale              = 1.4826 // Scales the median absolute deviation to a standard deviation
+)
+
+// adaptiveThreshold is the gap above which a new event starts on a given day
+type adaptiveThreshold struct {
+	limit  time.Duration // Gaps longer than this split
+	reason string        // How the limit was chosen: "adaptive threshold 1h05", "delta 45m (adaptive threshold 12m)"
+}
+
+// adaptiveMaxGap returns the configured adaptive threshold ceiling, or the default one
+func (c *Config) adaptiveMaxGap() time.Duration {
+	if c.AdaptiveMaxGap > 0 {
+		return c.AdaptiveMaxGap
+	}
+	return DefaultAdaptiveMaxGap
+}
+
+// formatGap formats a gap for split explanations: "3h12", "45m", "20s"
+func formatGap(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02d", d/time.Hour, (d%time.Hour)/time.Minute)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
+// median returns the median of values, sorting them
+func median(values []float64) float64 {
+	slices.Sort(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// logGapThreshold returns the gap above which a gap is an outlier of the distribution, in seconds
+// Gap durations are log-normal rather than normal: bursts of seconds, pauses of minutes, breaks of hours.
+// In log space, a gap is an outlier when it is more than adaptiveOutlierFactor robust deviations (MAD) above the median
+func logGapThreshold(gaps []time.Duration) float64 {
+	logs := make([]float64, len(gaps))
+	for i, gap := range gaps {
+		logs[i] = math.Log(math.Max(gap.Seconds(), 1))
+	}
+
+	center := median(logs)
+	deviations := make([]float64, len(logs))
+	for i, l := range logs {
+		deviations[i] = math.Abs(l - center)
+	}
+	spread := median(deviations) * madScale
+
+	return math.Exp(center + adaptiveOutlierFactor*spread)
+}
+
+// newAdaptiveThreshold computes the threshold of a day from its gaps, within [floor, ceiling]
+func newAdaptiveThreshold(gaps []time.Duration, floor, ceiling time.Duration) adaptiveThreshold {
+	if len(gaps) < adaptiveMinGaps {
+		return adaptiveThreshold{
+			limit:  floor,
+			reason: fmt.Sprintf("delta %s (%d gaps, too few for an adaptive threshold)", formatGap(floor), len(gaps)),
+		}
+	}
+
+	seconds := logGapThreshold(gaps)
+	switch {
+	case seconds < floor.Seconds():
+		computed := time.Duration(seconds * float64(time.Second))
+		return adaptiveThreshold{
+			limit:  floor,
+			reason: fmt.Sprintf("delta %s (adaptive threshold %s)", formatGap(floor), formatGap(computed)),
+		}
+	case seconds > ceiling.Seconds():
+		return adaptiveThreshold{
+			limit:  ceiling,
+			reason: fmt.Sprintf("max gap %s (adaptive threshold above)", formatGap(ceiling)),
+		}
+	default:
+		limit := time.Duration(seconds * float64(time.Second))
+		return adaptiveThreshold{limit: limit, reason: "adaptive threshold " + formatGap(limit)}
+	}
+}
+
+// adaptiveThresholds computes the threshold of each day of sorted files, keyed by "2006-01-02"
+// Only gaps within a day make its rhythm: the night before the day boundary belongs to the previous day,
+// and a gap to the next day is compared to the threshold of the day it leaves
+func adaptiveThresholds(ctx *executionContext, files []FileMetadata, floor, ceiling time.Duration) map[string]adaptiveThreshold {
+	gapsByDay := make(map[string][]time.Duration)
+	var days []string
+	for i := range files {
+		day := adaptiveDay(ctx, files[i])
+		if _, ok := gapsByDay[day]; !ok {
+			gapsByDay[day] = nil
+			days = append(days, day)
+		}
+		if i > 0 && adaptiveDay(ctx, files[i-1]) == day {
+			gapsByDay[day] = append(gapsByDay[day], files[i].DateTime.Sub(files[i-1].DateTime))
+		}
+	}
+
+	thresholds := make(map[string]adaptiveThreshold, len(days))
+	for _, day := range days {
+		gaps := gapsByDay[day]
+		thresholds[day] = newAdaptiveThreshold(gaps, floor, ceiling)
+		ctx.log.Debug("adaptive threshold",
+			"day", day,
+			"gaps", len(gaps),
+			"threshold", thresholds[day].limit,
+			"reason", thresholds[day].reason)
+	}
+	return thresholds
+}
+
+// adaptiveDay returns the day of a file for adaptive thresholds, shifted by the day boundary
+func adaptiveDay(ctx *executionContext, file FileMetadata) string {
+	return file.DateTime.Add(-ctx.dayBoundary).Format(time.DateOnly)
+}
+
+// groupFilesAdaptive groups sorted files by gaps above the adaptive threshold of their day
+// Each group but the first records why it was split from the previous one
+func groupFilesAdaptive(ctx *executionContext, files []FileMetadata, floor, ceiling time.Duration) []Group {
+	if len(files) == 0 {
+		return nil
+	}
+
+	thresholds := adaptiveThresholds(ctx, files, floor, ceiling)
+
+	var groups []Group
+	currentGroup := Group{Files: []FileMetadata{files[0]}}
+
+	for i := 1; i < len(files); i++ {
+		gap := files[i].DateTime.Sub(files[i-1].DateTime)
+		threshold := thresholds[adaptiveDay(ctx, files[i-1])]
+
+		if gap <= threshold.limit {
+			currentGroup.Files = append(currentGroup.Files, files[i])
+			continue
+		}
+
+		currentGroup.Folder = currentGroup.Files[0].DateTime.Format(dateFormatPattern)
+		groups = append(groups, currentGroup)
+
+		split := fmt.Sprintf("gap %s > %s", formatGap(gap), threshold.reason)
+		ctx.log.Debug("gap exceeds adaptive threshold, creating new group",
+			"prev_file", files[i-1].FileInfo.Name(),
+			"curr_file", files[i].FileInfo.Name(),
+			"split", split)
+		currentGroup = Group{Files: []FileMetadata{files[i]}, Split: split}
+	}
+
+	currentGroup.Folder = currentGroup.Files[0].DateTime.Format(dateFormatPattern)
+	groups = append(groups, currentGroup)
+
+	return groups
+}
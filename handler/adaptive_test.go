@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// filesWithGaps returns files starting at start, separated by the given gaps
+func filesWithGaps(start time.Time, gaps ...time.Duration) []FileMetadata {
+	files := []FileMetadata{{FileInfo: memFileInfo{name: "IMG_0000.JPG"}, DateTime: start}}
+	for i, gap := range gaps {
+		start = start.Add(gap)
+		files = append(files, FileMetadata{FileInfo: memFileInfo{name: fmt.Sprintf("IMG_%04d.JPG", i+1)}, DateTime: start})
+	}
+	return files
+}
+
+// repeatGaps repeats a gap pattern n times
+func repeatGaps(n int, pattern ...time.Duration) []time.Duration {
+	var gaps []time.Duration
+	for range n {
+		gaps = append(gaps, pattern...)
+	}
+	return gaps
+}
+
+func TestFormatGap(t *testing.T) {
+	tests := []struct {
+		gap  time.Duration
+		want string
+	}{
+		{3*time.Hour + 12*time.Minute + 40*time.Second, "3h12"},
+		{time.Hour + 5*time.Minute, "1h05"},
+		{45 * time.Minute, "45m"},
+		{20 * time.Second, "20s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatGap(tt.gap); got != tt.want {
+			t.Errorf("formatGap(%v) = %q, want %q", tt.gap, got, tt.want)
+		}
+	}
+}
+
+func TestNewAdaptiveThreshold(t *testing.T) {
+	wedding := repeatGaps(10, 10*time.Second, 30*time.Second, 90*time.Second, 5*time.Minute)
+	hike := repeatGaps(5, 2*time.Minute, 10*time.Minute, 20*time.Minute, time.Hour)
+	steady := repeatGaps(10, time.Minute)
+
+	tests := []struct {
+		name       string
+		gaps       []time.Duration
+		floor      time.Duration
+		wantLimit  func(time.Duration) bool
+		wantReason string
+	}{
+		{"too few gaps", steady[:3], 45 * time.Minute, func(d time.Duration) bool { return d == 45*time.Minute }, "too few"},
+		{"wedding above delta", wedding, 45 * time.Minute, func(d time.Duration) bool { return d > 45*time.Minute && d < 6*time.Hour }, "adaptive threshold"},
+		{"hike reaches the ceiling", hike, 45 * time.Minute, func(d time.Duration) bool { return d == 6*time.Hour }, "max gap 6h00"},
+		{"steady pace uses the floor", steady, 10 * time.Minute, func(d time.Duration) bool { return d == 10*time.Minute }, "delta 10m (adaptive threshold 1m)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newAdaptiveThreshold(tt.gaps, tt.floor, 6*time.Hour)
+			if !tt.wantLimit(got.limit) {
+				t.Errorf("limit = %v", got.limit)
+			}
+			if !strings.Contains(got.reason, tt.wantReason) {
+				t.Errorf("reason = %q, want it to contain %q", got.reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestGroupFilesAdaptive(t *testing.T) {
+	// Wedding: continuous shooting with a 50 minute speech break, then a hike the next morning with an hour between viewpoints
+	gaps := repeatGaps(10, 10*time.Second, 30*time.Second, 90*time.Second, 5*time.Minute)
+	gaps = append(gaps, 50*time.Minute)
+	gaps = append(gaps, repeatGaps(5, 10*time.Second, 30*time.Second, 90*time.Second, 5*time.Minute)...)
+	gaps = append(gaps, 14*time.Hour)
+	gaps = append(gaps, repeatGaps(3, 2*time.Minute, 10*time.Minute, 20*time.Minute, time.Hour)...)
+	files := filesWithGaps(time.Date(2024, 6, 15, 14, 0, 0, 0, time.Local), gaps...)
+
+	ctx := newDefaultExecutionContext()
+	groups := groupFilesAdaptive(ctx, files, 45*time.Minute, 6*time.Hour)
+
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want the wedding and the hike", len(groups))
+	}
+	if groups[0].Split != "" {
+		t.Errorf("first group Split = %q, want empty", groups[0].Split)
+	}
+	if !strings.HasPrefix(groups[1].Split, "gap 14h00 > adaptive threshold ") {
+		t.Errorf("second group Split = %q, want the overnight gap above the wedding threshold", groups[1].Split)
+	}
+	if len(groups[0].Files)+len(groups[1].Files) != len(files) {
+		t.Errorf("groups hold %d files, want %d", len(groups[0].Files)+len(groups[1].Files), len(files))
+	}
+
+	// A fixed delta splits both events
+	if fixed := groupFilesByGaps(files, 45*time.Minute); len(fixed) <= len(groups) {
+		t.Errorf("groupFilesByGaps() = %d groups, want more than adaptive grouping", len(fixed))
+	}
+}
+
+func TestSplit_GroupByAdaptive(t *testing.T) {
+	tmpDir := t.TempDir()
+	start := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
+
+	// A steady minute pace with a 30 minute pause, then the afternoon after a 3 hour break
+	gaps := append(repeatGaps(6, time.Minute), 30*time.Minute)
+	gaps = append(gaps, repeatGaps(6, time.Minute)...)
+	gaps = append(gaps, 3*time.Hour)
+	gaps = append(gaps, repeatGaps(5, time.Minute)...)
+	for i, file := range filesWithGaps(start, gaps...) {
+		createTestFile(t, tmpDir, fmt.Sprintf("IMG_%04d.JPG", i), file.DateTime)
+	}
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        10 * time.Minute,
+		Mode:         ModeRun,
+		UseEXIF:      true,
+		MinGroupSize: 5,
+		GroupBy:      GroupByAdaptive,
+	}
+
+	result, err := Split(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	groups := result.Stats.Groups
+	if len(groups) != 3 {
+		t.Fatalf("got %d groups, want 3", len(groups))
+	}
+	if want := "gap 30m > delta 10m (adaptive threshold 1m)"; groups[1].Split != want {
+		t.Errorf("Split = %q, want %q", groups[1].Split, want)
+	}
+	assertExists(t, filepath.Join(tmpDir, groups[2].Folder, "IMG_0014.JPG"))
+
+	report := NewSplitReport(cfg.Mode, result, nil)
+	if report.Split.Groups[2].Split == "" {
+		t.Error("report group should explain its split")
+	}
+}
+
+func TestConfigValidate_Adaptive(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"default ceiling", func(c *Config) { c.GroupBy = GroupByAdaptive }, false},
+		{"with day boundary", func(c *Config) { c.GroupBy = GroupByAdaptive; c.DayBoundary = "04:00" }, false},
+		{"negative ceiling", func(c *Config) { c.GroupBy = GroupByAdaptive; c.AdaptiveMaxGap = -time.Hour }, true},
+		{"ceiling below delta", func(c *Config) { c.GroupBy = GroupByAdaptive; c.AdaptiveMaxGap = 10 * time.Minute }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig(t.TempDir())
+			tt.modify(cfg)
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
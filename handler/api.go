@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Progress stages reported to ProgressFunc (v2.10.0+)
+const (
+	StageScan    = "Scanning files"
+	StageGeocode = "Geocoding locations"
+	StageProcess = "Processing groups"
+	StageMove    = "Moving files" // Regroup
+)
+
+// Conflict resolutions returned by Resolver.ResolveConflict (v2.10.0+)
+const (
+	ResolveRename    = conflictRename    // Rename the source file
+	ResolveSkip      = conflictSkip      // Keep the target file, leave the source in place
+	ResolveOverwrite = conflictOverwrite // Replace the target file with the source
+	ResolveQuit      = conflictQuit      // Abort the merge
+)
+
+// Group is an event detected by the planning stage (v2.10.0+)
+type Group struct {
+	Folder   string         // Destination folder relative to BasePath (e.g., "2024 - 0615 - 1200" or "Paris/2024 - 0615 - 1200")
+	Files    []FileMetadata // Files of the event, in chronological order
+	AtRoot   bool           // Fewer files than MinGroupSize: files stay at the root (or location folder)
+	Existing bool           // Files are appended to an already organized event folder (incremental mode)
+	Root     string         // Folder the files stay in when AtRoot, instead of the root (trip day folders, v2.10.0+)
+	Split    string         // Why the group starts after the previous one, e.g. "gap 3h12 > adaptive threshold 1h05" (adaptive grouping, v2.10.0+)
+}
+
+// Plan describes where Split moves each scanned file (v2.10.0+)
+// Groups may be edited before being passed to Execute
+type Plan struct {
+	Groups []Group // Event folders first, then groups left at root
+
+	events   []eventFolder // Existing event folders (incremental mode)
+	ruleHits []RuleHit     // Files matched by each routing rule
+
+	dateConflicts []DateConflict      // Files whose dates disagree
+	inferences    []LocationInference // Files without GPS placed with a geotagged neighbour
+
+	// Files sent to screenshots/ and messaging/ folders
+	screenshots int
+	messaging   int
+}
+
+// SplitResult is the outcome of Split (v2.10.0+)
+type SplitResult struct {
+	Stats         *ProcessingStats  // Processing statistics (dryrun and run modes), nil when no media file was found
+	Validation    *ValidationReport // Validation report (validate mode only)
+	OrphanRefresh bool              // The folder was already organized: only orphan RAW files were separated
+}
+
+// ProgressEvent reports the advancement of a stage (v2.10.0+)
+type ProgressEvent struct {
+	Stage   string // StageScan, StageGeocode, StageProcess or StageMove
+	Current int    // Items done
+	Total   int    // Items in the stage
+}
+
+// ProgressFunc receives progress updates, called from the goroutine running the stage (v2.10.0+)
+type ProgressFunc func(ProgressEvent)
+
+// Resolver answers the questions picsplit would otherwise ask on the terminal (v2.10.0+)
+type Resolver interface {
+	// ConfirmCleanup is asked before removing empty directories, unless Force is set
+	ConfirmCleanup(dirs []string) bool
+
+	// ResolveConflict chooses what to do when a merged file already exists in the target:
+	// ResolveRename, ResolveSkip, ResolveOverwrite or ResolveQuit
+	// applyToAll reuses the answer for the remaining conflicts
+	ResolveConflict(conflict *FileConflict) (resolution string, applyToAll bool, err error)
+}
+
+// terminalResolver asks the user on stdin (default Resolver)
+type terminalResolver struct{}
+
+func (terminalResolver) ConfirmCleanup(dirs []string) bool {
+	return askConfirmation(dirs)
+}
+
+func (terminalResolver) ResolveConflict(conflict *FileConflict) (string, bool, error) {
+	return askUserConflictResolution(conflict)
+}
+
+// Scan collects the media files of cfg.BasePath with their metadata (first stage of Split)
+func Scan(runCtx context.Context, cfg *Config) ([]FileMetadata, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	ctx, err := newExecutionContext(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize extension context: %w", err)
+	}
+
+	return collectMediaFilesWithMetadata(runCtx, cfg, ctx)
+}
+
+// BuildPlan groups scanned files into events (second stage of Split)
+// In incremental mode, groups close to an existing event folder are appended to it
+func BuildPlan(runCtx context.Context, cfg *Config, files []FileMetadata) (*Plan, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	ctx, err := newExecutionContext(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize extension context: %w", err)
+	}
+
+	// Grouping sorts files: keep the caller's slice untouched
+	return buildPlan(runCtx, cfg, ctx, append([]FileMetadata(nil), files...))
+}
+
+// Execute moves files according to plan (last stage of Split)
+// cfg.Mode must be dryrun or run
+func Execute(runCtx context.Context, cfg *Config, plan *Plan) (*ProcessingStats, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if cfg.Mode != ModeDryRun && cfg.Mode != ModeRun {
+		return nil, fmt.Errorf("execute requires dryrun or run mode, got %s", cfg.Mode)
+	}
+
+	ctx, err := newExecutionContext(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize extension context: %w", err)
+	}
+
+	stats, err := executePlan(runCtx, cfg, ctx, plan)
+	cfg.Metrics.ObserveRun(cfg.Mode, stats, err)
+	return stats, err
+}
+
+// logger returns the injected logger, or the default one
+func (c *Config) logger() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return slog.Default()
+}
+
+// resolver returns the injected resolver, or terminal prompts
+func (c *Config) resolver() Resolver {
+	if c.Resolver != nil {
+		return c.Resolver
+	}
+	return terminalResolver{}
+}
+
+// fileSystem returns the injected file system, or the local one
+func (c *Config) fileSystem() FileSystem {
+	if c.FS != nil {
+		return c.FS
+	}
+	return OSFileSystem{}
+}
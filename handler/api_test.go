@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubResolver answers every question with fixed values and records the calls
+type stubResolver struct {
+	confirm    bool
+	resolution string
+	cleanups   int
+	conflicts  int
+}
+
+func (r *stubResolver) ConfirmCleanup(_ []string) bool {
+	r.cleanups++
+	return r.confirm
+}
+
+func (r *stubResolver) ResolveConflict(_ *FileConflict) (string, bool, error) {
+	r.conflicts++
+	return r.resolution, false, nil
+}
+
+func TestScanBuildPlanExecute(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+
+	createTestFile(t, tmpDir, "IMG_0001.JPG", baseTime)
+	createTestFile(t, tmpDir, "IMG_0002.JPG", baseTime.Add(5*time.Minute))
+	createTestFile(t, tmpDir, "IMG_0003.JPG", baseTime.Add(3*time.Hour))
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		MinGroupSize: 2,
+	}
+	runCtx := context.Background()
+
+	files, err := Scan(runCtx, cfg)
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Scan() returned %d files, want 3", len(files))
+	}
+
+	plan, err := BuildPlan(runCtx, cfg, files)
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %v", err)
+	}
+	if len(plan.Groups) != 2 {
+		t.Fatalf("BuildPlan() returned %d groups, want 2", len(plan.Groups))
+	}
+	if plan.Groups[0].AtRoot || len(plan.Groups[0].Files) != 2 {
+		t.Errorf("first group = %+v, want an event folder with 2 files", plan.Groups[0])
+	}
+	if !plan.Groups[1].AtRoot {
+		t.Errorf("second group should stay at root (below MinGroupSize)")
+	}
+
+	// Callers may edit the plan before executing it
+	plan.Groups[0].Folder = "Picnic"
+
+	stats, err := Execute(runCtx, cfg, plan)
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+
+	assertExists(t, filepath.Join(tmpDir, "Picnic", "IMG_0001.JPG"))
+	assertExists(t, filepath.Join(tmpDir, "Picnic", "IMG_0002.JPG"))
+	assertExists(t, filepath.Join(tmpDir, "IMG_0003.JPG"))
+
+	if stats.TotalFiles != 3 || stats.ProcessedFiles != 3 || stats.GroupsCreated != 1 || stats.SmallGroupsCount != 1 {
+		t.Errorf("stats = total %d, processed %d, groups %d, small groups %d, want 3, 3, 1, 1",
+			stats.TotalFiles, stats.ProcessedFiles, stats.GroupsCreated, stats.SmallGroupsCount)
+	}
+}
+
+func TestExecute_RequiresDryRunOrRun(t *testing.T) {
+	cfg := &Config{BasePath: t.TempDir(), Delta: time.Hour, Mode: ModeValidate}
+
+	if _, err := Execute(context.Background(), cfg, &Plan{}); err == nil {
+		t.Error("Execute() should fail in validate mode")
+	}
+}
+
+func TestSplit_ReturnsResult(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	createTestFile(t, tmpDir, "IMG_0001.JPG", baseTime)
+
+	cfg := &Config{BasePath: tmpDir, Delta: time.Hour, Mode: ModeDryRun, MinGroupSize: 1}
+
+	result, err := Split(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+	if result.Stats == nil || result.Stats.ProcessedFiles != 1 {
+		t.Errorf("Split() stats = %+v, want 1 processed file", result.Stats)
+	}
+
+	cfg.Mode = ModeValidate
+	result, err = Split(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Split() validate error: %v", err)
+	}
+	if result.Validation == nil || result.Validation.TotalFiles != 1 {
+		t.Errorf("Split() validation = %+v, want 1 file", result.Validation)
+	}
+}
+
+func TestSplit_InjectedLoggerAndProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	createTestFile(t, tmpDir, "IMG_0001.JPG", baseTime)
+	createTestFile(t, tmpDir, "IMG_0002.JPG", baseTime.Add(2*time.Hour))
+
+	var logs bytes.Buffer
+	var events []ProgressEvent
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        time.Hour,
+		Mode:         ModeRun,
+		MinGroupSize: 1,
+		Logger:       slog.New(slog.NewTextHandler(&logs, nil)),
+		Progress:     func(event ProgressEvent) { events = append(events, event) },
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	if !strings.Contains(logs.String(), "moving file") {
+		t.Errorf("injected logger did not receive file moves:\n%s", logs.String())
+	}
+
+	want := []ProgressEvent{
+		{StageScan, 1, 2},
+		{StageScan, 2, 2},
+		{StageProcess, 1, 2},
+		{StageProcess, 2, 2},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("progress events = %v, want %v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("progress event %d = %v, want %v", i, events[i], want[i])
+		}
+	}
+}
+
+func TestSplit_Canceled(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	createTestFile(t, tmpDir, "IMG_0001.JPG", baseTime)
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := &Config{BasePath: tmpDir, Delta: time.Hour, Mode: ModeRun, MinGroupSize: 1}
+
+	_, err := Split(runCtx, cfg)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Split() error = %v, want context.Canceled", err)
+	}
+	assertExists(t, filepath.Join(tmpDir, "IMG_0001.JPG"))
+}
+
+func TestSplit_ResolverDeclinesCleanup(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	createTestFile(t, tmpDir, "IMG_0001.JPG", baseTime)
+	if err := os.Mkdir(filepath.Join(tmpDir, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := &stubResolver{confirm: false}
+	cfg := &Config{
+		BasePath:         tmpDir,
+		Delta:            time.Hour,
+		Mode:             ModeRun,
+		MinGroupSize:     1,
+		CleanupEmptyDirs: true,
+		Resolver:         resolver,
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	if resolver.cleanups != 1 {
+		t.Errorf("ConfirmCleanup called %d times, want 1", resolver.cleanups)
+	}
+	assertExists(t, filepath.Join(tmpDir, "empty"))
+}
+
+func TestMerge_Resolver(t *testing.T) {
+	tmpDir := t.TempDir()
+	source := filepath.Join(tmpDir, "source")
+	target := filepath.Join(tmpDir, "target")
+	createTestFileInDir(t, source, "photo.jpg", "source")
+	createTestFileInDir(t, target, "photo.jpg", "target")
+
+	resolver := &stubResolver{resolution: ResolveRename}
+	cfg := &MergeConfig{
+		SourceFolders: []string{source},
+		TargetFolder:  target,
+		Mode:          ModeRun,
+		Resolver:      resolver,
+	}
+
+	if err := Merge(context.Background(), cfg); err != nil {
+		t.Fatalf("Merge() error: %v", err)
+	}
+
+	if resolver.conflicts != 1 {
+		t.Errorf("ResolveConflict called %d times, want 1", resolver.conflicts)
+	}
+	assertExists(t, filepath.Join(target, "photo.jpg"))
+	assertExists(t, filepath.Join(target, "photo_1.jpg"))
+}
@@ -0,0 +1,22 @@
+//go:build darwin || freebsd || netbsd
+
+package handler
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// birthTime reads the creation time of a file from its stat
+func birthTime(name string) (time.Time, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, errNoBirthTime
+	}
+	return time.Unix(stat.Birthtimespec.Unix()), nil
+}
@@ -0,0 +1,22 @@
+//go:build linux
+
+package handler
+
+import (
+	"io/fs"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// birthTime reads the creation time of a file with statx, only filled by file systems recording it (ext4, btrfs, xfs)
+func birthTime(name string) (time.Time, error) {
+	var stx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, name, unix.AT_STATX_SYNC_AS_STAT, unix.STATX_BTIME, &stx); err != nil {
+		return time.Time{}, &fs.PathError{Op: "statx", Path: name, Err: err}
+	}
+	if stx.Mask&unix.STATX_BTIME == 0 {
+		return time.Time{}, errNoBirthTime
+	}
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)), nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !windows
+
+package handler
+
+import "time"
+
+// birthTime is not available on this platform
+func birthTime(string) (time.Time, error) {
+	return time.Time{}, errNoBirthTime
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package handler
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// birthTime reads the creation time of a file from its attributes
+func birthTime(name string) (time.Time, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, errNoBirthTime
+	}
+	return time.Unix(0, data.CreationTime.Nanoseconds()), nil
+}
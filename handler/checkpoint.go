@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// checkpointName is the resume checkpoint written in the source folder when a run is interrupted (v2.10.0+)
+	checkpointName    = ".picsplit-checkpoint.json"
+	checkpointVersion = 1
+)
+
+// checkpoint is the on-disk format of the resume checkpoint
+// It keeps the remaining part of the plan so a resumed run puts files in the folders
+// chosen by the interrupted one, even if regrouping what is left would name them differently
+// Names chosen before the interruption are kept too, so the RAW of a renamed JPEG keeps its name,
+// and so are the files already moved, so their duplicates left in the source are still detected
+type checkpoint struct {
+	CreatedAt    time.Time         `json:"created_at"`
+	Groups       []checkpointGroup `json:"groups"`
+	RenamedBases map[string]string `json:"renamed_bases,omitempty"` // Source basename -> destination basename
+	RenameSeq    map[string]int    `json:"rename_seq,omitempty"`    // Last {seq} number per destination folder
+	Placed       []string          `json:"placed,omitempty"`        // Files moved before the interruption, relative to BasePath
+	Version      int               `json:"version"`
+}
+
+// checkpointGroup is a group of the plan with the files not moved yet
+type checkpointGroup struct {
+	Folder   string   `json:"folder"`
+	Files    []string `json:"files"` // File names in the source folder
+	AtRoot   bool     `json:"at_root,omitempty"`
+	Existing bool     `json:"existing,omitempty"`
+	Root     string   `json:"root,omitempty"`
+}
+
+// writeCheckpoint saves the groups not processed yet; files already moved are left out
+// Returns the checkpoint path, or "" when nothing is left to resume
+func writeCheckpoint(cfg *Config, ctx *executionContext, groups []Group) (string, error) {
+	cp := checkpoint{Version: checkpointVersion, CreatedAt: time.Now()}
+
+	for _, group := range groups {
+		saved := checkpointGroup{Folder: group.Folder, AtRoot: group.AtRoot, Existing: group.Existing, Root: group.Root}
+		for _, file := range group.Files {
+			name := file.FileInfo.Name()
+			if fileExists(ctx.fs, filepath.Join(cfg.BasePath, name)) {
+				saved.Files = append(saved.Files, name)
+			}
+		}
+		if len(saved.Files) > 0 {
+			cp.Groups = append(cp.Groups, saved)
+		}
+	}
+
+	if len(cp.Groups) == 0 {
+		removeCheckpoint(cfg, ctx)
+		return "", nil
+	}
+
+	// Basenames are keyed by source path: saved relative to BasePath
+	if len(ctx.renamedBases) > 0 {
+		cp.RenamedBases = make(map[string]string, len(ctx.renamedBases))
+		for key, base := range ctx.renamedBases {
+			if rel, err := filepath.Rel(cfg.BasePath, key); err == nil {
+				cp.RenamedBases[rel] = base
+			}
+		}
+	}
+	if len(ctx.renameSeq) > 0 {
+		cp.RenameSeq = ctx.renameSeq
+	}
+	cp.Placed = ctx.placed
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	path := filepath.Join(cfg.BasePath, checkpointName)
+	if err := ctx.fs.WriteFile(path, data, 0644); err != nil { //nolint:gosec // checkpoint is meant to be readable
+		return "", fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return path, nil
+}
+
+// loadCheckpointPlan rebuilds the plan of an interrupted run from its checkpoint
+// Files that disappeared since the interruption are skipped
+func loadCheckpointPlan(cfg *Config, ctx *executionContext) (*Plan, error) {
+	path := filepath.Join(cfg.BasePath, checkpointName)
+	data, err := readFile(ctx.fs, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, &PicsplitError{Type: ErrTypeValidation, Op: "resume", Path: path, Err: ErrNoCheckpoint}
+		}
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %d in %s", cp.Version, path)
+	}
+
+	// Names chosen before the interruption, before any file of the rest is placed
+	for rel, base := range cp.RenamedBases {
+		ctx.assignBase(filepath.Join(cfg.BasePath, rel), base)
+	}
+	for folder, seq := range cp.RenameSeq {
+		if ctx.renameSeq == nil {
+			ctx.renameSeq = make(map[string]int)
+		}
+		ctx.renameSeq[folder] = seq
+	}
+	ctx.placed = cp.Placed
+
+	plan := &Plan{}
+	var missing int
+	for _, saved := range cp.Groups {
+		group := Group{Folder: saved.Folder, AtRoot: saved.AtRoot, Existing: saved.Existing, Root: saved.Root}
+		for _, name := range saved.Files {
+			metadata, err := groupingMetadata(cfg, ctx, filepath.Join(cfg.BasePath, name))
+			if err != nil {
+				ctx.log.Debug("checkpoint file no longer available", "file", name, "error", err)
+				missing++
+				continue
+			}
+			group.Files = append(group.Files, *metadata)
+		}
+		if len(group.Files) > 0 {
+			plan.Groups = append(plan.Groups, group)
+		}
+	}
+
+	if missing > 0 {
+		ctx.log.Warn("files of the checkpoint are missing, skipping them", "count", missing)
+	}
+	ctx.log.Info("resuming interrupted run",
+		"checkpoint", path,
+		"interrupted_at", cp.CreatedAt.Format(time.RFC3339),
+		"groups", len(plan.Groups))
+
+	if cfg.Incremental {
+		plan.events = scanEventFolders(cfg, ctx)
+	}
+
+	return plan, nil
+}
+
+// hasCheckpoint checks if an interrupted run left a checkpoint in the source folder
+func hasCheckpoint(cfg *Config, ctx *executionContext) bool {
+	return fileExists(ctx.fs, filepath.Join(cfg.BasePath, checkpointName))
+}
+
+// removeCheckpoint deletes the checkpoint once the run it describes is complete
+func removeCheckpoint(cfg *Config, ctx *executionContext) {
+	path := filepath.Join(cfg.BasePath, checkpointName)
+	if err := ctx.fs.Remove(path); err != nil && !os.IsNotExist(err) {
+		ctx.log.Warn("failed to remove checkpoint", "path", path, "error", err)
+	}
+}
@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExecute_InterruptedThenResumed(t *testing.T) {
+	fsys := NewMemFileSystem()
+	morning := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
+	evening := time.Date(2024, 6, 15, 20, 0, 0, 0, time.Local)
+	writeMemFile(t, fsys, "/photos/a.jpg", []byte("a"), morning)
+	writeMemFile(t, fsys, "/photos/b.jpg", []byte("b"), morning.Add(5*time.Minute))
+	writeMemFile(t, fsys, "/photos/c.jpg", []byte("c"), evening)
+
+	cfg := &Config{
+		BasePath:     "/photos",
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		MinGroupSize: 1,
+		FS:           fsys,
+	}
+
+	files, err := Scan(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	plan, err := BuildPlan(context.Background(), cfg, files)
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %v", err)
+	}
+
+	// Canceled before the first group: everything goes to the checkpoint
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	stats, err := Execute(canceled, cfg, plan)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Execute() error = %v, want context.Canceled", err)
+	}
+	if !stats.Interrupted || stats.CheckpointPath == "" {
+		t.Fatalf("stats = interrupted %v, checkpoint %q, want interrupted with checkpoint", stats.Interrupted, stats.CheckpointPath)
+	}
+	assertMemExists(t, fsys, "/photos/a.jpg")
+
+	// A new file would change the grouping; the resumed run must keep the saved plan
+	writeMemFile(t, fsys, "/photos/d.jpg", []byte("d"), morning.Add(2*time.Hour))
+
+	cfg.Resume = true
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split(resume) error: %v", err)
+	}
+
+	morningFolder := filepath.Join("/photos", morning.Format(dateFormatPattern))
+	eveningFolder := filepath.Join("/photos", evening.Format(dateFormatPattern))
+	assertMemExists(t, fsys, filepath.Join(morningFolder, "a.jpg"))
+	assertMemExists(t, fsys, filepath.Join(morningFolder, "b.jpg"))
+	assertMemExists(t, fsys, filepath.Join(eveningFolder, "c.jpg"))
+	assertMemExists(t, fsys, "/photos/d.jpg")
+	if fileExists(fsys, filepath.Join("/photos", checkpointName)) {
+		t.Error("checkpoint should be removed once the resumed run completes")
+	}
+}
+
+// cancelAfterRenameFS cancels the run once a file has been moved
+type cancelAfterRenameFS struct {
+	FileSystem
+	cancel context.CancelFunc
+}
+
+func (f cancelAfterRenameFS) Rename(oldpath, newpath string) error {
+	defer f.cancel()
+	return f.FileSystem.Rename(oldpath, newpath)
+}
+
+func TestSplit_ResumeKeepsRenamedPairs(t *testing.T) {
+	fsys := NewMemFileSystem()
+	shot := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
+	writeMemFile(t, fsys, "/photos/DSC_0001.JPG", []byte("jpeg"), shot)
+	writeMemFile(t, fsys, "/photos/DSC_0001.NEF", []byte("raw"), shot.Add(time.Second))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cfg := &Config{
+		BasePath:          "/photos",
+		Delta:             30 * time.Minute,
+		Mode:              ModeRun,
+		MinGroupSize:      1,
+		SeparateOrphanRaw: true,
+		RenameTemplate:    "{date:20060102}_{seq:3}.{ext}",
+		FS:                cancelAfterRenameFS{FileSystem: fsys, cancel: cancel},
+	}
+
+	// Interrupted once the JPEG is renamed, before its RAW
+	result, err := Split(canceled, cfg)
+	if !errors.Is(err, context.Canceled) || result.Stats.CheckpointPath == "" {
+		t.Fatalf("Split() error = %v, checkpoint %q, want an interruption with checkpoint", err, result.Stats.CheckpointPath)
+	}
+	folder := filepath.Join("/photos", shot.Format(dateFormatPattern))
+	assertMemExists(t, fsys, filepath.Join(folder, "20240615_001.jpg"))
+	assertMemExists(t, fsys, "/photos/DSC_0001.NEF")
+
+	cfg.FS = fsys
+	cfg.Resume = true
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split(resume) error: %v", err)
+	}
+
+	// The RAW keeps the name of its JPEG and stays paired
+	assertMemExists(t, fsys, filepath.Join(folder, rawFolderName, "20240615_001.nef"))
+}
+
+func TestSplit_ResumeDetectsDuplicatesOfMovedFiles(t *testing.T) {
+	fsys := NewMemFileSystem()
+	day1 := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	writeMemFile(t, fsys, "/photos/original.jpg", []byte("same content"), day1)
+	writeMemFile(t, fsys, "/photos/copy.jpg", []byte("same content"), day2)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cfg := &Config{
+		BasePath:         "/photos",
+		Delta:            30 * time.Minute,
+		Mode:             ModeRun,
+		MinGroupSize:     1,
+		DetectDuplicates: true,
+		MoveDuplicates:   true,
+		FS:               cancelAfterRenameFS{FileSystem: fsys, cancel: cancel},
+	}
+
+	// Interrupted once the original is moved, before its duplicate
+	result, err := Split(canceled, cfg)
+	if !errors.Is(err, context.Canceled) || result.Stats.CheckpointPath == "" {
+		t.Fatalf("Split() error = %v, checkpoint %q, want an interruption with checkpoint", err, result.Stats.CheckpointPath)
+	}
+	assertMemExists(t, fsys, filepath.Join("/photos", day1.Format(dateFormatPattern), "original.jpg"))
+	assertMemExists(t, fsys, "/photos/copy.jpg")
+
+	cfg.FS = fsys
+	cfg.Resume = true
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split(resume) error: %v", err)
+	}
+
+	// The copy is still recognized as a duplicate of the file moved before the interruption
+	assertMemExists(t, fsys, filepath.Join("/photos", duplicatesFolderName, "copy.jpg"))
+	if _, err := fsys.Stat(filepath.Join("/photos", day2.Format(dateFormatPattern), "copy.jpg")); err == nil {
+		t.Error("duplicate was moved into its event folder")
+	}
+}
+
+func TestSplit_ResumeWithoutCheckpoint(t *testing.T) {
+	fsys := NewMemFileSystem()
+	writeMemFile(t, fsys, "/photos/a.jpg", []byte("a"), time.Now())
+
+	cfg := &Config{
+		BasePath:     "/photos",
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		MinGroupSize: 1,
+		Resume:       true,
+		FS:           fsys,
+	}
+
+	_, err := Split(context.Background(), cfg)
+	if !errors.Is(err, ErrNoCheckpoint) {
+		t.Errorf("Split() error = %v, want ErrNoCheckpoint", err)
+	}
+	assertMemExists(t, fsys, "/photos/a.jpg")
+}
+
+func TestMerge_Interrupted(t *testing.T) {
+	fsys := NewMemFileSystem()
+	writeMemFile(t, fsys, "/lib/a/photo.jpg", []byte("a"), time.Now())
+	writeMemFile(t, fsys, "/lib/b/other.jpg", []byte("b"), time.Now())
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := &MergeConfig{
+		SourceFolders: []string{"/lib/a"},
+		TargetFolder:  "/lib/b",
+		Mode:          ModeRun,
+		OnConflict:    ResolveRename,
+		FS:            fsys,
+	}
+	if err := Merge(canceled, cfg); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Merge() error = %v, want context.Canceled", err)
+	}
+
+	// Unmoved files stay in the source so the merge can be run again
+	assertMemExists(t, fsys, "/lib/a/photo.jpg")
+	if err := Merge(context.Background(), cfg); err != nil {
+		t.Fatalf("Merge() rerun error: %v", err)
+	}
+	assertMemExists(t, fsys, "/lib/b/photo.jpg")
+}
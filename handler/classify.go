@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Screenshots and images received in messaging apps are sent to their own folders instead of
+// creating noisy events (v2.10.0+): screenshots/2024, messaging/WhatsApp/2024
+
+const (
+	screenshotsFolderName = "screenshots"
+	messagingFolderName   = "messaging"
+
+	// Folder granularity of classified files
+	ClassifyByYear  = "year"
+	ClassifyByMonth = "month"
+
+	// Media kinds found by the classifier
+	mediaKindScreenshot = "screenshot"
+	mediaKindMessaging  = "messaging"
+
+	// screenshotComment is the EXIF UserComment written by iOS on screenshots
+	screenshotComment = "Screenshot"
+)
+
+// pngSignature starts every PNG file, followed by the IHDR chunk holding the image size
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// errNotPNG is returned when a file does not start with a PNG header
+var errNotPNG = errors.New("not a PNG file")
+
+// filenamePattern recognizes a file name written by a screenshot tool or a messaging app
+type filenamePattern struct {
+	re   *regexp.Regexp
+	kind string // mediaKindScreenshot or mediaKindMessaging
+	app  string // Messaging app, used as folder name
+}
+
+// filenamePatterns are matched against the file name, case-insensitive
+// Their dates are read by the filename date patterns (filenamedate.go)
+var filenamePatterns = []filenamePattern{
+	// WhatsApp: IMG-20240615-WA0003.jpg, VID-20240615-WA0001.mp4
+	{re: regexp.MustCompile(`(?i)^(?:IMG|VID|AUD|PTT|STK|DOC)-\d{8}-WA\d+`), kind: mediaKindMessaging, app: "WhatsApp"},
+	// Signal: signal-2024-06-15-143012.jpg, signal-2024-06-15-14-30-12-123.jpg
+	{re: regexp.MustCompile(`(?i)^signal-\d{4}-\d{2}-\d{2}-\d{2}`), kind: mediaKindMessaging, app: "Signal"},
+	// Telegram Desktop: photo_2024-06-15_14-30-12.jpg, video_2024-06-15_14-30-12.mp4
+	{re: regexp.MustCompile(`(?i)^(?:photo|video)_\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}`), kind: mediaKindMessaging, app: "Telegram"},
+	// Screenshot_20240615-143012.png (Android), "Screenshot 2024-06-15 at 14.30.12.png" (macOS),
+	// "Capture d’écran 2024-06-15 à 14.30.12.png" (localized macOS names)
+	{re: regexp.MustCompile(`(?i)^(?:screenshot|screen shot|capture d.[ée]cran|bildschirmfoto|schermafbeelding|captura de pantalla)`), kind: mediaKindScreenshot},
+}
+
+// screenResolutions are the sizes of common phone, tablet and computer screens
+// A PNG without camera Make/Model at one of these sizes is a screenshot
+var screenResolutions = map[resolution]bool{
+	// iPhone
+	{1136, 640}: true, {1334, 750}: true, {1792, 828}: true, {2208, 1242}: true, {2436, 1125}: true,
+	{2532, 1170}: true, {2556, 1179}: true, {2622, 1206}: true, {2688, 1242}: true, {2778, 1284}: true,
+	{2796, 1290}: true, {2868, 1320}: true,
+	// iPad
+	{2048, 1536}: true, {2160, 1620}: true, {2224, 1668}: true, {2360, 1640}: true, {2388, 1668}: true,
+	{2732, 2048}: true,
+	// Android
+	{1280, 720}: true, {1920, 1080}: true, {2340, 1080}: true, {2400, 1080}: true, {2560, 1440}: true,
+	{3088, 1440}: true, {3120, 1440}: true, {3200, 1440}: true,
+	// Computers
+	{1366, 768}: true, {1440, 900}: true, {1536, 864}: true, {1680, 1050}: true, {2560, 1600}: true,
+	{2880, 1800}: true, {3024, 1964}: true, {3456, 2234}: true, {3840, 2160}: true, {5120, 2880}: true,
+}
+
+// classification counts the files sent to the classified folders
+type classification struct {
+	groups      []Group        // One group per destination folder, sorted by folder
+	files       []FileMetadata // Files left for event grouping
+	screenshots int
+	messaging   int
+}
+
+// classifyFiles takes screenshots and messaging app files out of event grouping
+func classifyFiles(cfg *Config, ctx *executionContext, files []FileMetadata) *classification {
+	result := &classification{}
+	if !cfg.Classify {
+		result.files = files
+		return result
+	}
+
+	byFolder := make(map[string][]FileMetadata)
+	var folders []string
+	for _, file := range files {
+		kind, app := classifyFile(&file)
+		if kind == "" {
+			result.files = append(result.files, file)
+			continue
+		}
+
+		if kind == mediaKindScreenshot {
+			result.screenshots++
+		} else {
+			result.messaging++
+		}
+
+		folder := classifiedFolder(kind, app, file.DateTime, cfg.ClassifyBy)
+		if _, ok := byFolder[folder]; !ok {
+			folders = append(folders, folder)
+		}
+		byFolder[folder] = append(byFolder[folder], file)
+		ctx.log.Debug("file classified", "file", file.FileInfo.Name(), "kind", kind, "folder", folder)
+	}
+
+	slices.Sort(folders)
+	for _, folder := range folders {
+		folderFiles := byFolder[folder]
+		sortFilesByDateTime(folderFiles)
+		result.groups = append(result.groups, Group{Folder: folder, Files: folderFiles})
+	}
+
+	if len(result.groups) > 0 {
+		ctx.log.Info("files classified",
+			"screenshots", result.screenshots,
+			"messaging", result.messaging,
+			"folders", len(result.groups))
+	}
+	return result
+}
+
+// classifyFile returns the kind of a screenshot or messaging app file, and its app; "" for other files
+// Files with camera Make or Model are camera shots whatever their name
+func classifyFile(file *FileMetadata) (kind, app string) {
+	if file.Make != "" || file.Model != "" {
+		return "", ""
+	}
+
+	name := file.FileInfo.Name()
+	if pattern := matchFilenamePattern(name); pattern != nil {
+		return pattern.kind, pattern.app
+	}
+
+	if strings.EqualFold(file.UserComment, screenshotComment) {
+		return mediaKindScreenshot, ""
+	}
+	if isPNG(name) && file.Width > 0 && screenResolutions[newResolution(file.Width, file.Height)] {
+		return mediaKindScreenshot, ""
+	}
+	return "", ""
+}
+
+// classifiedFolder returns the folder of a classified file: screenshots/2024 or messaging/WhatsApp/2024-06
+func classifiedFolder(kind, app string, date time.Time, by string) string {
+	period := date.Format("2006")
+	if by == ClassifyByMonth {
+		period = date.Format("2006-01")
+	}
+
+	if kind == mediaKindScreenshot {
+		return filepath.Join(screenshotsFolderName, period)
+	}
+	return filepath.Join(messagingFolderName, app, period)
+}
+
+// matchFilenamePattern returns the first pattern matching a file name, nil if none does
+func matchFilenamePattern(name string) *filenamePattern {
+	for i := range filenamePatterns {
+		if filenamePatterns[i].re.MatchString(name) {
+			return &filenamePatterns[i]
+		}
+	}
+	return nil
+}
+
+// isPNG checks the extension of a file name
+func isPNG(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".png")
+}
+
+// readPNGSize reads the image size from the IHDR chunk of a PNG file
+func readPNGSize(fsys FileSystem, filePath string) (width, height int, err error) {
+	f, err := fsys.Open(filePath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	// Signature (8), chunk length (4), "IHDR" (4), width (4), height (4)
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return 0, 0, errNotPNG
+	}
+	if !bytes.Equal(header[:8], pngSignature) || string(header[12:16]) != "IHDR" {
+		return 0, 0, errNotPNG
+	}
+
+	return int(binary.BigEndian.Uint32(header[16:20])), int(binary.BigEndian.Uint32(header[20:24])), nil
+}
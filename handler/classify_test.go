@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"context"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// createPNG builds the start of a PNG file: signature and IHDR chunk
+func createPNG(width, height int) []byte {
+	data := append([]byte{}, pngSignature...)
+	data = binary.BigEndian.AppendUint32(data, 13)
+	data = append(data, "IHDR"...)
+	data = binary.BigEndian.AppendUint32(data, uint32(width))
+	data = binary.BigEndian.AppendUint32(data, uint32(height))
+	data = append(data, 8, 6, 0, 0, 0)
+	return append(data, 0, 0, 0, 0) // CRC, not checked
+}
+
+func TestClassifyFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     FileMetadata
+		wantKind string
+		wantApp  string
+	}{
+		{"whatsapp", FileMetadata{FileInfo: memFileInfo{name: "IMG-20240615-WA0003.jpg"}}, mediaKindMessaging, "WhatsApp"},
+		{"telegram", FileMetadata{FileInfo: memFileInfo{name: "photo_2024-06-15_14-30-12.jpg"}}, mediaKindMessaging, "Telegram"},
+		{"signal", FileMetadata{FileInfo: memFileInfo{name: "signal-2024-06-15-143012.jpg"}}, mediaKindMessaging, "Signal"},
+		{"android screenshot", FileMetadata{FileInfo: memFileInfo{name: "Screenshot_20240615-143012.png"}}, mediaKindScreenshot, ""},
+		{"localized screenshot", FileMetadata{FileInfo: memFileInfo{name: "Capture d’écran 2024-06-15 à 14.30.12.png"}}, mediaKindScreenshot, ""},
+		{"iOS user comment", FileMetadata{FileInfo: memFileInfo{name: "IMG_0042.PNG"}, UserComment: "Screenshot"}, mediaKindScreenshot, ""},
+		{"png at screen size", FileMetadata{FileInfo: memFileInfo{name: "IMG_0042.PNG"}, Width: 1179, Height: 2556}, mediaKindScreenshot, ""},
+		{"png at other size", FileMetadata{FileInfo: memFileInfo{name: "drawing.png"}, Width: 800, Height: 600}, "", ""},
+		{"jpeg at screen size", FileMetadata{FileInfo: memFileInfo{name: "IMG_0042.JPG"}, Width: 1920, Height: 1080}, "", ""},
+		{"camera shot", FileMetadata{FileInfo: memFileInfo{name: "IMG_0042.PNG"}, Make: "Apple", Width: 1179, Height: 2556}, "", ""},
+		{"renamed camera shot", FileMetadata{FileInfo: memFileInfo{name: "IMG-20240615-WA0003.jpg"}, Model: "NIKON Z 6"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, app := classifyFile(&tt.file)
+			if kind != tt.wantKind || app != tt.wantApp {
+				t.Errorf("classifyFile() = (%q, %q), want (%q, %q)", kind, app, tt.wantKind, tt.wantApp)
+			}
+		})
+	}
+}
+
+func TestClassifiedFolder(t *testing.T) {
+	date := time.Date(2024, 6, 15, 14, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		kind, app, by string
+		want          string
+	}{
+		{mediaKindScreenshot, "", ClassifyByYear, filepath.Join("screenshots", "2024")},
+		{mediaKindScreenshot, "", ClassifyByMonth, filepath.Join("screenshots", "2024-06")},
+		{mediaKindMessaging, "WhatsApp", "", filepath.Join("messaging", "WhatsApp", "2024")},
+		{mediaKindMessaging, "Signal", ClassifyByMonth, filepath.Join("messaging", "Signal", "2024-06")},
+	}
+
+	for _, tt := range tests {
+		if got := classifiedFolder(tt.kind, tt.app, date, tt.by); got != tt.want {
+			t.Errorf("classifiedFolder(%s, %s, %s) = %q, want %q", tt.kind, tt.app, tt.by, got, tt.want)
+		}
+	}
+}
+
+func TestReadPNGSize(t *testing.T) {
+	fsys := NewMemFileSystem()
+	writeMemFile(t, fsys, "/photos/shot.png", createPNG(1179, 2556), time.Now())
+	writeMemFile(t, fsys, "/photos/fake.png", []byte("not a png at all, just text"), time.Now())
+
+	width, height, err := readPNGSize(fsys, "/photos/shot.png")
+	if err != nil || width != 1179 || height != 2556 {
+		t.Errorf("readPNGSize() = %dx%d, %v, want 1179x2556", width, height, err)
+	}
+	if _, _, err := readPNGSize(fsys, "/photos/fake.png"); err == nil {
+		t.Error("readPNGSize() should fail on a file without PNG header")
+	}
+}
+
+func TestExifUserComment(t *testing.T) {
+	utf16LE := []byte("UNICODE\x00")
+	for _, r := range "Screenshot" {
+		utf16LE = binary.LittleEndian.AppendUint16(utf16LE, uint16(r))
+	}
+
+	tests := []struct {
+		name  string
+		order byteOrder
+		value []byte
+		want  string
+	}{
+		{"ascii", binary.BigEndian, []byte("ASCII\x00\x00\x00Screenshot"), "Screenshot"},
+		{"unicode", binary.LittleEndian, utf16LE, "Screenshot"},
+		{"undefined padded", binary.BigEndian, []byte("\x00\x00\x00\x00\x00\x00\x00\x00   \x00\x00"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dirs := &exifDirs{
+				order: tt.order,
+				ifd0:  []tiffEntry{asciiEntry(0x010F, "")},
+				exif:  []tiffEntry{{tag: 0x9286, typ: 7, count: uint32(len(tt.value)), value: tt.value}},
+			}
+			x, err := decodeTIFF(dirs.encode())
+			if err != nil {
+				t.Fatalf("decodeTIFF() error: %v", err)
+			}
+			if got := exifUserComment(x); got != tt.want {
+				t.Errorf("exifUserComment() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractMetadata_FilenameDate(t *testing.T) {
+	fsys := NewMemFileSystem()
+	modTime := time.Date(2025, 1, 10, 8, 0, 0, 0, time.Local)
+	writeMemFile(t, fsys, "/photos/IMG-20240615-WA0003.jpg", []byte("no exif"), modTime)
+	writeMemFile(t, fsys, "/photos/Screenshot_20240615-143012.png", createPNG(1080, 2400), modTime)
+
+	ctx := newDefaultExecutionContext()
+	ctx.fs = fsys
+	ctx.photoExtensions = map[string]bool{".jpg": true, ".png": true}
+
+	metadata, err := ExtractMetadata(ctx, "/photos/IMG-20240615-WA0003.jpg")
+	if err != nil {
+		t.Fatalf("ExtractMetadata() error: %v", err)
+	}
+	if metadata.Source != DateSourceFilename || !metadata.DateTime.Equal(time.Date(2024, 6, 15, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("ExtractMetadata() = %v from %s, want 2024-06-15 from Filename", metadata.DateTime, metadata.Source)
+	}
+
+	metadata, err = ExtractMetadata(ctx, "/photos/Screenshot_20240615-143012.png")
+	if err != nil {
+		t.Fatalf("ExtractMetadata() error: %v", err)
+	}
+	if metadata.Width != 1080 || metadata.Height != 2400 {
+		t.Errorf("PNG size = %dx%d, want 1080x2400", metadata.Width, metadata.Height)
+	}
+	if metadata.Source != DateSourceFilename {
+		t.Errorf("Source = %s, want Filename", metadata.Source)
+	}
+}
+
+func TestSplit_Classify(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+
+	createTestFile(t, tmpDir, "IMG_0001.JPG", baseTime)
+	createTestFile(t, tmpDir, "IMG-20240615-WA0001.jpg", baseTime.Add(time.Minute))
+	createTestFile(t, tmpDir, "IMG-20240302-WA0007.jpg", baseTime)
+	createTestFile(t, tmpDir, "Screenshot_20240615-120500.png", baseTime)
+	createTestFile(t, tmpDir, "Screenshot_20230101-090000.png", baseTime)
+
+	cfg := &Config{
+		BasePath:        tmpDir,
+		Delta:           30 * time.Minute,
+		Mode:            ModeRun,
+		UseEXIF:         true,
+		MinGroupSize:    5,
+		CustomPhotoExts: []string{"png"},
+		Classify:        true,
+		ClassifyBy:      ClassifyByMonth,
+	}
+
+	result, err := Split(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	assertExists(t, filepath.Join(tmpDir, "IMG_0001.JPG")) // Small group left at root
+	assertExists(t, filepath.Join(tmpDir, "messaging", "WhatsApp", "2024-06", "IMG-20240615-WA0001.jpg"))
+	assertExists(t, filepath.Join(tmpDir, "messaging", "WhatsApp", "2024-03", "IMG-20240302-WA0007.jpg"))
+	assertExists(t, filepath.Join(tmpDir, "screenshots", "2024-06", "Screenshot_20240615-120500.png"))
+	assertExists(t, filepath.Join(tmpDir, "screenshots", "2023-01", "Screenshot_20230101-090000.png"))
+
+	if result.Stats.ScreenshotFiles != 2 || result.Stats.MessagingFiles != 2 {
+		t.Errorf("classified = %d screenshots, %d messaging, want 2, 2",
+			result.Stats.ScreenshotFiles, result.Stats.MessagingFiles)
+	}
+}
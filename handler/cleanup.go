@@ -45,6 +45,12 @@ type CleanupResult struct {
 //   - CleanupResult containing the list of removed directories and errors
 //   - error if a fatal error occurs
 func CleanupEmptyDirs(rootPath string, mode ExecutionMode, force bool, customIgnoredFiles []string) (*CleanupResult, error) {
+	return cleanupEmptyDirs(newDefaultExecutionContext(), rootPath, mode, force, customIgnoredFiles)
+}
+
+// cleanupEmptyDirs is CleanupEmptyDirs with the logger, confirmation resolver and file system of ctx
+func cleanupEmptyDirs(ctx *executionContext, rootPath string, mode ExecutionMode, force bool, customIgnoredFiles []string) (*CleanupResult, error) {
+	log := ctx.log
 	result := &CleanupResult{
 		RemovedDirs: []string{},
 		FailedDirs:  make(map[string]error),
@@ -52,7 +58,7 @@ func CleanupEmptyDirs(rootPath string, mode ExecutionMode, force bool, customIgn
 
 	// Validate mode does not perform cleanup
 	if mode == ModeValidate {
-		slog.Debug("skipping cleanup in validate mode")
+		log.Debug("skipping cleanup in validate mode")
 		return result, nil
 	}
 
@@ -61,7 +67,7 @@ func CleanupEmptyDirs(rootPath string, mode ExecutionMode, force bool, customIgn
 	allIgnoredFiles = append(allIgnoredFiles, customIgnoredFiles...)
 
 	if len(customIgnoredFiles) > 0 {
-		slog.Debug("using custom ignored files for cleanup", "files", customIgnoredFiles)
+		log.Debug("using custom ignored files for cleanup", "files", customIgnoredFiles)
 	}
 
 	// Make multiple passes to remove nested empty directories
@@ -71,9 +77,9 @@ func CleanupEmptyDirs(rootPath string, mode ExecutionMode, force bool, customIgn
 		emptyDirs := []string{}
 
 		// Collect empty directories
-		err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+		err := walkDir(ctx.fs, rootPath, func(path string, d fs.DirEntry, err error) error {
 			if err != nil {
-				slog.Warn("failed to access path during cleanup", "path", path, "error", err)
+				log.Warn("failed to access path during cleanup", "path", path, "error", err)
 				return nil // Continue walk
 			}
 
@@ -89,14 +95,14 @@ func CleanupEmptyDirs(rootPath string, mode ExecutionMode, force bool, customIgn
 
 			// Skip protected directories
 			if isProtectedDir(path) {
-				slog.Debug("skipping protected directory", "path", path)
+				log.Debug("skipping protected directory", "path", path)
 				return fs.SkipDir
 			}
 
 			// Check if empty (considering ignored files)
-			empty, err := isDirEmptyWithIgnored(path, allIgnoredFiles)
+			empty, err := isDirEmptyWithIgnored(ctx.fs, path, allIgnoredFiles)
 			if err != nil {
-				slog.Warn("failed to check if directory is empty", "path", path, "error", err)
+				log.Warn("failed to check if directory is empty", "path", path, "error", err)
 				result.FailedDirs[path] = err
 				return nil // Continue walk
 			}
@@ -119,8 +125,8 @@ func CleanupEmptyDirs(rootPath string, mode ExecutionMode, force bool, customIgn
 
 		// In Run mode without force, ask confirmation on first pass
 		if mode == ModeRun && !force && pass == 0 {
-			if !askConfirmation(emptyDirs) {
-				slog.Info("cleanup cancelled by user")
+			if !ctx.resolver.ConfirmCleanup(emptyDirs) {
+				log.Info("cleanup cancelled by user")
 				return result, nil
 			}
 		}
@@ -132,34 +138,34 @@ func CleanupEmptyDirs(rootPath string, mode ExecutionMode, force bool, customIgn
 			dir := emptyDirs[i]
 
 			// Re-check if empty (may have changed during this pass)
-			empty, err := isDirEmptyWithIgnored(dir, allIgnoredFiles)
+			empty, err := isDirEmptyWithIgnored(ctx.fs, dir, allIgnoredFiles)
 			if err != nil {
-				slog.Warn("failed to re-check if directory is empty", "path", dir, "error", err)
+				log.Warn("failed to re-check if directory is empty", "path", dir, "error", err)
 				result.FailedDirs[dir] = err
 				continue
 			}
 
 			if !empty {
-				slog.Debug("directory no longer empty, skipping", "path", dir)
+				log.Debug("directory no longer empty, skipping", "path", dir)
 				continue
 			}
 
 			if mode == ModeDryRun {
-				slog.Info("would remove empty directory", "path", dir)
+				log.Info("would remove empty directory", "path", dir)
 				result.RemovedDirs = append(result.RemovedDirs, dir)
 				removedInPass++
 			} else {
 				// First remove ignored files in directory
-				if err := removeIgnoredFiles(dir, allIgnoredFiles); err != nil {
-					slog.Warn("failed to remove ignored files", "path", dir, "error", err)
+				if err := removeIgnoredFiles(ctx.fs, dir, allIgnoredFiles); err != nil {
+					log.Warn("failed to remove ignored files", "path", dir, "error", err)
 				}
 
 				// Then remove empty directory
-				if err := os.Remove(dir); err != nil {
-					slog.Warn("failed to remove empty directory", "path", dir, "error", err)
+				if err := ctx.fs.Remove(dir); err != nil {
+					log.Warn("failed to remove empty directory", "path", dir, "error", err)
 					result.FailedDirs[dir] = err
 				} else {
-					slog.Info("removed empty directory", "path", dir)
+					log.Info("removed empty directory", "path", dir)
 					result.RemovedDirs = append(result.RemovedDirs, dir)
 					removedInPass++
 				}
@@ -183,12 +189,12 @@ func CleanupEmptyDirs(rootPath string, mode ExecutionMode, force bool, customIgn
 // isDirEmpty checks if a directory is empty
 // Ignores default system files (.DS_Store, Thumbs.db, etc.)
 func isDirEmpty(path string) (bool, error) {
-	return isDirEmptyWithIgnored(path, ignoredFiles)
+	return isDirEmptyWithIgnored(OSFileSystem{}, path, ignoredFiles)
 }
 
 // isDirEmptyWithIgnored checks if a directory is empty ignoring certain files
-func isDirEmptyWithIgnored(path string, ignoredFilesList []string) (bool, error) {
-	entries, err := os.ReadDir(path)
+func isDirEmptyWithIgnored(fsys FileSystem, path string, ignoredFilesList []string) (bool, error) {
+	entries, err := fsys.ReadDir(path)
 	if err != nil {
 		return false, fmt.Errorf("failed to read directory: %w", err)
 	}
@@ -207,7 +213,11 @@ func isDirEmptyWithIgnored(path string, ignoredFilesList []string) (bool, error)
 }
 
 // isIgnoredFile checks if a file should be ignored
+// Contact sheets are always ignored: a folder holding only its sheet is empty (v2.10.0+)
 func isIgnoredFile(name string, ignoredFilesList []string) bool {
+	if isContactSheet(name) {
+		return true
+	}
 	for _, ignored := range ignoredFilesList {
 		if name == ignored {
 			return true
@@ -217,8 +227,8 @@ func isIgnoredFile(name string, ignoredFilesList []string) bool {
 }
 
 // removeIgnoredFiles removes all ignored files from a directory
-func removeIgnoredFiles(dirPath string, ignoredFilesList []string) error {
-	entries, err := os.ReadDir(dirPath)
+func removeIgnoredFiles(fsys FileSystem, dirPath string, ignoredFilesList []string) error {
+	entries, err := fsys.ReadDir(dirPath)
 	if err != nil {
 		return fmt.Errorf("failed to read directory: %w", err)
 	}
@@ -232,7 +242,7 @@ func removeIgnoredFiles(dirPath string, ignoredFilesList []string) error {
 		// Check if file should be removed (is ignored)
 		if isIgnoredFile(entry.Name(), ignoredFilesList) {
 			filePath := filepath.Join(dirPath, entry.Name())
-			if err := os.Remove(filePath); err != nil {
+			if err := fsys.Remove(filePath); err != nil {
 				slog.Debug("failed to remove ignored file", "path", filePath, "error", err)
 				// Continue anyway, not critical
 			} else {
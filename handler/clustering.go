@@ -31,7 +31,7 @@ func ClusterByLocation(files []FileMetadata, radiusMeters float64) ([]LocationCl
 	}
 
 	if len(filesWithGPS) == 0 {
-		slog.Warn("GPS clustering disabled: no files with GPS coordinates",
+		slog.Debug("GPS clustering disabled: no files with GPS coordinates",
 			"total_files", len(files))
 		return nil, filesWithoutGPS
 	}
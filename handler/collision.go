@@ -0,0 +1,309 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// conflictFail aborts the move when the destination name is taken (Split only, v2.10.0+)
+	conflictFail = "fail"
+)
+
+// errCollisionSkipped signals that a file was left in place because its destination name was taken
+var errCollisionSkipped = errors.New("destination exists, file left in place")
+
+// collisionStats counts how destination name collisions were resolved during a Split run
+type collisionStats struct {
+	renamed     int
+	skipped     int
+	identical   int
+	overwritten int
+}
+
+// collisionPolicy returns the configured collision policy (rename when unset)
+func (c *Config) collisionPolicy() string {
+	if c.OnCollision == "" {
+		return conflictRename
+	}
+	return c.OnCollision
+}
+
+// placeFile moves a file from cfg.BasePath into destDir (relative to cfg.BasePath)
+// The file is renamed with the rename template if any, name collisions are resolved
+// with cfg.OnCollision and sidecars follow the file
+// Returns errCollisionSkipped when the file is left in the source folder
+func placeFile(cfg *Config, ctx *executionContext, file FileMetadata, destDir string) error {
+	dryRun := cfg.Mode == ModeDryRun
+	fileName := file.FileInfo.Name()
+
+	destName, err := resolveDestName(cfg, ctx, file, destDir)
+	if err != nil {
+		return err
+	}
+
+	if err := moveFileAs(ctx, cfg.BasePath, fileName, destDir, destName, dryRun); err != nil {
+		return err
+	}
+	ctx.recordRename(fileName, destDir, destName)
+	if !dryRun && destDir != duplicatesFolderName {
+		ctx.placed = append(ctx.placed, filepath.Join(destDir, destName))
+	}
+
+	moveSidecars(cfg, ctx, fileName, destDir, destName)
+	return nil
+}
+
+// resolveDestName returns the name a file must take inside destDir
+// The first file of a basename family (RAW, JPEG, sidecars) decides the destination
+// basename; other members reuse it so pairs still match after a rename
+func resolveDestName(cfg *Config, ctx *executionContext, file FileMetadata, destDir string) (string, error) {
+	fileName := file.FileInfo.Name()
+	ext := filepath.Ext(fileName)
+	base := strings.TrimSuffix(fileName, ext)
+	key := filepath.Join(cfg.BasePath, base)
+	policy := cfg.collisionPolicy()
+
+	destExt := ext
+	if ctx.renamer != nil {
+		destExt = renameExtension(fileName)
+	}
+
+	destBase, locked := ctx.assignedBase(key)
+	if !locked {
+		destBase = base
+		if ctx.renamer != nil {
+			destBase = ctx.templateBasename(file, cfg.BasePath, destDir)
+		}
+	}
+	destName := destBase + destExt
+
+	srcPath := filepath.Join(cfg.BasePath, fileName)
+	dstPath := filepath.Join(cfg.BasePath, destDir, destName)
+
+	// File already in place (small group at root)
+	if srcPath == dstPath {
+		ctx.assignBase(key, destBase)
+		return destName, nil
+	}
+
+	_, err := ctx.fs.Stat(dstPath)
+	switch {
+	case err == nil:
+		// Same content already there: nothing to move
+		if identical, _ := sameFileContent(ctx.fs, srcPath, dstPath); identical {
+			ctx.collisions.identical++
+			ctx.log.Info("identical file already at destination, skipping", "file", fileName, "dest", dstPath)
+			return "", errCollisionSkipped
+		}
+
+		switch policy {
+		case conflictSkip:
+			ctx.collisions.skipped++
+			ctx.log.Warn("destination exists, skipping file", "file", fileName, "dest", dstPath)
+			return "", errCollisionSkipped
+		case conflictOverwrite:
+			ctx.collisions.overwritten++
+			ctx.log.Warn("destination exists, overwriting", "file", fileName, "dest", dstPath)
+			ctx.assignBase(key, destBase)
+			return destName, nil
+		case conflictFail:
+			return "", &PicsplitError{
+				Type: ErrTypeIO,
+				Op:   "move_file",
+				Path: dstPath,
+				Err:  ErrDestinationExists,
+			}
+		case conflictRename:
+			// The family was checked when its first member was placed: renaming this one
+			// would split the pair, keep it in the source instead
+			if locked {
+				ctx.collisions.skipped++
+				ctx.log.Warn("destination of a paired file taken, keeping its pair name and skipping",
+					"file", fileName, "dest", dstPath)
+				return "", errCollisionSkipped
+			}
+		}
+	case !os.IsNotExist(err):
+		return "", fmt.Errorf("failed to check destination %s: %w", dstPath, err)
+	case policy != conflictRename || locked:
+		ctx.assignBase(key, destBase)
+		return destName, nil
+	default:
+		// Destination free, but another member of the family (e.g. the RAW of a JPEG)
+		// may already be taken by a different shot from another camera
+		familyDirs := collisionFamilyDirs(cfg.BasePath, destDir)
+		if !basenameTaken(ctx.fs, familyDirs, destBase) {
+			ctx.assignBase(key, destBase)
+			return destName, nil
+		}
+	}
+
+	// Rename policy: pick a basename free for the whole family
+	newBase := uniqueBasename(ctx.fs, collisionFamilyDirs(cfg.BasePath, destDir), destBase)
+	ctx.assignBase(key, newBase)
+	ctx.collisions.renamed++
+	ctx.log.Info("destination name taken, renaming", "file", fileName, "new_name", newBase+destExt, "dest", destDir)
+
+	return newBase + destExt, nil
+}
+
+// collisionFamilyDirs returns the group folder and its raw/, orphan/ and mov/ subfolders
+// for a destination directory; RAW, JPEG and video of one shot are spread across them
+func collisionFamilyDirs(basePath, destDir string) []string {
+	root := filepath.Join(basePath, destDir)
+	switch filepath.Base(root) {
+	case rawFolderName, orphanFolderName, movFolderName:
+		root = filepath.Dir(root)
+	}
+
+	dirs := []string{
+		filepath.Join(root, rawFolderName),
+		filepath.Join(root, orphanFolderName),
+		filepath.Join(root, movFolderName),
+	}
+
+	// The source folder holds the family itself; only its subfolders matter
+	if filepath.Clean(root) != filepath.Clean(basePath) {
+		dirs = append([]string{root}, dirs...)
+	}
+
+	return dirs
+}
+
+// basenameTaken checks whether any file named "<base>.*" exists in one of the directories
+func basenameTaken(fsys FileSystem, dirs []string, base string) bool {
+	prefix := strings.ToLower(base) + "."
+
+	for _, dir := range dirs {
+		entries, err := fsys.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasPrefix(strings.ToLower(entry.Name()), prefix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// uniqueBasename generates a basename free in all directories
+// Example: DSC_0001 -> DSC_0001_1 -> DSC_0001_2 (same scheme as generateUniqueName)
+func uniqueBasename(fsys FileSystem, dirs []string, base string) string {
+	counter := 1
+	for {
+		candidate := fmt.Sprintf("%s_%d", base, counter)
+		if !basenameTaken(fsys, dirs, candidate) {
+			return candidate
+		}
+		counter++
+	}
+}
+
+// sameFileContent reports whether two files have the same size and SHA256 hash
+func sameFileContent(fsys FileSystem, pathA, pathB string) (bool, error) {
+	infoA, err := fsys.Stat(pathA)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := fsys.Stat(pathB)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	hashA, err := sha256File(fsys, pathA)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := sha256File(fsys, pathB)
+	if err != nil {
+		return false, err
+	}
+
+	return hashA == hashB, nil
+}
+
+// moveSidecars moves the sidecars of fileName (DSC_0001.xmp, DSC_0001.NEF.xmp, IMG_0001.AAE...)
+// next to it, using the destination basename chosen for the media file
+// Basename sidecars follow the RAW when the shot has one, otherwise the first file moved
+func moveSidecars(cfg *Config, ctx *executionContext, fileName, destDir, destName string) {
+	ext := filepath.Ext(fileName)
+	base := strings.TrimSuffix(fileName, ext)
+	destBase := strings.TrimSuffix(destName, filepath.Ext(destName))
+
+	followBase := ctx.isRaw(fileName) || !hasRawSibling(ctx, cfg.BasePath, base)
+
+	for _, sidecarExt := range ctx.sidecarExtensionVariants() {
+		// source sidecar name -> destination sidecar name
+		candidates := [][2]string{{fileName + sidecarExt, destName + sidecarExt}}
+		if followBase {
+			candidates = append(candidates, [2]string{base + sidecarExt, destBase + sidecarExt})
+		}
+
+		for _, candidate := range candidates {
+			sidecar, newName := candidate[0], candidate[1]
+			info, err := ctx.fs.Stat(filepath.Join(cfg.BasePath, sidecar))
+			if err != nil || info.IsDir() {
+				continue
+			}
+
+			dstPath := filepath.Join(cfg.BasePath, destDir, newName)
+			if _, err := ctx.fs.Stat(dstPath); err == nil && cfg.collisionPolicy() != conflictOverwrite {
+				ctx.log.Warn("sidecar destination exists, leaving sidecar in place", "sidecar", sidecar, "dest", dstPath)
+				continue
+			}
+
+			if err := moveFileAs(ctx, cfg.BasePath, sidecar, destDir, newName, cfg.Mode == ModeDryRun); err != nil {
+				ctx.log.Warn("failed to move sidecar", "sidecar", sidecar, "error", err)
+				continue
+			}
+			ctx.recordRename(sidecar, destDir, newName)
+		}
+	}
+}
+
+// hasRawSibling checks if a RAW file with the given basename exists in dir
+func hasRawSibling(ctx *executionContext, dir, base string) bool {
+	for ext := range ctx.rawExtensions {
+		for _, variant := range []string{ext, strings.ToUpper(ext)} {
+			if _, err := ctx.fs.Stat(filepath.Join(dir, base+variant)); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sidecarExtensionVariants returns sidecar extensions in lower and upper case, sorted
+func (ctx *executionContext) sidecarExtensionVariants() []string {
+	variants := make([]string, 0, len(ctx.sidecarExtensions)*2)
+	for ext := range ctx.sidecarExtensions {
+		variants = append(variants, ext, strings.ToUpper(ext))
+	}
+	sort.Strings(variants)
+	return variants
+}
+
+// assignedBase returns the destination basename already chosen for a source basename
+func (ctx *executionContext) assignedBase(key string) (string, bool) {
+	base, ok := ctx.renamedBases[key]
+	return base, ok
+}
+
+// assignBase records the destination basename chosen for a source basename
+func (ctx *executionContext) assignBase(key, base string) {
+	if ctx.renamedBases == nil {
+		ctx.renamedBases = make(map[string]string)
+	}
+	ctx.renamedBases[key] = base
+}
@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestFile writes content to dir/name, creating parent folders
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create folder for %s: %v", name, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+// statMetadata returns ModTime-based metadata for dir/name
+func statMetadata(t *testing.T, dir, name string) FileMetadata {
+	t.Helper()
+
+	fi, err := os.Stat(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("failed to stat %s: %v", name, err)
+	}
+	return fileInfoToMetadata(fi)
+}
+
+func assertExists(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected %s to exist: %v", path, err)
+	}
+}
+
+func assertNotExists(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected %s not to exist", path)
+	}
+}
+
+func TestPlaceFile_NoCollision(t *testing.T) {
+	tmpDir := t.TempDir()
+	group := "2024 - 0615 - 1200"
+	writeTestFile(t, tmpDir, "DSC_0001.JPG", "camera A")
+	writeTestFile(t, tmpDir, "DSC_0001.xmp", "sidecar")
+
+	cfg := &Config{BasePath: tmpDir, Mode: ModeRun}
+	ctx := newDefaultExecutionContext()
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, group), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := placeFile(cfg, ctx, statMetadata(t, tmpDir, "DSC_0001.JPG"), group); err != nil {
+		t.Fatalf("placeFile() error: %v", err)
+	}
+
+	assertExists(t, filepath.Join(tmpDir, group, "DSC_0001.JPG"))
+	assertExists(t, filepath.Join(tmpDir, group, "DSC_0001.xmp"))
+	assertNotExists(t, filepath.Join(tmpDir, "DSC_0001.xmp"))
+}
+
+func TestPlaceFile_IdenticalSkipped(t *testing.T) {
+	tmpDir := t.TempDir()
+	group := "2024 - 0615 - 1200"
+	writeTestFile(t, tmpDir, "DSC_0001.JPG", "same content")
+	writeTestFile(t, tmpDir, filepath.Join(group, "DSC_0001.JPG"), "same content")
+
+	cfg := &Config{BasePath: tmpDir, Mode: ModeRun, OnCollision: conflictOverwrite}
+	ctx := newDefaultExecutionContext()
+
+	err := placeFile(cfg, ctx, statMetadata(t, tmpDir, "DSC_0001.JPG"), group)
+	if !errors.Is(err, errCollisionSkipped) {
+		t.Fatalf("expected errCollisionSkipped, got %v", err)
+	}
+
+	assertExists(t, filepath.Join(tmpDir, "DSC_0001.JPG"))
+	if ctx.collisions.identical != 1 {
+		t.Errorf("identical = %d, want 1", ctx.collisions.identical)
+	}
+}
+
+func TestPlaceFile_Policies(t *testing.T) {
+	group := "2024 - 0615 - 1200"
+
+	tests := []struct {
+		name        string
+		policy      string
+		wantErr     error
+		wantDest    string // content expected at group/DSC_0001.JPG
+		wantRenamed bool
+	}{
+		{"rename (default)", "", nil, "camera A", true},
+		{"rename", conflictRename, nil, "camera A", true},
+		{"skip", conflictSkip, errCollisionSkipped, "camera A", false},
+		{"overwrite", conflictOverwrite, nil, "camera B", false},
+		{"fail", conflictFail, ErrDestinationExists, "camera A", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writeTestFile(t, tmpDir, filepath.Join(group, "DSC_0001.JPG"), "camera A")
+			writeTestFile(t, tmpDir, "DSC_0001.JPG", "camera B")
+
+			cfg := &Config{BasePath: tmpDir, Mode: ModeRun, OnCollision: tt.policy}
+			ctx := newDefaultExecutionContext()
+
+			err := placeFile(cfg, ctx, statMetadata(t, tmpDir, "DSC_0001.JPG"), group)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("placeFile() error: %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(tmpDir, group, "DSC_0001.JPG"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.wantDest {
+				t.Errorf("destination content = %q, want %q", content, tt.wantDest)
+			}
+
+			renamedPath := filepath.Join(tmpDir, group, "DSC_0001_1.JPG")
+			if tt.wantRenamed {
+				assertExists(t, renamedPath)
+			} else {
+				assertNotExists(t, renamedPath)
+			}
+		})
+	}
+}
+
+func TestPlaceFile_RenameKeepsPairsInSync(t *testing.T) {
+	tmpDir := t.TempDir()
+	group := "2024 - 0615 - 1200"
+
+	// Camera A already organized
+	writeTestFile(t, tmpDir, filepath.Join(group, "DSC_0001.JPG"), "camera A jpeg")
+	writeTestFile(t, tmpDir, filepath.Join(group, rawFolderName, "DSC_0001.NEF"), "camera A raw")
+
+	// Camera B: same names, different shots
+	writeTestFile(t, tmpDir, "DSC_0001.JPG", "camera B jpeg")
+	writeTestFile(t, tmpDir, "DSC_0001.NEF", "camera B raw")
+	writeTestFile(t, tmpDir, "DSC_0001.xmp", "camera B sidecar")
+	writeTestFile(t, tmpDir, "DSC_0001.JPG.xmp", "camera B jpeg sidecar")
+
+	cfg := &Config{BasePath: tmpDir, Mode: ModeRun, SeparateOrphanRaw: true}
+	ctx := newDefaultExecutionContext()
+
+	for _, name := range []string{"DSC_0001.JPG", "DSC_0001.NEF"} {
+		fi, err := os.Stat(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := processPicture(cfg, ctx, fileInfoToMetadata(fi), group); err != nil {
+			t.Fatalf("processPicture(%s) error: %v", name, err)
+		}
+	}
+
+	assertExists(t, filepath.Join(tmpDir, group, "DSC_0001_1.JPG"))
+	assertExists(t, filepath.Join(tmpDir, group, "DSC_0001_1.JPG.xmp"))
+	assertExists(t, filepath.Join(tmpDir, group, rawFolderName, "DSC_0001_1.NEF"))
+	assertExists(t, filepath.Join(tmpDir, group, rawFolderName, "DSC_0001_1.xmp"))
+	assertNotExists(t, filepath.Join(tmpDir, group, orphanFolderName))
+
+	// Originals untouched
+	content, err := os.ReadFile(filepath.Join(tmpDir, group, rawFolderName, "DSC_0001.NEF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "camera A raw" {
+		t.Errorf("existing RAW was modified: %q", content)
+	}
+
+	if ctx.collisions.renamed != 1 {
+		t.Errorf("renamed = %d, want 1 (pair renamed once)", ctx.collisions.renamed)
+	}
+}
+
+func TestPlaceFile_RawCollisionRenamesWholeFamily(t *testing.T) {
+	tmpDir := t.TempDir()
+	group := "2024 - 0615 - 1200"
+
+	// Only the RAW name is taken at destination
+	writeTestFile(t, tmpDir, filepath.Join(group, rawFolderName, "DSC_0001.NEF"), "camera A raw")
+	writeTestFile(t, tmpDir, "DSC_0001.JPG", "camera B jpeg")
+	writeTestFile(t, tmpDir, "DSC_0001.NEF", "camera B raw")
+
+	cfg := &Config{BasePath: tmpDir, Mode: ModeRun}
+	ctx := newDefaultExecutionContext()
+
+	for _, name := range []string{"DSC_0001.JPG", "DSC_0001.NEF"} {
+		fi, err := os.Stat(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := processPicture(cfg, ctx, fileInfoToMetadata(fi), group); err != nil {
+			t.Fatalf("processPicture(%s) error: %v", name, err)
+		}
+	}
+
+	assertExists(t, filepath.Join(tmpDir, group, "DSC_0001_1.JPG"))
+	assertExists(t, filepath.Join(tmpDir, group, rawFolderName, "DSC_0001_1.NEF"))
+}
+
+func TestPlaceFile_PairedRawTakenKeepsPairName(t *testing.T) {
+	tmpDir := t.TempDir()
+	group := "2024 - 0615 - 1200"
+	writeTestFile(t, tmpDir, "DSC_0001.JPG", "camera B jpeg")
+	writeTestFile(t, tmpDir, "DSC_0001.NEF", "camera B raw")
+	if err := os.MkdirAll(filepath.Join(tmpDir, group), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{BasePath: tmpDir, Mode: ModeRun}
+	ctx := newDefaultExecutionContext()
+
+	if err := processPicture(cfg, ctx, statMetadata(t, tmpDir, "DSC_0001.JPG"), group); err != nil {
+		t.Fatalf("processPicture(DSC_0001.JPG) error: %v", err)
+	}
+
+	// The RAW name gets taken after the JPEG was placed
+	writeTestFile(t, tmpDir, filepath.Join(group, rawFolderName, "DSC_0001.NEF"), "camera A raw")
+
+	err := processPicture(cfg, ctx, statMetadata(t, tmpDir, "DSC_0001.NEF"), group)
+	if !errors.Is(err, errCollisionSkipped) {
+		t.Fatalf("processPicture(DSC_0001.NEF) = %v, want errCollisionSkipped", err)
+	}
+
+	// The JPEG keeps its name and the RAW is not renamed apart from it
+	assertExists(t, filepath.Join(tmpDir, group, "DSC_0001.JPG"))
+	assertExists(t, filepath.Join(tmpDir, "DSC_0001.NEF"))
+	assertNotExists(t, filepath.Join(tmpDir, group, rawFolderName, "DSC_0001_1.NEF"))
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, group, rawFolderName, "DSC_0001.NEF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "camera A raw" {
+		t.Errorf("existing RAW was modified: %q", content)
+	}
+	if base, _ := ctx.assignedBase(filepath.Join(tmpDir, "DSC_0001")); base != "DSC_0001" {
+		t.Errorf("assigned base = %q, want DSC_0001", base)
+	}
+	if ctx.collisions.skipped != 1 || ctx.collisions.renamed != 0 {
+		t.Errorf("collisions = %+v, want 1 skipped, 0 renamed", ctx.collisions)
+	}
+}
+
+func TestSplit_OnCollisionRerun(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	group := baseTime.Format(dateFormatPattern)
+
+	// Previous run left an identical file and a different file with the same name
+	writeTestFile(t, tmpDir, filepath.Join(group, "IMG_0001.JPG"), "test content")
+	writeTestFile(t, tmpDir, filepath.Join(group, "IMG_0002.JPG"), "other shot")
+
+	createTestFile(t, tmpDir, "IMG_0001.JPG", baseTime)
+	createTestFile(t, tmpDir, "IMG_0002.JPG", baseTime.Add(time.Minute))
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        30 * time.Minute,
+		UseEXIF:      false,
+		Mode:         ModeRun,
+		MinGroupSize: 1,
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	// Identical file stays in source, different one is renamed
+	assertExists(t, filepath.Join(tmpDir, "IMG_0001.JPG"))
+	assertExists(t, filepath.Join(tmpDir, group, "IMG_0002_1.JPG"))
+	assertNotExists(t, filepath.Join(tmpDir, "IMG_0002.JPG"))
+}
+
+func TestConfig_Validate_OnCollision(t *testing.T) {
+	tests := []struct {
+		policy  string
+		wantErr bool
+	}{
+		{"", false},
+		{conflictRename, false},
+		{conflictSkip, false},
+		{conflictOverwrite, false},
+		{conflictFail, false},
+		{"ask", true},
+		{"invalid", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			cfg := DefaultConfig(t.TempDir())
+			cfg.OnCollision = tt.policy
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
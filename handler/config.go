@@ -2,6 +2,8 @@ package handler
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"time"
 )
@@ -29,6 +31,7 @@ type Config struct {
 	UseGPS          bool
 	GPSRadius       float64 // Radius in meters for GPS clustering
 	GPSUseGeocoding bool    // Use reverse geocoding for GPS location names (requires internet)
+	GPSHierarchy    bool    // Location folders as "Country/Region/City" instead of "coordinates - country - city" (v2.10.0+)
 
 	// Custom extensions (v2.5.0+)
 	// These are ADDITIVE to the default extensions
@@ -53,6 +56,64 @@ type Config struct {
 	SkipDuplicates   bool          // Skip duplicate files automatically (requires DetectDuplicates) (v2.8.0+)
 	MoveDuplicates   bool          // Move duplicates to duplicates/ subfolder (requires DetectDuplicates, mutually exclusive with SkipDuplicates) (v2.8.0+)
 	MinGroupSize     int           // Minimum group size to create folder (default: 5). Groups below threshold stay at parent root (v2.9.0+)
+
+	// Name collisions (v2.10.0+)
+	OnCollision string // Policy when the destination name is taken: rename (default), skip, overwrite, fail
+
+	// File renaming on import (v2.10.0+)
+	RenameTemplate string // Template for new file names (e.g., "{date:20060102_150405}_{camera}_{seq:4}.{ext}"), empty keeps original names
+
+	// Incremental split (v2.10.0+)
+	Incremental bool // Append new files to existing event folders when they fall within Delta of them
+
+	// Interrupted runs (v2.10.0+)
+	Resume bool // Continue the run interrupted in BasePath from its checkpoint instead of planning again
+
+	// Routing rules (v2.10.0+)
+	RulesFile string // JSON rules file routing matching files before event grouping, empty disables rules
+
+	// Dates in file names (v2.10.0+)
+	FilenameDatePatterns []string // Regexes with (?P<year>) (?P<month>) (?P<day>) and optional hour, minute, second, ampm groups, tried before the built-in ones
+
+	// Calendar grouping (v2.10.0+)
+	GroupBy        string        // Grouping strategy: gap (default, events split by Delta), adaptive, day, week, month, year
+	DayBoundary    string        // Time the calendar day starts at for adaptive/day/week/month/year grouping (e.g., "04:00"), empty is midnight
+	AdaptiveMaxGap time.Duration // Ceiling of the adaptive threshold, Delta is its floor (default: 6h)
+
+	// GPS track geotagging (v2.10.0+)
+	GPXPath     string        // GPX, KML or FIT track, or folder of tracks, positioning files without GPS
+	GPXOffset   time.Duration // Added to file dates before matching the track (camera clock error or time zone)
+	GPXMaxGap   time.Duration // Longest time between a file and the track points it is positioned from (default: 10m)
+	GPXWriteXMP bool          // Write interpolated positions to new XMP sidecars (run mode)
+
+	// Location inference (v2.10.0+)
+	GPSInferWindow time.Duration // Files without GPS join the location of the closest geotagged file within this time, 0 disables
+
+	// Trip detection (v2.10.0+)
+	Trips       bool    // Group runs of consecutive active days into "2024-06 Italy trip/<day>/<events>" folders
+	TripMinDays int     // Shortest trip in days (default: 3)
+	Home        string  // Home coordinates "lat,lon", required by Trips: days spent within HomeRadius end trips
+	HomeRadius  float64 // Home radius in meters (default: 50000)
+
+	// Date sources (v2.10.0+)
+	DatePriority          []string      // Date sources tried in order: exif, video, filename, xmp, mtime, birthtime (default: DefaultDatePriority)
+	DateConflictPolicy    string        // Date kept when the dates of a file disagree: priority (default), oldest, newest, skip
+	DateConflictThreshold time.Duration // Dates of a file further apart are reported as a conflict, 0 disables detection
+	MinValidYear          int           // Dates before this year come from unset clocks and are ignored (default: 1990)
+
+	// Screenshot and messaging app classification (v2.10.0+)
+	Classify   bool   // Send screenshots to screenshots/ and messaging app images to messaging/<app>/ instead of events
+	ClassifyBy string // Subfolder period of classified files: year (default) or month
+
+	// Contact sheets (v2.10.0+)
+	ContactSheet bool // Write a contact.jpg of photo thumbnails into each event folder created or extended
+
+	// Library integration (v2.10.0+)
+	Logger   *slog.Logger // Logger for all messages (default: slog.Default())
+	Progress ProgressFunc // Receives progress updates (default: none)
+	Resolver Resolver     // Answers cleanup confirmations (default: prompt on stdin)
+	FS       FileSystem   // Storage holding BasePath, --gpx tracks and --rules (default: local file system)
+	Metrics  *Metrics     // Collects Prometheus metrics of the runs (default: none)
 }
 
 // Validate checks if the configuration is valid
@@ -85,8 +146,113 @@ func (c *Config) Validate() error {
 		return errors.New("min-group-size must be >= 0")
 	}
 
+	if c.Resume && c.Mode == ModeValidate {
+		return errors.New("--resume requires dryrun or run mode")
+	}
+
+	switch c.OnCollision {
+	case "", conflictRename, conflictSkip, conflictOverwrite, conflictFail:
+	default:
+		return fmt.Errorf("invalid on-collision policy: %s (must be: rename, skip, overwrite, or fail)", c.OnCollision)
+	}
+
+	if c.RenameTemplate != "" {
+		if _, err := parseRenameTemplate(c.RenameTemplate); err != nil {
+			return fmt.Errorf("invalid rename template: %w", err)
+		}
+	}
+
+	switch c.GroupBy {
+	case "", GroupByGap:
+		if c.DayBoundary != "" {
+			return errors.New("--day-boundary requires --group-by adaptive, day, week, month, or year")
+		}
+	case GroupByAdaptive:
+		if c.AdaptiveMaxGap < 0 {
+			return fmt.Errorf("invalid adaptive max gap: %v (must be >= 0)", c.AdaptiveMaxGap)
+		}
+		if c.adaptiveMaxGap() < c.Delta {
+			return fmt.Errorf("adaptive max gap %v must not be below delta %v", c.adaptiveMaxGap(), c.Delta)
+		}
+	case GroupByDay, GroupByWeek, GroupByMonth, GroupByYear:
+		if c.Incremental {
+			return errors.New("--incremental requires --group-by gap (period folders are reused by name)")
+		}
+	default:
+		return fmt.Errorf("invalid group-by strategy: %s (must be: gap, adaptive, day, week, month, or year)", c.GroupBy)
+	}
+
+	if _, err := parseDayBoundary(c.DayBoundary); err != nil {
+		return err
+	}
+
+	if err := c.validateTrips(); err != nil {
+		return err
+	}
+
+	if c.GPXPath != "" {
+		if _, err := c.fileSystem().Stat(c.GPXPath); err != nil {
+			return fmt.Errorf("invalid GPS track: %w", err)
+		}
+	} else if c.GPXWriteXMP {
+		return errors.New("--gpx-write-xmp requires --gpx")
+	}
+	if c.GPXMaxGap < 0 {
+		return fmt.Errorf("invalid GPS track max gap: %v (must be >= 0)", c.GPXMaxGap)
+	}
+
+	if c.GPSHierarchy {
+		if !c.GPSUseGeocoding {
+			return errors.New("--gps-hierarchy requires --gps-geocoding")
+		}
+		if c.Incremental {
+			return errors.New("--gps-hierarchy cannot be used with --incremental")
+		}
+	}
+
+	if c.GPSInferWindow < 0 {
+		return fmt.Errorf("invalid GPS inference window: %v (must be >= 0)", c.GPSInferWindow)
+	}
+	if c.GPSInferWindow > 0 && !c.UseGPS {
+		return errors.New("--gps-infer-window requires --gps")
+	}
+
+	if _, err := newFilenameDatePatterns(c.FilenameDatePatterns); err != nil {
+		return err
+	}
+
+	if _, err := parseDatePriority(c.DatePriority); err != nil {
+		return fmt.Errorf("invalid date priority: %w", err)
+	}
+
+	switch c.DateConflictPolicy {
+	case "", DateConflictPriority, DateConflictOldest, DateConflictNewest, DateConflictSkip:
+	default:
+		return fmt.Errorf("invalid date-conflict policy: %s (must be: priority, oldest, newest, or skip)", c.DateConflictPolicy)
+	}
+
+	if c.DateConflictThreshold < 0 {
+		return errors.New("date-conflict-threshold must be >= 0")
+	}
+
+	if c.MinValidYear < 0 || c.MinValidYear > time.Now().Year() {
+		return fmt.Errorf("invalid min-valid-year: %d (must be between 0 and %d)", c.MinValidYear, time.Now().Year())
+	}
+
+	switch c.ClassifyBy {
+	case "", ClassifyByYear, ClassifyByMonth:
+	default:
+		return fmt.Errorf("invalid classify-by period: %s (must be: year or month)", c.ClassifyBy)
+	}
+
+	if c.RulesFile != "" {
+		if _, err := loadRules(c.fileSystem(), c.RulesFile); err != nil {
+			return fmt.Errorf("invalid rules: %w", err)
+		}
+	}
+
 	// Check if path exists and is a directory
-	fi, err := os.Stat(c.BasePath)
+	fi, err := c.fileSystem().Stat(c.BasePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return errors.New("path does not exist")
@@ -120,5 +286,33 @@ func DefaultConfig(basePath string) *Config {
 		DetectDuplicates:  false,                  // Detection disabled by default (v2.8.0+)
 		SkipDuplicates:    false,                  // Skip disabled by default (v2.8.0+)
 		MinGroupSize:      5,                      // Groups below 5 files stay at root by default (v2.9.0+)
+		OnCollision:       conflictRename,         // Rename on name collision by default (v2.10.0+)
+	}
+}
+
+// validateTrips validates trip detection options
+func (c *Config) validateTrips() error {
+	if !c.Trips {
+		return nil
 	}
+
+	if isCalendarGrouping(c.GroupBy) {
+		return errors.New("--trips requires --group-by gap or adaptive (trip days hold events)")
+	}
+	if c.Incremental {
+		return errors.New("--trips cannot be used with --incremental")
+	}
+	if c.TripMinDays < 0 {
+		return fmt.Errorf("invalid trip min days: %d (must be >= 0)", c.TripMinDays)
+	}
+	if c.HomeRadius < 0 {
+		return fmt.Errorf("invalid home radius: %.0f (must be >= 0)", c.HomeRadius)
+	}
+	if c.Home == "" {
+		return errors.New("--trips requires --home (trips are runs of days away from it)")
+	}
+	if _, err := parseHome(c.Home); err != nil {
+		return err
+	}
+	return nil
 }
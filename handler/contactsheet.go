@@ -0,0 +1,478 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png" // PNG photos are decoded when they have no embedded thumbnail
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// A contact sheet shows what an event folder holds without opening it: contact.jpg is a grid
+// of thumbnails of its photos (v2.10.0+)
+// Thumbnails come from the preview embedded in the file when there is one (EXIF thumbnail of
+// JPEG and HEIC, JPEG preview of RAW files), otherwise JPEG and PNG photos are decoded
+// Videos and formats without a pure Go decoder are left out of the sheet
+
+const (
+	// contactSheetName is the file written into each event folder
+	contactSheetName = "contact.jpg"
+
+	// contactSheetCell is the size of the square cell a thumbnail is fitted in, in pixels
+	contactSheetCell = 160
+
+	// contactSheetGap separates cells and surrounds the grid
+	contactSheetGap = 4
+
+	// contactSheetColumns is the width of the grid in thumbnails
+	contactSheetColumns = 8
+
+	// contactSheetMaxThumbnails bounds the sheet of large events: photos are sampled evenly
+	contactSheetMaxThumbnails = 96
+
+	// contactSheetQuality is the JPEG quality of the sheet
+	contactSheetQuality = 85
+
+	// maxPreviewSize ignores embedded previews too large to be a preview
+	maxPreviewSize = 16 << 20
+
+	// maxPreviewIFDs bounds the directories walked to find RAW previews
+	maxPreviewIFDs = 16
+
+	// thumbnailSamples is the number of source pixels averaged per side of a thumbnail pixel
+	thumbnailSamples = 4
+)
+
+// TIFF tags of the JPEG previews of RAW files
+const (
+	tagJPEGOffset = 0x0201 // JPEGInterchangeFormat
+	tagJPEGLength = 0x0202 // JPEGInterchangeFormatLength
+)
+
+// contactSheetBackground fills the gaps and the margins of the cells
+var contactSheetBackground = color.RGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xFF}
+
+// jpegPreview is a JPEG image embedded in a RAW file
+type jpegPreview struct {
+	offset int64
+	length int64
+}
+
+// isContactSheet checks if name is a contact sheet written by picsplit rather than a photo to organize
+func isContactSheet(name string) bool {
+	return strings.EqualFold(name, contactSheetName)
+}
+
+// WriteContactSheets writes a contact sheet into every event folder of an organized library (v2.10.0+)
+// Event folders are looked up at the root of cfg.BasePath and one level below (GPS location folders)
+// Existing sheets are replaced; in dryrun and validate modes, nothing is written
+func WriteContactSheets(runCtx context.Context, cfg *Config) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	ctx, err := newExecutionContext(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize extension context: %w", err)
+	}
+
+	startTime := time.Now()
+
+	folders, err := listEventFolders(ctx, cfg.BasePath)
+	if err != nil {
+		return fmt.Errorf("failed to read event folders: %w", err)
+	}
+
+	written := 0
+	for i, folder := range folders {
+		ctx.reportProgress("contact sheets", i, len(folders))
+
+		if cfg.Mode != ModeRun {
+			ctx.log.Info("[DRY RUN] would write contact sheet",
+				"folder", folder,
+				"photos", len(contactSheetPhotos(ctx, filepath.Join(cfg.BasePath, folder))))
+			continue
+		}
+
+		thumbnails, err := writeContactSheet(runCtx, ctx, filepath.Join(cfg.BasePath, folder))
+		if runCtx.Err() != nil {
+			return runCtx.Err()
+		}
+		if err != nil {
+			if !cfg.ContinueOnError {
+				return fmt.Errorf("failed to write contact sheet of %s: %w", folder, err)
+			}
+			ctx.log.Error("failed to write contact sheet, continuing", "folder", folder, "error", err)
+			continue
+		}
+		if thumbnails > 0 {
+			written++
+			ctx.log.Info("contact sheet written", "folder", folder, "thumbnails", thumbnails)
+		}
+	}
+	ctx.reportProgress("contact sheets", len(folders), len(folders))
+
+	ctx.log.Info("contact sheets completed",
+		"folders", len(folders),
+		"written", written,
+		"duration", time.Since(startTime).Round(time.Millisecond))
+
+	return nil
+}
+
+// writeContactSheet writes the contact sheet of the photos of dir and returns its number of thumbnails
+// No sheet is written when no photo has a thumbnail; photos whose thumbnail cannot be read are left out
+func writeContactSheet(runCtx context.Context, ctx *executionContext, dir string) (int, error) {
+	photos := sampleEvenly(contactSheetPhotos(ctx, dir), contactSheetMaxThumbnails)
+
+	var thumbnails []image.Image
+	for _, photo := range photos {
+		if err := runCtx.Err(); err != nil {
+			return 0, err
+		}
+
+		thumbnail, err := loadThumbnail(ctx.fs, photo)
+		if err != nil {
+			ctx.log.Debug("no thumbnail for contact sheet", "file", filepath.Base(photo), "error", err)
+			continue
+		}
+		thumbnails = append(thumbnails, thumbnail)
+	}
+	if len(thumbnails) == 0 {
+		return 0, nil
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, layoutContactSheet(thumbnails), &jpeg.Options{Quality: contactSheetQuality}); err != nil {
+		return 0, fmt.Errorf("failed to encode contact sheet: %w", err)
+	}
+	sheetPath := filepath.Join(dir, contactSheetName)
+	if err := ctx.fs.WriteFile(sheetPath, buf.Bytes(), 0644); err != nil { //nolint:gosec // contact sheet is meant to be readable
+		return 0, fmt.Errorf("failed to write %s: %w", sheetPath, err)
+	}
+
+	ctx.log.Debug("contact sheet written", "path", sheetPath, "thumbnails", len(thumbnails), "photos", len(photos))
+	return len(thumbnails), nil
+}
+
+// refreshContactSheet rewrites the contact sheet of a folder whose files changed
+// The sheet is removed when no photo is left to show, so it never shows files moved away
+func refreshContactSheet(runCtx context.Context, ctx *executionContext, dir string) (int, error) {
+	thumbnails, err := writeContactSheet(runCtx, ctx, dir)
+	if err != nil || thumbnails > 0 {
+		return thumbnails, err
+	}
+	if err := ctx.fs.Remove(filepath.Join(dir, contactSheetName)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("failed to remove contact sheet: %w", err)
+	}
+	return 0, nil
+}
+
+// hasContactSheet checks if dir holds a contact sheet, written by an earlier run
+func hasContactSheet(ctx *executionContext, dir string) bool {
+	return fileExists(ctx.fs, filepath.Join(dir, contactSheetName))
+}
+
+// contactSheetPhotos lists the photos of an event folder, sorted by name, then its orphan RAW files
+// A RAW file next to its JPEG/HEIC (--nomvraw) is left out: the pair shows once
+func contactSheetPhotos(ctx *executionContext, dir string) []string {
+	var photos []string
+	for _, subDir := range []string{dir, filepath.Join(dir, orphanFolderName)} {
+		entries, err := ctx.fs.ReadDir(subDir)
+		if err != nil {
+			continue
+		}
+
+		paired := make(map[string]bool)
+		for _, entry := range entries {
+			if !entry.IsDir() && ctx.isPhoto(entry.Name()) && !ctx.isRaw(entry.Name()) {
+				paired[strings.ToLower(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))] = true
+			}
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !ctx.isPhoto(name) || isContactSheet(name) {
+				continue
+			}
+			if ctx.isRaw(name) && paired[strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))] {
+				continue
+			}
+			photos = append(photos, filepath.Join(subDir, name))
+		}
+	}
+	return photos
+}
+
+// sampleEvenly keeps at most limit items spread over the whole list, first one included
+func sampleEvenly(items []string, limit int) []string {
+	if len(items) <= limit {
+		return items
+	}
+	sampled := make([]string, 0, limit)
+	for i := range limit {
+		sampled = append(sampled, items[i*len(items)/limit])
+	}
+	return sampled
+}
+
+// layoutContactSheet draws the thumbnails row by row, each centered in its cell
+func layoutContactSheet(thumbnails []image.Image) *image.RGBA {
+	columns := min(len(thumbnails), contactSheetColumns)
+	rows := (len(thumbnails) + columns - 1) / columns
+	sheet := image.NewRGBA(image.Rect(0, 0,
+		columns*contactSheetCell+(columns+1)*contactSheetGap,
+		rows*contactSheetCell+(rows+1)*contactSheetGap))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(contactSheetBackground), image.Point{}, draw.Src)
+
+	for i, thumbnail := range thumbnails {
+		bounds := thumbnail.Bounds()
+		x := contactSheetGap + (i%columns)*(contactSheetCell+contactSheetGap) + (contactSheetCell-bounds.Dx())/2
+		y := contactSheetGap + (i/columns)*(contactSheetCell+contactSheetGap) + (contactSheetCell-bounds.Dy())/2
+		draw.Draw(sheet, image.Rect(x, y, x+bounds.Dx(), y+bounds.Dy()), thumbnail, bounds.Min, draw.Src)
+	}
+	return sheet
+}
+
+// loadThumbnail returns the thumbnail of a photo fitted in a contact sheet cell, upright
+func loadThumbnail(fsys FileSystem, filePath string) (image.Image, error) {
+	f, err := fsys.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 16)
+	n, _ := f.ReadAt(header, 0)
+	format := rawFormat(header[:n])
+
+	// EXIF gives the orientation, and the thumbnail of JPEG and HEIC files
+	orientation := 1
+	var preview []byte
+	if x, err := decodeEXIF(fsys, filePath); err == nil {
+		orientation = exifOrientation(x)
+		preview, _ = x.JpegThumbnail()
+	}
+
+	var img image.Image
+	if len(preview) > 0 {
+		img, err = jpeg.Decode(bytes.NewReader(preview))
+	}
+	if img == nil {
+		switch {
+		case format != "":
+			img, err = decodeRAWPreview(f, format)
+		case isISOBMFF(f):
+			err = errors.New("no embedded thumbnail")
+		default:
+			if _, err = f.Seek(0, io.SeekStart); err == nil {
+				img, _, err = image.Decode(f)
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return orient(scaleDown(img, contactSheetCell), orientation), nil
+}
+
+// exifOrientation returns the EXIF orientation of a photo, 1 (upright) when missing
+func exifOrientation(x *exif.Exif) int {
+	if orientation := exifInt(x, exif.Orientation); orientation >= 1 && orientation <= 8 {
+		return orientation
+	}
+	return 1
+}
+
+// decodeRAWPreview decodes the smallest JPEG preview of a RAW file still filling a cell,
+// the largest one when all are smaller
+func decodeRAWPreview(r io.ReaderAt, format string) (image.Image, error) {
+	var previews []jpegPreview
+	switch format {
+	case "tiff":
+		previews = tiffPreviews(r)
+	case "raf":
+		header := make([]byte, 8)
+		if _, err := r.ReadAt(header, rafJPEGOffset); err != nil {
+			return nil, fmt.Errorf("failed to read RAF header: %w", err)
+		}
+		previews = []jpegPreview{{offset: int64(binary.BigEndian.Uint32(header)), length: int64(binary.BigEndian.Uint32(header[4:]))}}
+	}
+
+	var best *jpegPreview
+	var bestWidth int
+	for i, preview := range previews {
+		if preview.offset <= 0 || preview.length <= 0 || preview.length > maxPreviewSize {
+			continue
+		}
+		config, err := jpeg.DecodeConfig(io.NewSectionReader(r, preview.offset, preview.length))
+		if err != nil {
+			continue
+		}
+		width := max(config.Width, config.Height)
+		better := best == nil ||
+			(width >= contactSheetCell && (bestWidth < contactSheetCell || width < bestWidth)) ||
+			(width < contactSheetCell && bestWidth < contactSheetCell && width > bestWidth)
+		if better {
+			best, bestWidth = &previews[i], width
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no JPEG preview in %s file", format)
+	}
+
+	return jpeg.Decode(io.NewSectionReader(r, best.offset, best.length))
+}
+
+// tiffPreviews lists the JPEG previews referenced from IFD0, the next IFDs and the SubIFDs
+// of a TIFF-based RAW (thumbnail in IFD1 for CR2 and ARW, full-size preview in a SubIFD for NEF)
+func tiffPreviews(r io.ReaderAt) []jpegPreview {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil
+	}
+	var order byteOrder
+	switch string(header[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+
+	var previews []jpegPreview
+	pending := []int64{int64(order.Uint32(header[4:]))}
+	visited := make(map[int64]bool)
+	for len(pending) > 0 && len(visited) < maxPreviewIFDs {
+		offset := pending[0]
+		pending = pending[1:]
+		if offset <= 0 || visited[offset] {
+			continue
+		}
+		visited[offset] = true
+
+		entries, err := readIFD(r, 0, offset, order)
+		if err != nil {
+			continue
+		}
+		var preview jpegPreview
+		for _, entry := range entries {
+			switch entry.tag {
+			case tagJPEGOffset:
+				preview.offset = tiffLong(entry, order)
+			case tagJPEGLength:
+				preview.length = tiffLong(entry, order)
+			case tagSubIFDs:
+				for i := 0; i+4 <= len(entry.value); i += 4 {
+					pending = append(pending, int64(order.Uint32(entry.value[i:])))
+				}
+			}
+		}
+		if preview.offset > 0 && preview.length > 0 {
+			previews = append(previews, preview)
+		}
+
+		// Next IFD pointer, after the entries
+		countBytes := make([]byte, 2)
+		if _, err := r.ReadAt(countBytes, offset); err != nil {
+			continue
+		}
+		next := make([]byte, 4)
+		if _, err := r.ReadAt(next, offset+2+12*int64(order.Uint16(countBytes))); err == nil {
+			pending = append(pending, int64(order.Uint32(next)))
+		}
+	}
+	return previews
+}
+
+// tiffLong returns the value of a SHORT or LONG entry with a single value
+func tiffLong(entry tiffEntry, order byteOrder) int64 {
+	switch {
+	case entry.typ == 3 && len(entry.value) >= 2:
+		return int64(order.Uint16(entry.value))
+	case entry.typ == 4 && len(entry.value) >= 4:
+		return int64(order.Uint32(entry.value))
+	}
+	return 0
+}
+
+// scaleDown fits an image in a size x size square, averaging a grid of source pixels per pixel
+// Images already small enough are only copied
+func scaleDown(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	scale := min(1, float64(size)/float64(max(width, height)))
+	dstWidth := max(1, int(float64(width)*scale+0.5))
+	dstHeight := max(1, int(float64(height)*scale+0.5))
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := range dstHeight {
+		y0, y1 := bounds.Min.Y+y*height/dstHeight, bounds.Min.Y+max((y+1)*height/dstHeight, y*height/dstHeight+1)
+		yStep := max(1, (y1-y0)/thumbnailSamples)
+		for x := range dstWidth {
+			x0, x1 := bounds.Min.X+x*width/dstWidth, bounds.Min.X+max((x+1)*width/dstWidth, x*width/dstWidth+1)
+			xStep := max(1, (x1-x0)/thumbnailSamples)
+
+			var r, g, b, samples uint32
+			for sy := y0; sy < y1; sy += yStep {
+				for sx := x0; sx < x1; sx += xStep {
+					cr, cg, cb, _ := src.At(sx, sy).RGBA()
+					r, g, b = r+cr, g+cg, b+cb
+					samples++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / samples >> 8), //nolint:gosec // 16-bit average shifted to 8 bits
+				G: uint8(g / samples >> 8), //nolint:gosec // 16-bit average shifted to 8 bits
+				B: uint8(b / samples >> 8), //nolint:gosec // 16-bit average shifted to 8 bits
+				A: 0xFF,
+			})
+		}
+	}
+	return dst
+}
+
+// orient turns an image stored sideways or upside down upright, from its EXIF orientation
+// Mirrored orientations (2, 4, 5, 7) are only rotated: cameras do not write them
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	var dst *image.RGBA
+	var target func(x, y int) (int, int)
+	switch orientation {
+	case 3, 4: // Upside down
+		dst = image.NewRGBA(image.Rect(0, 0, width, height))
+		target = func(x, y int) (int, int) { return width - 1 - x, height - 1 - y }
+	case 5, 6: // Top of the scene on the left: rotate clockwise
+		dst = image.NewRGBA(image.Rect(0, 0, height, width))
+		target = func(x, y int) (int, int) { return height - 1 - y, x }
+	case 7, 8: // Top of the scene on the right: rotate counterclockwise
+		dst = image.NewRGBA(image.Rect(0, 0, height, width))
+		target = func(x, y int) (int, int) { return y, width - 1 - x }
+	default:
+		return src
+	}
+
+	for y := range height {
+		for x := range width {
+			tx, ty := target(x, y)
+			dst.SetRGBA(tx, ty, src.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
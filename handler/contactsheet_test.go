@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	red  = color.RGBA{R: 0xFF, A: 0xFF}
+	blue = color.RGBA{B: 0xFF, A: 0xFF}
+)
+
+// encodeTestImage encodes a plain image of the given size and color as JPEG or PNG
+func encodeTestImage(t *testing.T, width, height int, c color.RGBA, asPNG bool) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if asPNG {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// createPreviewTIFF builds a little-endian TIFF whose IFD chain references one JPEG preview per IFD,
+// IFD0 also holding the orientation: the layout of a RAW file, and of an EXIF block with a thumbnail
+// in IFD1 (nil preview in IFD0)
+func createPreviewTIFF(orientation uint16, previews ...[]byte) []byte {
+	order := binary.LittleEndian
+
+	ifdEntries := func(i int) [][3]uint32 {
+		var entries [][3]uint32
+		if i == 0 {
+			entries = append(entries, [3]uint32{0x0112, 3, uint32(orientation)})
+		}
+		if previews[i] != nil {
+			entries = append(entries, [3]uint32{tagJPEGOffset, 4, 0}, [3]uint32{tagJPEGLength, 4, uint32(len(previews[i]))})
+		}
+		return entries
+	}
+
+	offset := 8
+	for i := range previews {
+		offset += 2 + 12*len(ifdEntries(i)) + 4
+	}
+	previewOffsets := make([]int, len(previews))
+	for i, preview := range previews {
+		previewOffsets[i] = offset
+		offset += len(preview)
+	}
+
+	data := []byte{'I', 'I', 0x2A, 0x00}
+	data = order.AppendUint32(data, 8)
+	for i, preview := range previews {
+		entries := ifdEntries(i)
+		if preview != nil {
+			entries[len(entries)-2][2] = uint32(previewOffsets[i])
+		}
+		data = order.AppendUint16(data, uint16(len(entries)))
+		for _, entry := range entries {
+			data = order.AppendUint16(data, uint16(entry[0]))
+			data = order.AppendUint16(data, uint16(entry[1]))
+			data = order.AppendUint32(data, 1)
+			data = order.AppendUint32(data, entry[2])
+		}
+		next := 0
+		if i+1 < len(previews) {
+			next = len(data) + 4
+		}
+		data = order.AppendUint32(data, uint32(next))
+	}
+	for _, preview := range previews {
+		data = append(data, preview...)
+	}
+	return data
+}
+
+// createJPEGWithThumbnail builds a JPEG whose EXIF block holds a thumbnail in IFD1
+func createJPEGWithThumbnail(main, thumbnail []byte) []byte {
+	tiff := createPreviewTIFF(1, nil, thumbnail)
+
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	data = binary.BigEndian.AppendUint16(data, uint16(2+6+len(tiff)))
+	data = append(data, "Exif\x00\x00"...)
+	data = append(data, tiff...)
+	return append(data, main[2:]...) // Main image without its SOI marker
+}
+
+func TestLoadThumbnail(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   func(t *testing.T) []byte
+		width  int
+		height int
+		color  color.RGBA
+	}{
+		{
+			name:  "JPEG decoded",
+			data:  func(t *testing.T) []byte { return encodeTestImage(t, 400, 100, blue, false) },
+			width: 160, height: 40, color: blue,
+		},
+		{
+			name: "EXIF thumbnail preferred",
+			data: func(t *testing.T) []byte {
+				return createJPEGWithThumbnail(encodeTestImage(t, 320, 240, blue, false), encodeTestImage(t, 160, 120, red, false))
+			},
+			width: 160, height: 120, color: red,
+		},
+		{
+			name: "RAW preview filling a cell over the larger one",
+			data: func(t *testing.T) []byte {
+				return createPreviewTIFF(1, encodeTestImage(t, 640, 480, blue, false), encodeTestImage(t, 200, 150, red, false))
+			},
+			width: 160, height: 120, color: red,
+		},
+		{
+			name: "RAW preview rotated",
+			data: func(t *testing.T) []byte {
+				return createPreviewTIFF(6, encodeTestImage(t, 320, 240, blue, false))
+			},
+			width: 120, height: 160, color: blue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "IMG_0001")
+			if err := os.WriteFile(path, tt.data(t), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			img, err := loadThumbnail(OSFileSystem{}, path)
+			if err != nil {
+				t.Fatalf("loadThumbnail() error: %v", err)
+			}
+			if bounds := img.Bounds(); bounds.Dx() != tt.width || bounds.Dy() != tt.height {
+				t.Errorf("thumbnail is %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), tt.width, tt.height)
+			}
+			r, g, b, _ := img.At(tt.width/2, tt.height/2).RGBA()
+			if wr, wg, wb, _ := tt.color.RGBA(); absDiff(r, wr) > 0x1000 || absDiff(g, wg) > 0x1000 || absDiff(b, wb) > 0x1000 {
+				t.Errorf("thumbnail color = %v %v %v, want %v", r>>8, g>>8, b>>8, tt.color)
+			}
+		})
+	}
+}
+
+// absDiff returns the distance between two color channels
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func TestOrient(t *testing.T) {
+	// A 2x1 image, red on the left
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.SetRGBA(0, 0, red)
+	src.SetRGBA(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		width       int
+		height      int
+		redAt       image.Point
+	}{
+		{1, 2, 1, image.Point{0, 0}},
+		{3, 2, 1, image.Point{1, 0}},
+		{6, 1, 2, image.Point{0, 0}}, // Clockwise: the left goes on top
+		{8, 1, 2, image.Point{0, 1}}, // Counterclockwise: the left goes at the bottom
+	}
+
+	for _, tt := range tests {
+		dst := orient(src, tt.orientation)
+		if bounds := dst.Bounds(); bounds.Dx() != tt.width || bounds.Dy() != tt.height {
+			t.Errorf("orientation %d: got %dx%d, want %dx%d", tt.orientation, bounds.Dx(), bounds.Dy(), tt.width, tt.height)
+			continue
+		}
+		if got := dst.RGBAAt(tt.redAt.X, tt.redAt.Y); got != red {
+			t.Errorf("orientation %d: pixel at %v = %v, want red", tt.orientation, tt.redAt, got)
+		}
+	}
+}
+
+func TestWriteContactSheets(t *testing.T) {
+	tmpDir := t.TempDir()
+	event := filepath.Join(tmpDir, "Rome", "2024 - 0612 - 1000")
+	if err := os.MkdirAll(filepath.Join(event, orphanFolderName), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{
+		"IMG_0001.JPG":         encodeTestImage(t, 320, 240, blue, false),
+		"IMG_0001.NEF":         []byte("RAW paired with the JPEG, shown once"),
+		"IMG_0002.PNG":         encodeTestImage(t, 100, 200, red, true),
+		"IMG_0003.JPG":         []byte("not an image"),
+		"MOV_0001.MP4":         []byte("video"),
+		contactSheetName:       []byte("previous sheet"),
+		"orphan/DSC_0001.NEF":  createPreviewTIFF(1, encodeTestImage(t, 320, 240, red, false)),
+		"../../Notes/note.JPG": encodeTestImage(t, 10, 10, red, false), // Not an event folder
+	}
+	for name, data := range files {
+		path := filepath.Join(event, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := DefaultConfig(tmpDir)
+	cfg.CustomPhotoExts = []string{"png"}
+
+	// Dry run writes nothing
+	cfg.Mode = ModeDryRun
+	if err := WriteContactSheets(context.Background(), cfg); err != nil {
+		t.Fatalf("WriteContactSheets() dry run error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(event, contactSheetName)); string(data) != "previous sheet" {
+		t.Error("dry run should not replace the contact sheet")
+	}
+
+	cfg.Mode = ModeRun
+	if err := WriteContactSheets(context.Background(), cfg); err != nil {
+		t.Fatalf("WriteContactSheets() error: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(event, contactSheetName))
+	if err != nil {
+		t.Fatalf("contact sheet not written: %v", err)
+	}
+	defer f.Close()
+	sheet, err := jpeg.DecodeConfig(f)
+	if err != nil {
+		t.Fatalf("contact sheet is not a JPEG: %v", err)
+	}
+
+	// IMG_0001.JPG, IMG_0002.PNG and orphan/DSC_0001.NEF in one row
+	if want := 3*contactSheetCell + 4*contactSheetGap; sheet.Width != want || sheet.Height != contactSheetCell+2*contactSheetGap {
+		t.Errorf("contact sheet is %dx%d, want 3 thumbnails (%dx%d)", sheet.Width, sheet.Height, want, contactSheetCell+2*contactSheetGap)
+	}
+	assertNotExists(t, filepath.Join(tmpDir, "Notes", contactSheetName))
+}
+
+func TestSplit_ContactSheet(t *testing.T) {
+	tmpDir := t.TempDir()
+	shot := time.Date(2024, 6, 15, 10, 0, 0, 0, time.Local)
+	for i, name := range []string{"IMG_0001.JPG", "IMG_0002.JPG"} {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, encodeTestImage(t, 64, 48, blue, false), 0600); err != nil {
+			t.Fatal(err)
+		}
+		modTime := shot.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		ContactSheet: true,
+	}
+
+	result, err := Split(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	event := filepath.Join(tmpDir, "2024 - 0615 - 1000")
+	assertExists(t, filepath.Join(event, contactSheetName))
+	if result.Stats.ContactSheets != 1 {
+		t.Errorf("ContactSheets = %d, want 1", result.Stats.ContactSheets)
+	}
+
+	// The sheet is not a photo of the event: regrouping leaves it in place
+	cfg.Delta = time.Hour
+	if _, err := Regroup(context.Background(), cfg); err != nil {
+		t.Fatalf("Regroup() error: %v", err)
+	}
+	assertExists(t, filepath.Join(event, contactSheetName))
+
+	// Extending the event refreshes its sheet, even without --contact-sheet
+	sheet, err := os.ReadFile(filepath.Join(event, contactSheetName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPhoto := filepath.Join(tmpDir, "IMG_0003.JPG")
+	if err := os.WriteFile(newPhoto, encodeTestImage(t, 64, 48, red, false), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(newPhoto, shot.Add(5*time.Minute), shot.Add(5*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	cfg.ContactSheet = false
+	cfg.Incremental = true
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split(incremental) error: %v", err)
+	}
+	assertExists(t, filepath.Join(event, "IMG_0003.JPG"))
+	refreshed, err := os.ReadFile(filepath.Join(event, contactSheetName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(sheet, refreshed) {
+		t.Error("contact sheet of the extended event was not refreshed")
+	}
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The date of a file comes from the first --date-priority source holding a valid date (v2.10.0+)
+// Files whose dates disagree by more than --date-conflict-threshold are reported, and resolved by --date-conflict-policy
+
+// Date sources of --date-priority
+const (
+	DatePriorityEXIF      = "exif"      // EXIF DateTimeOriginal of photos and RAW files
+	DatePriorityVideo     = "video"     // MP4/MOV creation_time
+	DatePriorityFilename  = "filename"  // Date written in the file name
+	DatePriorityXMP       = "xmp"       // exif:DateTimeOriginal, photoshop:DateCreated or xmp:CreateDate of XMP
+	DatePriorityMTime     = "mtime"     // File modification time
+	DatePriorityBirthTime = "birthtime" // File creation time, when the file system records it
+)
+
+// Date conflict policies
+const (
+	DateConflictPriority = "priority" // Keep the date of the first --date-priority source (default)
+	DateConflictOldest   = "oldest"   // Keep the oldest date
+	DateConflictNewest   = "newest"   // Keep the newest date (cameras with an unset clock)
+	DateConflictSkip     = "skip"     // Leave the file at the root, out of events
+)
+
+// DefaultDatePriority is the date source order used when Config.DatePriority is empty
+// birthtime is left out: copying a file sets it to the copy time on most file systems
+var DefaultDatePriority = []string{
+	DatePriorityEXIF, DatePriorityVideo, DatePriorityFilename, DatePriorityXMP, DatePriorityMTime,
+}
+
+// dateSourceNames maps --date-priority names to date sources
+var dateSourceNames = map[string]DateSource{
+	DatePriorityEXIF:      DateSourceEXIF,
+	DatePriorityVideo:     DateSourceVideoMeta,
+	DatePriorityFilename:  DateSourceFilename,
+	DatePriorityXMP:       DateSourceXMP,
+	DatePriorityMTime:     DateSourceModTime,
+	DatePriorityBirthTime: DateSourceBirthTime,
+}
+
+// defaultDateSources is DefaultDatePriority parsed
+var defaultDateSources = []DateSource{
+	DateSourceEXIF, DateSourceVideoMeta, DateSourceFilename, DateSourceXMP, DateSourceModTime,
+}
+
+// errNoBirthTime is returned when the creation time of a file is unknown
+var errNoBirthTime = errors.New("file creation time not available")
+
+// DateCandidate is a date found for a file by one date source (v2.10.0+)
+type DateCandidate struct {
+	Date   time.Time
+	Source DateSource
+}
+
+// DateConflict is a file whose dates disagree by more than Config.DateConflictThreshold (v2.10.0+)
+type DateConflict struct {
+	File   string          // File name
+	Dates  []DateCandidate // Valid dates of the file, in --date-priority order
+	Spread time.Duration   // Between the oldest and the newest date
+	Chosen DateSource      // Source of the date kept by the conflict policy
+}
+
+// parseDatePriority converts --date-priority names to date sources, DefaultDatePriority when empty
+func parseDatePriority(names []string) ([]DateSource, error) {
+	if len(names) == 0 {
+		return defaultDateSources, nil
+	}
+
+	sources := make([]DateSource, 0, len(names))
+	for _, name := range names {
+		source, ok := dateSourceNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown date source: %s (must be: exif, video, filename, xmp, mtime, or birthtime)", name)
+		}
+		if slices.Contains(sources, source) {
+			return nil, fmt.Errorf("date source listed twice: %s", name)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// minValidYear returns the configured minimum valid year, or the default one
+func (c *Config) minValidYear() int {
+	if c.MinValidYear > 0 {
+		return c.MinValidYear
+	}
+	return defaultMinValidYear
+}
+
+// fileBirthTime returns the creation time of a file when its file system records it
+func fileBirthTime(fsys FileSystem, filePath string) (time.Time, error) {
+	if bt, ok := fsys.(BirthTimeFileSystem); ok {
+		return bt.BirthTime(filePath)
+	}
+	return time.Time{}, errNoBirthTime
+}
+
+// usesDateSource checks if a source is listed in --date-priority
+func (ctx *executionContext) usesDateSource(source DateSource) bool {
+	return slices.Contains(ctx.datePriority, source)
+}
+
+// addDateCandidate records the date found by a source, replacing an earlier one of the same source
+// Dates of unlisted sources, before the minimum valid year or in the future are ignored
+func (ctx *executionContext) addDateCandidate(metadata *FileMetadata, source DateSource, date time.Time) {
+	if !ctx.usesDateSource(source) {
+		return
+	}
+	if !isValidDateTime(date, ctx.minValidYear) {
+		ctx.log.Debug("ignored invalid date",
+			"file", metadata.FileInfo.Name(),
+			"source", source.String(),
+			"date", date.Format(time.RFC3339))
+		return
+	}
+
+	for i := range metadata.Dates {
+		if metadata.Dates[i].Source == source {
+			metadata.Dates[i].Date = date
+			return
+		}
+	}
+	metadata.Dates = append(metadata.Dates, DateCandidate{Date: date, Source: source})
+}
+
+// chooseDate sets the date of a file from its candidates, following --date-priority and the conflict policy
+// Files without any valid candidate keep their modification time
+func (ctx *executionContext) chooseDate(metadata *FileMetadata) {
+	slices.SortStableFunc(metadata.Dates, func(a, b DateCandidate) int {
+		return slices.Index(ctx.datePriority, a.Source) - slices.Index(ctx.datePriority, b.Source)
+	})
+	if len(metadata.Dates) > 0 {
+		metadata.DateTime = metadata.Dates[0].Date
+		metadata.Source = metadata.Dates[0].Source
+	}
+
+	oldest, newest, spread := dateSpread(metadata.Dates)
+	if ctx.dateConflictThreshold <= 0 || spread <= ctx.dateConflictThreshold {
+		return
+	}
+
+	metadata.DateConflict = true
+	switch ctx.dateConflictPolicy {
+	case DateConflictOldest:
+		metadata.DateTime, metadata.Source = oldest.Date, oldest.Source
+	case DateConflictNewest:
+		metadata.DateTime, metadata.Source = newest.Date, newest.Source
+	}
+	ctx.log.Debug("date conflict",
+		"file", metadata.FileInfo.Name(),
+		"spread", spread,
+		"source", metadata.Source.String(),
+		"date", metadata.DateTime.Format(time.RFC3339))
+}
+
+// dateSpread returns the oldest and newest dates and the duration between them
+func dateSpread(dates []DateCandidate) (oldest, newest DateCandidate, spread time.Duration) {
+	if len(dates) == 0 {
+		return oldest, newest, 0
+	}
+
+	oldest, newest = dates[0], dates[0]
+	for _, candidate := range dates[1:] {
+		if candidate.Date.Before(oldest.Date) {
+			oldest = candidate
+		}
+		if candidate.Date.After(newest.Date) {
+			newest = candidate
+		}
+	}
+	return oldest, newest, newest.Date.Sub(oldest.Date)
+}
+
+// dateConflicts lists the files with a date conflict, and the files left for grouping
+// With the skip policy, conflicting files are taken out of grouping and left at the root
+func dateConflicts(cfg *Config, files []FileMetadata) (conflicts []DateConflict, kept []FileMetadata, skipped *Group) {
+	kept = files
+	for _, file := range files {
+		if !file.DateConflict {
+			continue
+		}
+		_, _, spread := dateSpread(file.Dates)
+		conflicts = append(conflicts, DateConflict{
+			File:   file.FileInfo.Name(),
+			Dates:  file.Dates,
+			Spread: spread,
+			Chosen: file.Source,
+		})
+	}
+
+	if len(conflicts) == 0 || cfg.DateConflictPolicy != DateConflictSkip {
+		return conflicts, kept, nil
+	}
+
+	kept = make([]FileMetadata, 0, len(files)-len(conflicts))
+	skipped = &Group{AtRoot: true}
+	for _, file := range files {
+		if file.DateConflict {
+			skipped.Files = append(skipped.Files, file)
+		} else {
+			kept = append(kept, file)
+		}
+	}
+	return conflicts, kept, skipped
+}
+
+// formatDateCandidates formats the dates of a file for logs: "EXIF=2000-01-01T00:00:00Z ModTime=2024-06-15T14:30:12Z"
+func formatDateCandidates(dates []DateCandidate) string {
+	parts := make([]string, 0, len(dates))
+	for _, candidate := range dates {
+		parts = append(parts, candidate.Source.String()+"="+candidate.Date.Format(time.RFC3339))
+	}
+	return strings.Join(parts, " ")
+}
+
+// printDateConflicts logs the files whose dates disagree
+func printDateConflicts(conflicts []DateConflict) {
+	for _, conflict := range conflicts {
+		slog.Warn("date conflict",
+			"file", conflict.File,
+			"dates", formatDateCandidates(conflict.Dates),
+			"spread", conflict.Spread.Round(time.Minute),
+			"chosen", conflict.Chosen.String())
+	}
+}
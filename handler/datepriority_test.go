@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// birthTimeFS is a memory file system recording the same creation time for every file
+type birthTimeFS struct {
+	*MemFileSystem
+	birthTime time.Time
+}
+
+func (b birthTimeFS) BirthTime(string) (time.Time, error) { return b.birthTime, nil }
+
+func TestParseDatePriority(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		want    []DateSource
+		wantErr bool
+	}{
+		{"default", nil, defaultDateSources, false},
+		{"custom order", []string{"filename", " EXIF ", "mtime"}, []DateSource{DateSourceFilename, DateSourceEXIF, DateSourceModTime}, false},
+		{"birthtime", []string{"birthtime"}, []DateSource{DateSourceBirthTime}, false},
+		{"unknown source", []string{"exif", "gps"}, nil, true},
+		{"listed twice", []string{"exif", "video", "exif"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDatePriority(tt.names)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDatePriority() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDatePriority() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChooseDate(t *testing.T) {
+	unsetClock := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	nameDate := time.Date(2024, 6, 15, 14, 30, 12, 0, time.UTC)
+	modTime := time.Date(2024, 6, 16, 9, 0, 0, 0, time.UTC)
+	candidates := []DateCandidate{
+		{Date: modTime, Source: DateSourceModTime},
+		{Date: nameDate, Source: DateSourceFilename},
+		{Date: unsetClock, Source: DateSourceEXIF},
+	}
+
+	tests := []struct {
+		name         string
+		priority     []DateSource
+		policy       string
+		threshold    time.Duration
+		wantDate     time.Time
+		wantSource   DateSource
+		wantConflict bool
+	}{
+		{"priority order", defaultDateSources, "", 0, unsetClock, DateSourceEXIF, false},
+		{"filename first", []DateSource{DateSourceFilename, DateSourceEXIF, DateSourceModTime}, "", 0, nameDate, DateSourceFilename, false},
+		{"conflict keeps priority", defaultDateSources, DateConflictPriority, 30 * 24 * time.Hour, unsetClock, DateSourceEXIF, true},
+		{"conflict newest", defaultDateSources, DateConflictNewest, 30 * 24 * time.Hour, modTime, DateSourceModTime, true},
+		{"conflict oldest", defaultDateSources, DateConflictOldest, 30 * 24 * time.Hour, unsetClock, DateSourceEXIF, true},
+		{"spread below threshold", defaultDateSources, DateConflictNewest, 30 * 365 * 24 * time.Hour, unsetClock, DateSourceEXIF, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newDefaultExecutionContext()
+			ctx.datePriority = tt.priority
+			ctx.dateConflictPolicy = tt.policy
+			ctx.dateConflictThreshold = tt.threshold
+
+			metadata := &FileMetadata{
+				FileInfo: memFileInfo{name: "IMG_20240615_143012.jpg", modTime: modTime},
+				DateTime: modTime,
+				Dates:    append([]DateCandidate{}, candidates...),
+			}
+			ctx.chooseDate(metadata)
+
+			if !metadata.DateTime.Equal(tt.wantDate) || metadata.Source != tt.wantSource {
+				t.Errorf("chooseDate() = %v from %s, want %v from %s",
+					metadata.DateTime, metadata.Source, tt.wantDate, tt.wantSource)
+			}
+			if metadata.DateConflict != tt.wantConflict {
+				t.Errorf("DateConflict = %v, want %v", metadata.DateConflict, tt.wantConflict)
+			}
+		})
+	}
+}
+
+func TestAddDateCandidate(t *testing.T) {
+	ctx := newDefaultExecutionContext()
+	ctx.datePriority = []DateSource{DateSourceEXIF, DateSourceXMP}
+	ctx.minValidYear = 2001
+	metadata := &FileMetadata{FileInfo: memFileInfo{name: "IMG_0001.JPG"}}
+
+	ctx.addDateCandidate(metadata, DateSourceEXIF, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))    // Before minValidYear
+	ctx.addDateCandidate(metadata, DateSourceModTime, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) // Not listed
+	ctx.addDateCandidate(metadata, DateSourceXMP, time.Now().AddDate(0, 0, 3))                     // Future
+	ctx.addDateCandidate(metadata, DateSourceXMP, time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC))
+	ctx.addDateCandidate(metadata, DateSourceXMP, time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC)) // Sidecar overrides embedded
+
+	want := []DateCandidate{{Date: time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC), Source: DateSourceXMP}}
+	if !reflect.DeepEqual(metadata.Dates, want) {
+		t.Errorf("Dates = %v, want %v", metadata.Dates, want)
+	}
+}
+
+func TestExtractMetadata_DatePriority(t *testing.T) {
+	tmpDir := t.TempDir()
+	unsetClock := time.Date(2000, 1, 1, 0, 0, 0, 0, time.Local)
+	modTime := time.Date(2024, 6, 15, 18, 0, 0, 0, time.Local)
+	filePath := filepath.Join(tmpDir, "IMG_20240615_143012.jpg")
+	createJPEGWithEXIF(t, filePath, unsetClock)
+	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		cfg        Config
+		wantDate   time.Time
+		wantSource DateSource
+	}{
+		{"default", Config{}, unsetClock, DateSourceEXIF},
+		{"filename first", Config{DatePriority: []string{"filename", "exif"}}, time.Date(2024, 6, 15, 14, 30, 12, 0, time.Local), DateSourceFilename},
+		{"mtime only", Config{DatePriority: []string{"mtime"}}, modTime, DateSourceModTime},
+		{"min valid year", Config{MinValidYear: 2001, DatePriority: []string{"exif", "mtime"}}, modTime, DateSourceModTime},
+		{"conflict newest", Config{DateConflictThreshold: 24 * time.Hour, DateConflictPolicy: DateConflictNewest}, modTime, DateSourceModTime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := newExecutionContext(&tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			metadata, err := ExtractMetadata(ctx, filePath)
+			if err != nil {
+				t.Fatalf("ExtractMetadata() error: %v", err)
+			}
+			if !metadata.DateTime.Equal(tt.wantDate) || metadata.Source != tt.wantSource {
+				t.Errorf("ExtractMetadata() = %v from %s, want %v from %s",
+					metadata.DateTime, metadata.Source, tt.wantDate, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestExtractMetadata_BirthTimeAndXMP(t *testing.T) {
+	modTime := time.Date(2025, 1, 10, 8, 0, 0, 0, time.Local)
+	created := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
+	fsys := birthTimeFS{MemFileSystem: NewMemFileSystem(), birthTime: created}
+	writeMemFile(t, fsys.MemFileSystem, "/photos/scan.jpg", []byte("no exif"), modTime)
+	writeMemFile(t, fsys.MemFileSystem, "/photos/edit.jpg", []byte("no exif"), modTime)
+	writeMemFile(t, fsys.MemFileSystem, "/photos/edit.xmp",
+		[]byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">`+
+			`<rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmlns:photoshop="http://ns.adobe.com/photoshop/1.0/" `+
+			`xmp:CreateDate="2024-06-20T10:00:00" photoshop:DateCreated="2024-06-14T16:45:00"/></rdf:RDF></x:xmpmeta>`), modTime)
+
+	ctx, err := newExecutionContext(&Config{FS: fsys, DatePriority: []string{"xmp", "birthtime", "mtime"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := ExtractMetadata(ctx, "/photos/scan.jpg")
+	if err != nil {
+		t.Fatalf("ExtractMetadata() error: %v", err)
+	}
+	if metadata.Source != DateSourceBirthTime || !metadata.DateTime.Equal(created) {
+		t.Errorf("scan.jpg = %v from %s, want %v from BirthTime", metadata.DateTime, metadata.Source, created)
+	}
+
+	metadata, err = ExtractMetadata(ctx, "/photos/edit.jpg")
+	if err != nil {
+		t.Fatalf("ExtractMetadata() error: %v", err)
+	}
+	if want := time.Date(2024, 6, 14, 16, 45, 0, 0, time.Local); metadata.Source != DateSourceXMP || !metadata.DateTime.Equal(want) {
+		t.Errorf("edit.jpg = %v from %s, want %v from XMP photoshop:DateCreated", metadata.DateTime, metadata.Source, want)
+	}
+}
+
+func TestSplit_DateConflictSkip(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+
+	for i := range 5 {
+		createTestFile(t, tmpDir, fmt.Sprintf("DSC_%04d.JPG", i+1), baseTime.Add(time.Duration(i)*time.Minute))
+	}
+	// Copied months later: the name and the modification time disagree
+	createTestFile(t, tmpDir, "IMG_20240101_080000.jpg", baseTime.Add(2*time.Minute))
+
+	cfg := &Config{
+		BasePath:              tmpDir,
+		Delta:                 30 * time.Minute,
+		Mode:                  ModeRun,
+		UseEXIF:               true,
+		MinGroupSize:          5,
+		DateConflictThreshold: 30 * 24 * time.Hour,
+		DateConflictPolicy:    DateConflictSkip,
+	}
+
+	result, err := Split(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	assertExists(t, filepath.Join(tmpDir, "IMG_20240101_080000.jpg"))
+	assertExists(t, filepath.Join(tmpDir, "2024 - 0615 - 1200", "DSC_0001.JPG"))
+
+	conflicts := result.Stats.DateConflicts
+	if len(conflicts) != 1 || conflicts[0].File != "IMG_20240101_080000.jpg" || conflicts[0].Chosen != DateSourceFilename {
+		t.Fatalf("DateConflicts = %+v, want IMG_20240101_080000.jpg from Filename", conflicts)
+	}
+	if len(conflicts[0].Dates) != 2 {
+		t.Errorf("conflict dates = %v, want Filename and ModTime", conflicts[0].Dates)
+	}
+}
+
+func TestConfigValidate_DatePriority(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{"unknown source", func(c *Config) { c.DatePriority = []string{"exif", "gps"} }},
+		{"unknown policy", func(c *Config) { c.DateConflictPolicy = "latest" }},
+		{"negative threshold", func(c *Config) { c.DateConflictThreshold = -time.Hour }},
+		{"future min year", func(c *Config) { c.MinValidYear = time.Now().Year() + 1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig(t.TempDir())
+			tt.modify(cfg)
+			if err := cfg.Validate(); err == nil {
+				t.Error("Validate() should fail")
+			}
+		})
+	}
+}
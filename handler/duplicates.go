@@ -1,11 +1,11 @@
 package handler
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
 )
 
 // DuplicateDetector detects duplicate files via SHA256 hash
@@ -13,15 +13,22 @@ type DuplicateDetector struct {
 	hashes     map[string]string  // hash → first file path
 	duplicates map[string]string  // duplicate path → original path
 	sizeGroups map[int64][]string // size → file paths (pre-filtering)
+	fs         FileSystem         // Storage holding the files (v2.10.0+)
 	enabled    bool
 }
 
 // NewDuplicateDetector creates a new duplicate detector
 func NewDuplicateDetector(enabled bool) *DuplicateDetector {
+	return newDuplicateDetector(enabled, OSFileSystem{})
+}
+
+// newDuplicateDetector creates a duplicate detector hashing files of fsys
+func newDuplicateDetector(enabled bool, fsys FileSystem) *DuplicateDetector {
 	return &DuplicateDetector{
 		hashes:     make(map[string]string),
 		duplicates: make(map[string]string),
 		sizeGroups: make(map[int64][]string),
+		fs:         fsys,
 		enabled:    enabled,
 	}
 }
@@ -35,9 +42,33 @@ func (d *DuplicateDetector) AddFile(filePath string, size int64) {
 	d.sizeGroups[size] = append(d.sizeGroups[size], filePath)
 }
 
+// addPlacedFile registers a file placed before the files being checked (e.g. moved before an
+// interruption): files with the same content are reported as its duplicates (v2.10.0+)
+// Must be called after AddFile; the file is only hashed when a pending file has its size
+func (d *DuplicateDetector) addPlacedFile(runCtx context.Context, filePath string, size int64) error {
+	if !d.enabled || len(d.sizeGroups[size]) == 0 {
+		return nil
+	}
+
+	hash, err := sha256FileContext(runCtx, d.fs, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+	d.sizeGroups[size] = append(d.sizeGroups[size], filePath)
+	if _, found := d.hashes[hash]; !found {
+		d.hashes[hash] = filePath
+	}
+	return nil
+}
+
 // Check verifies if the file is a duplicate
 // Returns (isDuplicate, originalPath, error)
 func (d *DuplicateDetector) Check(filePath string, size int64) (bool, string, error) {
+	return d.CheckContext(context.Background(), filePath, size)
+}
+
+// CheckContext is Check with hashing stopped when runCtx is canceled (v2.10.0+)
+func (d *DuplicateDetector) CheckContext(runCtx context.Context, filePath string, size int64) (bool, string, error) {
 	if !d.enabled {
 		return false, "", nil
 	}
@@ -49,7 +80,7 @@ func (d *DuplicateDetector) Check(filePath string, size int64) (bool, string, er
 	}
 
 	// Calculate hash
-	hash, err := sha256File(filePath)
+	hash, err := sha256FileContext(runCtx, d.fs, filePath)
 	if err != nil {
 		return false, "", fmt.Errorf("failed to hash file: %w", err)
 	}
@@ -93,17 +124,35 @@ func (d *DuplicateDetector) GetStats() (totalFiles int, uniqueSizes int, potenti
 }
 
 // sha256File calculates the SHA256 hash of a file
-func sha256File(filePath string) (string, error) {
-	f, err := os.Open(filePath)
+func sha256File(fsys FileSystem, filePath string) (string, error) {
+	return sha256FileContext(context.Background(), fsys, filePath)
+}
+
+// sha256FileContext calculates the SHA256 hash of a file, giving up when runCtx is canceled
+func sha256FileContext(runCtx context.Context, fsys FileSystem, filePath string) (string, error) {
+	f, err := fsys.Open(filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open file: %w", err)
 	}
 	defer f.Close()
 
 	h := sha256.New()
-	if _, err := io.Copy(h, f); err != nil {
+	if _, err := io.Copy(h, contextReader{runCtx: runCtx, r: f}); err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
 
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
+
+// contextReader fails reads once its context is canceled, so long copies stop early
+type contextReader struct {
+	runCtx context.Context
+	r      io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.runCtx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
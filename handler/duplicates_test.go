@@ -371,7 +371,7 @@ func TestSha256File(t *testing.T) {
 	}
 
 	// Compute hash
-	hash1, err := sha256File(testFile)
+	hash1, err := sha256File(OSFileSystem{}, testFile)
 	if err != nil {
 		t.Errorf("sha256File() error = %v, want nil", err)
 	}
@@ -380,7 +380,7 @@ func TestSha256File(t *testing.T) {
 	}
 
 	// Compute hash again - should be identical
-	hash2, err := sha256File(testFile)
+	hash2, err := sha256File(OSFileSystem{}, testFile)
 	if err != nil {
 		t.Errorf("sha256File() error = %v, want nil", err)
 	}
@@ -394,7 +394,7 @@ func TestSha256File(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	hash3, err := sha256File(testFile2)
+	hash3, err := sha256File(OSFileSystem{}, testFile2)
 	if err != nil {
 		t.Errorf("sha256File() error = %v, want nil", err)
 	}
@@ -408,7 +408,7 @@ func TestSha256File(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	hash4, err := sha256File(testFile3)
+	hash4, err := sha256File(OSFileSystem{}, testFile3)
 	if err != nil {
 		t.Errorf("sha256File() error = %v, want nil", err)
 	}
@@ -419,7 +419,7 @@ func TestSha256File(t *testing.T) {
 
 // TestSha256File_NonExistent tests error handling for non-existent files
 func TestSha256File_NonExistent(t *testing.T) {
-	_, err := sha256File("/nonexistent/file.txt")
+	_, err := sha256File(OSFileSystem{}, "/nonexistent/file.txt")
 	if err == nil {
 		t.Error("sha256File() error = nil, want error for non-existent file")
 	}
@@ -428,7 +428,7 @@ func TestSha256File_NonExistent(t *testing.T) {
 // TestSha256File_Directory tests error handling for directories
 func TestSha256File_Directory(t *testing.T) {
 	tmpDir := t.TempDir()
-	_, err := sha256File(tmpDir)
+	_, err := sha256File(OSFileSystem{}, tmpDir)
 	if err == nil {
 		t.Error("sha256File() error = nil, want error for directory")
 	}
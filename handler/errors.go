@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -59,6 +60,9 @@ func (e *PicsplitError) Suggestion() string {
 		return "Check file format and configuration"
 
 	case ErrTypeIO:
+		if errors.Is(e.Err, ErrDestinationExists) {
+			return "Use --on-collision rename, skip or overwrite to resolve name collisions"
+		}
 		if e.Err != nil {
 			errMsg := e.Err.Error()
 			if strings.Contains(errMsg, "disk full") || strings.Contains(errMsg, "no space") {
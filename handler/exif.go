@@ -1,15 +1,20 @@
 package handler
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf16"
 
 	"github.com/abema/go-mp4"
 	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
 )
 
 // DateSource indicates the origin of the extracted date
@@ -22,17 +27,29 @@ const (
 	DateSourceEXIF
 	// DateSourceVideoMeta indicates the date comes from video metadata
 	DateSourceVideoMeta
+	// DateSourceFilename indicates the date comes from the file name (e.g., IMG-20240615-WA0003.jpg) (v2.10.0+)
+	DateSourceFilename
+	// DateSourceXMP indicates the date comes from XMP metadata (sidecar or embedded packet) (v2.10.0+)
+	DateSourceXMP
+	// DateSourceBirthTime indicates the date comes from the file system creation time (v2.10.0+)
+	DateSourceBirthTime
 )
 
+// errNoCreationTime is returned for videos without movie header
+var errNoCreationTime = errors.New("no creation time found in video metadata")
+
 const (
-	minValidYear  = 1990
-	maxFutureDays = 1 // tolerance for clock skew
+	defaultMinValidYear = 1990 // Earlier dates come from unset clocks, see Config.MinValidYear
+	maxFutureDays       = 1    // tolerance for clock skew
 )
 
 const (
 	dateSourceModTimeStr   = "ModTime"
 	dateSourceEXIFStr      = "EXIF"
 	dateSourceVideoMetaStr = "VideoMeta"
+	dateSourceFilenameStr  = "Filename"
+	dateSourceXMPStr       = "XMP"
+	dateSourceBirthTimeStr = "BirthTime"
 )
 
 // String returns a text representation of the date source
@@ -42,6 +59,12 @@ func (ds DateSource) String() string {
 		return dateSourceEXIFStr
 	case DateSourceVideoMeta:
 		return dateSourceVideoMetaStr
+	case DateSourceFilename:
+		return dateSourceFilenameStr
+	case DateSourceXMP:
+		return dateSourceXMPStr
+	case DateSourceBirthTime:
+		return dateSourceBirthTimeStr
 	default:
 		return dateSourceModTimeStr
 	}
@@ -54,17 +77,55 @@ type GPSCoord struct {
 }
 
 // FileMetadata contains all metadata extracted from a file
+// Fields other than FileInfo, DateTime and Source are filled by the extractor that knows them
+// and stay zero when the file does not carry the information
 type FileMetadata struct {
 	FileInfo os.FileInfo
 	DateTime time.Time
 	GPS      *GPSCoord
 	Source   DateSource
+
+	GPSInterpolated bool // GPS position interpolated from a --gpx track, not read from the file (v2.10.0+)
+
+	// Camera (v2.10.0+)
+	Make         string // EXIF Make (e.g., "Apple", "NIKON CORPORATION")
+	Model        string // EXIF Model (e.g., "iPhone 15", "NIKON Z 6")
+	SerialNumber string // EXIF BodySerialNumber, or DNG CameraSerialNumber
+	LensModel    string // EXIF LensModel (e.g., "NIKKOR Z 24-70mm f/4 S")
+
+	// Shot and image (v2.10.0+)
+	FocalLength float64 // EXIF FocalLength in mm
+	ISO         int     // EXIF ISOSpeedRatings
+	Width       int     // Pixels, EXIF PixelXDimension or video frame width
+	Height      int     // Pixels, EXIF PixelYDimension or video frame height
+	Orientation int     // EXIF Orientation (1-8)
+	UserComment string  // EXIF UserComment (e.g., "Screenshot" written by iOS)
+
+	// Video (v2.10.0+)
+	Duration   time.Duration // MP4/MOV mvhd duration
+	VideoCodec string        // Sample entry of the first video track (e.g., "avc1", "hvc1")
+
+	// XMP, from the sidecar or the packet embedded in the photo (v2.10.0+)
+	Label    string   // xmp:Label (e.g., "Red")
+	Keywords []string // dc:subject
+	Rating   int      // xmp:Rating: 1-5 stars, -1 rejected, 0 unrated
+
+	// Date candidates (v2.10.0+)
+	Dates        []DateCandidate // Valid dates found by the --date-priority sources
+	DateConflict bool            // Dates disagree by more than Config.DateConflictThreshold
+
+	// relPath is the path relative to BasePath of a file read from event folders (regroup, library report)
+	// FileInfo cannot identify it: two files may have the same name, size and date in two folders
+	relPath string
 }
 
 // ExtractMetadata extracts all metadata from a file (date and GPS if available)
 // Uses execution context to respect custom extensions
 func ExtractMetadata(ctx *executionContext, filePath string) (*FileMetadata, error) {
-	info, err := os.Stat(filePath)
+	start := time.Now()
+	defer func() { ctx.metrics.observeExtraction(time.Since(start)) }()
+
+	info, err := ctx.fs.Stat(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat file: %w", err)
 	}
@@ -78,60 +139,312 @@ func ExtractMetadata(ctx *executionContext, filePath string) (*FileMetadata, err
 
 	// Determine file type using context
 	if ctx.isPhoto(info.Name()) {
-		// For RAW files, search for associated JPG
-		if ctx.isRaw(info.Name()) {
-			jpegPath, err := findAssociatedJPEG(filePath)
-			if err == nil {
-				filePath = jpegPath
-				slog.Debug("using associated JPEG for RAW file", "jpeg", jpegPath, "raw", info.Name())
+		extractPhotoMetadata(ctx, filePath, metadata)
+
+		// PNG files rarely carry EXIF: dimensions come from the IHDR chunk (v2.10.0+)
+		if metadata.Width == 0 && isPNG(info.Name()) {
+			if width, height, err := readPNGSize(ctx.fs, filePath); err == nil {
+				metadata.Width, metadata.Height = width, height
 			}
 		}
+	} else if ctx.isMovie(info.Name()) {
+		extractMovieMetadata(ctx, filePath, metadata)
+	}
 
-		// Extract EXIF
-		dateTime, err := extractEXIFDate(filePath)
-		if err == nil && isValidDateTime(dateTime) {
-			metadata.DateTime = dateTime
-			metadata.Source = DateSourceEXIF
-			slog.Debug("extracted EXIF date", "file", info.Name(), "date", dateTime.Format(time.RFC3339))
-		} else {
-			slog.Debug("failed to extract EXIF date", "file", info.Name(), "error", err)
-		}
+	// Phones, screenshot tools and messaging apps put the date in the file name (v2.10.0+)
+	ctx.addFilenameDate(metadata)
 
-		// Extract GPS
-		gps, err := extractGPS(filePath)
-		if err == nil && gps != nil {
-			metadata.GPS = gps
-			slog.Debug("extracted GPS coordinates", "file", info.Name(), "lat", gps.Lat, "lon", gps.Lon)
-		}
-	} else if ctx.isMovie(info.Name()) {
-		// Extract video metadata
-		dateTime, err := extractVideoMetadata(filePath)
-		if err == nil && isValidDateTime(dateTime) {
-			metadata.DateTime = dateTime
-			metadata.Source = DateSourceVideoMeta
-			slog.Debug("extracted video metadata", "file", info.Name(), "date", dateTime.Format(time.RFC3339))
-		} else {
-			slog.Debug("failed to extract video metadata", "file", info.Name(), "error", err)
+	// Ratings, keywords and dates of a sidecar override the embedded ones (v2.10.0+)
+	if xmp, err := readXMPSidecar(ctx.fs, filePath); err == nil {
+		ctx.applyXMP(metadata, xmp)
+	} else if !errors.Is(err, errNoXMP) {
+		ctx.log.Debug("failed to read XMP sidecar", "file", info.Name(), "error", err)
+	}
+
+	// File system dates (v2.10.0+)
+	ctx.addDateCandidate(metadata, DateSourceModTime, info.ModTime())
+	if ctx.usesDateSource(DateSourceBirthTime) {
+		if birthTime, err := fileBirthTime(ctx.fs, filePath); err == nil {
+			ctx.addDateCandidate(metadata, DateSourceBirthTime, birthTime)
 		}
 	}
 
+	// The first --date-priority source with a valid date wins (v2.10.0+)
+	ctx.chooseDate(metadata)
+
 	return metadata, nil
 }
 
-// extractEXIFDate extracts the DateTimeOriginal from a photo
-func extractEXIFDate(filePath string) (time.Time, error) {
-	f, err := os.Open(filePath)
+// groupingMetadata reads the metadata files are grouped by, the same way for new files and for
+// files already organized (incremental ranges, resume, regroup): ExtractMetadata with UseEXIF,
+// so dates follow --date-priority, ModTime otherwise
+func groupingMetadata(cfg *Config, ctx *executionContext, filePath string) (*FileMetadata, error) {
+	if cfg.UseEXIF {
+		return ExtractMetadata(ctx, filePath)
+	}
+
+	info, err := ctx.fs.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return &FileMetadata{FileInfo: info, DateTime: info.ModTime(), Source: DateSourceModTime}, nil
+}
+
+// extractPhotoMetadata fills metadata from the EXIF block and embedded XMP of a photo
+func extractPhotoMetadata(ctx *executionContext, filePath string, metadata *FileMetadata) {
+	name := metadata.FileInfo.Name()
+
+	// Decode EXIF once for date, GPS, camera and image details
+	x, err := decodeEXIF(ctx.fs, filePath)
+
+	// RAW files are read directly (v2.10.0+), the associated JPG is used when the RAW container is not understood
+	if err != nil && ctx.isRaw(name) {
+		ctx.log.Debug("failed to read RAW metadata", "file", name, "error", err)
+		if jpegPath, jpegErr := findAssociatedJPEG(ctx.fs, filePath); jpegErr == nil {
+			ctx.log.Debug("using associated JPEG for RAW file", "jpeg", jpegPath, "raw", name)
+			filePath = jpegPath
+			x, err = decodeEXIF(ctx.fs, jpegPath)
+		}
+	}
+	if err != nil {
+		ctx.log.Debug("failed to extract EXIF date", "file", name, "error", err)
+		return
+	}
+
+	// Extract EXIF date
+	if dateTime, err := exifDateTime(x); err == nil {
+		ctx.log.Debug("extracted EXIF date", "file", name, "date", dateTime.Format(time.RFC3339))
+		ctx.addDateCandidate(metadata, DateSourceEXIF, dateTime)
+	} else {
+		ctx.log.Debug("failed to extract EXIF date", "file", name, "error", err)
+	}
+
+	// Extract GPS
+	gps, err := exifGPS(x)
+	if err == nil && gps != nil {
+		metadata.GPS = gps
+		ctx.log.Debug("extracted GPS coordinates", "file", name, "lat", gps.Lat, "lon", gps.Lon)
+	}
+
+	// Extract camera, lens and image details (v2.10.0+)
+	metadata.applyEXIFDetails(x)
+
+	if xmp, err := readEmbeddedXMP(ctx.fs, filePath, x); err == nil {
+		ctx.applyXMP(metadata, xmp)
+	} else if !errors.Is(err, errNoXMP) {
+		ctx.log.Debug("failed to read embedded XMP", "file", name, "error", err)
+	}
+}
+
+// extractMovieMetadata fills metadata from the MP4/MOV boxes of a video
+func extractMovieMetadata(ctx *executionContext, filePath string, metadata *FileMetadata) {
+	name := metadata.FileInfo.Name()
+
+	video, err := readVideoMetadata(ctx.fs, filePath)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to open file: %w", err)
+		ctx.log.Debug("failed to extract video metadata", "file", name, "error", err)
+		return
+	}
+
+	// Duration, codec and frame size are kept even without a valid creation date (v2.10.0+)
+	metadata.Duration = video.duration
+	metadata.VideoCodec = video.codec
+	metadata.Width = video.width
+	metadata.Height = video.height
+
+	if video.creationTime.IsZero() {
+		ctx.log.Debug("failed to extract video metadata", "file", name, "error", errNoCreationTime)
+		return
+	}
+	ctx.log.Debug("extracted video metadata", "file", name, "date", video.creationTime.Format(time.RFC3339))
+	ctx.addDateCandidate(metadata, DateSourceVideoMeta, video.creationTime)
+}
+
+// decodeEXIF opens a photo and decodes its EXIF block
+func decodeEXIF(fsys FileSystem, filePath string) (*exif.Exif, error) {
+	f, err := fsys.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer f.Close()
 
+	header := make([]byte, 16)
+	n, _ := f.ReadAt(header, 0)
+
+	// RAW containers (TIFF IFDs, RAF, CR3) are read without loading the whole file (v2.10.0+)
+	if format := rawFormat(header[:n]); format != "" {
+		tiff, err := readRAWEXIF(f, format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s metadata: %w", format, err)
+		}
+		return decodeTIFF(tiff)
+	}
+
+	// HEIC/HEIF/AVIF keep EXIF in an item of the ISOBMFF meta box (v2.10.0+)
+	if isISOBMFF(f) {
+		tiff, err := extractISOBMFFExif(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate EXIF item: %w", err)
+		}
+		return decodeTIFF(tiff)
+	}
+
 	x, err := exif.Decode(f)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to decode EXIF: %w", err)
+		return nil, fmt.Errorf("failed to decode EXIF: %w", err)
+	}
+
+	return x, nil
+}
+
+// decodeTIFF decodes an EXIF block starting at its TIFF header
+func decodeTIFF(tiff []byte) (*exif.Exif, error) {
+	x, err := exif.Decode(bytes.NewReader(tiff))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode EXIF: %w", err)
+	}
+	return x, nil
+}
+
+// EXIF fields goexif does not load (v2.10.0+)
+const (
+	exifBodySerialNumber   exif.FieldName = "BodySerialNumber"   // Exif IFD 0xA431
+	exifCameraSerialNumber exif.FieldName = "CameraSerialNumber" // IFD0 0xC62F (DNG)
+	exifXMLPacket          exif.FieldName = "XMLPacket"          // IFD0 0x02BC (TIFF, DNG and most RAW)
+)
+
+func init() {
+	exif.RegisterParsers(extraFieldsParser{})
+}
+
+// extraFieldsParser loads the serial number and XMP fields after the standard goexif parser
+type extraFieldsParser struct{}
+
+// Parse never fails: a missing or corrupted field only leaves it empty
+func (extraFieldsParser) Parse(x *exif.Exif) error {
+	if len(x.Tiff.Dirs) == 0 {
+		return nil
+	}
+	x.LoadTags(x.Tiff.Dirs[0], map[uint16]exif.FieldName{
+		0xC62F:       exifCameraSerialNumber,
+		tagXMLPacket: exifXMLPacket,
+	}, false)
+
+	pointer, err := x.Get(exif.ExifIFDPointer)
+	if err != nil {
+		return nil
+	}
+	offset, err := pointer.Int64(0)
+	if err != nil || offset <= 0 || offset >= int64(len(x.Raw)) {
+		return nil
+	}
+	// Values are read at offsets relative to the TIFF header
+	r := bytes.NewReader(x.Raw)
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil
+	}
+	dir, _, err := tiff.DecodeDir(r, x.Tiff.Order)
+	if err != nil {
+		return nil
+	}
+	x.LoadTags(dir, map[uint16]exif.FieldName{0xA431: exifBodySerialNumber}, false)
+	return nil
+}
+
+// exifString returns a trimmed ASCII EXIF field, or "" if absent
+func exifString(x *exif.Exif, name exif.FieldName) string {
+	tag, err := x.Get(name)
+	if err != nil {
+		return ""
+	}
+	value, err := tag.StringVal()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(value, "\x00"))
+}
+
+// applyEXIFDetails copies camera, lens and image fields of decoded EXIF
+func (m *FileMetadata) applyEXIFDetails(x *exif.Exif) {
+	m.Make = exifString(x, exif.Make)
+	m.Model = exifString(x, exif.Model)
+	m.SerialNumber = exifString(x, exifBodySerialNumber)
+	if m.SerialNumber == "" {
+		m.SerialNumber = exifString(x, exifCameraSerialNumber)
+	}
+	m.LensModel = exifString(x, exif.LensModel)
+
+	m.FocalLength = exifRational(x, exif.FocalLength)
+	m.ISO = exifInt(x, exif.ISOSpeedRatings)
+	m.Orientation = exifInt(x, exif.Orientation)
+	m.UserComment = exifUserComment(x)
+
+	// ImageWidth is often the thumbnail in RAW files: prefer the EXIF pixel dimensions
+	m.Width = exifInt(x, exif.PixelXDimension)
+	m.Height = exifInt(x, exif.PixelYDimension)
+	if m.Width == 0 || m.Height == 0 {
+		m.Width = exifInt(x, exif.ImageWidth)
+		m.Height = exifInt(x, exif.ImageLength)
+	}
+}
+
+// exifUserComment returns the UserComment text without its character code prefix, or "" if absent
+func exifUserComment(x *exif.Exif) string {
+	tag, err := x.Get(exif.UserComment)
+	if err != nil || len(tag.Val) < 8 {
+		return ""
+	}
+
+	// The first 8 bytes name the encoding: "ASCII\0\0\0", "UNICODE\0" (UTF-16) or undefined (zeros)
+	code, value := string(tag.Val[:8]), tag.Val[8:]
+	if strings.HasPrefix(code, "UNICODE") {
+		units := make([]uint16, len(value)/2)
+		for i := range units {
+			units[i] = x.Tiff.Order.Uint16(value[2*i:])
+		}
+		return strings.TrimSpace(strings.TrimRight(string(utf16.Decode(units)), "\x00"))
+	}
+	return strings.TrimSpace(strings.TrimRight(string(value), "\x00 "))
+}
+
+// exifInt returns the first value of an integer EXIF field, or 0 if absent
+func exifInt(x *exif.Exif, name exif.FieldName) int {
+	tag, err := x.Get(name)
+	if err != nil {
+		return 0
+	}
+	value, err := tag.Int(0)
+	if err != nil {
+		return 0
 	}
+	return value
+}
 
-	// Search for DateTimeOriginal (preferred) or DateTime
+// exifRational returns the first value of a rational EXIF field, or 0 if absent or undefined
+func exifRational(x *exif.Exif, name exif.FieldName) float64 {
+	tag, err := x.Get(name)
+	if err != nil {
+		return 0
+	}
+	num, den, err := tag.Rat2(0)
+	if err != nil || den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
+
+// extractEXIFDate extracts the DateTimeOriginal from a photo
+func extractEXIFDate(fsys FileSystem, filePath string) (time.Time, error) {
+	x, err := decodeEXIF(fsys, filePath)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return exifDateTime(x)
+}
+
+// exifDateTime returns DateTimeOriginal (preferred) or DateTime from decoded EXIF
+func exifDateTime(x *exif.Exif) (time.Time, error) {
 	dateTime, err := x.DateTime()
 	if err != nil {
 		return time.Time{}, fmt.Errorf("failed to get DateTime: %w", err)
@@ -140,125 +453,181 @@ func extractEXIFDate(filePath string) (time.Time, error) {
 	return dateTime, nil
 }
 
+// videoMetadata holds what picsplit reads from the moov box of a video (v2.10.0+)
+type videoMetadata struct {
+	creationTime time.Time // Zero when the video has no mvhd box
+	codec        string
+	duration     time.Duration
+	width        int
+	height       int
+}
+
 // extractVideoMetadata extracts creation date from MP4/MOV video
-func extractVideoMetadata(filePath string) (time.Time, error) {
-	f, err := os.Open(filePath)
+func extractVideoMetadata(fsys FileSystem, filePath string) (time.Time, error) {
+	video, err := readVideoMetadata(fsys, filePath)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if video.creationTime.IsZero() {
+		return time.Time{}, errNoCreationTime
+	}
+
+	return video.creationTime, nil
+}
+
+// readVideoMetadata reads creation date, duration, codec and frame size from MP4/MOV video
+// Only the boxes leading to mvhd and to the sample entries of the video track are read
+func readVideoMetadata(fsys FileSystem, filePath string) (*videoMetadata, error) {
+	f, err := fsys.Open(filePath)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to open video: %w", err)
+		return nil, fmt.Errorf("failed to open video: %w", err)
 	}
 	defer f.Close()
 
 	// Get file ModTime for comparison
 	fileInfo, err := f.Stat()
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to stat video: %w", err)
+		return nil, fmt.Errorf("failed to stat video: %w", err)
 	}
 	modTime := fileInfo.ModTime()
 
-	var foundTime *time.Time
+	video := &videoMetadata{}
+	trackHandler := "" // Handler type of the track being read ("vide", "soun"...)
 
 	// Parse MP4 file
 	_, err = mp4.ReadBoxStructure(f, func(h *mp4.ReadHandle) (interface{}, error) {
-		// Expand container boxes (moov, trak, etc.) to read their children
-		if h.BoxInfo.Type == mp4.BoxTypeMoov() || h.BoxInfo.Type == mp4.BoxTypeTrak() {
-			// Expand to read child boxes
+		switch h.BoxInfo.Type {
+		case mp4.BoxTypeMoov(), mp4.BoxTypeMdia(), mp4.BoxTypeMinf(), mp4.BoxTypeStbl():
+			// Expand container boxes to read their children
 			return h.Expand()
-		}
-
-		// Search for mvhd box (movie header) which contains creation_time
-		box, _, err := h.ReadPayload()
-		if err != nil {
-			return nil, err
-		}
-
-		// If it's a mvhd box
-		if mvhd, ok := box.(*mp4.Mvhd); ok {
-			// Convert MP4 timestamp (seconds since 1904-01-01) to time.Time
-			// MP4 spec: timestamps should be UTC
-			mp4Epoch := time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
-			creationTimestamp := mvhd.GetCreationTime()
-			// Safe conversion with overflow check
-			if creationTimestamp > uint64(1<<63-1) {
-				return nil, fmt.Errorf("creation time overflow")
+		case mp4.BoxTypeTrak():
+			trackHandler = ""
+			return h.Expand()
+		case mp4.BoxTypeStsd():
+			if trackHandler == "vide" && video.codec == "" {
+				return h.Expand()
 			}
-			creationTimeUTC := mp4Epoch.Add(time.Duration(int64(creationTimestamp)) * time.Second)
-
-			// Some cameras (Nikon, etc.) incorrectly store local time in the UTC field
-			// Detect this by comparing wall clock times (HH:MM:SS) between MP4 UTC and ModTime local
-			// If they match, camera stored local time as UTC (common bug)
-			mp4Hour, mp4Min, mp4Sec := creationTimeUTC.Clock()
-			modHour, modMin, modSec := modTime.Clock()
-
-			// Calculate absolute differences
-			hourDiff := mp4Hour - modHour
-			if hourDiff < 0 {
-				hourDiff = -hourDiff
+			return nil, nil
+		case mp4.BoxTypeMvhd():
+			// Movie header contains creation_time and duration
+			box, _, err := h.ReadPayload()
+			if err != nil {
+				return nil, err
 			}
-			minDiff := mp4Min - modMin
-			if minDiff < 0 {
-				minDiff = -minDiff
+			mvhd, ok := box.(*mp4.Mvhd)
+			if !ok {
+				return nil, fmt.Errorf("unexpected mvhd payload %T", box)
 			}
-			secDiff := mp4Sec - modSec
-			if secDiff < 0 {
-				secDiff = -secDiff
+			if video.creationTime, err = mvhdCreationTime(mvhd, modTime, filePath); err != nil {
+				return nil, err
 			}
-			wallClockDiffSeconds := hourDiff*3600 + minDiff*60 + secDiff
-
-			// If wall clock times are within 5 seconds, camera stored local as UTC
-			var creationTime time.Time
-			if wallClockDiffSeconds < 5 {
-				// Camera stored local time as UTC, reinterpret by changing timezone
-				// The time 21:45:03Z should be interpreted as 21:45:03 Local (not converted)
-				year, month, day := creationTimeUTC.Date()
-				hour, min, sec := creationTimeUTC.Clock()
-				creationTime = time.Date(year, month, day, hour, min, sec, 0, time.Local)
-
-				slog.Debug("MP4 timestamp appears to be local time (stored as UTC)",
-					"file", filepath.Base(filePath),
-					"mp4_utc_clock", fmt.Sprintf("%02d:%02d:%02d", mp4Hour, mp4Min, mp4Sec),
-					"mod_local_clock", fmt.Sprintf("%02d:%02d:%02d", modHour, modMin, modSec),
-					"corrected_time", creationTime,
-					"wall_diff_sec", wallClockDiffSeconds)
-			} else {
-				// Proper UTC timestamp, keep as is
-				slog.Debug("MP4 timestamp is proper UTC",
-					"file", filepath.Base(filePath),
-					"mp4_utc_clock", fmt.Sprintf("%02d:%02d:%02d", mp4Hour, mp4Min, mp4Sec),
-					"mod_local_clock", fmt.Sprintf("%02d:%02d:%02d", modHour, modMin, modSec),
-					"wall_diff_sec", wallClockDiffSeconds)
-				creationTime = creationTimeUTC
+			if mvhd.Timescale > 0 {
+				video.duration = time.Duration(float64(mvhd.GetDuration()) / float64(mvhd.Timescale) * float64(time.Second))
 			}
-
-			foundTime = &creationTime
+			return nil, nil
+		case mp4.BoxTypeHdlr():
+			if box, _, err := h.ReadPayload(); err == nil {
+				if hdlr, ok := box.(*mp4.Hdlr); ok {
+					trackHandler = string(hdlr.HandlerType[:])
+				}
+			}
+			return nil, nil
 		}
 
+		// Children of stsd are sample entries: their type is the codec
+		if len(h.Path) >= 2 && h.Path[len(h.Path)-2] == mp4.BoxTypeStsd() && video.codec == "" {
+			video.codec = strings.TrimSpace(h.BoxInfo.Type.String())
+			if box, _, err := h.ReadPayload(); err == nil {
+				if entry, ok := box.(*mp4.VisualSampleEntry); ok {
+					video.width = int(entry.Width)
+					video.height = int(entry.Height)
+				}
+			}
+		}
 		return nil, nil
 	})
 
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to parse MP4: %w", err)
+		return nil, fmt.Errorf("failed to parse MP4: %w", err)
 	}
 
-	if foundTime == nil {
-		return time.Time{}, fmt.Errorf("no creation time found in video metadata")
+	return video, nil
+}
+
+// mvhdCreationTime converts the creation time of a movie header
+func mvhdCreationTime(mvhd *mp4.Mvhd, modTime time.Time, filePath string) (time.Time, error) {
+	// Convert MP4 timestamp (seconds since 1904-01-01) to time.Time
+	// MP4 spec: timestamps should be UTC
+	mp4Epoch := time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	creationTimestamp := mvhd.GetCreationTime()
+	// Safe conversion with overflow check
+	if creationTimestamp > uint64(1<<63-1) {
+		return time.Time{}, fmt.Errorf("creation time overflow")
+	}
+	creationTimeUTC := mp4Epoch.Add(time.Duration(int64(creationTimestamp)) * time.Second)
+
+	// Some cameras (Nikon, etc.) incorrectly store local time in the UTC field
+	// Detect this by comparing wall clock times (HH:MM:SS) between MP4 UTC and ModTime local
+	// If they match, camera stored local time as UTC (common bug)
+	mp4Hour, mp4Min, mp4Sec := creationTimeUTC.Clock()
+	modHour, modMin, modSec := modTime.Clock()
+
+	// Calculate absolute differences
+	hourDiff := mp4Hour - modHour
+	if hourDiff < 0 {
+		hourDiff = -hourDiff
+	}
+	minDiff := mp4Min - modMin
+	if minDiff < 0 {
+		minDiff = -minDiff
+	}
+	secDiff := mp4Sec - modSec
+	if secDiff < 0 {
+		secDiff = -secDiff
+	}
+	wallClockDiffSeconds := hourDiff*3600 + minDiff*60 + secDiff
+
+	// If wall clock times are within 5 seconds, camera stored local as UTC
+	var creationTime time.Time
+	if wallClockDiffSeconds < 5 {
+		// Camera stored local time as UTC, reinterpret by changing timezone
+		// The time 21:45:03Z should be interpreted as 21:45:03 Local (not converted)
+		year, month, day := creationTimeUTC.Date()
+		hour, min, sec := creationTimeUTC.Clock()
+		creationTime = time.Date(year, month, day, hour, min, sec, 0, time.Local)
+
+		slog.Debug("MP4 timestamp appears to be local time (stored as UTC)",
+			"file", filepath.Base(filePath),
+			"mp4_utc_clock", fmt.Sprintf("%02d:%02d:%02d", mp4Hour, mp4Min, mp4Sec),
+			"mod_local_clock", fmt.Sprintf("%02d:%02d:%02d", modHour, modMin, modSec),
+			"corrected_time", creationTime,
+			"wall_diff_sec", wallClockDiffSeconds)
+	} else {
+		// Proper UTC timestamp, keep as is
+		slog.Debug("MP4 timestamp is proper UTC",
+			"file", filepath.Base(filePath),
+			"mp4_utc_clock", fmt.Sprintf("%02d:%02d:%02d", mp4Hour, mp4Min, mp4Sec),
+			"mod_local_clock", fmt.Sprintf("%02d:%02d:%02d", modHour, modMin, modSec),
+			"wall_diff_sec", wallClockDiffSeconds)
+		creationTime = creationTimeUTC
 	}
 
-	return *foundTime, nil
+	return creationTime, nil
 }
 
 // extractGPS extracts GPS coordinates from EXIF
-func extractGPS(filePath string) (*GPSCoord, error) {
-	f, err := os.Open(filePath)
+func extractGPS(fsys FileSystem, filePath string) (*GPSCoord, error) {
+	x, err := decodeEXIF(fsys, filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
-	defer f.Close()
 
-	x, err := exif.Decode(f)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode EXIF: %w", err)
-	}
+	return exifGPS(x)
+}
 
+// exifGPS returns GPS coordinates from decoded EXIF
+func exifGPS(x *exif.Exif) (*GPSCoord, error) {
 	lat, lon, err := x.LatLong()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get GPS coordinates: %w", err)
@@ -276,9 +645,9 @@ func extractGPS(filePath string) (*GPSCoord, error) {
 }
 
 // isValidDateTime verifies the date is consistent
-func isValidDateTime(t time.Time) bool {
+func isValidDateTime(t time.Time, minYear int) bool {
 	// Check minimum year
-	if t.Year() < minValidYear {
+	if t.Year() < minYear {
 		return false
 	}
 
@@ -289,7 +658,7 @@ func isValidDateTime(t time.Time) bool {
 
 // findAssociatedJPEG finds the corresponding JPEG or HEIC file for a RAW file
 // Ex: PHOTO_01.NEF → PHOTO_01.JPG, PHOTO_01.jpeg, PHOTO_01.heic, or PHOTO_01.HEIC
-func findAssociatedJPEG(rawPath string) (string, error) {
+func findAssociatedJPEG(fsys FileSystem, rawPath string) (string, error) {
 	dir := filepath.Dir(rawPath)
 	baseName := strings.TrimSuffix(filepath.Base(rawPath), filepath.Ext(rawPath))
 
@@ -298,7 +667,7 @@ func findAssociatedJPEG(rawPath string) (string, error) {
 
 	for _, ext := range photoExtensions {
 		photoPath := filepath.Join(dir, baseName+ext)
-		if _, err := os.Stat(photoPath); err == nil {
+		if _, err := fsys.Stat(photoPath); err == nil {
 			return photoPath, nil
 		}
 	}
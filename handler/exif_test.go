@@ -28,6 +28,11 @@ func TestDateSource_String(t *testing.T) {
 			source:   DateSourceVideoMeta,
 			expected: "VideoMeta",
 		},
+		{
+			name:     "Filename source",
+			source:   DateSourceFilename,
+			expected: "Filename",
+		},
 	}
 
 	for _, tt := range tests {
@@ -90,7 +95,7 @@ func TestIsValidDateTime(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := isValidDateTime(tt.dateTime)
+			result := isValidDateTime(tt.dateTime, defaultMinValidYear)
 			if result != tt.expected {
 				t.Errorf("isValidDateTime(%v) = %v, want %v", tt.dateTime, result, tt.expected)
 			}
@@ -171,7 +176,7 @@ func TestFindAssociatedJPEG(t *testing.T) {
 			}
 
 			// Execute the test
-			result, err := findAssociatedJPEG(rawPath)
+			result, err := findAssociatedJPEG(OSFileSystem{}, rawPath)
 
 			if tt.shouldError {
 				if err == nil {
@@ -424,7 +429,7 @@ func TestExtractEXIFDate_WithValidEXIF(t *testing.T) {
 	createJPEGWithEXIF(t, testFile, expectedDate)
 
 	// Extract EXIF date
-	actualDate, err := extractEXIFDate(testFile)
+	actualDate, err := extractEXIFDate(OSFileSystem{}, testFile)
 	if err != nil {
 		t.Fatalf("extractEXIFDate() failed: %v", err)
 	}
@@ -444,7 +449,7 @@ func TestExtractEXIFDate_InvalidFile(t *testing.T) {
 		t.Fatalf("failed to create test file: %v", err)
 	}
 
-	_, err := extractEXIFDate(testFile)
+	_, err := extractEXIFDate(OSFileSystem{}, testFile)
 	if err == nil {
 		t.Error("extractEXIFDate() expected error for invalid EXIF, got nil")
 	}
@@ -533,7 +538,7 @@ func TestExtractGPS_NoGPSData(t *testing.T) {
 	// Create JPEG without GPS
 	createJPEGWithEXIF(t, testFile, time.Now())
 
-	_, err := extractGPS(testFile)
+	_, err := extractGPS(OSFileSystem{}, testFile)
 	if err == nil {
 		t.Error("extractGPS() expected error for file without GPS, got nil")
 	}
@@ -547,7 +552,7 @@ func TestExtractGPS_InvalidFile(t *testing.T) {
 		t.Fatalf("failed to create test file: %v", err)
 	}
 
-	_, err := extractGPS(testFile)
+	_, err := extractGPS(OSFileSystem{}, testFile)
 	if err == nil {
 		t.Error("extractGPS() expected error for invalid file, got nil")
 	}
@@ -559,7 +564,7 @@ func TestExtractVideoMetadata_ValidMP4(t *testing.T) {
 	mp4Path := getTestMP4Fixture()
 
 	// Extract video metadata
-	actualTime, err := extractVideoMetadata(mp4Path)
+	actualTime, err := extractVideoMetadata(OSFileSystem{}, mp4Path)
 	if err != nil {
 		t.Fatalf("extractVideoMetadata() failed: %v", err)
 	}
@@ -571,6 +576,20 @@ func TestExtractVideoMetadata_ValidMP4(t *testing.T) {
 	}
 }
 
+func TestReadVideoMetadata_Details(t *testing.T) {
+	video, err := readVideoMetadata(OSFileSystem{}, getTestMP4Fixture())
+	if err != nil {
+		t.Fatalf("readVideoMetadata() failed: %v", err)
+	}
+
+	if video.codec != "avc1" || video.width != 320 || video.height != 240 {
+		t.Errorf("video track = %s %dx%d, want avc1 320x240", video.codec, video.width, video.height)
+	}
+	if video.duration != time.Second {
+		t.Errorf("duration = %v, want 1s", video.duration)
+	}
+}
+
 func TestExtractVideoMetadata_InvalidMP4(t *testing.T) {
 	tempDir := t.TempDir()
 	testFile := filepath.Join(tempDir, "invalid.mp4")
@@ -579,7 +598,7 @@ func TestExtractVideoMetadata_InvalidMP4(t *testing.T) {
 		t.Fatalf("failed to create test file: %v", err)
 	}
 
-	_, err := extractVideoMetadata(testFile)
+	_, err := extractVideoMetadata(OSFileSystem{}, testFile)
 	if err == nil {
 		t.Error("extractVideoMetadata() expected error for invalid MP4, got nil")
 	}
@@ -605,7 +624,7 @@ func TestExtractVideoMetadata_NoCreationTime(t *testing.T) {
 		t.Fatalf("failed to create test file: %v", err)
 	}
 
-	_, err := extractVideoMetadata(testFile)
+	_, err := extractVideoMetadata(OSFileSystem{}, testFile)
 	if err == nil {
 		t.Error("extractVideoMetadata() expected error for MP4 without creation time, got nil")
 	}
@@ -726,21 +745,21 @@ func TestExtractMetadata_MovieFallback(t *testing.T) {
 }
 
 func TestExtractEXIFDate_FileOpenError(t *testing.T) {
-	_, err := extractEXIFDate("/nonexistent/file.jpg")
+	_, err := extractEXIFDate(OSFileSystem{}, "/nonexistent/file.jpg")
 	if err == nil {
 		t.Error("extractEXIFDate() expected error for non-existent file, got nil")
 	}
 }
 
 func TestExtractVideoMetadata_FileOpenError(t *testing.T) {
-	_, err := extractVideoMetadata("/nonexistent/video.mp4")
+	_, err := extractVideoMetadata(OSFileSystem{}, "/nonexistent/video.mp4")
 	if err == nil {
 		t.Error("extractVideoMetadata() expected error for non-existent file, got nil")
 	}
 }
 
 func TestExtractGPS_FileOpenError(t *testing.T) {
-	_, err := extractGPS("/nonexistent/file.jpg")
+	_, err := extractGPS(OSFileSystem{}, "/nonexistent/file.jpg")
 	if err == nil {
 		t.Error("extractGPS() expected error for non-existent file, got nil")
 	}
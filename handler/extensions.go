@@ -2,8 +2,10 @@ package handler
 
 import (
 	"fmt"
+	"log/slog"
 	"path/filepath"
 	"strings"
+	"time"
 	"unicode"
 )
 
@@ -25,11 +27,15 @@ var (
 		".nrw": true,
 		".crw": true,
 		".cr2": true,
+		".cr3": true,
 		".rw2": true,
 		".dng": true,
 		".arw": true,
 		".orf": true,
 		".raf": true,
+		".pef": true,
+		".srw": true,
+		".3fr": true,
 	}
 
 	defaultPhotoExtensions = map[string]bool{
@@ -40,6 +46,13 @@ var (
 		".webp": true,
 		".avif": true,
 	}
+
+	// Sidecar files travel with their media file (v2.10.0+)
+	defaultSidecarExtensions = map[string]bool{
+		".xmp": true, // Lightroom, darktable, Capture One
+		".aae": true, // iPhone edits
+		".thm": true, // Canon/GoPro video thumbnails
+	}
 )
 
 // ValidateExtension validates that an extension is reasonable
@@ -106,9 +119,50 @@ func buildExtensionMap(defaults map[string]bool, custom []string) (map[string]bo
 // executionContext holds runtime configuration including extension maps
 // Built once per execution with custom extensions merged into defaults
 type executionContext struct {
-	movieExtensions map[string]bool
-	rawExtensions   map[string]bool
-	photoExtensions map[string]bool
+	movieExtensions   map[string]bool
+	rawExtensions     map[string]bool
+	photoExtensions   map[string]bool
+	sidecarExtensions map[string]bool
+
+	// Destination basenames chosen during the run, keyed by source path without extension
+	// Keeps RAW/JPEG pairs and sidecars on the same name after a collision rename (v2.10.0+)
+	renamedBases map[string]string
+	collisions   collisionStats
+	orphanRaws   []string // Orphan RAW files sent to orphan/, relative to BasePath (v2.10.0+)
+
+	// File renaming on import (v2.10.0+)
+	renamer   *renameTemplate // nil when --rename-template is not set
+	renameSeq map[string]int  // Next sequence number per destination group folder
+	renames   []renameRecord  // Renamed files, written to the rename manifest
+	placed    []string        // Files placed by the run (and the run it resumes), relative to BasePath
+
+	// Routing rules (v2.10.0+)
+	rules *routingRules // nil when --rules is not set
+
+	// Dates in file names (v2.10.0+)
+	datePatterns []filenameDatePattern // --filename-date-pattern ones first, then built-in ones
+
+	// Calendar grouping (v2.10.0+)
+	dayBoundary time.Duration // Calendar days start at this time of day
+
+	// GPS track geotagging (v2.10.0+)
+	track *gpsTrack // nil when --gpx is not set
+
+	// Trip detection (v2.10.0+)
+	home *GPSCoord // Days within the home radius end trips, nil without --home
+
+	// Date sources (v2.10.0+)
+	datePriority          []DateSource // Sources tried in order, unlisted ones are ignored
+	dateConflictPolicy    string
+	dateConflictThreshold time.Duration // 0 disables conflict detection
+	minValidYear          int
+
+	// Injected by library callers (v2.10.0+)
+	log      *slog.Logger
+	progress ProgressFunc // nil when nobody listens
+	resolver Resolver
+	fs       FileSystem
+	metrics  *Metrics // nil when metrics are not collected
 }
 
 // newExecutionContext creates a context with default + custom extensions
@@ -129,10 +183,72 @@ func newExecutionContext(cfg *Config) (*executionContext, error) {
 		return nil, fmt.Errorf("invalid photo extensions: %w", err)
 	}
 
+	var renamer *renameTemplate
+	if cfg.RenameTemplate != "" {
+		renamer, err = parseRenameTemplate(cfg.RenameTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rename template: %w", err)
+		}
+	}
+
+	datePatterns, err := newFilenameDatePatterns(cfg.FilenameDatePatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	dayBoundary, err := parseDayBoundary(cfg.DayBoundary)
+	if err != nil {
+		return nil, err
+	}
+
+	home, err := parseHome(cfg.Home)
+	if err != nil {
+		return nil, err
+	}
+
+	datePriority, err := parseDatePriority(cfg.DatePriority)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date priority: %w", err)
+	}
+
+	var rules *routingRules
+	if cfg.RulesFile != "" {
+		rules, err = loadRules(cfg.fileSystem(), cfg.RulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rules: %w", err)
+		}
+	}
+
+	var track *gpsTrack
+	if cfg.GPXPath != "" {
+		track, err = loadTracks(cfg.fileSystem(), cfg.GPXPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPS track: %w", err)
+		}
+	}
+
 	return &executionContext{
-		movieExtensions: movieExts,
-		rawExtensions:   rawExts,
-		photoExtensions: photoExts,
+		movieExtensions:       movieExts,
+		rawExtensions:         rawExts,
+		photoExtensions:       photoExts,
+		sidecarExtensions:     defaultSidecarExtensions,
+		renamedBases:          make(map[string]string),
+		renamer:               renamer,
+		renameSeq:             make(map[string]int),
+		rules:                 rules,
+		datePatterns:          datePatterns,
+		dayBoundary:           dayBoundary,
+		track:                 track,
+		home:                  home,
+		datePriority:          datePriority,
+		dateConflictPolicy:    cfg.DateConflictPolicy,
+		dateConflictThreshold: cfg.DateConflictThreshold,
+		minValidYear:          cfg.minValidYear(),
+		log:                   cfg.logger(),
+		progress:              cfg.Progress,
+		resolver:              cfg.resolver(),
+		fs:                    cfg.fileSystem(),
+		metrics:               cfg.Metrics,
 	}, nil
 }
 
@@ -140,9 +256,17 @@ func newExecutionContext(cfg *Config) (*executionContext, error) {
 // Useful for testing and backward compatibility
 func newDefaultExecutionContext() *executionContext {
 	return &executionContext{
-		movieExtensions: defaultMovieExtensions,
-		rawExtensions:   defaultRawExtensions,
-		photoExtensions: defaultPhotoExtensions,
+		movieExtensions:   defaultMovieExtensions,
+		rawExtensions:     defaultRawExtensions,
+		photoExtensions:   defaultPhotoExtensions,
+		sidecarExtensions: defaultSidecarExtensions,
+		renamedBases:      make(map[string]string),
+		datePatterns:      builtinDatePatterns,
+		datePriority:      defaultDateSources,
+		minValidYear:      defaultMinValidYear,
+		log:               slog.Default(),
+		resolver:          terminalResolver{},
+		fs:                OSFileSystem{},
 	}
 }
 
@@ -169,3 +293,16 @@ func (ctx *executionContext) isMediaFile(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
 	return ctx.movieExtensions[ext] || ctx.rawExtensions[ext] || ctx.photoExtensions[ext]
 }
+
+// isSidecar checks if filename is a sidecar file (.xmp, .aae, .thm) (case-insensitive)
+func (ctx *executionContext) isSidecar(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return ctx.sidecarExtensions[ext]
+}
+
+// reportProgress notifies the progress listener, if any
+func (ctx *executionContext) reportProgress(stage string, current, total int) {
+	if ctx.progress != nil {
+		ctx.progress(ProgressEvent{Stage: stage, Current: current, Total: total})
+	}
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Phones, screenshot tools, messaging apps and drones write the capture date in the file name
+// It replaces the modification time of files that lost their EXIF (v2.10.0+)
+
+// Named groups of filename date patterns: year, month and day are required
+const (
+	dateGroupYear   = "year"
+	dateGroupMonth  = "month"
+	dateGroupDay    = "day"
+	dateGroupHour   = "hour"
+	dateGroupMinute = "minute"
+	dateGroupSecond = "second"
+	dateGroupAMPM   = "ampm" // "AM" or "PM" for 12-hour clocks
+)
+
+// filenameDatePattern reads a date from a file name with named groups
+type filenameDatePattern struct {
+	re   *regexp.Regexp
+	name string // Shown in debug logs
+	utc  bool   // The name holds a UTC time (Pixel)
+}
+
+// builtinDatePatterns are tried after the --filename-date-pattern ones, in order
+// GoPro names (GOPR0042.JPG, GX010042.MP4) carry no date: their EXIF or MP4 date is used
+var builtinDatePatterns = []filenameDatePattern{
+	// Pixel: PXL_20240615_143012345.mp4, in UTC
+	{name: "Pixel", utc: true, re: regexp.MustCompile(`(?i)^PXL_(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})_(?P<hour>\d{2})(?P<minute>\d{2})(?P<second>\d{2})`)},
+	// Android: IMG_20240615_143012.jpg, VID_20240615_143012.mp4, PANO_20240615_143012.jpg
+	{name: "Android", re: regexp.MustCompile(`(?i)^(?:IMG|VID|MVIMG|PANO|BURST\d*)_(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})_(?P<hour>\d{2})(?P<minute>\d{2})(?P<second>\d{2})`)},
+	// Samsung: 20240615_143012.jpg, 20240615_143012(0).mp4
+	{name: "Samsung", re: regexp.MustCompile(`^(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})_(?P<hour>\d{2})(?P<minute>\d{2})(?P<second>\d{2})`)},
+	// WhatsApp: IMG-20240615-WA0003.jpg (day only)
+	{name: "WhatsApp", re: regexp.MustCompile(`(?i)^(?:IMG|VID|AUD|PTT|STK|DOC)-(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})-WA\d+`)},
+	// Signal: signal-2024-06-15-143012.jpg, signal-2024-06-15-14-30-12-123.jpg
+	{name: "Signal", re: regexp.MustCompile(`(?i)^signal-(?P<year>\d{4})-(?P<month>\d{2})-(?P<day>\d{2})-(?P<hour>\d{2})-?(?P<minute>\d{2})-?(?P<second>\d{2})`)},
+	// Telegram Desktop: photo_2024-06-15_14-30-12.jpg
+	{name: "Telegram", re: regexp.MustCompile(`(?i)^(?:photo|video)_(?P<year>\d{4})-(?P<month>\d{2})-(?P<day>\d{2})_(?P<hour>\d{2})-(?P<minute>\d{2})-(?P<second>\d{2})`)},
+	// macOS: "Screenshot 2024-06-15 at 14.30.12.png", "Screen Shot 2024-06-15 at 2.30.12 PM.png"
+	// (narrow no-break space before AM/PM since macOS 13)
+	{name: "macOS screenshot", re: regexp.MustCompile(`(?i)^screen ?shot (?P<year>\d{4})-(?P<month>\d{2})-(?P<day>\d{2}) at (?P<hour>\d{1,2})\.(?P<minute>\d{2})\.(?P<second>\d{2})(?:[ \x{202F}](?P<ampm>[AP]M))?`)},
+	// Android: Screenshot_20240615-143012.png, Screenshot_20240615_143012_Chrome.jpg
+	{name: "Android screenshot", re: regexp.MustCompile(`(?i)^screenshot_(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})[-_](?P<hour>\d{2})(?P<minute>\d{2})(?P<second>\d{2})`)},
+	// DJI: DJI_20240615143012_0001_D.JPG (DJI_0001.JPG carries no date)
+	{name: "DJI", re: regexp.MustCompile(`(?i)^DJI_(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})(?P<hour>\d{2})(?P<minute>\d{2})(?P<second>\d{2})_`)},
+}
+
+// errInvalidFilenameDate is returned when the groups of a matching pattern do not form a valid date
+var errInvalidFilenameDate = errors.New("invalid date in file name")
+
+// compileFilenameDatePattern compiles a --filename-date-pattern regex
+// Example: `^(?P<year>\d{4})-(?P<month>\d{2})-(?P<day>\d{2}) (?P<hour>\d{2})h(?P<minute>\d{2})`
+func compileFilenameDatePattern(expr string) (filenameDatePattern, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return filenameDatePattern{}, fmt.Errorf("invalid filename date pattern: %w", err)
+	}
+
+	for _, group := range []string{dateGroupYear, dateGroupMonth, dateGroupDay} {
+		if re.SubexpIndex(group) < 0 {
+			return filenameDatePattern{}, fmt.Errorf("filename date pattern %q has no (?P<%s>...) group", expr, group)
+		}
+	}
+	return filenameDatePattern{name: expr, re: re}, nil
+}
+
+// newFilenameDatePatterns returns the user patterns followed by the built-in ones
+func newFilenameDatePatterns(exprs []string) ([]filenameDatePattern, error) {
+	patterns := make([]filenameDatePattern, 0, len(exprs)+len(builtinDatePatterns))
+	for _, expr := range exprs {
+		pattern, err := compileFilenameDatePattern(expr)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return append(patterns, builtinDatePatterns...), nil
+}
+
+// filenameDate returns the date written in a file name with the first matching pattern
+// Names holding only the day keep the time of modTime when it is the same day
+func filenameDate(patterns []filenameDatePattern, name string, modTime time.Time) (time.Time, string, bool) {
+	for _, pattern := range patterns {
+		match := pattern.re.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+
+		date, hasTime, err := pattern.date(match)
+		if err != nil {
+			continue
+		}
+
+		if !hasTime {
+			y, m, d := modTime.In(time.Local).Date()
+			if date.Equal(time.Date(y, m, d, 0, 0, 0, 0, time.Local)) {
+				return modTime, pattern.name, true
+			}
+		}
+		return date, pattern.name, true
+	}
+	return time.Time{}, "", false
+}
+
+// date builds the date of a match, hasTime is false when the pattern has no hour
+func (p *filenameDatePattern) date(match []string) (date time.Time, hasTime bool, err error) {
+	group := func(name string) string {
+		if idx := p.re.SubexpIndex(name); idx >= 0 {
+			return match[idx]
+		}
+		return ""
+	}
+
+	var values [6]int
+	for i, name := range []string{dateGroupYear, dateGroupMonth, dateGroupDay, dateGroupHour, dateGroupMinute, dateGroupSecond} {
+		value := group(name)
+		if value == "" {
+			continue
+		}
+		if values[i], err = strconv.Atoi(value); err != nil {
+			return time.Time{}, false, errInvalidFilenameDate
+		}
+	}
+	year, month, day, hour, minute, second := values[0], values[1], values[2], values[3], values[4], values[5]
+	hasTime = group(dateGroupHour) != ""
+
+	if len(group(dateGroupYear)) == 2 {
+		year += 2000
+	}
+	switch strings.ToUpper(group(dateGroupAMPM)) {
+	case "AM":
+		if hour == 12 {
+			hour = 0
+		}
+	case "PM":
+		if hour < 12 {
+			hour += 12
+		}
+	}
+
+	loc := time.Local
+	if p.utc {
+		loc = time.UTC
+	}
+	date = time.Date(year, time.Month(month), day, hour, minute, second, 0, loc)
+
+	// time.Date normalizes out of range values (2024-13-45): reject them instead
+	if date.Year() != year || int(date.Month()) != month || date.Day() != day ||
+		date.Hour() != hour || date.Minute() != minute || date.Second() != second {
+		return time.Time{}, false, errInvalidFilenameDate
+	}
+	return date.In(time.Local), hasTime, nil
+}
+
+// addFilenameDate records the date written in the name of a file as a date candidate
+func (ctx *executionContext) addFilenameDate(metadata *FileMetadata) {
+	if !ctx.usesDateSource(DateSourceFilename) {
+		return
+	}
+
+	name := metadata.FileInfo.Name()
+	date, pattern, ok := filenameDate(ctx.datePatterns, name, metadata.FileInfo.ModTime())
+	if !ok {
+		return
+	}
+	ctx.log.Debug("extracted date from file name", "file", name, "pattern", pattern, "date", date.Format(time.RFC3339))
+	ctx.addDateCandidate(metadata, DateSourceFilename, date)
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilenameDate(t *testing.T) {
+	modTime := time.Date(2024, 7, 1, 9, 0, 0, 0, time.Local)
+	at := func(y int, m time.Month, d, h, mi, s int) time.Time {
+		return time.Date(y, m, d, h, mi, s, 0, time.Local)
+	}
+
+	tests := []struct {
+		name        string
+		want        time.Time
+		wantPattern string
+		wantOK      bool
+	}{
+		{"PXL_20240615_143012345.mp4", time.Date(2024, 6, 15, 14, 30, 12, 0, time.UTC), "Pixel", true},
+		{"PXL_20240615_143012345.RAW-01.COVER.jpg", time.Date(2024, 6, 15, 14, 30, 12, 0, time.UTC), "Pixel", true},
+		{"IMG_20240615_143012.jpg", at(2024, 6, 15, 14, 30, 12), "Android", true},
+		{"VID_20240615_143012.mp4", at(2024, 6, 15, 14, 30, 12), "Android", true},
+		{"20240615_143012.jpg", at(2024, 6, 15, 14, 30, 12), "Samsung", true},
+		{"20240615_143012(0).mp4", at(2024, 6, 15, 14, 30, 12), "Samsung", true},
+		{"IMG-20240615-WA0003.jpg", at(2024, 6, 15, 0, 0, 0), "WhatsApp", true},
+		{"VID-20240701-WA0001.mp4", modTime, "WhatsApp", true}, // Same day: ModTime gives the time
+		{"signal-2024-06-15-143012.jpg", at(2024, 6, 15, 14, 30, 12), "Signal", true},
+		{"signal-2024-06-15-14-30-12-123.jpg", at(2024, 6, 15, 14, 30, 12), "Signal", true},
+		{"photo_2024-06-15_14-30-12.jpg", at(2024, 6, 15, 14, 30, 12), "Telegram", true},
+		{"Screenshot 2024-06-15 at 14.30.12.png", at(2024, 6, 15, 14, 30, 12), "macOS screenshot", true},
+		{"Screen Shot 2024-06-15 at 2.30.12 PM.png", at(2024, 6, 15, 14, 30, 12), "macOS screenshot", true},
+		{"Screenshot 2024-06-15 at 12.05.00 AM.png", at(2024, 6, 15, 0, 5, 0), "macOS screenshot", true},
+		{"Screenshot_20240615-143012.png", at(2024, 6, 15, 14, 30, 12), "Android screenshot", true},
+		{"Screenshot_20240615_143012_Chrome.jpg", at(2024, 6, 15, 14, 30, 12), "Android screenshot", true},
+		{"DJI_20240615143012_0001_D.JPG", at(2024, 6, 15, 14, 30, 12), "DJI", true},
+		{"DJI_0001.JPG", time.Time{}, "", false},
+		{"GX010042.MP4", time.Time{}, "", false},
+		{"Screenshot.png", time.Time{}, "", false},
+		{"IMG-20241345-WA0003.jpg", time.Time{}, "", false},                    // Invalid month
+		{"IMG_20240615_256012.jpg", time.Time{}, "", false},                    // Invalid hour
+		{"IMG-19800101-WA0003.jpg", at(1980, 1, 1, 0, 0, 0), "WhatsApp", true}, // Year bounds are checked with the other date sources
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, pattern, ok := filenameDate(builtinDatePatterns, tt.name, modTime)
+			if ok != tt.wantOK {
+				t.Fatalf("filenameDate() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !got.Equal(tt.want) || pattern != tt.wantPattern {
+				t.Errorf("filenameDate() = %v (%s), want %v (%s)", got, pattern, tt.want, tt.wantPattern)
+			}
+		})
+	}
+}
+
+func TestNewFilenameDatePatterns(t *testing.T) {
+	patterns, err := newFilenameDatePatterns([]string{
+		`^Trip (?P<day>\d{2})\.(?P<month>\d{2})\.(?P<year>\d{2}) (?P<hour>\d{2})h(?P<minute>\d{2})`,
+	})
+	if err != nil {
+		t.Fatalf("newFilenameDatePatterns() error: %v", err)
+	}
+	if len(patterns) != len(builtinDatePatterns)+1 {
+		t.Fatalf("got %d patterns, want user pattern then %d built-in ones", len(patterns), len(builtinDatePatterns))
+	}
+
+	got, _, ok := filenameDate(patterns, "Trip 15.06.24 14h30.jpg", time.Now())
+	if want := time.Date(2024, 6, 15, 14, 30, 0, 0, time.Local); !ok || !got.Equal(want) {
+		t.Errorf("filenameDate() = %v, %v, want %v", got, ok, want)
+	}
+
+	// User patterns are tried first
+	patterns, err = newFilenameDatePatterns([]string{`^IMG_(?P<year>\d{4})(?P<day>\d{2})(?P<month>\d{2})`})
+	if err != nil {
+		t.Fatalf("newFilenameDatePatterns() error: %v", err)
+	}
+	got, _, _ = filenameDate(patterns, "IMG_20241506_143012.jpg", time.Now())
+	if want := time.Date(2024, 6, 15, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("filenameDate() = %v, want %v from the user pattern", got, want)
+	}
+
+	errorTests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{`(?P<year>\d{4}`, "invalid filename date pattern"},
+		{`(?P<year>\d{4})(?P<month>\d{2})`, "(?P<day>...)"},
+		{`(\d{4})(\d{2})(\d{2})`, "(?P<year>...)"},
+	}
+	for _, tt := range errorTests {
+		if _, err := newFilenameDatePatterns([]string{tt.expr}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("newFilenameDatePatterns(%q) error = %v, want %q", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestConfigValidate_FilenameDate(t *testing.T) {
+	cfg := DefaultConfig(t.TempDir())
+	cfg.FilenameDatePatterns = []string{`(?P<year>\d{4})`}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject a pattern without month and day groups")
+	}
+}
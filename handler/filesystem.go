@@ -0,0 +1,418 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileSystem is the storage picsplit reads and organizes (v2.10.0+)
+// Paths are built with filepath.Join from Config.BasePath; errors follow the os package
+// conventions (errors.Is(err, fs.ErrNotExist), fs.ErrExist) so backends are interchangeable
+// Inputs given by path (--gpx tracks, --rules file) are read from it too; the run report
+// (--report) and the metrics textfile are written to the local disk, and watch requires
+// OSFileSystem since file events and open writers come from the OS
+type FileSystem interface {
+	ReadDir(name string) ([]fs.DirEntry, error) // Entries sorted by name
+	Stat(name string) (fs.FileInfo, error)
+	Open(name string) (File, error)
+	Rename(oldpath, newpath string) error
+	MkdirAll(path string, perm fs.FileMode) error
+	Remove(name string) error // File or empty directory
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// BirthTimeFileSystem is implemented by file systems knowing when a file was created (v2.10.0+)
+// It is the birthtime source of Config.DatePriority
+type BirthTimeFileSystem interface {
+	BirthTime(name string) (time.Time, error)
+}
+
+// File is a file opened for reading (EXIF, video metadata, hashing)
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+	Stat() (fs.FileInfo, error)
+}
+
+// OSFileSystem is the local file system (default)
+type OSFileSystem struct{}
+
+func (OSFileSystem) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (OSFileSystem) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (OSFileSystem) Rename(oldpath, newpath string) error       { return os.Rename(oldpath, newpath) }
+func (OSFileSystem) Remove(name string) error                   { return os.Remove(name) }
+func (OSFileSystem) BirthTime(name string) (time.Time, error)   { return birthTime(name) }
+
+func (OSFileSystem) Open(name string) (File, error) {
+	return os.Open(name) //nolint:gosec // paths come from the folder being organized
+}
+
+func (OSFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (OSFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+// MemFileSystem is an in-memory FileSystem, for hermetic tests and dry runs on copies
+// Safe for concurrent use
+type MemFileSystem struct {
+	mu      sync.RWMutex
+	entries map[string]*memEntry // cleaned path -> entry
+}
+
+// memEntry is a file or directory of a MemFileSystem
+type memEntry struct {
+	name    string
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewMemFileSystem creates an empty in-memory file system containing only the root directory
+func NewMemFileSystem() *MemFileSystem {
+	m := &MemFileSystem{entries: make(map[string]*memEntry)}
+	root := string(filepath.Separator)
+	m.entries[root] = &memEntry{name: root, mode: fs.ModeDir | permDirectory, modTime: time.Now()}
+	return m
+}
+
+// memPath normalizes a path: absolute, cleaned
+func memPath(name string) string {
+	return filepath.Clean(string(filepath.Separator) + name)
+}
+
+// ReadDir lists the direct children of a directory, sorted by name
+func (m *MemFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	dir := memPath(name)
+	entry, ok := m.entries[dir]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !entry.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	var result []fs.DirEntry
+	for path, child := range m.entries {
+		if path != dir && filepath.Dir(path) == dir {
+			result = append(result, fs.FileInfoToDirEntry(child.info()))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+
+	return result, nil
+}
+
+// Stat returns the file info of a file or directory
+func (m *MemFileSystem) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, ok := m.entries[memPath(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return entry.info(), nil
+}
+
+// Open opens a file for reading; later writes to the file are not visible to the reader
+func (m *MemFileSystem) Open(name string) (File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, ok := m.entries[memPath(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memFile{Reader: bytes.NewReader(entry.data), info: entry.info()}, nil
+}
+
+// Rename moves a file or a directory with its content, replacing an existing file like os.Rename
+func (m *MemFileSystem) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	src, dst := memPath(oldpath), memPath(newpath)
+	entry, ok := m.entries[src]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if parent, ok := m.entries[filepath.Dir(dst)]; !ok || !parent.mode.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if existing, ok := m.entries[dst]; ok && existing.mode.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrExist}
+	}
+	if src == dst {
+		return nil
+	}
+
+	delete(m.entries, src)
+	entry.name = filepath.Base(dst)
+	m.entries[dst] = entry
+
+	// Move directory content
+	if entry.mode.IsDir() {
+		prefix := src + string(filepath.Separator)
+		for path, child := range m.entries {
+			if strings.HasPrefix(path, prefix) {
+				delete(m.entries, path)
+				m.entries[dst+string(filepath.Separator)+strings.TrimPrefix(path, prefix)] = child
+			}
+		}
+	}
+
+	return nil
+}
+
+// MkdirAll creates a directory and its missing parents
+func (m *MemFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir := memPath(path)
+	var missing []string
+	for {
+		entry, ok := m.entries[dir]
+		if ok {
+			if !entry.mode.IsDir() {
+				return &fs.PathError{Op: "mkdir", Path: path, Err: errors.New("not a directory")}
+			}
+			break
+		}
+		missing = append(missing, dir)
+		dir = filepath.Dir(dir)
+	}
+
+	for _, dir := range missing {
+		m.entries[dir] = &memEntry{name: filepath.Base(dir), mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+	}
+	return nil
+}
+
+// Remove removes a file or an empty directory
+func (m *MemFileSystem) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := memPath(name)
+	entry, ok := m.entries[path]
+	if !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.mode.IsDir() {
+		for child := range m.entries {
+			if child != path && filepath.Dir(child) == path {
+				return &fs.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+			}
+		}
+	}
+
+	delete(m.entries, path)
+	return nil
+}
+
+// WriteFile creates or replaces a file; the parent directory must exist
+func (m *MemFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := memPath(name)
+	if parent, ok := m.entries[filepath.Dir(path)]; !ok || !parent.mode.IsDir() {
+		return &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if existing, ok := m.entries[path]; ok && existing.mode.IsDir() {
+		return &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+
+	m.entries[path] = &memEntry{
+		name:    filepath.Base(path),
+		data:    append([]byte(nil), data...),
+		mode:    perm.Perm(),
+		modTime: time.Now(),
+	}
+	return nil
+}
+
+// Chtimes sets the modification time of a file, used as date when EXIF is missing
+func (m *MemFileSystem) Chtimes(name string, modTime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[memPath(name)]
+	if !ok {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: fs.ErrNotExist}
+	}
+	entry.modTime = modTime
+	return nil
+}
+
+// info returns a snapshot of the entry metadata
+func (e *memEntry) info() fs.FileInfo {
+	return memFileInfo{name: e.name, size: int64(len(e.data)), mode: e.mode, modTime: e.modTime}
+}
+
+// memFileInfo implements fs.FileInfo for MemFileSystem entries
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memFileInfo) Sys() any           { return nil }
+
+// memFile is an open MemFileSystem file
+type memFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+// walkDir walks the tree rooted at root like filepath.WalkDir, on any FileSystem
+// Returning fs.SkipDir from fn skips the directory
+func walkDir(fsys FileSystem, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Stat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+
+	err = walkDirEntry(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// walkDirEntry visits path, then its children when it is a directory
+func walkDirEntry(fsys FileSystem, path string, entry fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, entry, nil); err != nil || !entry.IsDir() {
+		return err
+	}
+
+	children, err := fsys.ReadDir(path)
+	if err != nil {
+		// Report the read error; the directory itself was already visited
+		if err := fn(path, entry, err); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	for _, child := range children {
+		if err := walkDirEntry(fsys, filepath.Join(path, child.Name()), child, fn); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				if child.IsDir() {
+					continue
+				}
+				return nil // SkipDir on a file skips the rest of its directory
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeAll removes a path and everything it contains
+func removeAll(fsys FileSystem, path string) error {
+	info, err := fsys.Stat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if info.IsDir() {
+		entries, err := fsys.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := removeAll(fsys, filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return fsys.Remove(path)
+}
+
+// removeEmptyDirs removes path and its subdirectories that hold no file, deepest first
+// Files are never removed, except the contact sheet of a folder holding nothing else;
+// returns true when path itself was removed
+func removeEmptyDirs(fsys FileSystem, path string) (bool, error) {
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		return false, err
+	}
+
+	empty := true
+	var sheets []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			if isContactSheet(entry.Name()) {
+				sheets = append(sheets, filepath.Join(path, entry.Name()))
+				continue
+			}
+			empty = false
+			continue
+		}
+		removed, err := removeEmptyDirs(fsys, filepath.Join(path, entry.Name()))
+		if err != nil {
+			return false, err
+		}
+		if !removed {
+			empty = false
+		}
+	}
+	if !empty {
+		return false, nil
+	}
+	for _, sheet := range sheets {
+		if err := fsys.Remove(sheet); err != nil {
+			return false, err
+		}
+	}
+	return true, fsys.Remove(path)
+}
+
+// readFile reads a whole file from fsys
+func readFile(fsys FileSystem, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// fileExists reports whether path exists on fsys
+func fileExists(fsys FileSystem, path string) bool {
+	_, err := fsys.Stat(path)
+	return err == nil
+}
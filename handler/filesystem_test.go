@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeMemFile creates a file and its parent folders on a MemFileSystem
+func writeMemFile(t *testing.T, fsys *MemFileSystem, path string, data []byte, modTime time.Time) {
+	t.Helper()
+
+	if err := fsys.MkdirAll(filepath.Dir(path), permDirectory); err != nil {
+		t.Fatalf("MkdirAll(%s) error: %v", filepath.Dir(path), err)
+	}
+	if err := fsys.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile(%s) error: %v", path, err)
+	}
+	if err := fsys.Chtimes(path, modTime); err != nil {
+		t.Fatalf("Chtimes(%s) error: %v", path, err)
+	}
+}
+
+// assertMemExists fails the test if path does not exist on fsys
+func assertMemExists(t *testing.T, fsys FileSystem, path string) {
+	t.Helper()
+	if !fileExists(fsys, path) {
+		t.Errorf("expected %s to exist", path)
+	}
+}
+
+func TestMemFileSystem_Basics(t *testing.T) {
+	fsys := NewMemFileSystem()
+	modTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	writeMemFile(t, fsys, "/photos/b.jpg", []byte("bbb"), modTime)
+	writeMemFile(t, fsys, "/photos/a.jpg", []byte("a"), modTime)
+
+	entries, err := fsys.ReadDir("/photos")
+	if err != nil {
+		t.Fatalf("ReadDir() error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name() != "a.jpg" || entries[1].Name() != "b.jpg" {
+		t.Fatalf("ReadDir() = %v, want [a.jpg b.jpg]", entries)
+	}
+
+	info, err := fsys.Stat("/photos/b.jpg")
+	if err != nil {
+		t.Fatalf("Stat() error: %v", err)
+	}
+	if info.Size() != 3 || !info.ModTime().Equal(modTime) || info.IsDir() {
+		t.Errorf("Stat() = size %d, modtime %v, dir %v, want 3, %v, false", info.Size(), info.ModTime(), info.IsDir(), modTime)
+	}
+
+	f, err := fsys.Open("/photos/b.jpg")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil || string(data) != "bbb" {
+		t.Errorf("Open() read %q, %v, want \"bbb\"", data, err)
+	}
+
+	if _, err := fsys.Stat("/missing.jpg"); !os.IsNotExist(err) {
+		t.Errorf("Stat() on missing file error = %v, want not exist", err)
+	}
+	if err := fsys.WriteFile("/missing/file.jpg", nil, 0644); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("WriteFile() without parent error = %v, want not exist", err)
+	}
+	if err := fsys.Remove("/photos"); err == nil {
+		t.Error("Remove() on non-empty directory should fail")
+	}
+}
+
+func TestMemFileSystem_Rename(t *testing.T) {
+	fsys := NewMemFileSystem()
+	modTime := time.Now()
+	writeMemFile(t, fsys, "/src/event/raw/a.nef", []byte("raw"), modTime)
+	writeMemFile(t, fsys, "/src/event/a.jpg", []byte("jpg"), modTime)
+
+	// Directories move with their content
+	if err := fsys.MkdirAll("/dst", permDirectory); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Rename("/src/event", "/dst/event"); err != nil {
+		t.Fatalf("Rename() directory error: %v", err)
+	}
+	assertMemExists(t, fsys, "/dst/event/a.jpg")
+	assertMemExists(t, fsys, "/dst/event/raw/a.nef")
+	if fileExists(fsys, "/src/event/raw/a.nef") {
+		t.Error("renamed directory content still at its old path")
+	}
+
+	// Files replace an existing file, like os.Rename
+	if err := fsys.Rename("/dst/event/raw/a.nef", "/dst/event/a.jpg"); err != nil {
+		t.Fatalf("Rename() over file error: %v", err)
+	}
+	data, _ := readFile(fsys, "/dst/event/a.jpg")
+	if string(data) != "raw" {
+		t.Errorf("renamed file content = %q, want \"raw\"", data)
+	}
+
+	if err := fsys.Rename("/dst/event/a.jpg", "/nowhere/a.jpg"); !os.IsNotExist(err) {
+		t.Errorf("Rename() to missing folder error = %v, want not exist", err)
+	}
+}
+
+func TestWalkDirAndRemoveAll(t *testing.T) {
+	fsys := NewMemFileSystem()
+	modTime := time.Now()
+	writeMemFile(t, fsys, "/lib/event/a.jpg", nil, modTime)
+	writeMemFile(t, fsys, "/lib/event/mov/b.mov", nil, modTime)
+	writeMemFile(t, fsys, "/lib/.git/config", nil, modTime)
+
+	var visited []string
+	err := walkDir(fsys, "/lib", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return fs.SkipDir
+		}
+		visited = append(visited, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkDir() error: %v", err)
+	}
+
+	want := "/lib,/lib/event,/lib/event/a.jpg,/lib/event/mov,/lib/event/mov/b.mov"
+	if got := strings.Join(visited, ","); got != filepath.FromSlash(want) {
+		t.Errorf("walkDir() visited %s, want %s", got, want)
+	}
+
+	if err := removeAll(fsys, "/lib/event"); err != nil {
+		t.Fatalf("removeAll() error: %v", err)
+	}
+	if fileExists(fsys, "/lib/event") {
+		t.Error("removeAll() left the directory")
+	}
+	assertMemExists(t, fsys, "/lib/.git/config")
+}
+
+func TestSplit_MemFileSystem(t *testing.T) {
+	// Real EXIF and MP4 content, served from memory
+	exifPath := filepath.Join(t.TempDir(), "exif.jpg")
+	exifDate := time.Date(2024, 6, 15, 14, 0, 0, 0, time.UTC)
+	createJPEGWithEXIF(t, exifPath, exifDate)
+	jpegData, err := os.ReadFile(exifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp4Data, err := os.ReadFile(getTestMP4Fixture())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := NewMemFileSystem()
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) // Must be ignored in favor of metadata
+	writeMemFile(t, fsys, "/photos/IMG_0001.JPG", jpegData, modTime)
+	writeMemFile(t, fsys, "/photos/IMG_0001.NEF", []byte("raw"), modTime)
+	writeMemFile(t, fsys, "/photos/IMG_0001.xmp", []byte("<xmp/>"), modTime)
+	writeMemFile(t, fsys, "/photos/IMG_0002.JPG", jpegData, modTime)
+	writeMemFile(t, fsys, "/photos/CLIP.MP4", mp4Data, modTime)
+	writeMemFile(t, fsys, "/photos/empty/.DS_Store", nil, modTime)
+
+	cfg := &Config{
+		BasePath:         "/photos",
+		Delta:            30 * time.Minute,
+		Mode:             ModeRun,
+		UseEXIF:          true,
+		MinGroupSize:     1,
+		DetectDuplicates: true,
+		MoveDuplicates:   true,
+		CleanupEmptyDirs: true,
+		Force:            true,
+		FS:               fsys,
+	}
+
+	result, err := Split(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	photoEvent := filepath.Join("/photos", exifDate.Local().Format(dateFormatPattern))
+	videoEvent := filepath.Join("/photos", time.Date(2024, 12, 20, 15, 30, 0, 0, time.UTC).Local().Format(dateFormatPattern))
+
+	assertMemExists(t, fsys, filepath.Join(photoEvent, "IMG_0001.JPG"))
+	assertMemExists(t, fsys, filepath.Join(photoEvent, rawFolderName, "IMG_0001.xmp")) // Sidecars follow the RAW
+	assertMemExists(t, fsys, filepath.Join(photoEvent, rawFolderName, "IMG_0001.NEF"))
+	assertMemExists(t, fsys, filepath.Join(videoEvent, movFolderName, "CLIP.MP4"))
+	assertMemExists(t, fsys, filepath.Join("/photos", duplicatesFolderName, "IMG_0002.JPG"))
+	if fileExists(fsys, "/photos/empty") {
+		t.Error("empty folder should be cleaned up")
+	}
+
+	if len(result.Stats.DuplicatesDetected) != 1 || result.Stats.GroupsCreated != 2 {
+		t.Errorf("stats = %d duplicates, %d groups, want 1, 2", len(result.Stats.DuplicatesDetected), result.Stats.GroupsCreated)
+	}
+}
+
+func TestMerge_MemFileSystem(t *testing.T) {
+	fsys := NewMemFileSystem()
+	modTime := time.Now()
+	writeMemFile(t, fsys, "/lib/a/photo.jpg", []byte("a"), modTime)
+	writeMemFile(t, fsys, "/lib/a/raw/photo.nef", []byte("a"), modTime)
+	writeMemFile(t, fsys, "/lib/b/photo.jpg", []byte("b"), modTime)
+
+	cfg := &MergeConfig{
+		SourceFolders: []string{"/lib/a"},
+		TargetFolder:  "/lib/b",
+		Mode:          ModeRun,
+		OnConflict:    ResolveRename,
+		FS:            fsys,
+	}
+	if err := Merge(context.Background(), cfg); err != nil {
+		t.Fatalf("Merge() error: %v", err)
+	}
+
+	assertMemExists(t, fsys, "/lib/b/photo.jpg")
+	assertMemExists(t, fsys, "/lib/b/photo_1.jpg")
+	assertMemExists(t, fsys, "/lib/b/raw/photo.nef")
+	if fileExists(fsys, "/lib/a") {
+		t.Error("merged source folder should be removed")
+	}
+}
+
+func TestRemoveEmptyDirs_ContactSheet(t *testing.T) {
+	fsys := NewMemFileSystem()
+	writeMemFile(t, fsys, "/lib/event/"+contactSheetName, []byte("sheet"), time.Now())
+	writeMemFile(t, fsys, "/lib/other/photo.jpg", []byte("photo"), time.Now())
+	writeMemFile(t, fsys, "/lib/other/"+contactSheetName, []byte("sheet"), time.Now())
+
+	if removed, err := removeEmptyDirs(fsys, "/lib/event"); err != nil || !removed {
+		t.Errorf("removeEmptyDirs(event) = %v, %v, want removed", removed, err)
+	}
+	if removed, err := removeEmptyDirs(fsys, "/lib/other"); err != nil || removed {
+		t.Errorf("removeEmptyDirs(other) = %v, %v, want kept", removed, err)
+	}
+	assertMemExists(t, fsys, "/lib/other/"+contactSheetName)
+}
@@ -7,9 +7,11 @@ import (
 	"log/slog"
 	"math"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 )
 
 const (
@@ -91,12 +93,19 @@ const (
 	geocodingTimeout   = 3 * time.Second
 	nominatimRateLimit = 1 * time.Second // Nominatim requires max 1 req/s
 	geocodingUserAgent = "picsplit/2.9.0 (https://github.com/sebastienfr/picsplit)"
-	nominatimURL       = "https://nominatim.openstreetmap.org/reverse"
 	bigDataCloudURL    = "https://api.bigdatacloud.net/data/reverse-geocode-client"
+
+	// Nominatim address detail: city level for "coordinates - country - city" folders,
+	// suburb level for --gps-hierarchy, which falls back to the suburb when there is no city
+	nominatimZoomCity   = 10
+	nominatimZoomSuburb = 14
 )
 
 var (
+	nominatimURL = "https://nominatim.openstreetmap.org/reverse" // Variable for tests
+
 	geocodeCache      = make(map[string]string)
+	locationCache     = make(map[string]*LocationInfo) // nil when geocoding failed (v2.10.0+)
 	geocodeCacheMutex sync.RWMutex
 	lastNominatimCall time.Time
 	nominatimMutex    sync.Mutex
@@ -106,12 +115,47 @@ var (
 type LocationInfo struct {
 	City    string
 	Country string
+
+	// Address components for --gps-hierarchy (v2.10.0+)
+	Region string // State, province or region (e.g., "Île-de-France")
+	Suburb string // District of the city, when the geocoder returns one
+	Place  string // Name of the place itself (e.g., a park or a village)
+}
+
+// locality returns the most precise populated place: the city, else the suburb, else the place name
+func (l *LocationInfo) locality() string {
+	for _, name := range []string{l.City, l.Suburb, l.Place} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// folderPath returns the "Country/Region/City" folder of the location
+// Levels the geocoder did not return, or repeating their parent ("Berlin/Berlin"), are left out
+func (l *LocationInfo) folderPath() string {
+	var parts []string
+	for _, part := range []string{l.Country, l.Region, l.locality()} {
+		part = sanitizeFolderName(part)
+		if part == "" || (len(parts) > 0 && strings.EqualFold(parts[len(parts)-1], part)) {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return filepath.Join(parts...)
 }
 
 // ReverseGeocode tries to get a human-readable location name from GPS coordinates
 // Returns an enriched folder name with format: "coordinates - country - city"
 // Falls back to just coordinates if geocoding fails (offline, timeout, error) or if useGeocoding is false
 func ReverseGeocode(lat, lon float64, useGeocoding bool) string {
+	return reverseGeocode(context.Background(), lat, lon, useGeocoding)
+}
+
+// reverseGeocode is ReverseGeocode with API calls aborted when runCtx is canceled
+// Coordinates returned because of a cancellation are not cached
+func reverseGeocode(runCtx context.Context, lat, lon float64, useGeocoding bool) string {
 	coords := FormatLocationName(GPSCoord{Lat: lat, Lon: lon})
 
 	// If geocoding disabled, return coordinates only
@@ -120,24 +164,12 @@ func ReverseGeocode(lat, lon float64, useGeocoding bool) string {
 	}
 
 	// Check cache first
-	cacheKey := fmt.Sprintf("%.4f,%.4f", lat, lon)
+	cacheKey := geocodeCacheKey(lat, lon)
 	if cached, found := getCachedLocation(cacheKey); found {
 		return cached
 	}
 
-	// Try to get location info (non-blocking, with timeout)
-	ctx, cancel := context.WithTimeout(context.Background(), geocodingTimeout)
-	defer cancel()
-
-	var locationInfo *LocationInfo
-
-	// Try Nominatim first
-	if info := tryNominatim(ctx, lat, lon); info != nil {
-		locationInfo = info
-	} else if info := tryBigDataCloud(ctx, lat, lon); info != nil {
-		// Fallback to BigDataCloud
-		locationInfo = info
-	}
+	locationInfo := lookupLocation(runCtx, lat, lon, nominatimZoomCity)
 
 	// Build folder name
 	folderName := coords
@@ -155,24 +187,75 @@ func ReverseGeocode(lat, lon float64, useGeocoding bool) string {
 
 	// Sanitize and cache
 	folderName = sanitizeFolderName(folderName)
-	setCachedLocation(cacheKey, folderName)
+	if runCtx.Err() == nil {
+		setCachedLocation(cacheKey, folderName)
+	}
 
 	return folderName
 }
 
+// reverseGeocodePath returns the "Country/Region/City" folder of GPS coordinates (v2.10.0+)
+// Falls back to just coordinates if geocoding fails or if useGeocoding is false
+func reverseGeocodePath(runCtx context.Context, lat, lon float64, useGeocoding bool) string {
+	if useGeocoding {
+		if info := lookupLocation(runCtx, lat, lon, nominatimZoomSuburb); info != nil {
+			if path := info.folderPath(); path != "" {
+				return path
+			}
+		}
+	}
+	return FormatLocationName(GPSCoord{Lat: lat, Lon: lon})
+}
+
+// lookupLocation returns the address of GPS coordinates from Nominatim at the given zoom,
+// then BigDataCloud, nil if both fail
+// Results are cached per zoom, except when runCtx is canceled
+func lookupLocation(runCtx context.Context, lat, lon float64, zoom int) *LocationInfo {
+	cacheKey := locationCacheKey(lat, lon, zoom)
+	geocodeCacheMutex.RLock()
+	info, found := locationCache[cacheKey]
+	geocodeCacheMutex.RUnlock()
+	if found {
+		return info
+	}
+
+	// Try to get location info (non-blocking, with timeout)
+	ctx, cancel := context.WithTimeout(runCtx, geocodingTimeout)
+	defer cancel()
+
+	// Try Nominatim first, fallback to BigDataCloud
+	info = tryNominatim(ctx, lat, lon, zoom)
+	if info == nil {
+		info = tryBigDataCloud(ctx, lat, lon)
+	}
+
+	if runCtx.Err() == nil {
+		geocodeCacheMutex.Lock()
+		locationCache[cacheKey] = info
+		geocodeCacheMutex.Unlock()
+	}
+	return info
+}
+
 // tryNominatim attempts reverse geocoding using OpenStreetMap Nominatim
-func tryNominatim(ctx context.Context, lat, lon float64) *LocationInfo {
+// zoom sets the address detail (nominatimZoomCity or nominatimZoomSuburb)
+func tryNominatim(ctx context.Context, lat, lon float64, zoom int) *LocationInfo {
 	// Respect rate limit (1 req/s)
 	nominatimMutex.Lock()
 	timeSinceLastCall := time.Since(lastNominatimCall)
 	if timeSinceLastCall < nominatimRateLimit {
-		time.Sleep(nominatimRateLimit - timeSinceLastCall)
+		select {
+		case <-time.After(nominatimRateLimit - timeSinceLastCall):
+		case <-ctx.Done():
+			nominatimMutex.Unlock()
+			return nil
+		}
 	}
 	lastNominatimCall = time.Now()
 	nominatimMutex.Unlock()
 
-	url := fmt.Sprintf("%s?lat=%.4f&lon=%.4f&format=json&zoom=10&addressdetails=1",
-		nominatimURL, lat, lon)
+	url := fmt.Sprintf("%s?lat=%.4f&lon=%.4f&format=json&zoom=%d&addressdetails=1",
+		nominatimURL, lat, lon, zoom)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -195,12 +278,15 @@ func tryNominatim(ctx context.Context, lat, lon float64) *LocationInfo {
 	}
 
 	var result struct {
+		Name    string `json:"name"`
 		Address struct {
+			Suburb  string `json:"suburb"`
 			City    string `json:"city"`
 			Town    string `json:"town"`
 			Village string `json:"village"`
 			County  string `json:"county"`
 			State   string `json:"state"`
+			Region  string `json:"region"`
 			Country string `json:"country"`
 		} `json:"address"`
 	}
@@ -219,6 +305,15 @@ func tryNominatim(ctx context.Context, lat, lon float64) *LocationInfo {
 		city = result.Address.Village
 	}
 
+	// Choose best available region name
+	region := result.Address.State
+	if region == "" {
+		region = result.Address.Region
+	}
+	if region == "" {
+		region = result.Address.County
+	}
+
 	if result.Address.Country == "" {
 		return nil
 	}
@@ -226,6 +321,9 @@ func tryNominatim(ctx context.Context, lat, lon float64) *LocationInfo {
 	return &LocationInfo{
 		City:    city,
 		Country: result.Address.Country,
+		Region:  region,
+		Suburb:  result.Address.Suburb,
+		Place:   result.Name,
 	}
 }
 
@@ -254,9 +352,10 @@ func tryBigDataCloud(ctx context.Context, lat, lon float64) *LocationInfo {
 	}
 
 	var result struct {
-		City        string `json:"city"`
-		Locality    string `json:"locality"`
-		CountryName string `json:"countryName"`
+		City                 string `json:"city"`
+		Locality             string `json:"locality"`
+		PrincipalSubdivision string `json:"principalSubdivision"`
+		CountryName          string `json:"countryName"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
@@ -276,10 +375,13 @@ func tryBigDataCloud(ctx context.Context, lat, lon float64) *LocationInfo {
 	return &LocationInfo{
 		City:    city,
 		Country: result.CountryName,
+		Region:  result.PrincipalSubdivision,
+		Place:   result.Locality,
 	}
 }
 
 // sanitizeFolderName removes or replaces characters that are invalid in folder names
+// Accented letters are composed (v2.10.0+) so that "Zürich" names one folder whatever its Unicode form
 func sanitizeFolderName(name string) string {
 	// Replace invalid characters: / \ : * ? " < > |
 	replacer := strings.NewReplacer(
@@ -293,9 +395,25 @@ func sanitizeFolderName(name string) string {
 		">", "",
 		"|", "-",
 	)
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, composeUnicode(name))
 	return strings.TrimSpace(replacer.Replace(name))
 }
 
+// geocodeCacheKey rounds coordinates to ~11 m so nearby clusters share a geocoding result
+func geocodeCacheKey(lat, lon float64) string {
+	return fmt.Sprintf("%.4f,%.4f", lat, lon)
+}
+
+// locationCacheKey is geocodeCacheKey for addresses, which depend on the Nominatim zoom
+func locationCacheKey(lat, lon float64, zoom int) string {
+	return fmt.Sprintf("%s/%d", geocodeCacheKey(lat, lon), zoom)
+}
+
 // getCachedLocation retrieves a cached geocoding result
 func getCachedLocation(key string) (string, bool) {
 	geocodeCacheMutex.RLock()
@@ -304,6 +422,14 @@ func getCachedLocation(key string) (string, bool) {
 	return name, exists
 }
 
+// isLocationCached checks if the address of a cache key was already looked up
+func isLocationCached(key string) bool {
+	geocodeCacheMutex.RLock()
+	defer geocodeCacheMutex.RUnlock()
+	_, exists := locationCache[key]
+	return exists
+}
+
 // setCachedLocation stores a geocoding result in cache
 func setCachedLocation(key, value string) {
 	geocodeCacheMutex.Lock()
@@ -2,9 +2,15 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestCalculateDistance(t *testing.T) {
@@ -339,6 +345,26 @@ func TestSanitizeFolderName(t *testing.T) {
 			input:    "48.8566N-2.3522E - France - Paris",
 			expected: "48.8566N-2.3522E - France - Paris",
 		},
+		{
+			name:     "decomposed accents",
+			input:    "Sa\u0303o Paulo - I\u0302le-de-France - Zu\u0308rich",
+			expected: "São Paulo - Île-de-France - Zürich",
+		},
+		{
+			name:     "stacked diacritics",
+			input:    "Ha\u0300 No\u0323\u0302i",
+			expected: "Hà Nội",
+		},
+		{
+			name:     "mark without precomposed letter",
+			input:    "Q\u0301 Tromsø",
+			expected: "Q\u0301 Tromsø",
+		},
+		{
+			name:     "control characters",
+			input:    "Paris\tFrance\n",
+			expected: "ParisFrance",
+		},
 	}
 
 	for _, tt := range tests {
@@ -351,6 +377,98 @@ func TestSanitizeFolderName(t *testing.T) {
 	}
 }
 
+func TestLocationInfoFolderPath(t *testing.T) {
+	tests := []struct {
+		name string
+		info LocationInfo
+		want string
+	}{
+		{"full address", LocationInfo{Country: "France", Region: "Île-de-France", City: "Paris", Suburb: "Montmartre"}, filepath.Join("France", "Île-de-France", "Paris")},
+		{"suburb without city", LocationInfo{Country: "Japan", Region: "Tokyo", Suburb: "Shibuya"}, filepath.Join("Japan", "Tokyo", "Shibuya")},
+		{"place name only", LocationInfo{Country: "United States", Region: "California", Place: "Yosemite National Park"}, filepath.Join("United States", "California", "Yosemite National Park")},
+		{"city state", LocationInfo{Country: "Germany", Region: "Berlin", City: "Berlin"}, filepath.Join("Germany", "Berlin")},
+		{"country only", LocationInfo{Country: "Antarctica"}, "Antarctica"},
+		{"invalid characters", LocationInfo{Country: "Côte d'Ivoire", Region: "Abidjan/Lagunes"}, filepath.Join("Côte d'Ivoire", "Abidjan-Lagunes")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.folderPath(); got != tt.want {
+				t.Errorf("folderPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildGroups_GPSHierarchy(t *testing.T) {
+	louvre := GPSCoord{Lat: 48.8606, Lon: 2.3376}
+	defense := GPSCoord{Lat: 48.8924, Lon: 2.2380}
+	unknown := GPSCoord{Lat: 45.0, Lon: 6.0}
+
+	// Both clusters are in Paris, the geocoder returned the region in two Unicode forms
+	geocodeCacheMutex.Lock()
+	locationCache[locationCacheKey(louvre.Lat, louvre.Lon, nominatimZoomSuburb)] = &LocationInfo{Country: "France", Region: "Île-de-France", City: "Paris"}
+	locationCache[locationCacheKey(defense.Lat, defense.Lon, nominatimZoomSuburb)] = &LocationInfo{Country: "France", Region: "I\u0302le-de-France", City: "Paris", Suburb: "La Défense"}
+	locationCache[locationCacheKey(unknown.Lat, unknown.Lon, nominatimZoomSuburb)] = nil
+	geocodeCacheMutex.Unlock()
+	t.Cleanup(func() {
+		geocodeCacheMutex.Lock()
+		locationCache = make(map[string]*LocationInfo)
+		geocodeCacheMutex.Unlock()
+	})
+
+	shot := time.Date(2024, 6, 15, 10, 0, 0, 0, time.Local)
+	files := []FileMetadata{
+		{FileInfo: memFileInfo{name: "LOUVRE.JPG"}, DateTime: shot, GPS: &louvre},
+		{FileInfo: memFileInfo{name: "DEFENSE.JPG"}, DateTime: shot.Add(10 * time.Minute), GPS: &defense},
+		{FileInfo: memFileInfo{name: "ALPS.JPG"}, DateTime: shot.Add(5 * time.Hour), GPS: &unknown},
+	}
+	cfg := &Config{Delta: 30 * time.Minute, UseGPS: true, GPSRadius: 2000, GPSUseGeocoding: true, GPSHierarchy: true}
+
+	groups, _, err := buildGroups(context.Background(), cfg, newDefaultExecutionContext(), files)
+	if err != nil {
+		t.Fatalf("buildGroups() error: %v", err)
+	}
+
+	got := make(map[string]int)
+	for _, group := range groups {
+		got[group.Folder] = len(group.Files)
+	}
+	want := map[string]int{
+		filepath.Join("France", "Île-de-France", "Paris", "2024 - 0615 - 1000"): 2, // Clusters of one city share their events
+		filepath.Join("45.0000N-6.0000E", "2024 - 0615 - 1500"):                 1, // Not geocoded
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+}
+
+func TestConfigValidate_GPSHierarchy(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"without geocoding", func(c *Config) { c.UseGPS = true; c.GPSHierarchy = true }, "requires --gps-geocoding"},
+		{"incremental", func(c *Config) {
+			c.UseGPS = true
+			c.GPSUseGeocoding = true
+			c.GPSHierarchy = true
+			c.Incremental = true
+		}, "--incremental"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig(t.TempDir())
+			tt.modify(cfg)
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestReverseGeocode_Cache(t *testing.T) {
 	// Reset cache before test
 	geocodeCacheMutex.Lock()
@@ -439,11 +557,41 @@ func containsString(s, substr string) bool {
 	return len(s) >= len(substr) && (s[:len(substr)] == substr || s[len(s)-len(substr):] == substr || s == substr)
 }
 
+func TestLookupLocation_NominatimZoom(t *testing.T) {
+	var zooms []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zooms = append(zooms, r.URL.Query().Get("zoom"))
+		fmt.Fprint(w, `{"address": {"city": "Lyon", "country": "France"}}`)
+	}))
+	defer server.Close()
+
+	defaultURL := nominatimURL
+	nominatimURL = server.URL
+	t.Cleanup(func() {
+		nominatimURL = defaultURL
+		geocodeCacheMutex.Lock()
+		geocodeCache = make(map[string]string)
+		locationCache = make(map[string]*LocationInfo)
+		geocodeCacheMutex.Unlock()
+	})
+
+	// City level by default, so existing folder names do not change
+	if name := reverseGeocode(context.Background(), 45.7640, 4.8357, true); !strings.HasSuffix(name, "France - Lyon") {
+		t.Errorf("reverseGeocode() = %q, want the city", name)
+	}
+	// Finer level for --gps-hierarchy, which can fall back to the suburb
+	reverseGeocodePath(context.Background(), 45.7640, 4.8357, true)
+
+	if want := []string{"10", "14"}; fmt.Sprint(zooms) != fmt.Sprint(want) {
+		t.Errorf("zoom requested = %v, want %v", zooms, want)
+	}
+}
+
 func TestTryNominatim_InvalidCoordinates(t *testing.T) {
 	ctx := context.Background()
 
 	// Test with invalid coordinates (should return nil or handle gracefully)
-	result := tryNominatim(ctx, 999.0, 999.0)
+	result := tryNominatim(ctx, 999.0, 999.0, nominatimZoomCity)
 
 	// Should handle invalid coordinates gracefully (return nil or valid response)
 	// We don't assert the result as it depends on the API behavior
@@ -465,7 +613,7 @@ func TestTryNominatim_CanceledContext(t *testing.T) {
 	cancel() // Cancel immediately
 
 	// Should return nil when context is canceled
-	result := tryNominatim(ctx, 48.8566, 2.3522)
+	result := tryNominatim(ctx, 48.8566, 2.3522, nominatimZoomCity)
 
 	if result != nil {
 		t.Error("tryNominatim should return nil with canceled context")
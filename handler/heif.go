@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/abema/go-mp4"
+)
+
+// HEIC, HEIF and AVIF are ISOBMFF files: the EXIF block is not a JPEG segment but an item
+// of the meta box, declared in iinf (item type "Exif") and located by iloc (v2.10.0+)
+
+const (
+	// maxExifItemSize bounds the Exif item read in memory (EXIF is limited to 64 KB in JPEG,
+	// some cameras write larger blocks with maker notes)
+	maxExifItemSize = 4 << 20
+
+	// iloc construction methods
+	ilocFileOffset = 0 // Extents are offsets in the file
+	ilocIdatOffset = 1 // Extents are offsets in the idat box of meta
+)
+
+var (
+	errNoExifItem  = errors.New("no Exif item in meta box")
+	errBoxTruncate = errors.New("truncated box")
+)
+
+var (
+	boxTypeIinf = mp4.StrToBoxType("iinf")
+	boxTypeInfe = mp4.StrToBoxType("infe")
+	boxTypeIloc = mp4.StrToBoxType("iloc")
+	boxTypeIdat = mp4.StrToBoxType("idat")
+)
+
+// ilocExtent is a part of an item's data
+type ilocExtent struct {
+	offset uint64
+	length uint64
+}
+
+// ilocItem tells where the data of an item is stored
+type ilocItem struct {
+	extents            []ilocExtent
+	baseOffset         uint64
+	constructionMethod uint16
+}
+
+// isISOBMFF checks for the ftyp box that starts every ISOBMFF file
+func isISOBMFF(r io.ReaderAt) bool {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return false
+	}
+	return string(header[4:8]) == "ftyp"
+}
+
+// extractISOBMFFExif returns the EXIF block of an HEIC/HEIF/AVIF file, starting at its TIFF header
+func extractISOBMFFExif(r io.ReadSeeker) ([]byte, error) {
+	meta := mp4.BoxTypeMeta()
+	boxes, err := mp4.ExtractBoxes(r, nil, []mp4.BoxPath{
+		{meta, boxTypeIinf},
+		{meta, boxTypeIloc},
+		{meta, boxTypeIdat},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read meta box: %w", err)
+	}
+
+	var iinf, iloc, idat *mp4.BoxInfo
+	for _, box := range boxes {
+		switch box.Type {
+		case boxTypeIinf:
+			iinf = box
+		case boxTypeIloc:
+			iloc = box
+		case boxTypeIdat:
+			idat = box
+		}
+	}
+	if iinf == nil || iloc == nil {
+		return nil, errNoExifItem
+	}
+
+	iinfData, err := readBoxPayload(r, iinf)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := findExifItemID(iinfData)
+	if err != nil {
+		return nil, err
+	}
+
+	ilocData, err := readBoxPayload(r, iloc)
+	if err != nil {
+		return nil, err
+	}
+	items, err := parseIloc(ilocData)
+	if err != nil {
+		return nil, err
+	}
+	item, found := items[itemID]
+	if !found {
+		return nil, fmt.Errorf("exif item %d has no location", itemID)
+	}
+
+	data, err := readItemData(r, item, idat)
+	if err != nil {
+		return nil, err
+	}
+	return exifItemTIFF(data)
+}
+
+// readBoxPayload reads the content of a box after its header
+func readBoxPayload(r io.ReadSeeker, box *mp4.BoxInfo) ([]byte, error) {
+	size := box.Size - box.HeaderSize
+	if size > maxExifItemSize {
+		return nil, fmt.Errorf("%s box too large: %d bytes", box.Type, size)
+	}
+	if _, err := box.SeekToPayload(r); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("failed to read %s box: %w", box.Type, err)
+	}
+	return data, nil
+}
+
+// findExifItemID returns the ID of the item of type "Exif" declared in an iinf payload
+func findExifItemID(iinf []byte) (uint32, error) {
+	br := &boxReader{data: iinf}
+	version := br.u8()
+	br.skip(3) // flags
+	if version == 0 {
+		br.u16() // entry_count
+	} else {
+		br.u32()
+	}
+
+	for br.err == nil && br.remaining() >= 8 {
+		size := int(br.u32())
+		boxType := mp4.StrToBoxType(string(br.bytes(4)))
+		if size < 8 || size-8 > br.remaining() {
+			return 0, errBoxTruncate
+		}
+		payload := br.bytes(size - 8)
+
+		if boxType != boxTypeInfe {
+			continue
+		}
+		infe := &boxReader{data: payload}
+		infeVersion := infe.u8()
+		infe.skip(3) // flags
+		if infeVersion < 2 {
+			continue // Versions 0 and 1 have no item type
+		}
+
+		var id uint32
+		if infeVersion == 2 {
+			id = uint32(infe.u16())
+		} else {
+			id = infe.u32()
+		}
+		infe.u16() // item_protection_index
+		if itemType := string(infe.bytes(4)); infe.err == nil && itemType == "Exif" {
+			return id, nil
+		}
+	}
+
+	if br.err != nil {
+		return 0, br.err
+	}
+	return 0, errNoExifItem
+}
+
+// parseIloc reads the item locations of an iloc payload (ISO/IEC 14496-12 8.11.3)
+func parseIloc(iloc []byte) (map[uint32]ilocItem, error) {
+	br := &boxReader{data: iloc}
+	version := br.u8()
+	br.skip(3) // flags
+	if version > 2 {
+		return nil, fmt.Errorf("unsupported iloc version %d", version)
+	}
+
+	sizes := br.u8()
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0x0F)
+	sizes = br.u8()
+	baseOffsetSize, indexSize := int(sizes>>4), int(sizes&0x0F)
+	if version == 0 {
+		indexSize = 0 // Reserved bits
+	}
+
+	var itemCount uint32
+	if version < 2 {
+		itemCount = uint32(br.u16())
+	} else {
+		itemCount = br.u32()
+	}
+
+	items := make(map[uint32]ilocItem)
+	for i := uint32(0); i < itemCount && br.err == nil; i++ {
+		var id uint32
+		if version < 2 {
+			id = uint32(br.u16())
+		} else {
+			id = br.u32()
+		}
+
+		var item ilocItem
+		if version > 0 {
+			item.constructionMethod = br.u16() & 0x0F
+		}
+		br.u16() // data_reference_index
+		item.baseOffset = br.uint(baseOffsetSize)
+
+		extentCount := br.u16()
+		for e := uint16(0); e < extentCount && br.err == nil; e++ {
+			br.uint(indexSize) // extent_index
+			item.extents = append(item.extents, ilocExtent{
+				offset: br.uint(offsetSize),
+				length: br.uint(lengthSize),
+			})
+		}
+		items[id] = item
+	}
+
+	if br.err != nil {
+		return nil, fmt.Errorf("invalid iloc box: %w", br.err)
+	}
+	return items, nil
+}
+
+// readItemData concatenates the extents of an item, stored in the file or in idat
+func readItemData(r io.ReadSeeker, item ilocItem, idat *mp4.BoxInfo) ([]byte, error) {
+	var origin uint64
+	switch item.constructionMethod {
+	case ilocFileOffset:
+	case ilocIdatOffset:
+		if idat == nil {
+			return nil, errors.New("exif item stored in missing idat box")
+		}
+		origin = idat.Offset + idat.HeaderSize
+	default:
+		return nil, fmt.Errorf("unsupported iloc construction method %d", item.constructionMethod)
+	}
+
+	var data bytes.Buffer
+	for _, extent := range item.extents {
+		length := extent.length
+		if length == 0 {
+			return nil, errors.New("exif item with unbounded extent")
+		}
+		if uint64(data.Len())+length > maxExifItemSize {
+			return nil, fmt.Errorf("exif item too large: more than %d bytes", maxExifItemSize)
+		}
+
+		offset := origin + item.baseOffset + extent.offset
+		if _, err := r.Seek(int64(offset), io.SeekStart); err != nil { //nolint:gosec // offset bounded by the file size
+			return nil, err
+		}
+		if _, err := io.CopyN(&data, r, int64(length)); err != nil { //nolint:gosec // length bounded above
+			return nil, fmt.Errorf("failed to read exif item: %w", err)
+		}
+	}
+	return data.Bytes(), nil
+}
+
+// exifItemTIFF strips the Exif item header: a 4-byte offset to the TIFF header,
+// usually pointing past an "Exif\0\0" prefix (ISO/IEC 23008-12 A.2.1)
+func exifItemTIFF(data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, errBoxTruncate
+	}
+	offset := uint64(binary.BigEndian.Uint32(data)) + 4
+	if offset >= uint64(len(data)) {
+		return nil, fmt.Errorf("invalid exif item header offset %d", offset-4)
+	}
+	return data[offset:], nil
+}
+
+// boxReader reads big-endian box fields, keeping the first error
+type boxReader struct {
+	err  error
+	data []byte
+	pos  int
+}
+
+func (b *boxReader) remaining() int {
+	return len(b.data) - b.pos
+}
+
+func (b *boxReader) bytes(n int) []byte {
+	if b.err != nil || n > b.remaining() {
+		b.err = errBoxTruncate
+		return nil
+	}
+	value := b.data[b.pos : b.pos+n]
+	b.pos += n
+	return value
+}
+
+func (b *boxReader) skip(n int) {
+	b.bytes(n)
+}
+
+func (b *boxReader) u8() uint8 {
+	if value := b.bytes(1); value != nil {
+		return value[0]
+	}
+	return 0
+}
+
+func (b *boxReader) u16() uint16 {
+	if value := b.bytes(2); value != nil {
+		return binary.BigEndian.Uint16(value)
+	}
+	return 0
+}
+
+func (b *boxReader) u32() uint32 {
+	if value := b.bytes(4); value != nil {
+		return binary.BigEndian.Uint32(value)
+	}
+	return 0
+}
+
+// uint reads an unsigned field of 0, 4 or 8 bytes, as used by iloc
+func (b *boxReader) uint(size int) uint64 {
+	switch size {
+	case 0:
+		return 0
+	case 4:
+		return uint64(b.u32())
+	case 8:
+		if value := b.bytes(8); value != nil {
+			return binary.BigEndian.Uint64(value)
+		}
+		return 0
+	default:
+		if b.err == nil {
+			b.err = fmt.Errorf("unsupported field size %d", size)
+		}
+		return 0
+	}
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// createTIFFEXIF builds a little-endian TIFF EXIF block with DateTimeOriginal and optional GPS
+func createTIFFEXIF(dateTime time.Time, gps *GPSCoord) []byte {
+	le := binary.LittleEndian
+	entry := func(tag, typ uint16, count, value uint32) []byte {
+		b := make([]byte, 12)
+		le.PutUint16(b, tag)
+		le.PutUint16(b[2:], typ)
+		le.PutUint32(b[4:], count)
+		le.PutUint32(b[8:], value)
+		return b
+	}
+	rationals := func(v float64) []byte {
+		v = math.Abs(v)
+		deg := math.Floor(v)
+		minutes := math.Floor((v - deg) * 60)
+		seconds := ((v-deg)*60 - minutes) * 60
+		b := make([]byte, 24)
+		le.PutUint32(b, uint32(deg))
+		le.PutUint32(b[4:], 1)
+		le.PutUint32(b[8:], uint32(minutes))
+		le.PutUint32(b[12:], 1)
+		le.PutUint32(b[16:], uint32(seconds*10000))
+		le.PutUint32(b[20:], 10000)
+		return b
+	}
+	const (
+		typeASCII    = 2
+		typeLong     = 4
+		typeRational = 5
+	)
+
+	date := []byte(dateTime.Format("2006:01:02 15:04:05") + "\x00")
+
+	entries := 1
+	if gps != nil {
+		entries = 2
+	}
+	ifd0Size := 2 + 12*entries + 4
+	dateOffset := 8 + ifd0Size
+	gpsIFDOffset := dateOffset + len(date)
+
+	data := []byte{'I', 'I', 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00}
+	data = le.AppendUint16(data, uint16(entries))
+	if gps != nil {
+		data = append(data, entry(0x8825, typeLong, 1, uint32(gpsIFDOffset))...) // GPSInfo
+	}
+	data = append(data, entry(0x9003, typeASCII, uint32(len(date)), uint32(dateOffset))...) // DateTimeOriginal
+	data = le.AppendUint32(data, 0)
+	data = append(data, date...)
+
+	if gps != nil {
+		latRef, lonRef := uint32('N'), uint32('E')
+		if gps.Lat < 0 {
+			latRef = 'S'
+		}
+		if gps.Lon < 0 {
+			lonRef = 'W'
+		}
+		valuesOffset := uint32(gpsIFDOffset + 2 + 12*4 + 4)
+		data = le.AppendUint16(data, 4)
+		data = append(data, entry(1, typeASCII, 2, latRef)...)
+		data = append(data, entry(2, typeRational, 3, valuesOffset)...)
+		data = append(data, entry(3, typeASCII, 2, lonRef)...)
+		data = append(data, entry(4, typeRational, 3, valuesOffset+24)...)
+		data = le.AppendUint32(data, 0)
+		data = append(data, rationals(gps.Lat)...)
+		data = append(data, rationals(gps.Lon)...)
+	}
+	return data
+}
+
+// isoBox builds an ISOBMFF box; fullBox prepends version and flags
+func isoBox(boxType string, fullBox bool, version byte, payload ...[]byte) []byte {
+	var body []byte
+	if fullBox {
+		body = append(body, version, 0, 0, 0)
+	}
+	for _, p := range payload {
+		body = append(body, p...)
+	}
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	box = append(box, boxType...)
+	return append(box, body...)
+}
+
+// createHEIF builds a minimal HEIC/AVIF file: an image item and an Exif item stored in mdat,
+// or in the idat box of meta when inIdat is set
+func createHEIF(brand, imageType string, tiff []byte, inIdat bool) []byte {
+	be := binary.BigEndian
+	exifItem := append(be.AppendUint32(nil, 6), "Exif\x00\x00"...)
+	exifItem = append(exifItem, tiff...)
+	image := []byte("image-data")
+
+	ftyp := isoBox("ftyp", false, 0, []byte(brand), []byte{0, 0, 0, 0}, []byte(brand), []byte("mif1"))
+	hdlr := isoBox("hdlr", true, 0, make([]byte, 4), []byte("pict"), make([]byte, 13))
+	iinf := isoBox("iinf", true, 0, []byte{0, 2},
+		isoBox("infe", true, 2, []byte{0, 1, 0, 0}, []byte(imageType)),
+		isoBox("infe", true, 2, []byte{0, 2, 0, 0}, []byte("Exif")))
+
+	method := uint16(ilocFileOffset)
+	if inIdat {
+		method = ilocIdatOffset
+	}
+	buildMeta := func(mdatPayload uint32) []byte {
+		iloc := []byte{0x44, 0x00} // offset_size 4, length_size 4, base_offset_size 0, index_size 0
+		iloc = be.AppendUint16(iloc, 2)
+		// Image item, always in mdat
+		iloc = be.AppendUint16(iloc, 1)
+		iloc = be.AppendUint16(iloc, ilocFileOffset)
+		iloc = be.AppendUint16(iloc, 0)
+		iloc = be.AppendUint16(iloc, 1)
+		iloc = be.AppendUint32(iloc, mdatPayload)
+		iloc = be.AppendUint32(iloc, uint32(len(image)))
+		// Exif item
+		exifOffset := mdatPayload + uint32(len(image))
+		if inIdat {
+			exifOffset = 0
+		}
+		iloc = be.AppendUint16(iloc, 2)
+		iloc = be.AppendUint16(iloc, method)
+		iloc = be.AppendUint16(iloc, 0)
+		iloc = be.AppendUint16(iloc, 1)
+		iloc = be.AppendUint32(iloc, exifOffset)
+		iloc = be.AppendUint32(iloc, uint32(len(exifItem)))
+
+		children := [][]byte{hdlr, iinf, isoBox("iloc", true, 1, iloc)}
+		if inIdat {
+			children = append(children, isoBox("idat", false, 0, exifItem))
+		}
+		return isoBox("meta", true, 0, children...)
+	}
+
+	meta := buildMeta(0)
+	mdatPayload := uint32(len(ftyp) + len(meta) + 8)
+	meta = buildMeta(mdatPayload)
+
+	mdat := image
+	if !inIdat {
+		mdat = append(append([]byte{}, image...), exifItem...)
+	}
+
+	file := append(ftyp, meta...)
+	return append(file, isoBox("mdat", false, 0, mdat)...)
+}
+
+func TestExtractMetadata_HEIF(t *testing.T) {
+	shot := time.Date(2024, 6, 15, 14, 30, 12, 0, time.Local)
+	paris := &GPSCoord{Lat: 48.8566, Lon: 2.3522}
+	saoPaulo := &GPSCoord{Lat: -23.5505, Lon: -46.6333}
+
+	tests := []struct {
+		name      string
+		fileName  string
+		brand     string
+		imageType string
+		gps       *GPSCoord
+		inIdat    bool
+	}{
+		{"HEIC with GPS", "IMG_0001.HEIC", "heic", "hvc1", paris, false},
+		{"HEIC without GPS", "IMG_0002.heic", "heic", "hvc1", nil, false},
+		{"AVIF with GPS south west", "photo.avif", "avif", "av01", saoPaulo, false},
+		{"AVIF without GPS", "photo2.avif", "avif", "av01", nil, false},
+		{"HEIF with Exif in idat", "photo.heif", "mif1", "hvc1", paris, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFileSystem()
+			path := "/photos/" + tt.fileName
+			data := createHEIF(tt.brand, tt.imageType, createTIFFEXIF(shot, tt.gps), tt.inIdat)
+			writeMemFile(t, fsys, path, data, time.Now())
+
+			ctx, err := newExecutionContext(&Config{FS: fsys})
+			if err != nil {
+				t.Fatal(err)
+			}
+			metadata, err := ExtractMetadata(ctx, path)
+			if err != nil {
+				t.Fatalf("ExtractMetadata() error: %v", err)
+			}
+
+			if metadata.Source != DateSourceEXIF || !metadata.DateTime.Equal(shot) {
+				t.Errorf("date = %v from %v, want %v from EXIF", metadata.DateTime, metadata.Source, shot)
+			}
+
+			if tt.gps == nil {
+				if metadata.GPS != nil {
+					t.Errorf("GPS = %v, want nil", metadata.GPS)
+				}
+				return
+			}
+			if metadata.GPS == nil {
+				t.Fatal("GPS = nil, want coordinates")
+			}
+			if math.Abs(metadata.GPS.Lat-tt.gps.Lat) > 1e-4 || math.Abs(metadata.GPS.Lon-tt.gps.Lon) > 1e-4 {
+				t.Errorf("GPS = %v, want %v", metadata.GPS, tt.gps)
+			}
+		})
+	}
+}
+
+func TestExtractISOBMFFExif_Errors(t *testing.T) {
+	fsys := NewMemFileSystem()
+	shot := time.Date(2024, 6, 15, 14, 30, 12, 0, time.Local)
+
+	// Image without Exif item: only the image infe
+	withoutExif := createHEIF("heic", "hvc1", createTIFFEXIF(shot, nil), false)
+	copy(withoutExif[indexOf(withoutExif, "Exif"):], "mime")
+	writeMemFile(t, fsys, "/photos/no_exif.heic", withoutExif, time.Now())
+
+	// Truncated file: iloc points past the end
+	full := createHEIF("heic", "hvc1", createTIFFEXIF(shot, nil), false)
+	writeMemFile(t, fsys, "/photos/truncated.heic", full[:len(full)-40], time.Now())
+
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{"/photos/no_exif.heic", errNoExifItem},
+		{"/photos/truncated.heic", nil},
+	}
+
+	for _, tt := range tests {
+		_, err := extractEXIFDate(fsys, tt.path)
+		if err == nil {
+			t.Errorf("extractEXIFDate(%s) expected error", tt.path)
+			continue
+		}
+		if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("extractEXIFDate(%s) error = %v, want %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+// indexOf returns the position of the first occurrence of s in data
+func indexOf(data []byte, s string) int {
+	for i := 0; i+len(s) <= len(data); i++ {
+		if string(data[i:i+len(s)]) == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,419 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// eventIndexName caches the time range of event folders between incremental runs (v2.10.0+)
+	eventIndexName    = ".picsplit-index.json"
+	eventIndexVersion = 1
+)
+
+// eventFolder is an already organized event folder and the time range of its files
+type eventFolder struct {
+	relPath  string // Path relative to BasePath (e.g., "2024 - 0616 - 1900" or "Paris/2024 - 0616 - 1900")
+	start    time.Time
+	end      time.Time
+	files    int  // Media files in the folder and its subfolders
+	extended bool // New files were appended during this run
+}
+
+// eventIndex is the on-disk cache of event folder time ranges
+// Ranges depend on how file dates are resolved: they are dropped when Dates differs from the current settings
+type eventIndex struct {
+	Version int          `json:"version"`
+	Dates   string       `json:"dates,omitempty"`
+	Events  []indexEntry `json:"events"`
+}
+
+// indexEntry is the cached time range of one event folder
+// The entry is only trusted while the folder still holds the same number of media files
+type indexEntry struct {
+	Folder string    `json:"folder"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Files  int       `json:"files"`
+}
+
+// isDateFolderName checks if a folder name matches the event folder format (YYYY - MMDD - HHMM)
+func isDateFolderName(name string) bool {
+	if len(name) != len(dateFormatPattern) {
+		return false
+	}
+	_, err := time.Parse(dateFormatPattern, name)
+	return err == nil
+}
+
+// scanEventFolders lists the event folders in cfg.BasePath with the time range of their files
+// Event folders are looked up at root and one level below (GPS location folders)
+// Ranges come from the index when the folder did not change, from the files otherwise
+func scanEventFolders(cfg *Config, ctx *executionContext) []eventFolder {
+	relPaths, err := listEventFolders(ctx, cfg.BasePath)
+	if err != nil {
+		ctx.log.Warn("failed to read existing event folders", "path", cfg.BasePath, "error", err)
+		return nil
+	}
+
+	cached := loadEventIndex(cfg, ctx)
+
+	var events []eventFolder
+	var fromIndex int
+	for _, relPath := range relPaths {
+		if entry, ok := cached[filepath.ToSlash(relPath)]; ok && entry.Files == countEventFiles(cfg, ctx, relPath) {
+			events = append(events, eventFolder{relPath: relPath, start: entry.Start, end: entry.End, files: entry.Files})
+			fromIndex++
+			continue
+		}
+
+		if event, ok := readEventFolder(cfg, ctx, relPath); ok {
+			events = append(events, event)
+		}
+	}
+
+	ctx.log.Debug("existing event folders scanned", "count", len(events), "from_index", fromIndex)
+	return events
+}
+
+// listEventFolders returns the event folders of basePath, relative to it, at root and one level
+// below (GPS location folders)
+func listEventFolders(ctx *executionContext, basePath string) ([]string, error) {
+	entries, err := ctx.fs.ReadDir(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var relPaths []string
+	for _, entry := range entries {
+		if !entry.IsDir() || isSpecialFolder(entry.Name()) {
+			continue
+		}
+
+		if isDateFolderName(entry.Name()) {
+			relPaths = append(relPaths, entry.Name())
+			continue
+		}
+
+		// Location folder (GPS mode): look for event folders inside
+		subEntries, err := ctx.fs.ReadDir(filepath.Join(basePath, entry.Name()))
+		if err != nil {
+			continue
+		}
+		for _, sub := range subEntries {
+			if sub.IsDir() && isDateFolderName(sub.Name()) {
+				relPaths = append(relPaths, filepath.Join(entry.Name(), sub.Name()))
+			}
+		}
+	}
+	return relPaths, nil
+}
+
+// eventFolderDirs returns an event folder and its raw/, mov/ and orphan/ subfolders
+func eventFolderDirs(basePath, relPath string) []string {
+	return []string{
+		filepath.Join(basePath, relPath),
+		filepath.Join(basePath, relPath, rawFolderName),
+		filepath.Join(basePath, relPath, movFolderName),
+		filepath.Join(basePath, relPath, orphanFolderName),
+	}
+}
+
+// countEventFiles counts the media files of an event folder without reading metadata
+func countEventFiles(cfg *Config, ctx *executionContext, relPath string) int {
+	count := 0
+	for _, dir := range eventFolderDirs(cfg.BasePath, relPath) {
+		entries, err := ctx.fs.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && !isContactSheet(entry.Name()) && (ctx.isPhoto(entry.Name()) || ctx.isMovie(entry.Name())) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// readEventFolder computes the time range of the media files in an event folder
+// and its raw/, mov/ and orphan/ subfolders
+func readEventFolder(cfg *Config, ctx *executionContext, relPath string) (eventFolder, bool) {
+	event := eventFolder{relPath: relPath}
+
+	for _, dir := range eventFolderDirs(cfg.BasePath, relPath) {
+		entries, err := ctx.fs.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || isContactSheet(entry.Name()) || (!ctx.isPhoto(entry.Name()) && !ctx.isMovie(entry.Name())) {
+				continue
+			}
+
+			metadata, err := groupingMetadata(cfg, ctx, filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+			fileTime := metadata.DateTime
+
+			if event.files == 0 || fileTime.Before(event.start) {
+				event.start = fileTime
+			}
+			if event.files == 0 || fileTime.After(event.end) {
+				event.end = fileTime
+			}
+			event.files++
+		}
+	}
+
+	return event, event.files > 0
+}
+
+// appendToExistingEvents redirects groups whose files fall within Delta of an existing
+// event folder into that folder, instead of creating a new one next to it
+// Only folders under the same parent (root or location folder) are considered
+// Returns the number of groups appended
+func appendToExistingEvents(ctx *executionContext, groups []Group, events []eventFolder, delta time.Duration) int {
+	appended := 0
+
+	for i := range groups {
+		group := &groups[i]
+		start, end := groupTimeRange(group.Files)
+		parent := filepath.Dir(group.Folder)
+
+		var best *eventFolder
+		var bestGap time.Duration
+		for j := range events {
+			event := &events[j]
+			if filepath.Dir(event.relPath) != parent {
+				continue
+			}
+
+			gap := rangeGap(start, end, event.start, event.end)
+			if gap > delta {
+				continue
+			}
+			if best == nil || gap < bestGap {
+				best, bestGap = event, gap
+			}
+		}
+
+		if best == nil {
+			continue
+		}
+
+		ctx.log.Info("appending files to existing event folder",
+			"folder", best.relPath,
+			"files", len(group.Files),
+			"gap", bestGap)
+
+		group.Folder = best.relPath
+		group.Existing = true
+		appended++
+
+		// Later groups may now touch the extended event
+		if start.Before(best.start) {
+			best.start = start
+		}
+		if end.After(best.end) {
+			best.end = end
+		}
+		best.extended = true
+	}
+
+	return appended
+}
+
+// mergeTouchingEvents merges event folders that are now within Delta of each other
+// because new files extended one of them; the earliest folder is kept
+// Files are moved with the merge conflict logic, conflicts resolved with the --on-collision policy
+// Returns the remaining events and the number of folders merged
+func mergeTouchingEvents(runCtx context.Context, cfg *Config, ctx *executionContext, events []eventFolder) ([]eventFolder, int, error) {
+	sort.Slice(events, func(i, j int) bool {
+		if filepath.Dir(events[i].relPath) != filepath.Dir(events[j].relPath) {
+			return filepath.Dir(events[i].relPath) < filepath.Dir(events[j].relPath)
+		}
+		return events[i].start.Before(events[j].start)
+	})
+
+	var result []eventFolder
+	merged := 0
+
+	for _, event := range events {
+		if len(result) > 0 {
+			target := &result[len(result)-1]
+			touching := filepath.Dir(target.relPath) == filepath.Dir(event.relPath) &&
+				rangeGap(target.start, target.end, event.start, event.end) <= cfg.Delta
+
+			if touching && (target.extended || event.extended) {
+				if err := mergeEventFolder(runCtx, cfg, ctx, event.relPath, target.relPath); err != nil {
+					return nil, merged, err
+				}
+				merged++
+
+				if event.end.After(target.end) {
+					target.end = event.end
+				}
+				target.files += event.files
+				target.extended = true
+				continue
+			}
+		}
+		result = append(result, event)
+	}
+
+	return result, merged, nil
+}
+
+// mergeEventFolder moves an event folder into another one using the merge command logic
+func mergeEventFolder(runCtx context.Context, cfg *Config, ctx *executionContext, sourceRel, targetRel string) error {
+	ctx.log.Info("merging event folders that now touch", "source", sourceRel, "target", targetRel)
+
+	// Split collision policy applies to merge conflicts
+	mergeCfg := &MergeConfig{
+		SourceFolders:   []string{filepath.Join(cfg.BasePath, sourceRel)},
+		TargetFolder:    filepath.Join(cfg.BasePath, targetRel),
+		Mode:            cfg.Mode,
+		OnConflict:      cfg.collisionPolicy(),
+		CustomPhotoExts: cfg.CustomPhotoExts,
+		CustomVideoExts: cfg.CustomVideoExts,
+		CustomRawExts:   cfg.CustomRawExts,
+		Logger:          ctx.log,
+		FS:              ctx.fs,
+	}
+
+	if err := mergeInternal(runCtx, mergeCfg); err != nil {
+		return fmt.Errorf("failed to merge %s into %s: %w", sourceRel, targetRel, err)
+	}
+	return nil
+}
+
+// groupTimeRange returns the earliest and latest date of a group's files
+func groupTimeRange(files []FileMetadata) (time.Time, time.Time) {
+	var start, end time.Time
+	for i, file := range files {
+		if i == 0 || file.DateTime.Before(start) {
+			start = file.DateTime
+		}
+		if i == 0 || file.DateTime.After(end) {
+			end = file.DateTime
+		}
+	}
+	return start, end
+}
+
+// rangeGap returns the time between two ranges (0 when they overlap)
+func rangeGap(startA, endA, startB, endB time.Time) time.Duration {
+	switch {
+	case startA.After(endB):
+		return startA.Sub(endB)
+	case startB.After(endA):
+		return startB.Sub(endA)
+	default:
+		return 0
+	}
+}
+
+// isSpecialFolder checks if a folder is one created by picsplit inside or next to event folders
+func isSpecialFolder(name string) bool {
+	switch name {
+	case movFolderName, rawFolderName, orphanFolderName, duplicatesFolderName:
+		return true
+	}
+	return false
+}
+
+// loadEventIndex reads the event index of basePath, keyed by slash-separated folder path
+// A missing or unreadable index is not an error: folders are scanned instead
+func loadEventIndex(cfg *Config, ctx *executionContext) map[string]indexEntry {
+	entries := make(map[string]indexEntry)
+	indexPath := filepath.Join(cfg.BasePath, eventIndexName)
+
+	data, err := readFile(ctx.fs, indexPath)
+	if err != nil {
+		return entries
+	}
+
+	var index eventIndex
+	if err := json.Unmarshal(data, &index); err != nil || index.Version != eventIndexVersion {
+		ctx.log.Warn("ignoring invalid event index", "path", indexPath)
+		return entries
+	}
+	if index.Dates != indexDateSettings(cfg, ctx) {
+		ctx.log.Info("date settings changed since the event index was written, rereading event folders", "path", indexPath)
+		return entries
+	}
+
+	for _, entry := range index.Events {
+		entries[entry.Folder] = entry
+	}
+	return entries
+}
+
+// indexDateSettings describes the settings file dates are resolved with (see groupingMetadata)
+func indexDateSettings(cfg *Config, ctx *executionContext) string {
+	if !cfg.UseEXIF {
+		return "mtime"
+	}
+	return fmt.Sprintf("priority=%v patterns=%q conflict=%v/%v min_year=%d",
+		ctx.datePriority, cfg.FilenameDatePatterns, ctx.dateConflictThreshold, ctx.dateConflictPolicy, ctx.minValidYear)
+}
+
+// saveEventIndex writes the time ranges of all event folders to the index
+// File counts are taken after processing so the next run can trust the entries
+func saveEventIndex(cfg *Config, ctx *executionContext, events []eventFolder) error {
+	index := eventIndex{Version: eventIndexVersion, Dates: indexDateSettings(cfg, ctx), Events: make([]indexEntry, 0, len(events))}
+	for _, event := range events {
+		files := countEventFiles(cfg, ctx, event.relPath)
+		if files == 0 {
+			continue
+		}
+		index.Events = append(index.Events, indexEntry{
+			Folder: filepath.ToSlash(event.relPath),
+			Start:  event.start,
+			End:    event.end,
+			Files:  files,
+		})
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode event index: %w", err)
+	}
+
+	if err := ctx.fs.WriteFile(filepath.Join(cfg.BasePath, eventIndexName), data, 0644); err != nil { //nolint:gosec // index is meant to be readable
+		return fmt.Errorf("failed to write event index: %w", err)
+	}
+	return nil
+}
+
+// finishIncremental merges events that now touch and updates the event index
+// New event folders created by this run are added to the index
+func finishIncremental(runCtx context.Context, cfg *Config, ctx *executionContext, events []eventFolder, groups []Group) (int, error) {
+	for _, group := range groups {
+		if group.Existing {
+			continue
+		}
+		start, end := groupTimeRange(group.Files)
+		events = append(events, eventFolder{relPath: group.Folder, start: start, end: end, files: len(group.Files)})
+	}
+
+	events, merged, err := mergeTouchingEvents(runCtx, cfg, ctx, events)
+	if err != nil {
+		return merged, err
+	}
+
+	if cfg.Mode == ModeRun {
+		if err := saveEventIndex(cfg, ctx, events); err != nil {
+			ctx.log.Warn("failed to update event index", "error", err)
+		}
+	}
+
+	return merged, nil
+}
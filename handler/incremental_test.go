@@ -0,0 +1,268 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendToExistingEvents(t *testing.T) {
+	base := time.Date(2024, 6, 16, 19, 0, 0, 0, time.Local)
+	delta := 30 * time.Minute
+
+	events := []eventFolder{
+		{relPath: "2024 - 0616 - 1900", start: base, end: base.Add(2*time.Hour + 10*time.Minute)},
+		{relPath: filepath.Join("Paris", "2024 - 0616 - 1900"), start: base, end: base.Add(time.Hour)},
+	}
+
+	tests := []struct {
+		name       string
+		folderName string
+		offsets    []time.Duration
+		wantFolder string
+	}{
+		{"within delta after event", "2024 - 0616 - 2130", []time.Duration{150 * time.Minute, 160 * time.Minute}, "2024 - 0616 - 1900"},
+		{"inside event range", "2024 - 0616 - 1930", []time.Duration{30 * time.Minute}, "2024 - 0616 - 1900"},
+		{"within delta before event", "2024 - 0616 - 1840", []time.Duration{-20 * time.Minute}, "2024 - 0616 - 1900"},
+		{"beyond delta", "2024 - 0616 - 2300", []time.Duration{4 * time.Hour}, "2024 - 0616 - 2300"},
+		{"same location folder", filepath.Join("Paris", "2024 - 0616 - 2010"), []time.Duration{70 * time.Minute}, filepath.Join("Paris", "2024 - 0616 - 1900")},
+		{"other location folder", filepath.Join("Lyon", "2024 - 0616 - 2010"), []time.Duration{70 * time.Minute}, filepath.Join("Lyon", "2024 - 0616 - 2010")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := Group{Folder: tt.folderName}
+			for _, offset := range tt.offsets {
+				group.Files = append(group.Files, FileMetadata{DateTime: base.Add(offset)})
+			}
+
+			groups := []Group{group}
+			appended := appendToExistingEvents(newDefaultExecutionContext(), groups, append([]eventFolder(nil), events...), delta)
+
+			if groups[0].Folder != tt.wantFolder {
+				t.Errorf("Folder = %q, want %q", groups[0].Folder, tt.wantFolder)
+			}
+			wantAppended := tt.folderName != tt.wantFolder
+			if groups[0].Existing != wantAppended || (appended == 1) != wantAppended {
+				t.Errorf("existing = %v, appended = %d, want appended %v", groups[0].Existing, appended, wantAppended)
+			}
+		})
+	}
+}
+
+func TestSplit_IncrementalAppendsToExistingEvent(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Date(2024, 6, 16, 19, 0, 0, 0, time.Local)
+	event := base.Format(dateFormatPattern)
+
+	// Monday's split: event from 19:00 to 21:10
+	writeTestFile(t, tmpDir, filepath.Join(event, "IMG_0001.JPG"), "first")
+	writeTestFile(t, tmpDir, filepath.Join(event, "IMG_0002.JPG"), "last")
+	setModTime(t, filepath.Join(tmpDir, event, "IMG_0001.JPG"), base)
+	setModTime(t, filepath.Join(tmpDir, event, "IMG_0002.JPG"), base.Add(130*time.Minute))
+
+	// Tuesday: photos from 21:30 arrive, plus an unrelated event the next morning
+	createTestFile(t, tmpDir, "IMG_0003.JPG", base.Add(150*time.Minute))
+	createTestFile(t, tmpDir, "IMG_0004.JPG", base.Add(155*time.Minute))
+	createTestFile(t, tmpDir, "IMG_0100.JPG", base.Add(15*time.Hour))
+
+	cfg := &Config{
+		BasePath:          tmpDir,
+		Delta:             30 * time.Minute,
+		Mode:              ModeRun,
+		MinGroupSize:      1,
+		SeparateOrphanRaw: true,
+		Incremental:       true,
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	assertExists(t, filepath.Join(tmpDir, event, "IMG_0003.JPG"))
+	assertExists(t, filepath.Join(tmpDir, event, "IMG_0004.JPG"))
+	assertNotExists(t, filepath.Join(tmpDir, base.Add(150*time.Minute).Format(dateFormatPattern)))
+	assertExists(t, filepath.Join(tmpDir, base.Add(15*time.Hour).Format(dateFormatPattern), "IMG_0100.JPG"))
+}
+
+func TestSplit_IncrementalIgnoresMinGroupSizeForExistingEvent(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Date(2024, 6, 16, 19, 0, 0, 0, time.Local)
+	event := base.Format(dateFormatPattern)
+
+	writeTestFile(t, tmpDir, filepath.Join(event, "IMG_0001.JPG"), "first")
+	setModTime(t, filepath.Join(tmpDir, event, "IMG_0001.JPG"), base)
+	createTestFile(t, tmpDir, "IMG_0002.JPG", base.Add(10*time.Minute))
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		MinGroupSize: 5,
+		Incremental:  true,
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	// A single late file still joins its event
+	assertExists(t, filepath.Join(tmpDir, event, "IMG_0002.JPG"))
+}
+
+func setModTime(t *testing.T, path string, modTime time.Time) {
+	t.Helper()
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set file time: %v", err)
+	}
+}
+
+func TestSplit_IncrementalMergesTouchingEvents(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Date(2024, 6, 16, 19, 0, 0, 0, time.Local)
+	eventA := base.Format(dateFormatPattern)
+	eventB := base.Add(70 * time.Minute).Format(dateFormatPattern)
+
+	// Two events 50 minutes apart (delta 30m)
+	writeTestFile(t, tmpDir, filepath.Join(eventA, "IMG_0001.JPG"), "a1")
+	writeTestFile(t, tmpDir, filepath.Join(eventA, "IMG_0002.JPG"), "a2")
+	writeTestFile(t, tmpDir, filepath.Join(eventB, "IMG_0010.JPG"), "b1")
+	writeTestFile(t, tmpDir, filepath.Join(eventB, "IMG_0002.JPG"), "same name, other shot")
+	setModTime(t, filepath.Join(tmpDir, eventA, "IMG_0001.JPG"), base)
+	setModTime(t, filepath.Join(tmpDir, eventA, "IMG_0002.JPG"), base.Add(20*time.Minute))
+	setModTime(t, filepath.Join(tmpDir, eventB, "IMG_0010.JPG"), base.Add(70*time.Minute))
+	setModTime(t, filepath.Join(tmpDir, eventB, "IMG_0002.JPG"), base.Add(80*time.Minute))
+
+	// A late photo fills the gap between them
+	createTestFile(t, tmpDir, "IMG_0005.JPG", base.Add(45*time.Minute))
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		MinGroupSize: 1,
+		Incremental:  true,
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	assertExists(t, filepath.Join(tmpDir, eventA, "IMG_0005.JPG"))
+	assertExists(t, filepath.Join(tmpDir, eventA, "IMG_0010.JPG"))
+	assertExists(t, filepath.Join(tmpDir, eventA, "IMG_0002_1.JPG"))
+	assertNotExists(t, filepath.Join(tmpDir, eventB))
+}
+
+func TestSplit_IncrementalMergeKeepsSkippedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Date(2024, 6, 16, 19, 0, 0, 0, time.Local)
+	eventA := base.Format(dateFormatPattern)
+	eventB := base.Add(70 * time.Minute).Format(dateFormatPattern)
+
+	writeTestFile(t, tmpDir, filepath.Join(eventA, "IMG_0002.JPG"), "a2")
+	writeTestFile(t, tmpDir, filepath.Join(eventB, "IMG_0002.JPG"), "same name, other shot")
+	writeTestFile(t, tmpDir, filepath.Join(eventB, movFolderName, "MOV_0001.MP4"), "video")
+	setModTime(t, filepath.Join(tmpDir, eventA, "IMG_0002.JPG"), base.Add(20*time.Minute))
+	setModTime(t, filepath.Join(tmpDir, eventB, "IMG_0002.JPG"), base.Add(70*time.Minute))
+	setModTime(t, filepath.Join(tmpDir, eventB, movFolderName, "MOV_0001.MP4"), base.Add(80*time.Minute))
+	createTestFile(t, tmpDir, "IMG_0005.JPG", base.Add(45*time.Minute))
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		MinGroupSize: 1,
+		Incremental:  true,
+		OnCollision:  "skip",
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	// The skipped photo stays in its folder, the others are merged and emptied folders removed
+	data, err := os.ReadFile(filepath.Join(tmpDir, eventB, "IMG_0002.JPG"))
+	if err != nil || string(data) != "same name, other shot" {
+		t.Fatalf("skipped file was not kept in the source folder: %v", err)
+	}
+	assertExists(t, filepath.Join(tmpDir, eventA, movFolderName, "MOV_0001.MP4"))
+	assertNotExists(t, filepath.Join(tmpDir, eventB, movFolderName))
+}
+
+func TestSplit_IncrementalEventIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Date(2024, 6, 16, 19, 0, 0, 0, time.Local)
+	event := base.Format(dateFormatPattern)
+
+	createTestFile(t, tmpDir, "IMG_0001.JPG", base)
+	createTestFile(t, tmpDir, "IMG_0002.JPG", base.Add(10*time.Minute))
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		MinGroupSize: 1,
+		Incremental:  true,
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("first Split() error: %v", err)
+	}
+
+	index := loadEventIndex(cfg, newDefaultExecutionContext())
+	entry, ok := index[event]
+	if !ok {
+		t.Fatalf("event %q missing from index: %v", event, index)
+	}
+	if entry.Files != 2 || !entry.Start.Equal(base) || !entry.End.Equal(base.Add(10*time.Minute)) {
+		t.Errorf("index entry = %+v", entry)
+	}
+
+	// The index is trusted while the folder keeps the same file count
+	ctx := newDefaultExecutionContext()
+	events := scanEventFolders(cfg, ctx)
+	if len(events) != 1 || !events[0].end.Equal(base.Add(10*time.Minute)) {
+		t.Fatalf("scanEventFolders() = %+v", events)
+	}
+
+	// Changed folder: rescanned from the files
+	createTestFile(t, filepath.Join(tmpDir, event), "IMG_0003.JPG", base.Add(20*time.Minute))
+	events = scanEventFolders(cfg, ctx)
+	if len(events) != 1 || events[0].files != 3 || !events[0].end.Equal(base.Add(20*time.Minute)) {
+		t.Errorf("scanEventFolders() after change = %+v", events)
+	}
+}
+
+func TestSplit_IncrementalIndexFollowsDateSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	noon := time.Date(2024, 6, 16, 12, 0, 0, 0, time.Local)
+
+	// Copied in the evening: the modification time is not the shot time written in the name
+	createTestFile(t, tmpDir, "IMG_20240616_120000.JPG", noon.Add(7*time.Hour))
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		MinGroupSize: 1,
+		Incremental:  true,
+	}
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("first Split() error: %v", err)
+	}
+	event := noon.Add(7 * time.Hour).Format(dateFormatPattern)
+	assertExists(t, filepath.Join(tmpDir, event, "IMG_20240616_120000.JPG"))
+
+	// Dates now come from file names, like for the new file: the indexed range is outdated
+	createTestFile(t, tmpDir, "IMG_20240616_121000.JPG", noon.Add(24*time.Hour))
+	cfg.UseEXIF = true
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("second Split() error: %v", err)
+	}
+
+	assertExists(t, filepath.Join(tmpDir, event, "IMG_20240616_121000.JPG"))
+	assertNotExists(t, filepath.Join(tmpDir, noon.Add(10*time.Minute).Format(dateFormatPattern)))
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// A camera without GPS shooting next to a phone ends up in NoLocation (v2.10.0+)
+// With --gps-infer-window, a file without GPS joins the location of the geotagged file closest in time
+
+// LocationInference is a file without GPS assigned to the location of a geotagged neighbour
+type LocationInference struct {
+	File     string        // File without GPS
+	From     string        // Geotagged file closest in time
+	Gap      time.Duration // Time between the two files
+	Location string        // Location folder the file joined
+
+	cluster int // Index of the location cluster, to fill Location once clusters are named
+}
+
+// geotaggedFile is a file of a location cluster, for the nearest neighbour search
+type geotaggedFile struct {
+	file    FileMetadata
+	cluster int
+}
+
+// inferLocations adds each file without GPS to the cluster of the geotagged file closest in time, within window
+// On a tie the earlier neighbour wins; returns the files left without location and the inferences
+func inferLocations(clusters []LocationCluster, withoutGPS []FileMetadata, window time.Duration) ([]FileMetadata, []LocationInference) {
+	if window <= 0 || len(clusters) == 0 {
+		return withoutGPS, nil
+	}
+
+	var geotagged []geotaggedFile
+	for i, cluster := range clusters {
+		for _, file := range cluster.Files {
+			geotagged = append(geotagged, geotaggedFile{file: file, cluster: i})
+		}
+	}
+	sort.SliceStable(geotagged, func(i, j int) bool {
+		return geotagged[i].file.DateTime.Before(geotagged[j].file.DateTime)
+	})
+
+	var rest []FileMetadata
+	var inferences []LocationInference
+	for _, file := range withoutGPS {
+		// First geotagged file not before the file, the nearest one is either it or the previous one
+		next := sort.Search(len(geotagged), func(i int) bool {
+			return !geotagged[i].file.DateTime.Before(file.DateTime)
+		})
+
+		nearest, gap := -1, window
+		if next > 0 {
+			if d := file.DateTime.Sub(geotagged[next-1].file.DateTime); d <= gap {
+				nearest, gap = next-1, d
+			}
+		}
+		if next < len(geotagged) {
+			if d := geotagged[next].file.DateTime.Sub(file.DateTime); d < gap || (nearest < 0 && d <= gap) {
+				nearest, gap = next, d
+			}
+		}
+
+		if nearest < 0 {
+			rest = append(rest, file)
+			continue
+		}
+
+		neighbour := geotagged[nearest]
+		clusters[neighbour.cluster].Files = append(clusters[neighbour.cluster].Files, file)
+		inferences = append(inferences, LocationInference{
+			File:    file.FileInfo.Name(),
+			From:    neighbour.file.FileInfo.Name(),
+			Gap:     gap,
+			cluster: neighbour.cluster,
+		})
+	}
+	return rest, inferences
+}
+
+// printLocationInferences logs the files whose location was inferred
+func printLocationInferences(inferences []LocationInference) {
+	for _, inference := range inferences {
+		slog.Info("location inferred",
+			"file", inference.File,
+			"from", inference.From,
+			"gap", inference.Gap,
+			"location", inference.Location)
+	}
+}
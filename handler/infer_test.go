@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInferLocations(t *testing.T) {
+	start := time.Date(2024, 6, 15, 10, 0, 0, 0, time.Local)
+	file := func(name string, offset time.Duration) FileMetadata {
+		return FileMetadata{FileInfo: memFileInfo{name: name}, DateTime: start.Add(offset)}
+	}
+
+	tests := []struct {
+		name       string
+		withoutGPS []FileMetadata
+		window     time.Duration
+		want       map[string]string // File -> neighbour, files missing are left without location
+	}{
+		{"disabled", []FileMetadata{file("DSC_1.JPG", time.Minute)}, 0, nil},
+		{"closest neighbour", []FileMetadata{file("DSC_1.JPG", 50*time.Minute)}, time.Hour, map[string]string{"DSC_1.JPG": "ROME.JPG"}},
+		{"tie goes to the earlier neighbour", []FileMetadata{file("DSC_1.JPG", 30*time.Minute)}, time.Hour, map[string]string{"DSC_1.JPG": "PARIS.JPG"}},
+		{"before the first geotagged file", []FileMetadata{file("DSC_1.JPG", -5*time.Minute)}, 10 * time.Minute, map[string]string{"DSC_1.JPG": "PARIS.JPG"}},
+		{"outside the window", []FileMetadata{file("DSC_1.JPG", 15*time.Minute), file("DSC_2.JPG", 2*time.Hour)}, 10 * time.Minute, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parisFile := file("PARIS.JPG", 0)
+			parisFile.GPS = &paris
+			romeFile := file("ROME.JPG", time.Hour)
+			romeFile.GPS = &rome
+			clusters := []LocationCluster{
+				{Files: []FileMetadata{parisFile}, Centroid: paris},
+				{Files: []FileMetadata{romeFile}, Centroid: rome},
+			}
+
+			rest, inferences := inferLocations(clusters, tt.withoutGPS, tt.window)
+
+			got := make(map[string]string)
+			for _, inference := range inferences {
+				got[inference.File] = inference.From
+				if files := clusters[inference.cluster].Files; files[len(files)-1].FileInfo.Name() != inference.File {
+					t.Errorf("%s was not added to the cluster of %s", inference.File, inference.From)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("inferences = %v, want %v", got, tt.want)
+			}
+			if len(rest)+len(inferences) != len(tt.withoutGPS) {
+				t.Errorf("got %d files left and %d inferences for %d files", len(rest), len(inferences), len(tt.withoutGPS))
+			}
+		})
+	}
+}
+
+func TestSplit_GPSInference(t *testing.T) {
+	tmpDir := t.TempDir()
+	shot := time.Date(2024, 6, 15, 10, 0, 0, 0, time.Local)
+
+	// The phone is geotagged, the camera shooting next to it is not
+	createJPEGWithGPS(t, filepath.Join(tmpDir, "IMG_0001.JPG"), shot, &rome)
+	createJPEGWithGPS(t, filepath.Join(tmpDir, "IMG_0002.JPG"), shot.Add(5*time.Minute), &rome)
+	createJPEGWithEXIF(t, filepath.Join(tmpDir, "DSC_0001.JPG"), shot.Add(7*time.Minute))
+	createJPEGWithEXIF(t, filepath.Join(tmpDir, "DSC_0002.JPG"), shot.Add(3*time.Hour)) // Too far from the phone
+
+	cfg := &Config{
+		BasePath:       tmpDir,
+		Delta:          30 * time.Minute,
+		Mode:           ModeRun,
+		UseEXIF:        true,
+		UseGPS:         true,
+		GPSRadius:      2000,
+		GPSInferWindow: 15 * time.Minute,
+	}
+
+	result, err := Split(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	location := "41.9028N-12.4964E"
+	assertExists(t, filepath.Join(tmpDir, location, "2024 - 0615 - 1000", "DSC_0001.JPG"))
+	assertExists(t, filepath.Join(tmpDir, GetNoLocationFolderName(), "2024 - 0615 - 1300", "DSC_0002.JPG"))
+
+	inferences := result.Stats.LocationInferences
+	if len(inferences) != 1 {
+		t.Fatalf("got %d inferences, want 1", len(inferences))
+	}
+	want := LocationInference{File: "DSC_0001.JPG", From: "IMG_0002.JPG", Gap: 2 * time.Minute, Location: location}
+	if got := inferences[0]; got.File != want.File || got.From != want.From || got.Gap != want.Gap || got.Location != want.Location {
+		t.Errorf("inference = %+v, want %+v", got, want)
+	}
+}
+
+func TestConfigValidate_GPSInferWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"negative window", func(c *Config) { c.UseGPS = true; c.GPSInferWindow = -time.Minute }, "inference window"},
+		{"without GPS", func(c *Config) { c.GPSInferWindow = time.Minute }, "requires --gps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig(t.TempDir())
+			tt.modify(cfg)
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,501 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// picsplit report --html writes a map and a timeline of a library, to review a split before running it
+// or to keep as an index of an organized library (v2.10.0+)
+
+const (
+	libraryReportHTML    = "index.html"
+	libraryReportGeoJSON = "library.geojson"
+)
+
+// LibraryReportConfig configures the HTML and GeoJSON report of a library
+type LibraryReportConfig struct {
+	Split   *Config // Settings of the planned split, BasePath is the library (mode is forced to dryrun)
+	HTMLDir string  // Folder receiving index.html and library.geojson, created if missing
+}
+
+// libraryEvent is an event folder of the library, organized or planned
+type libraryEvent struct {
+	GroupSummary
+	Planned bool // Files still at the root, the folder is what split would create
+}
+
+// libraryFile is a geotagged file with the folder it is in, or would be moved to
+type libraryFile struct {
+	FileMetadata
+	Folder  string // Relative to BasePath, "" at the root
+	Planned bool
+}
+
+// libraryCluster is a location cluster with the folders its files are in
+type libraryCluster struct {
+	LocationCluster
+	Name    string   // Most common location folder, coordinates otherwise
+	Folders []string // Folders holding files of the cluster, sorted
+}
+
+// libraryMap holds the events, geotagged files and location clusters of a library
+type libraryMap struct {
+	name     string // Library folder name
+	events   []libraryEvent
+	files    []libraryFile
+	clusters []libraryCluster
+}
+
+// WriteLibraryReport writes index.html (timeline of event folders and map of location clusters, self-contained
+// and usable offline) and library.geojson (geotagged files and clusters) for cfg.Split.BasePath
+// Event folders already organized are read as they are; files at the root are grouped as split would group them
+func WriteLibraryReport(runCtx context.Context, cfg *LibraryReportConfig) error {
+	if cfg.HTMLDir == "" {
+		return errors.New("report requires an output folder (--html)")
+	}
+
+	// Nothing is moved or written next to the media
+	splitCfg := *cfg.Split
+	splitCfg.Mode = ModeDryRun
+	if err := splitCfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	ctx, err := newExecutionContext(&splitCfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize extension context: %w", err)
+	}
+
+	m := &libraryMap{name: filepath.Base(filepath.Clean(splitCfg.BasePath))}
+
+	// Event folders already organized
+	library, err := collectLibraryFiles(&splitCfg, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to collect library files: %w", err)
+	}
+	ctx.geotagFromTrack(&splitCfg, library.media)
+	organized, atRoot := libraryGroups(library)
+	m.addStats(organized, false)
+	for _, folder := range sortedKeys(atRoot) {
+		m.addFiles(folder, atRoot[folder], false)
+	}
+
+	// Files at the root, grouped as split would group them
+	files, err := collectMediaFilesWithMetadata(runCtx, &splitCfg, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to collect media files: %w", err)
+	}
+	if len(files) > 0 {
+		plan, err := buildPlan(runCtx, &splitCfg, ctx, files)
+		if err != nil {
+			return err
+		}
+		planned := &ProcessingStats{LocationInferences: plan.inferences}
+		for _, group := range plan.Groups {
+			if !group.AtRoot {
+				planned.Groups = append(planned.Groups, newGroupSummary(group))
+				continue
+			}
+			// Small groups stay at the (location or trip day) root, as in processRootGroup
+			root := group.Root
+			if root == "" && splitCfg.UseGPS {
+				root = groupRoot(group.Folder, splitCfg.GroupBy)
+			}
+			m.addFiles(root, group.Files, true)
+		}
+		m.addStats(planned, true)
+	}
+
+	radius := splitCfg.GPSRadius
+	if radius <= 0 {
+		radius = defaultGPSRadiusMeters
+	}
+	m.cluster(radius)
+
+	ctx.log.Info("library mapped",
+		"events", len(m.events),
+		"geotagged_files", len(m.files),
+		"locations", len(m.clusters))
+
+	return m.writeFiles(ctx.fs, cfg.HTMLDir)
+}
+
+// libraryGroups turns the files of organized folders into one group per event folder, in folder order
+// Files left in location folders or in raw/ and mov/ of the root are returned apart, by folder ("" for the root)
+// Files directly at the root are left out: they are not organized yet
+func libraryGroups(library *libraryFiles) (*ProcessingStats, map[string][]FileMetadata) {
+	byFolder := make(map[string][]FileMetadata)
+	for _, file := range library.media {
+		folder := filepath.Dir(file.relPath)
+		if folder == "." {
+			continue
+		}
+		if base := filepath.Base(folder); base == rawFolderName || base == movFolderName || base == orphanFolderName {
+			folder = filepath.Dir(folder)
+		}
+		if folder == "." {
+			folder = ""
+		}
+		byFolder[folder] = append(byFolder[folder], file)
+	}
+
+	stats := &ProcessingStats{}
+	atRoot := make(map[string][]FileMetadata)
+	for _, folder := range sortedKeys(byFolder) {
+		files := byFolder[folder]
+		sortFilesByDateTime(files)
+		if folder == "" || !isDateFolderName(filepath.Base(folder)) {
+			atRoot[folder] = files
+			continue
+		}
+		stats.Groups = append(stats.Groups, newGroupSummary(Group{Folder: folder, Files: files, Existing: true}))
+	}
+	return stats, atRoot
+}
+
+// sortedKeys returns the folders of a map in order
+func sortedKeys(byFolder map[string][]FileMetadata) []string {
+	folders := make([]string, 0, len(byFolder))
+	for folder := range byFolder {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+	return folders
+}
+
+// addStats adds the event folders of stats and their geotagged files
+func (m *libraryMap) addStats(stats *ProcessingStats, planned bool) {
+	for _, group := range stats.Groups {
+		m.events = append(m.events, libraryEvent{GroupSummary: group, Planned: planned})
+		m.addFiles(group.Folder, group.Metadata, planned)
+	}
+}
+
+// addFiles adds the geotagged files of a folder
+func (m *libraryMap) addFiles(folder string, files []FileMetadata, planned bool) {
+	for _, file := range files {
+		if file.GPS != nil {
+			m.files = append(m.files, libraryFile{FileMetadata: file, Folder: folder, Planned: planned})
+		}
+	}
+}
+
+// cluster groups the geotagged files by location and names each cluster after its location folder
+func (m *libraryMap) cluster(radius float64) {
+	folders := make(map[os.FileInfo]string, len(m.files))
+	metadata := make([]FileMetadata, 0, len(m.files))
+	for _, file := range m.files {
+		folders[file.FileInfo] = file.Folder
+		metadata = append(metadata, file.FileMetadata)
+	}
+
+	clusters, _ := ClusterByLocation(metadata, radius)
+	m.clusters = m.clusters[:0]
+	for _, cluster := range clusters {
+		counts := make(map[string]int)
+		locations := make(map[string]int)
+		for _, file := range cluster.Files {
+			folder := folders[file.FileInfo]
+			counts[folder]++
+			// GPS layouts put events in location folders: "Paris/2024 - 0615 - 1000"
+			if location := filepath.Dir(folder); location != "." && folder != "" {
+				locations[location]++
+			}
+		}
+
+		name, best := FormatLocationName(cluster.Centroid), 0
+		for location, count := range locations {
+			if count > best || (count == best && location < name) {
+				name, best = location, count
+			}
+		}
+
+		var names []string
+		for folder := range counts {
+			names = append(names, folder)
+		}
+		sort.Strings(names)
+		m.clusters = append(m.clusters, libraryCluster{LocationCluster: cluster, Name: name, Folders: names})
+	}
+	sort.SliceStable(m.clusters, func(i, j int) bool { return len(m.clusters[i].Files) > len(m.clusters[j].Files) })
+}
+
+// geoJSONFeature is a point of a GeoJSON FeatureCollection
+type geoJSONFeature struct {
+	Type     string         `json:"type"`
+	Geometry geoJSONPoint   `json:"geometry"`
+	Props    map[string]any `json:"properties"`
+}
+
+// geoJSONPoint is a GeoJSON point, coordinates are longitude then latitude
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// geoJSON returns a FeatureCollection with a point per location cluster, then per geotagged file
+func (m *libraryMap) geoJSON() ([]byte, error) {
+	features := make([]geoJSONFeature, 0, len(m.clusters)+len(m.files))
+	point := func(pos GPSCoord) geoJSONPoint {
+		return geoJSONPoint{Type: "Point", Coordinates: [2]float64{pos.Lon, pos.Lat}}
+	}
+
+	for _, cluster := range m.clusters {
+		features = append(features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: point(cluster.Centroid),
+			Props: map[string]any{
+				"kind":    "cluster",
+				"name":    cluster.Name,
+				"files":   len(cluster.Files),
+				"folders": cluster.Folders,
+			},
+		})
+	}
+	for _, file := range m.files {
+		features = append(features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: point(*file.GPS),
+			Props: map[string]any{
+				"kind":         "file",
+				"file":         file.FileInfo.Name(),
+				"folder":       file.Folder,
+				"date":         file.DateTime,
+				"planned":      file.Planned,
+				"interpolated": file.GPSInterpolated,
+			},
+		})
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"type":     "FeatureCollection",
+		"features": features,
+	}, "", "  ")
+}
+
+// writeFiles writes index.html and library.geojson to dir
+func (m *libraryMap) writeFiles(fsys FileSystem, dir string) error {
+	if err := fsys.MkdirAll(dir, permDirectory); err != nil {
+		return fmt.Errorf("failed to create report folder: %w", err)
+	}
+
+	geoJSON, err := m.geoJSON()
+	if err != nil {
+		return fmt.Errorf("failed to encode GeoJSON: %w", err)
+	}
+	if err := fsys.WriteFile(filepath.Join(dir, libraryReportGeoJSON), geoJSON, 0644); err != nil { //nolint:gosec // report is meant to be readable
+		return fmt.Errorf("failed to write GeoJSON: %w", err)
+	}
+
+	var page bytes.Buffer
+	if err := libraryPageTemplate.Execute(&page, m.page(time.Now())); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	if err := fsys.WriteFile(filepath.Join(dir, libraryReportHTML), page.Bytes(), 0644); err != nil { //nolint:gosec // report is meant to be readable
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	return nil
+}
+
+// libraryPage is the data of the HTML template, positions are in map units (x = longitude, y = -latitude)
+type libraryPage struct {
+	Name      string
+	Generated string
+	Organized int
+	Planned   int
+	Geotagged int
+
+	ViewBox  string
+	Land     []mapOutline
+	Water    []mapOutline
+	DotR     float64
+	Clusters []pageCluster
+	Dots     []pageDot
+
+	Start    string
+	End      string
+	Timeline []pageEvent
+}
+
+type pageCluster struct {
+	X, Y, R float64
+	Name    string
+	Files   int
+	Folders int
+}
+
+type pageDot struct {
+	X, Y  float64
+	Title string
+}
+
+type pageEvent struct {
+	Folder  string
+	Dates   string
+	Files   int
+	Planned bool
+	Split   string
+	Bar     template.CSS // Position of the bar in the library time span
+}
+
+// page lays out the map and the timeline
+func (m *libraryMap) page(now time.Time) libraryPage {
+	page := libraryPage{
+		Name:      m.name,
+		Generated: now.Format("2006-01-02 15:04"),
+		Geotagged: len(m.files),
+		Land:      outlinePaths(false),
+		Water:     outlinePaths(true),
+	}
+
+	// Map: fit the geotagged files, with a margin, 2:1 like the projection
+	minLon, maxLon, minLat, maxLat := -180.0, 180.0, -90.0, 90.0
+	if len(m.files) > 0 {
+		minLon, maxLon, minLat, maxLat = 180, -180, 90, -90
+		for _, file := range m.files {
+			minLon, maxLon = math.Min(minLon, file.GPS.Lon), math.Max(maxLon, file.GPS.Lon)
+			minLat, maxLat = math.Min(minLat, file.GPS.Lat), math.Max(maxLat, file.GPS.Lat)
+		}
+	}
+	width := math.Min(360, math.Max(4, math.Max(maxLon-minLon, 2*(maxLat-minLat))*1.4))
+	height := width / 2
+	x := math.Max(-180, math.Min(180-width, (minLon+maxLon-width)/2))
+	y := math.Max(-90, math.Min(90-height, -(minLat+maxLat+height)/2))
+	page.ViewBox = fmt.Sprintf("%.4f %.4f %.4f %.4f", x, y, width, height)
+	page.DotR = width / 500
+
+	for _, cluster := range m.clusters {
+		page.Clusters = append(page.Clusters, pageCluster{
+			X:       cluster.Centroid.Lon,
+			Y:       -cluster.Centroid.Lat,
+			R:       width / 150 * (1 + math.Log10(float64(len(cluster.Files)))),
+			Name:    cluster.Name,
+			Files:   len(cluster.Files),
+			Folders: len(cluster.Folders),
+		})
+	}
+	for _, file := range m.files {
+		page.Dots = append(page.Dots, pageDot{
+			X:     file.GPS.Lon,
+			Y:     -file.GPS.Lat,
+			Title: filepath.Join(file.Folder, file.FileInfo.Name()),
+		})
+	}
+
+	// Timeline: bars are placed in the time span of the library
+	if len(m.events) == 0 {
+		return page
+	}
+	events := append([]libraryEvent(nil), m.events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	start, end := events[0].Start, events[0].End
+	for _, event := range events {
+		if event.End.After(end) {
+			end = event.End
+		}
+	}
+	span := end.Sub(start)
+	page.Start, page.End = start.Format(time.DateOnly), end.Format(time.DateOnly)
+
+	for _, event := range events {
+		left, barWidth := 0.0, 100.0
+		if span > 0 {
+			left = float64(event.Start.Sub(start)) / float64(span) * 100
+			barWidth = math.Max(0.5, float64(event.End.Sub(event.Start))/float64(span)*100)
+			left = math.Min(left, 100-barWidth)
+		}
+		if event.Planned {
+			page.Planned++
+		} else {
+			page.Organized++
+		}
+		dates := event.Start.Format("2006-01-02 15:04") + " → " + event.End.Format("15:04")
+		if event.End.Format(time.DateOnly) != event.Start.Format(time.DateOnly) {
+			dates = event.Start.Format("2006-01-02 15:04") + " → " + event.End.Format("2006-01-02 15:04")
+		}
+		page.Timeline = append(page.Timeline, pageEvent{
+			Folder:  event.Folder,
+			Dates:   dates,
+			Files:   len(event.Files),
+			Planned: event.Planned,
+			Split:   event.Split,
+			Bar:     template.CSS(fmt.Sprintf("margin-left:%.2f%%;width:%.2f%%", left, barWidth)), //nolint:gosec // numbers only
+		})
+	}
+	return page
+}
+
+// libraryPageTemplate is the report page: inline style and SVG, no script and no external resource
+var libraryPageTemplate = template.Must(template.New("library").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}} - picsplit library</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0 auto; max-width: 1100px; padding: 1rem; color: #222; }
+h1 { margin-bottom: 0; }
+.summary { color: #666; margin-top: .3rem; }
+svg.map { width: 100%; background: #cfe3f3; border: 1px solid #aaa; }
+.land { fill: #f2efe6; stroke: #999; stroke-width: 1; vector-effect: non-scaling-stroke; }
+.water { fill: #cfe3f3; stroke: #999; stroke-width: 1; vector-effect: non-scaling-stroke; }
+.cluster { fill: rgba(214, 69, 65, .55); stroke: #a12; stroke-width: 1; vector-effect: non-scaling-stroke; }
+.file { fill: #124; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: .2rem .5rem; border-bottom: 1px solid #eee; font-size: .9rem; }
+td.bar { width: 40%; }
+td.bar div { height: .8rem; background: #3a7bd5; border-radius: 2px; }
+tr.planned td.bar div { background: repeating-linear-gradient(45deg, #3a7bd5, #3a7bd5 3px, #9bbcea 3px, #9bbcea 6px); }
+tr.planned td.folder::after { content: " (planned)"; color: #888; }
+.split { color: #888; font-size: .8rem; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p class="summary">{{.Organized}} organized events, {{.Planned}} planned, {{.Geotagged}} geotagged files, {{len .Clusters}} locations. Generated {{.Generated}} by picsplit.</p>
+
+<h2>Map</h2>
+<svg class="map" viewBox="{{.ViewBox}}" preserveAspectRatio="xMidYMid meet" xmlns="http://www.w3.org/2000/svg">
+{{- range .Land}}
+<path class="land" d="{{.Path}}"><title>{{.Name}}</title></path>
+{{- end}}
+{{- range .Water}}
+<path class="water" d="{{.Path}}"><title>{{.Name}}</title></path>
+{{- end}}
+{{- range .Clusters}}
+<circle class="cluster" cx="{{.X}}" cy="{{.Y}}" r="{{.R}}"><title>{{.Name}}: {{.Files}} files in {{.Folders}} folders</title></circle>
+{{- end}}
+{{- $r := .DotR}}
+{{- range .Dots}}
+<circle class="file" cx="{{.X}}" cy="{{.Y}}" r="{{$r}}"><title>{{.Title}}</title></circle>
+{{- end}}
+</svg>
+{{- if .Clusters}}
+<table>
+<tr><th>Location</th><th>Files</th><th>Folders</th></tr>
+{{- range .Clusters}}
+<tr><td>{{.Name}}</td><td>{{.Files}}</td><td>{{.Folders}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+<h2>Timeline{{if .Start}} <small>{{.Start}} → {{.End}}</small>{{end}}</h2>
+<table>
+<tr><th>Folder</th><th>Dates</th><th>Files</th><th></th></tr>
+{{- range .Timeline}}
+<tr{{if .Planned}} class="planned"{{end}}><td class="folder">{{.Folder}}{{if .Split}} <span class="split">{{.Split}}</span>{{end}}</td><td>{{.Dates}}</td><td>{{.Files}}</td><td class="bar"><div style="{{.Bar}}"></div></td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
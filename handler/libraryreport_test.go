@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteLibraryReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	outDir := filepath.Join(t.TempDir(), "review")
+
+	// An event already organized in Rome, and photos of Paris still at the root
+	event := filepath.Join(tmpDir, "Rome", "2024 - 0612 - 1000")
+	if err := os.MkdirAll(event, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 2 {
+		createJPEGWithGPS(t, filepath.Join(event, fmt.Sprintf("ROME_%d.JPG", i)), time.Date(2024, 6, 12, 10, i, 0, 0, time.Local), &rome)
+	}
+	for i := range 3 {
+		createJPEGWithGPS(t, filepath.Join(tmpDir, fmt.Sprintf("PARIS_%d.JPG", i)), time.Date(2024, 6, 10, 9, i, 0, 0, time.Local), &paris)
+	}
+	createJPEGWithEXIF(t, filepath.Join(tmpDir, "DSC_0001.JPG"), time.Date(2024, 6, 20, 9, 0, 0, 0, time.Local))
+
+	cfg := &LibraryReportConfig{
+		Split: &Config{
+			BasePath:     tmpDir,
+			Delta:        30 * time.Minute,
+			Mode:         ModeRun,
+			UseEXIF:      true,
+			UseGPS:       true,
+			GPSRadius:    2000,
+			MinGroupSize: 2,
+		},
+		HTMLDir: outDir,
+	}
+
+	if err := WriteLibraryReport(context.Background(), cfg); err != nil {
+		t.Fatalf("WriteLibraryReport() error: %v", err)
+	}
+
+	// Nothing is moved, whatever the mode
+	assertExists(t, filepath.Join(tmpDir, "PARIS_0.JPG"))
+
+	data, err := os.ReadFile(filepath.Join(outDir, libraryReportGeoJSON))
+	if err != nil {
+		t.Fatalf("failed to read GeoJSON: %v", err)
+	}
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Coordinates [2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		t.Fatalf("invalid GeoJSON: %v", err)
+	}
+
+	clusters := make(map[string]float64)
+	var files int
+	for _, feature := range collection.Features {
+		switch feature.Properties["kind"] {
+		case "cluster":
+			clusters[feature.Properties["name"].(string)] = feature.Properties["files"].(float64)
+		case "file":
+			files++
+			coords := feature.Geometry.Coordinates
+			if feature.Properties["file"] == "ROME_0.JPG" && (math.Abs(coords[0]-rome.Lon) > 1e-4 || math.Abs(coords[1]-rome.Lat) > 1e-4) {
+				t.Errorf("ROME_0.JPG coordinates = %v, want longitude then latitude", feature.Geometry.Coordinates)
+			}
+		}
+	}
+	if collection.Type != "FeatureCollection" || files != 5 {
+		t.Errorf("got %s with %d files, want a FeatureCollection with 5 geotagged files", collection.Type, files)
+	}
+	want := map[string]float64{"Rome": 2, "48.8566N-2.3522E": 3} // Planned folder of the Paris photos
+	if fmt.Sprint(clusters) != fmt.Sprint(want) {
+		t.Errorf("clusters = %v, want %v", clusters, want)
+	}
+
+	page, err := os.ReadFile(filepath.Join(outDir, libraryReportHTML))
+	if err != nil {
+		t.Fatalf("failed to read HTML report: %v", err)
+	}
+	html := string(page)
+	for _, expected := range []string{"Rome", filepath.Join("Rome", "2024 - 0612 - 1000"), `class="planned"`, `class="land"`} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("HTML report should contain %q", expected)
+		}
+	}
+	for _, external := range []string{"<script", "https://", "<link"} {
+		if strings.Contains(html, external) {
+			t.Errorf("HTML report should be self-contained, found %q", external)
+		}
+	}
+}
+
+func TestLibraryPageViewBox(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []GPSCoord
+		want  string
+	}{
+		{"no geotagged file", nil, "-180.0000 -90.0000 360.0000 180.0000"},
+		{"single place", []GPSCoord{paris}, "0.3522 -49.8566 4.0000 2.0000"},
+		{"whole world", []GPSCoord{{Lat: 80, Lon: -170}, {Lat: -80, Lon: 170}}, "-180.0000 -90.0000 360.0000 180.0000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &libraryMap{}
+			for _, gps := range tt.files {
+				m.files = append(m.files, libraryFile{FileMetadata: FileMetadata{FileInfo: memFileInfo{name: "IMG.JPG"}, GPS: &gps}})
+			}
+			if got := m.page(time.Now()).ViewBox; got != tt.want {
+				t.Errorf("ViewBox = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
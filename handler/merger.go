@@ -2,7 +2,9 @@ package handler
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -40,6 +42,12 @@ type MergeConfig struct {
 	TargetFolder  string        // Destination folder
 	Force         bool          // Force overwrite on conflicts
 	Mode          ExecutionMode // Execution mode: validate, dryrun, run (v2.8.0+)
+	OnConflict    string        // Resolution applied to every conflict without asking: rename, skip, overwrite, fail (v2.10.0+)
+
+	// Library integration (v2.10.0+)
+	Logger   *slog.Logger // Logger for all messages (default: slog.Default())
+	Resolver Resolver     // Answers conflicts when neither OnConflict nor Force is set (default: prompt on stdin)
+	FS       FileSystem   // Storage holding source and target folders (default: local file system)
 
 	// Custom extensions (v2.5.0+)
 	CustomPhotoExts []string // Additional photo extensions
@@ -66,10 +74,10 @@ type mergeStats struct {
 	conflicts        int
 }
 
-// isMediaFolderWithContext validates that a folder contains only media files and allowed subdirectories (mov/, raw/)
+// isMediaFolderWithContext validates that a folder contains only media files and allowed subdirectories (mov/, raw/, orphan/)
 // This prevents merging non-media folders (like GPS location folders or arbitrary directories)
 func isMediaFolderWithContext(folderPath string, ctx *executionContext) error {
-	entries, err := os.ReadDir(folderPath)
+	entries, err := ctx.fs.ReadDir(folderPath)
 	if err != nil {
 		return fmt.Errorf("failed to read folder %s: %w", folderPath, err)
 	}
@@ -78,8 +86,8 @@ func isMediaFolderWithContext(folderPath string, ctx *executionContext) error {
 		if entry.IsDir() {
 			// Only allow allowedSubdirMov and allowedSubdirRaw subdirectories
 			dirName := strings.ToLower(entry.Name())
-			if dirName != allowedSubdirMov && dirName != allowedSubdirRaw {
-				return fmt.Errorf("folder %s contains non-media subdirectory: %s (only '%s' and '%s' subdirectories are allowed)", folderPath, entry.Name(), allowedSubdirMov, allowedSubdirRaw)
+			if dirName != allowedSubdirMov && dirName != allowedSubdirRaw && dirName != orphanFolderName {
+				return fmt.Errorf("folder %s contains non-media subdirectory: %s (only '%s', '%s' and '%s' subdirectories are allowed)", folderPath, entry.Name(), allowedSubdirMov, allowedSubdirRaw, orphanFolderName)
 			}
 
 			// Recursively validate subdirectories
@@ -88,8 +96,8 @@ func isMediaFolderWithContext(folderPath string, ctx *executionContext) error {
 				return err
 			}
 		} else {
-			// Check if file is a media file using context
-			if !ctx.isMediaFile(entry.Name()) {
+			// Check if file is a media file (or a sidecar moved with it) using context
+			if !ctx.isMediaFile(entry.Name()) && !ctx.isSidecar(entry.Name()) {
 				return fmt.Errorf("folder %s contains non-media file: %s", folderPath, entry.Name())
 			}
 		}
@@ -108,7 +116,7 @@ func validateMergeFolders(sources []string, target string, ctx *executionContext
 	// Check each source folder
 	for _, source := range sources {
 		// Check if folder exists
-		info, err := os.Stat(source)
+		info, err := ctx.fs.Stat(source)
 		if err != nil {
 			if os.IsNotExist(err) {
 				return fmt.Errorf("source folder does not exist: %s", source)
@@ -128,7 +136,7 @@ func validateMergeFolders(sources []string, target string, ctx *executionContext
 	}
 
 	// If target exists, verify it's a directory
-	if info, err := os.Stat(target); err == nil {
+	if info, err := ctx.fs.Stat(target); err == nil {
 		if !info.IsDir() {
 			return fmt.Errorf("target exists but is not a directory: %s", target)
 		}
@@ -138,16 +146,17 @@ func validateMergeFolders(sources []string, target string, ctx *executionContext
 }
 
 // collectFilesRecursive collects all files from a directory recursively
-func collectFilesRecursive(rootDir string) ([]string, error) {
+// Contact sheets are left out: they only describe their own folder and are removed with it (v2.10.0+)
+func collectFilesRecursive(fsys FileSystem, rootDir string) ([]string, error) {
 	var files []string
 
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+	err := walkDir(fsys, rootDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
 		// Skip directories, collect only files
-		if !info.IsDir() {
+		if !d.IsDir() && !isContactSheet(d.Name()) {
 			files = append(files, path)
 		}
 
@@ -163,7 +172,7 @@ func collectFilesRecursive(rootDir string) ([]string, error) {
 
 // generateUniqueName generates a unique filename to avoid conflicts
 // Example: photo.jpg -> photo_1.jpg -> photo_2.jpg
-func generateUniqueName(targetPath string) string {
+func generateUniqueName(fsys FileSystem, targetPath string) string {
 	dir := filepath.Dir(targetPath)
 	base := filepath.Base(targetPath)
 	ext := filepath.Ext(base)
@@ -175,7 +184,7 @@ func generateUniqueName(targetPath string) string {
 		newPath := filepath.Join(dir, newName)
 
 		// Check if this name is available
-		if _, err := os.Stat(newPath); os.IsNotExist(err) {
+		if _, err := fsys.Stat(newPath); os.IsNotExist(err) {
 			return newPath
 		}
 
@@ -184,8 +193,8 @@ func generateUniqueName(targetPath string) string {
 }
 
 // detectConflict checks if a file already exists at target path
-func detectConflict(targetPath string) (*FileConflict, error) {
-	targetInfo, err := os.Stat(targetPath)
+func detectConflict(fsys FileSystem, targetPath string) (*FileConflict, error) {
+	targetInfo, err := fsys.Stat(targetPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil // No conflict
@@ -266,7 +275,7 @@ func askUserConflictResolution(conflict *FileConflict) (string, bool, error) {
 //   - ModeRun: Real execution (default - actually moves files and deletes source folders)
 //
 //nolint:gocyclo // Complex conflict handling logic, acceptable for this use case
-func Merge(cfg *MergeConfig) error {
+func Merge(runCtx context.Context, cfg *MergeConfig) error {
 	// Handle execution mode
 	switch cfg.Mode {
 	case ModeValidate:
@@ -275,11 +284,11 @@ func Merge(cfg *MergeConfig) error {
 
 	case ModeDryRun:
 		// Full simulation: process files but don't actually move them
-		return mergeInternal(cfg)
+		return mergeInternal(runCtx, cfg)
 
 	case ModeRun:
 		// Real execution: move files and delete source folders
-		return mergeInternal(cfg)
+		return mergeInternal(runCtx, cfg)
 
 	default:
 		return fmt.Errorf("invalid execution mode: %s", cfg.Mode)
@@ -354,21 +363,43 @@ func (r *MergeValidationReport) Print() {
 
 // validateMerge performs fast validation of merge operation without processing files
 func validateMerge(cfg *MergeConfig) error {
+	report, err := ValidateMerge(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Print report
+	report.Print()
+
+	// Return error if critical issues found
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("validation found %d critical issue(s)", len(report.Errors))
+	}
+
+	return nil
+}
+
+// ValidateMerge builds the merge validation report without printing it nor moving files (v2.10.0+)
+// Critical issues found while scanning are listed in the report Errors
+func ValidateMerge(cfg *MergeConfig) (*MergeValidationReport, error) {
 	// Create execution context with custom extensions
 	tempCfg := &Config{
 		CustomPhotoExts: cfg.CustomPhotoExts,
 		CustomVideoExts: cfg.CustomVideoExts,
 		CustomRawExts:   cfg.CustomRawExts,
+		Logger:          cfg.Logger,
+		Resolver:        cfg.Resolver,
+		FS:              cfg.FS,
 	}
 
 	ctx, err := newExecutionContext(tempCfg)
 	if err != nil {
-		return fmt.Errorf("failed to initialize extension context: %w", err)
+		return nil, fmt.Errorf("failed to initialize extension context: %w", err)
 	}
 
 	// Validate configuration
 	if err := validateMergeFolders(cfg.SourceFolders, cfg.TargetFolder, ctx); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
 	// Build validation report
@@ -384,12 +415,12 @@ func validateMerge(cfg *MergeConfig) error {
 	targetFiles := make(map[string]bool) // Track files already in target
 
 	// Check if target exists and collect existing files
-	if info, err := os.Stat(cfg.TargetFolder); err == nil && info.IsDir() {
+	if info, err := ctx.fs.Stat(cfg.TargetFolder); err == nil && info.IsDir() {
 		report.Warnings = append(report.Warnings,
 			fmt.Sprintf("Target folder already exists: %s", cfg.TargetFolder))
 
 		// Collect existing files in target
-		existingFiles, err := collectFilesRecursive(cfg.TargetFolder)
+		existingFiles, err := collectFilesRecursive(ctx.fs, cfg.TargetFolder)
 		if err != nil {
 			report.Errors = append(report.Errors,
 				fmt.Errorf("failed to scan existing target folder: %w", err))
@@ -403,7 +434,7 @@ func validateMerge(cfg *MergeConfig) error {
 
 	// Process each source folder
 	for _, sourceFolder := range cfg.SourceFolders {
-		files, err := collectFilesRecursive(sourceFolder)
+		files, err := collectFilesRecursive(ctx.fs, sourceFolder)
 		if err != nil {
 			report.Errors = append(report.Errors,
 				fmt.Errorf("failed to scan source folder %s: %w", sourceFolder, err))
@@ -416,7 +447,7 @@ func validateMerge(cfg *MergeConfig) error {
 		// Calculate size and detect conflicts
 		for _, file := range files {
 			// Get file size
-			if info, err := os.Stat(file); err == nil {
+			if info, err := ctx.fs.Stat(file); err == nil {
 				report.TotalBytes += info.Size()
 			}
 
@@ -434,24 +465,19 @@ func validateMerge(cfg *MergeConfig) error {
 		}
 	}
 
-	// Print report
-	report.Print()
-
-	// Return error if critical issues found
-	if len(report.Errors) > 0 {
-		return fmt.Errorf("validation found %d critical issue(s)", len(report.Errors))
-	}
-
-	return nil
+	return report, nil
 }
 
 // mergeInternal is the internal implementation of Merge
-func mergeInternal(cfg *MergeConfig) error {
+func mergeInternal(runCtx context.Context, cfg *MergeConfig) error {
 	// Create execution context with custom extensions
 	tempCfg := &Config{
 		CustomPhotoExts: cfg.CustomPhotoExts,
 		CustomVideoExts: cfg.CustomVideoExts,
 		CustomRawExts:   cfg.CustomRawExts,
+		Logger:          cfg.Logger,
+		Resolver:        cfg.Resolver,
+		FS:              cfg.FS,
 	}
 
 	ctx, err := newExecutionContext(tempCfg)
@@ -470,39 +496,48 @@ func mergeInternal(cfg *MergeConfig) error {
 	var globalResolution string
 	applyToAll := false
 
-	slog.Info("starting merge operation",
+	ctx.log.Info("starting merge operation",
 		"sources", cfg.SourceFolders,
 		"target", cfg.TargetFolder)
 	if cfg.Force {
-		slog.Info("merge mode: FORCE", "auto_overwrite", true)
+		ctx.log.Info("merge mode: FORCE", "auto_overwrite", true)
 	}
 	if cfg.Mode == ModeDryRun {
-		slog.Info("merge mode: DRY RUN", "simulation", true)
+		ctx.log.Info("merge mode: DRY RUN", "simulation", true)
 	}
 
 	// Create target folder if it doesn't exist
 	if cfg.Mode != ModeDryRun {
-		if err := os.MkdirAll(cfg.TargetFolder, permDirectory); err != nil {
+		if err := ctx.fs.MkdirAll(cfg.TargetFolder, permDirectory); err != nil {
 			return fmt.Errorf("failed to create target folder: %w", err)
 		}
 	} else {
-		slog.Info("[DRY RUN] would create target folder", "folder", cfg.TargetFolder)
+		ctx.log.Info("[DRY RUN] would create target folder", "folder", cfg.TargetFolder)
 	}
 
 	// Process each source folder
+	// An interruption stops before the next file; source folders keep the files not moved,
+	// so running the same merge again finishes it
+	interrupted := false
+sources:
 	for _, sourceFolder := range cfg.SourceFolders {
-		slog.Info("processing source folder", "folder", sourceFolder)
+		ctx.log.Info("processing source folder", "folder", sourceFolder)
 
 		// Collect all files from source
-		files, err := collectFilesRecursive(sourceFolder)
+		files, err := collectFilesRecursive(ctx.fs, sourceFolder)
 		if err != nil {
 			return err
 		}
 
-		slog.Debug("files found in source", "count", len(files), "folder", sourceFolder)
+		ctx.log.Debug("files found in source", "count", len(files), "folder", sourceFolder)
+		skippedBefore := stats.filesSkipped
 
 		// Process each file
 		for _, file := range files {
+			if runCtx.Err() != nil {
+				interrupted = true
+				break sources
+			}
 			stats.filesProcessed++
 
 			// Calculate relative path
@@ -515,7 +550,7 @@ func mergeInternal(cfg *MergeConfig) error {
 			targetPath := filepath.Join(cfg.TargetFolder, relPath)
 
 			// Check for conflict
-			conflict, err := detectConflict(targetPath)
+			conflict, err := detectConflict(ctx.fs, targetPath)
 			if err != nil {
 				return err
 			}
@@ -525,7 +560,7 @@ func mergeInternal(cfg *MergeConfig) error {
 				stats.conflicts++
 
 				// Fill in source info
-				sourceInfo, err := os.Stat(file)
+				sourceInfo, err := ctx.fs.Stat(file)
 				if err != nil {
 					return fmt.Errorf("failed to stat source file %s: %w", file, err)
 				}
@@ -534,19 +569,21 @@ func mergeInternal(cfg *MergeConfig) error {
 
 				// Determine resolution strategy
 				var resolution string
-				if cfg.Force {
+				if cfg.OnConflict != "" {
+					resolution = cfg.OnConflict
+				} else if cfg.Force {
 					resolution = conflictOverwrite
 				} else if applyToAll {
 					resolution = globalResolution
 				} else {
 					if cfg.Mode == ModeDryRun {
 						// In dry-run, simulate asking user
-						slog.Warn("[DRY RUN] conflict detected (would ask user)", "file", filepath.Base(targetPath))
+						ctx.log.Warn("[DRY RUN] conflict detected (would ask user)", "file", filepath.Base(targetPath))
 						resolution = conflictSkip // Default for dry-run
 					} else {
 						// Ask user
 						var applyAll bool
-						resolution, applyAll, err = askUserConflictResolution(conflict)
+						resolution, applyAll, err = ctx.resolver.ResolveConflict(conflict)
 						if err != nil {
 							return err
 						}
@@ -554,7 +591,7 @@ func mergeInternal(cfg *MergeConfig) error {
 						if applyAll {
 							applyToAll = true
 							globalResolution = resolution
-							slog.Info("applying resolution to all remaining conflicts", "resolution", resolution)
+							ctx.log.Info("applying resolution to all remaining conflicts", "resolution", resolution)
 						}
 					}
 				}
@@ -567,17 +604,24 @@ func mergeInternal(cfg *MergeConfig) error {
 				// Apply resolution
 				switch resolution {
 				case conflictRename:
-					finalTargetPath = generateUniqueName(targetPath)
+					finalTargetPath = generateUniqueName(ctx.fs, targetPath)
 					stats.filesRenamed++
-					slog.Info("renaming to avoid conflict", "file", filepath.Base(finalTargetPath))
+					ctx.log.Info("renaming to avoid conflict", "file", filepath.Base(finalTargetPath))
 				case conflictSkip:
 					stats.filesSkipped++
-					slog.Info("skipping file (keeping target)", "file", filepath.Base(file))
+					ctx.log.Info("skipping file (keeping target)", "file", filepath.Base(file))
 					continue // Skip this file
 				case conflictOverwrite:
 					finalTargetPath = targetPath
 					stats.filesOverwritten++
-					slog.Info("overwriting target", "file", filepath.Base(targetPath))
+					ctx.log.Info("overwriting target", "file", filepath.Base(targetPath))
+				case conflictFail:
+					return &PicsplitError{
+						Type: ErrTypeIO,
+						Op:   "merge_file",
+						Path: targetPath,
+						Err:  ErrDestinationExists,
+					}
 				}
 			} else {
 				finalTargetPath = targetPath
@@ -586,56 +630,73 @@ func mergeInternal(cfg *MergeConfig) error {
 			// Create parent directory
 			targetDir := filepath.Dir(finalTargetPath)
 			if cfg.Mode != ModeDryRun {
-				if err := os.MkdirAll(targetDir, permDirectory); err != nil {
+				if err := ctx.fs.MkdirAll(targetDir, permDirectory); err != nil {
 					return fmt.Errorf("failed to create directory %s: %w", targetDir, err)
 				}
 			}
 
 			// Move the file
 			if cfg.Mode == ModeDryRun {
-				slog.Info("[DRY RUN] would move file", "source", file, "dest", finalTargetPath)
+				ctx.log.Info("[DRY RUN] would move file", "source", file, "dest", finalTargetPath)
 			} else {
-				if err := os.Rename(file, finalTargetPath); err != nil {
+				if err := ctx.fs.Rename(file, finalTargetPath); err != nil {
 					return fmt.Errorf("failed to move %s to %s: %w", file, finalTargetPath, err)
 				}
 				stats.filesMoved++
-				slog.Debug("moved file", "source", file, "dest", finalTargetPath)
+				ctx.log.Debug("moved file", "source", file, "dest", finalTargetPath)
 			}
 		}
 
 		// Cleanup source folder after processing all files
-		if cfg.Mode == ModeDryRun {
-			slog.Info("[DRY RUN] would delete source folder", "folder", sourceFolder)
-		} else {
+		// Skipped files are still in the source: only the folders left empty are removed
+		skipped := stats.filesSkipped - skippedBefore
+		switch {
+		case skipped > 0 && cfg.Mode == ModeDryRun:
+			ctx.log.Info("[DRY RUN] would keep source folder with skipped files", "folder", sourceFolder, "skipped", skipped)
+		case skipped > 0:
+			if _, err := removeEmptyDirs(ctx.fs, sourceFolder); err != nil {
+				ctx.log.Warn("failed to remove empty folders of source", "folder", sourceFolder, "error", err)
+			}
+			ctx.log.Warn("source folder kept, it still holds skipped files", "folder", sourceFolder, "skipped", skipped)
+		case cfg.Mode == ModeDryRun:
+			ctx.log.Info("[DRY RUN] would delete source folder", "folder", sourceFolder)
+		default:
 			// Remove the folder (including empty subdirectories like mov/, raw/)
-			if err := os.RemoveAll(sourceFolder); err != nil {
-				slog.Warn("failed to remove source folder", "folder", sourceFolder, "error", err)
+			if err := removeAll(ctx.fs, sourceFolder); err != nil {
+				ctx.log.Warn("failed to remove source folder", "folder", sourceFolder, "error", err)
 			} else {
 				stats.foldersDeleted++
-				slog.Info("deleted source folder", "folder", sourceFolder)
+				ctx.log.Info("deleted source folder", "folder", sourceFolder)
 			}
 		}
 	}
 
 	// Print summary
 	fmt.Println()
-	slog.Info("=== Merge Summary ===")
-	slog.Info("merge statistics",
+	ctx.log.Info("=== Merge Summary ===")
+	ctx.log.Info("merge statistics",
 		"files_processed", stats.filesProcessed,
 		"files_moved", stats.filesMoved)
 	if stats.conflicts > 0 {
-		slog.Info("conflicts detected",
+		ctx.log.Info("conflicts detected",
 			"total", stats.conflicts,
 			"renamed", stats.filesRenamed,
 			"skipped", stats.filesSkipped,
 			"overwritten", stats.filesOverwritten)
 	}
-	slog.Info("cleanup completed",
+	ctx.log.Info("cleanup completed",
 		"folders_deleted", stats.foldersDeleted,
 		"target_folder", cfg.TargetFolder)
 
 	if cfg.Mode == ModeDryRun {
-		slog.Info("DRY RUN completed - no files were actually moved")
+		ctx.log.Info("DRY RUN completed - no files were actually moved")
+	}
+
+	if interrupted {
+		ctx.log.Warn("merge interrupted, run the same merge again to continue",
+			"status", "interrupted",
+			"sources", cfg.SourceFolders)
+		return runCtx.Err()
 	}
 
 	return nil
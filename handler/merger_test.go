@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -219,7 +221,7 @@ func TestGenerateUniqueName(t *testing.T) {
 	}
 
 	// Generate unique name
-	uniqueName := generateUniqueName(existingFile)
+	uniqueName := generateUniqueName(OSFileSystem{}, existingFile)
 
 	// Should be photo_1.jpg
 	expected := filepath.Join(tmpDir, "photo_1.jpg")
@@ -232,7 +234,7 @@ func TestGenerateUniqueName(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	uniqueName2 := generateUniqueName(existingFile)
+	uniqueName2 := generateUniqueName(OSFileSystem{}, existingFile)
 	expected2 := filepath.Join(tmpDir, "photo_2.jpg")
 	if uniqueName2 != expected2 {
 		t.Errorf("generateUniqueName() = %q, want %q", uniqueName2, expected2)
@@ -248,7 +250,7 @@ func TestCollectFilesRecursive(t *testing.T) {
 	createTestFileInDir(t, tmpDir, "mov/video.mov", "video")
 	createTestFileInDir(t, tmpDir, "raw/photo.nef", "raw")
 
-	files, err := collectFilesRecursive(tmpDir)
+	files, err := collectFilesRecursive(OSFileSystem{}, tmpDir)
 	if err != nil {
 		t.Fatalf("collectFilesRecursive() error = %v", err)
 	}
@@ -564,7 +566,7 @@ func TestMerge_BasicTwoFolders(t *testing.T) {
 		Mode:          ModeRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Merge() error = %v", err)
 	}
@@ -606,7 +608,7 @@ func TestMerge_MultipleFolders(t *testing.T) {
 		Mode:          ModeRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Merge() error = %v", err)
 	}
@@ -638,7 +640,7 @@ func TestMerge_EmptySourceFolder(t *testing.T) {
 		Mode:          ModeRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Merge() error = %v", err)
 	}
@@ -667,7 +669,7 @@ func TestMerge_TargetFolderExists(t *testing.T) {
 		Mode:          ModeRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Merge() error = %v", err)
 	}
@@ -704,7 +706,7 @@ func TestMerge_PreserveMovRawStructure(t *testing.T) {
 		Mode:          ModeRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Merge() error = %v", err)
 	}
@@ -745,7 +747,7 @@ func TestMerge_NoConflict(t *testing.T) {
 		Mode:          ModeRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Merge() error = %v", err)
 	}
@@ -776,7 +778,7 @@ func TestMerge_ConflictWithForceFlag(t *testing.T) {
 		Mode:          ModeRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Merge() error = %v", err)
 	}
@@ -811,7 +813,7 @@ func TestMerge_DryRunMode(t *testing.T) {
 		Mode:          ModeDryRun, // Dry run mode
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Merge() error = %v", err)
 	}
@@ -845,7 +847,7 @@ func TestMerge_DryRunWithConflicts(t *testing.T) {
 		Mode:          ModeDryRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Merge() error = %v", err)
 	}
@@ -885,7 +887,7 @@ func TestMerge_ErrorTargetNotDirectory(t *testing.T) {
 		Mode:          ModeRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err == nil {
 		t.Error("Merge() should error when target exists as file")
 	}
@@ -902,7 +904,7 @@ func TestMerge_ValidationError(t *testing.T) {
 		Mode:          ModeRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err == nil {
 		t.Error("Merge() should error on validation failure")
 	}
@@ -932,7 +934,7 @@ func TestMerge_MultipleConflictsWithForce(t *testing.T) {
 		Mode:          ModeRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Merge() error = %v", err)
 	}
@@ -971,7 +973,7 @@ func TestMerge_NestedStructurePreservation(t *testing.T) {
 		Mode:          ModeRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Merge() error = %v", err)
 	}
@@ -997,3 +999,58 @@ func TestMerge_NestedStructurePreservation(t *testing.T) {
 		t.Error("source folder should be deleted after merge")
 	}
 }
+
+func TestMerge_OnConflict(t *testing.T) {
+	tests := []struct {
+		onConflict  string
+		wantErr     error
+		wantContent string // content of target/photo.jpg
+		wantRenamed bool
+	}{
+		{conflictRename, nil, "target", true},
+		{conflictSkip, nil, "target", false},
+		{conflictOverwrite, nil, "source", false},
+		{conflictFail, ErrDestinationExists, "target", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.onConflict, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			source := filepath.Join(tmpDir, "source")
+			target := filepath.Join(tmpDir, "target")
+			createTestFileInDir(t, source, "photo.jpg", "source")
+			createTestFileInDir(t, source, "orphan/photo2.nef", "orphan raw")
+			createTestFileInDir(t, source, "photo.xmp", "sidecar")
+			createTestFileInDir(t, target, "photo.jpg", "target")
+
+			cfg := &MergeConfig{
+				SourceFolders: []string{source},
+				TargetFolder:  target,
+				Mode:          ModeRun,
+				OnConflict:    tt.onConflict,
+			}
+
+			err := Merge(context.Background(), cfg)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Merge() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Merge() error = %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(target, "photo.jpg"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.wantContent {
+				t.Errorf("target content = %q, want %q", content, tt.wantContent)
+			}
+
+			_, err = os.Stat(filepath.Join(target, "photo_1.jpg"))
+			if renamed := err == nil; renamed != tt.wantRenamed {
+				t.Errorf("photo_1.jpg exists = %v, want %v", renamed, tt.wantRenamed)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@
 package handler
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -88,7 +89,7 @@ func TestMerge_ErrorMovingFile(t *testing.T) {
 		Mode:          ModeRun,
 	}
 
-	err := Merge(cfg)
+	err := Merge(context.Background(), cfg)
 	if err == nil {
 		t.Error("Merge() should error when file move fails")
 	}
@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// metricsContentType is the Prometheus text exposition format, also read by OpenMetrics scrapers
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	// Metadata extraction is local I/O: milliseconds for photos, more for large videos
+	extractionBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+	// Geocoding goes through HTTP APIs with a 1 req/s rate limit and a timeout
+	geocodingBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10}
+)
+
+// Metrics collects Prometheus metrics across the runs of a long-running process (v2.10.0+)
+// A nil *Metrics is valid and records nothing
+type Metrics struct {
+	extraction *histogram
+	geocoding  *histogram
+
+	runs            map[string]float64 // Run status -> runs
+	files           map[string]float64 // File type -> files
+	errors          map[ErrorType]float64
+	geocodeRequests map[string]float64 // "hit" or "miss" -> requests
+
+	// TextFile, when set, is rewritten after every run for the node-exporter textfile collector
+	TextFile string
+
+	filesProcessed  float64
+	bytesMoved      float64
+	modTimeFallback float64
+	duplicates      float64
+	lastRunTime     float64
+	lastRunDuration float64
+
+	mu sync.Mutex
+}
+
+// histogram is a Prometheus histogram with cumulative buckets computed on export
+type histogram struct {
+	bounds []float64
+	counts []float64 // Observations per bucket (not cumulative), last one is +Inf
+	sum    float64
+	count  float64
+}
+
+// NewMetrics creates an empty metrics registry
+func NewMetrics() *Metrics {
+	return &Metrics{
+		extraction:      newHistogram(extractionBuckets),
+		geocoding:       newHistogram(geocodingBuckets),
+		runs:            make(map[string]float64),
+		files:           make(map[string]float64),
+		errors:          make(map[ErrorType]float64),
+		geocodeRequests: make(map[string]float64),
+	}
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]float64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(value float64) {
+	i := sort.SearchFloat64s(h.bounds, value)
+	h.counts[i]++
+	h.sum += value
+	h.count++
+}
+
+// ObserveRun adds the statistics of a split run, stats may be nil when the run failed early
+// Bytes are counted as moved in run mode only
+func (m *Metrics) ObserveRun(mode ExecutionMode, stats *ProcessingStats, runErr error) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	m.runs[runStatus(stats, runErr)]++
+	m.lastRunTime = float64(time.Now().Unix())
+	if stats != nil {
+		m.files["photo"] += float64(stats.PhotoCount)
+		m.files["video"] += float64(stats.VideoCount)
+		m.files["raw"] += float64(stats.RawCount)
+		m.filesProcessed += float64(stats.ProcessedFiles)
+		if mode == ModeRun {
+			m.bytesMoved += float64(stats.TotalBytes)
+		}
+		m.modTimeFallback += float64(stats.ModTimeFallbackCount)
+		m.duplicates += float64(len(stats.DuplicatesDetected))
+		for _, err := range stats.Errors {
+			m.errors[err.Type]++
+		}
+		m.lastRunDuration = stats.Duration().Seconds()
+	}
+	m.mu.Unlock()
+
+	if m.TextFile != "" {
+		if err := m.WriteFile(m.TextFile); err != nil {
+			slog.Warn("failed to write metrics file", "path", m.TextFile, "error", err)
+		}
+	}
+}
+
+// observeExtraction records the latency of a metadata extraction
+func (m *Metrics) observeExtraction(elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.extraction.observe(elapsed.Seconds())
+}
+
+// observeGeocode records a reverse geocoding, answered from the cache or by the APIs
+func (m *Metrics) observeGeocode(elapsed time.Duration, cached bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cached {
+		m.geocodeRequests["hit"]++
+		return
+	}
+	m.geocodeRequests["miss"]++
+	m.geocoding.observe(elapsed.Seconds())
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := &countingWriter{w: bufio.NewWriter(w)}
+
+	writeLabeled(out, "picsplit_runs_total", "Split runs by final status", "counter", "status", m.runs)
+	writeLabeled(out, "picsplit_files_total", "Media files handled by type", "counter", "type", m.files)
+	writeSingle(out, "picsplit_files_processed_total", "Files moved (or simulated in dryrun) to their destination", "counter", m.filesProcessed)
+	writeSingle(out, "picsplit_bytes_moved_total", "Bytes of media files moved in run mode", "counter", m.bytesMoved)
+	writeSingle(out, "picsplit_modtime_fallback_total", "Files dated by modification time because EXIF or video metadata was missing", "counter", m.modTimeFallback)
+	writeSingle(out, "picsplit_duplicates_total", "Duplicate files detected", "counter", m.duplicates)
+
+	errorsByType := make(map[string]float64, len(m.errors))
+	for errType, count := range m.errors {
+		errorsByType[string(errType)] = count
+	}
+	writeLabeled(out, "picsplit_errors_total", "Errors by type", "counter", "type", errorsByType)
+
+	writeHistogram(out, "picsplit_metadata_extraction_seconds", "Latency of EXIF and video metadata extraction", m.extraction)
+	writeHistogram(out, "picsplit_geocoding_seconds", "Latency of reverse geocoding API calls (cache misses)", m.geocoding)
+	writeLabeled(out, "picsplit_geocoding_requests_total", "Reverse geocoding requests by cache result", "counter", "cache", m.geocodeRequests)
+
+	writeSingle(out, "picsplit_last_run_timestamp_seconds", "Unix time of the last split run", "gauge", m.lastRunTime)
+	writeSingle(out, "picsplit_last_run_duration_seconds", "Duration of the last split run", "gauge", m.lastRunDuration)
+
+	if out.err != nil {
+		return out.n, out.err
+	}
+	return out.n, out.w.Flush()
+}
+
+// ServeHTTP exposes the metrics to a Prometheus scraper
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	if _, err := m.WriteTo(w); err != nil {
+		slog.Debug("failed to serve metrics", "error", err)
+	}
+}
+
+// WriteFile writes the metrics to path for the node-exporter textfile collector
+// The file is replaced atomically so the collector never reads a partial file
+func (m *Metrics) WriteFile(path string) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath) //nolint:gosec // path is given by the user
+	if err != nil {
+		return fmt.Errorf("failed to create metrics file: %w", err)
+	}
+
+	if _, err := m.WriteTo(file); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// countingWriter keeps the first write error and the number of bytes written
+type countingWriter struct {
+	w   *bufio.Writer
+	err error
+	n   int64
+}
+
+func (c *countingWriter) printf(format string, args ...any) {
+	if c.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(c.w, format, args...)
+	c.n += int64(n)
+	c.err = err
+}
+
+func writeHeader(out *countingWriter, name, help, metricType string) {
+	out.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func writeSingle(out *countingWriter, name, help, metricType string, value float64) {
+	writeHeader(out, name, help, metricType)
+	out.printf("%s %s\n", name, formatMetricValue(value))
+}
+
+// writeLabeled writes one sample per label value, sorted for stable output
+func writeLabeled(out *countingWriter, name, help, metricType, label string, values map[string]float64) {
+	writeHeader(out, name, help, metricType)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		out.printf("%s{%s=%q} %s\n", name, label, key, formatMetricValue(values[key]))
+	}
+}
+
+func writeHistogram(out *countingWriter, name, help string, h *histogram) {
+	writeHeader(out, name, help, "histogram")
+
+	var cumulative float64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		out.printf("%s_bucket{le=%q} %s\n", name, formatMetricValue(bound), formatMetricValue(cumulative))
+	}
+	out.printf("%s_bucket{le=\"+Inf\"} %s\n", name, formatMetricValue(h.count))
+	out.printf("%s_sum %s\n", name, formatMetricValue(h.sum))
+	out.printf("%s_count %s\n", name, formatMetricValue(h.count))
+}
+
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
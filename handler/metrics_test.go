@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	fsys := NewMemFileSystem()
+	start := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
+	writeMemFile(t, fsys, "/photos/a.jpg", []byte("a"), start)
+	writeMemFile(t, fsys, "/photos/b.jpg", []byte("a"), start.Add(time.Minute))
+	writeMemFile(t, fsys, "/photos/c.nef", []byte("raw"), start.Add(2*time.Minute))
+	writeMemFile(t, fsys, "/photos/d.mov", []byte("movie"), start.Add(3*time.Minute))
+
+	metrics := NewMetrics()
+	cfg := &Config{
+		BasePath:         "/photos",
+		Delta:            30 * time.Minute,
+		Mode:             ModeRun,
+		UseEXIF:          true,
+		MinGroupSize:     1,
+		DetectDuplicates: true,
+		FS:               fsys,
+		Metrics:          metrics,
+	}
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	server := httptest.NewServer(metrics)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET metrics error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := resp.Header.Get("Content-Type"); got != metricsContentType {
+		t.Errorf("Content-Type = %q, want %q", got, metricsContentType)
+	}
+
+	want := []string{
+		"# TYPE picsplit_runs_total counter",
+		`picsplit_runs_total{status="completed"} 1`,
+		`picsplit_files_total{type="photo"} 2`,
+		`picsplit_files_total{type="raw"} 1`,
+		`picsplit_files_total{type="video"} 1`,
+		"picsplit_bytes_moved_total 10",
+		"picsplit_modtime_fallback_total 4",
+		"picsplit_duplicates_total 1",
+		"# TYPE picsplit_metadata_extraction_seconds histogram",
+		`picsplit_metadata_extraction_seconds_bucket{le="+Inf"} 4`,
+		"picsplit_metadata_extraction_seconds_count 4",
+	}
+	for _, line := range want {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("metrics missing %q\n%s", line, body)
+		}
+	}
+}
+
+func TestMetrics_Observations(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.observeGeocode(300*time.Millisecond, false)
+	metrics.observeGeocode(0, true)
+	metrics.observeGeocode(3*time.Second, false)
+	metrics.ObserveRun(ModeDryRun, &ProcessingStats{
+		TotalBytes: 1024,
+		Errors: []*PicsplitError{
+			{Type: ErrTypeEXIF}, {Type: ErrTypeEXIF}, {Type: ErrTypePermission},
+		},
+	}, context.Canceled)
+
+	var buf strings.Builder
+	if _, err := metrics.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error: %v", err)
+	}
+	out := buf.String()
+
+	want := []string{
+		`picsplit_runs_total{status="interrupted"} 1`,
+		"picsplit_bytes_moved_total 0", // Nothing moves in dryrun
+		`picsplit_errors_total{type="EXIF"} 2`,
+		`picsplit_errors_total{type="Permission"} 1`,
+		`picsplit_geocoding_requests_total{cache="hit"} 1`,
+		`picsplit_geocoding_requests_total{cache="miss"} 2`,
+		`picsplit_geocoding_seconds_bucket{le="0.25"} 0`,
+		`picsplit_geocoding_seconds_bucket{le="0.5"} 1`, // Buckets are cumulative
+		`picsplit_geocoding_seconds_bucket{le="5"} 2`,
+		"picsplit_geocoding_seconds_sum 3.3",
+		"picsplit_geocoding_seconds_count 2",
+	}
+	for _, line := range want {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("metrics missing %q\n%s", line, out)
+		}
+	}
+}
+
+func TestMetrics_TextFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "picsplit.prom")
+	metrics := NewMetrics()
+	metrics.TextFile = path
+
+	metrics.ObserveRun(ModeRun, &ProcessingStats{PhotoCount: 3}, nil)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("metrics file not written: %v", err)
+	}
+	if !strings.Contains(string(data), `picsplit_files_total{type="photo"} 3`) {
+		t.Errorf("metrics file content:\n%s", data)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temporary metrics file should be renamed")
+	}
+
+	// Library callers without metrics pass a nil registry
+	var none *Metrics
+	none.ObserveRun(ModeRun, &ProcessingStats{}, nil)
+	none.observeExtraction(time.Second)
+}
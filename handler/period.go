@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Continuous sources (phone backups, scanners) are better grouped by calendar period than by time gaps (v2.10.0+)
+// Period folders: 2024, 2024/06, 2024/W24, 2024/2024-06-15
+
+// Grouping strategies
+const (
+	GroupByGap      = "gap"      // A new event starts after a gap longer than Delta (default)
+	GroupByAdaptive = "adaptive" // A new event starts after a gap far above the typical gap of the day, see adaptive.go
+	GroupByDay      = "day"      // 2024/2024-06-15
+	GroupByWeek     = "week"     // 2024/W24 (ISO 8601 week)
+	GroupByMonth    = "month"    // 2024/06
+	GroupByYear     = "year"     // 2024
+)
+
+// dayBoundaryLayout is the format of --day-boundary
+const dayBoundaryLayout = "15:04"
+
+// isCalendarGrouping checks if a grouping strategy uses calendar periods instead of time gaps
+func isCalendarGrouping(groupBy string) bool {
+	switch groupBy {
+	case GroupByDay, GroupByWeek, GroupByMonth, GroupByYear:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseDayBoundary parses the time the calendar day starts at ("04:00"), "" is midnight
+func parseDayBoundary(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	t, err := time.Parse(dayBoundaryLayout, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid day boundary %q (expected HH:MM)", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// periodFolder returns the folder of the calendar period holding a date
+// Dates before the day boundary belong to the previous day: 02:30 with a 04:00 boundary is the evening before
+func periodFolder(date time.Time, groupBy string, dayBoundary time.Duration) string {
+	date = date.Add(-dayBoundary)
+
+	switch groupBy {
+	case GroupByYear:
+		return date.Format("2006")
+	case GroupByMonth:
+		return filepath.Join(date.Format("2006"), date.Format("01"))
+	case GroupByWeek:
+		year, week := date.ISOWeek()
+		return filepath.Join(fmt.Sprintf("%04d", year), fmt.Sprintf("W%02d", week))
+	default:
+		return filepath.Join(date.Format("2006"), date.Format("2006-01-02"))
+	}
+}
+
+// periodFolderDepth returns the number of path elements of the folders created by a grouping strategy
+func periodFolderDepth(groupBy string) int {
+	switch groupBy {
+	case GroupByDay, GroupByWeek, GroupByMonth:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// groupFilesByPeriod groups sorted files by calendar period
+func groupFilesByPeriod(files []FileMetadata, groupBy string, dayBoundary time.Duration) []Group {
+	var groups []Group
+
+	for _, file := range files {
+		folder := periodFolder(file.DateTime, groupBy, dayBoundary)
+		if len(groups) > 0 && groups[len(groups)-1].Folder == folder {
+			groups[len(groups)-1].Files = append(groups[len(groups)-1].Files, file)
+			continue
+		}
+		groups = append(groups, Group{Folder: folder, Files: []FileMetadata{file}})
+	}
+
+	return groups
+}
+
+// groupFiles groups sorted files with the --group-by strategy
+func groupFiles(cfg *Config, ctx *executionContext, files []FileMetadata) []Group {
+	switch {
+	case isCalendarGrouping(cfg.GroupBy):
+		return groupFilesByPeriod(files, cfg.GroupBy, ctx.dayBoundary)
+	case cfg.GroupBy == GroupByAdaptive:
+		return groupFilesAdaptive(ctx, files, cfg.Delta, cfg.adaptiveMaxGap())
+	default:
+		return groupFilesByGaps(files, cfg.Delta)
+	}
+}
+
+// groupRoot returns the folder holding the event or period folder of a group: "Paris" for "Paris/2024/06"
+func groupRoot(folder string, groupBy string) string {
+	parts := strings.Split(filepath.ToSlash(folder), "/")
+	depth := periodFolderDepth(groupBy)
+	if len(parts) <= depth {
+		return ""
+	}
+	return filepath.Join(parts[:len(parts)-depth]...)
+}
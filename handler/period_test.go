@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPeriodFolder(t *testing.T) {
+	evening := time.Date(2024, 6, 15, 23, 10, 0, 0, time.Local)
+	lateNight := time.Date(2024, 6, 16, 2, 30, 0, 0, time.Local)
+	newYear := time.Date(2025, 1, 1, 1, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name        string
+		date        time.Time
+		groupBy     string
+		dayBoundary time.Duration
+		want        string
+	}{
+		{"day", evening, GroupByDay, 0, filepath.Join("2024", "2024-06-15")},
+		{"day after midnight", lateNight, GroupByDay, 0, filepath.Join("2024", "2024-06-16")},
+		{"day before boundary", lateNight, GroupByDay, 4 * time.Hour, filepath.Join("2024", "2024-06-15")},
+		{"week", evening, GroupByWeek, 0, filepath.Join("2024", "W24")},
+		{"month", evening, GroupByMonth, 0, filepath.Join("2024", "06")},
+		{"year", evening, GroupByYear, 0, "2024"},
+		{"year before boundary", newYear, GroupByYear, 4 * time.Hour, "2024"},
+		{"ISO week of the next year", time.Date(2024, 12, 30, 12, 0, 0, 0, time.Local), GroupByWeek, 0, filepath.Join("2025", "W01")},
+		{"ISO week of the previous year", time.Date(2027, 1, 1, 12, 0, 0, 0, time.Local), GroupByWeek, 0, filepath.Join("2026", "W53")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := periodFolder(tt.date, tt.groupBy, tt.dayBoundary); got != tt.want {
+				t.Errorf("periodFolder() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDayBoundary(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"00:00", 0, false},
+		{"04:00", 4 * time.Hour, false},
+		{"05:30", 5*time.Hour + 30*time.Minute, false},
+		{"4am", 0, true},
+		{"25:00", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseDayBoundary(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDayBoundary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseDayBoundary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupFilesByPeriod(t *testing.T) {
+	at := func(month time.Month, day, hour int) FileMetadata {
+		return FileMetadata{DateTime: time.Date(2024, month, day, hour, 0, 0, 0, time.Local)}
+	}
+	files := []FileMetadata{at(5, 31, 23), at(6, 1, 2), at(6, 1, 10), at(6, 20, 9), at(7, 1, 3)}
+
+	groups := groupFilesByPeriod(files, GroupByMonth, 4*time.Hour)
+
+	want := map[string]int{
+		filepath.Join("2024", "05"): 2, // 1 June 02:00 belongs to the night of 31 May
+		filepath.Join("2024", "06"): 3,
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(groups), len(want))
+	}
+	for _, group := range groups {
+		if len(group.Files) != want[group.Folder] {
+			t.Errorf("group %s has %d files, want %d", group.Folder, len(group.Files), want[group.Folder])
+		}
+	}
+}
+
+func TestGroupRoot(t *testing.T) {
+	tests := []struct {
+		folder  string
+		groupBy string
+		want    string
+	}{
+		{"Paris/2024 - 0615 - 1200", GroupByGap, "Paris"},
+		{"2024 - 0615 - 1200", GroupByGap, ""},
+		{"Paris/2024/06", GroupByMonth, "Paris"},
+		{"Paris/2024/W24", GroupByWeek, "Paris"},
+		{"NoLocation/2024", GroupByYear, "NoLocation"},
+		{"2024/2024-06-15", GroupByDay, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.folder, func(t *testing.T) {
+			if got := groupRoot(filepath.FromSlash(tt.folder), tt.groupBy); got != tt.want {
+				t.Errorf("groupRoot() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit_GroupByMonth(t *testing.T) {
+	tmpDir := t.TempDir()
+	june := time.Date(2024, 6, 3, 10, 0, 0, 0, time.Local)
+
+	// Days apart: --delta would split them, calendar months keep them together
+	for i := range 4 {
+		createTestFile(t, tmpDir, fmt.Sprintf("IMG_%04d.JPG", i+1), june.AddDate(0, 0, 7*i))
+	}
+	createTestFile(t, tmpDir, "IMG_0005.JPG", time.Date(2024, 7, 1, 3, 0, 0, 0, time.Local)) // Before the day boundary
+	createTestFile(t, tmpDir, "IMG_0006.JPG", time.Date(2024, 8, 10, 12, 0, 0, 0, time.Local))
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		UseEXIF:      true,
+		MinGroupSize: 3,
+		GroupBy:      GroupByMonth,
+		DayBoundary:  "04:00",
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	for i := range 5 {
+		assertExists(t, filepath.Join(tmpDir, "2024", "06", fmt.Sprintf("IMG_%04d.JPG", i+1)))
+	}
+	assertExists(t, filepath.Join(tmpDir, "IMG_0006.JPG")) // August is below MinGroupSize
+	assertNotExists(t, filepath.Join(tmpDir, "2024", "08"))
+}
+
+func TestConfigValidate_GroupBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"month with boundary", func(c *Config) { c.GroupBy = GroupByMonth; c.DayBoundary = "04:00" }, false},
+		{"unknown strategy", func(c *Config) { c.GroupBy = "quarter" }, true},
+		{"invalid boundary", func(c *Config) { c.GroupBy = GroupByDay; c.DayBoundary = "4h" }, true},
+		{"boundary with gaps", func(c *Config) { c.DayBoundary = "04:00" }, true},
+		{"incremental", func(c *Config) { c.GroupBy = GroupByWeek; c.Incremental = true }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig(t.TempDir())
+			tt.modify(cfg)
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -11,11 +11,8 @@ import (
 // createProgressBar creates a progress bar if conditions are met
 // Returns nil if progress bar should not be displayed
 func createProgressBar(total int, description string, logLevel string, logFormat string) *progressbar.ProgressBar {
-	isDebug := strings.ToLower(logLevel) == "debug"
-	isJSON := strings.ToLower(logFormat) == "json"
-
 	// Don't show progress bar in debug or json mode
-	if isDebug || isJSON {
+	if !progressBarEnabled(logLevel, logFormat) {
 		return nil
 	}
 
@@ -37,3 +34,27 @@ func createProgressBar(total int, description string, logLevel string, logFormat
 
 	return bar
 }
+
+// progressBarEnabled reports whether progress bars can be drawn: not in debug or json log mode
+func progressBarEnabled(logLevel string, logFormat string) bool {
+	return strings.ToLower(logLevel) != "debug" && strings.ToLower(logFormat) != "json"
+}
+
+// NewTerminalProgress returns a ProgressFunc drawing one progress bar per stage on stderr (v2.10.0+)
+// Returns nil in debug or json log mode, where bars would interleave with log lines
+func NewTerminalProgress(logLevel string, logFormat string) ProgressFunc {
+	if !progressBarEnabled(logLevel, logFormat) {
+		return nil
+	}
+
+	var bar *progressbar.ProgressBar
+	var stage string
+
+	return func(event ProgressEvent) {
+		if bar == nil || event.Stage != stage {
+			bar = createProgressBar(event.Total, event.Stage, logLevel, logFormat)
+			stage = event.Stage
+		}
+		_ = bar.Set(event.Current)
+	}
+}
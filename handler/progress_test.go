@@ -64,3 +64,22 @@ func TestCreateProgressBar_CaseInsensitive(t *testing.T) {
 		})
 	}
 }
+
+func TestNewTerminalProgress(t *testing.T) {
+	if NewTerminalProgress("debug", "text") != nil {
+		t.Error("NewTerminalProgress should return nil in debug mode")
+	}
+	if NewTerminalProgress("info", "json") != nil {
+		t.Error("NewTerminalProgress should return nil in json mode")
+	}
+
+	progress := NewTerminalProgress("info", "text")
+	if progress == nil {
+		t.Fatal("NewTerminalProgress should return a callback in normal mode")
+	}
+
+	// Stage changes start a new bar
+	progress(ProgressEvent{Stage: StageScan, Current: 1, Total: 2})
+	progress(ProgressEvent{Stage: StageScan, Current: 2, Total: 2})
+	progress(ProgressEvent{Stage: StageProcess, Current: 1, Total: 1})
+}
@@ -0,0 +1,421 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/abema/go-mp4"
+)
+
+// RAW files keep EXIF in their own container: TIFF IFDs for most brands (sometimes behind a
+// non-standard magic number), a JPEG preview referenced by the RAF header for Fujifilm and
+// CMT boxes of an ISOBMFF uuid box for Canon CR3 (v2.10.0+)
+// Readers only load the EXIF directories and re-encode them as a compact TIFF for the decoder,
+// instead of reading the whole (tens of MB) file in memory
+
+const (
+	// rafMagic starts every Fujifilm RAF file
+	rafMagic = "FUJIFILMCCD-RAW "
+
+	// rafJPEGOffset is the position of the embedded JPEG offset and length in the RAF header
+	rafJPEGOffset = 84
+
+	// cr3Brand is the major brand of Canon CR3 files
+	cr3Brand = "crx "
+
+	// jpegExifSignature starts the APP1 segment holding EXIF
+	jpegExifSignature = "Exif\x00\x00"
+
+	// maxIFDEntries bounds the directories read from a file
+	maxIFDEntries = 1000
+
+	// maxTIFFValueSize drops large values (maker notes, previews) from the compact TIFF
+	maxTIFFValueSize = 64 << 10
+)
+
+// errNoJPEGSegment is returned when a JPEG has no APP1 segment with the requested signature
+var errNoJPEGSegment = errors.New("segment not found")
+
+// TIFF tags handled by the IFD walker
+const (
+	tagExifIFD    = 0x8769
+	tagGPSIFD     = 0x8825
+	tagInteropIFD = 0xA005
+	tagSubIFDs    = 0x014A
+	tagMakerNote  = 0x927C
+	tagXMLPacket  = 0x02BC // XMP packet, kept up to maxXMPSize (Lightroom writes its edit history in it)
+)
+
+// cr3MetadataUUID is the Canon uuid box holding the CMT boxes of a CR3 file
+var cr3MetadataUUID = [16]byte{0x85, 0xc0, 0xb6, 0x87, 0x82, 0x0f, 0x11, 0xe0, 0x81, 0x11, 0xf4, 0xce, 0x46, 0x2b, 0x6a, 0x48}
+
+// tiffTypeSizes gives the size of one value of each TIFF field type
+var tiffTypeSizes = map[uint16]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// byteOrder reads and appends the integers of a TIFF structure
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// tiffEntry is a field of an IFD with its value bytes in the file's byte order
+type tiffEntry struct {
+	value []byte
+	count uint32
+	tag   uint16
+	typ   uint16
+}
+
+// exifDirs holds the directories picsplit needs: main image, EXIF and GPS
+type exifDirs struct {
+	order byteOrder
+	ifd0  []tiffEntry
+	exif  []tiffEntry
+	gps   []tiffEntry
+}
+
+// rawFormat identifies the container of a RAW (or TIFF) file from its first bytes
+// Returns "" for anything else (JPEG, unknown)
+func rawFormat(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte(rafMagic)):
+		return "raf"
+	case len(header) >= 12 && string(header[4:8]) == "ftyp" && string(header[8:12]) == cr3Brand:
+		return "cr3"
+	case len(header) >= 4 && (string(header[:2]) == "II" || string(header[:2]) == "MM"):
+		// Standard TIFF (NEF, ARW, DNG, PEF, SRW, 3FR...), RW2 ("IIU\0") and ORF ("IIRO", "IIRS", "MMOR")
+		return "tiff"
+	default:
+		return ""
+	}
+}
+
+// readRAWEXIF returns a compact TIFF holding the EXIF of a RAW file of the given format
+func readRAWEXIF(f File, format string) ([]byte, error) {
+	switch format {
+	case "tiff":
+		dirs, err := readTIFFDirs(f, 0)
+		if err != nil {
+			return nil, err
+		}
+		return dirs.encode(), nil
+	case "raf":
+		return readRAFEXIF(f)
+	case "cr3":
+		return readCR3EXIF(f)
+	default:
+		return nil, fmt.Errorf("unsupported RAW format %q", format)
+	}
+}
+
+// readTIFFDirs walks IFD0 of a TIFF structure starting at base and follows its EXIF and GPS pointers
+// The magic number is not checked: RW2 and ORF use their own
+func readTIFFDirs(r io.ReaderAt, base int64) (*exifDirs, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, base); err != nil {
+		return nil, fmt.Errorf("failed to read TIFF header: %w", err)
+	}
+
+	dirs := &exifDirs{}
+	switch string(header[:2]) {
+	case "II":
+		dirs.order = binary.LittleEndian
+	case "MM":
+		dirs.order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid TIFF byte order")
+	}
+
+	var err error
+	dirs.ifd0, err = readIFD(r, base, int64(dirs.order.Uint32(header[4:])), dirs.order)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IFD0: %w", err)
+	}
+
+	// Sub-directories are optional: a RAW without GPS is common
+	for _, entry := range dirs.ifd0 {
+		if entry.count != 1 || len(entry.value) != 4 {
+			continue
+		}
+		offset := int64(dirs.order.Uint32(entry.value))
+		switch entry.tag {
+		case tagExifIFD:
+			dirs.exif, _ = readIFD(r, base, offset, dirs.order)
+		case tagGPSIFD:
+			dirs.gps, _ = readIFD(r, base, offset, dirs.order)
+		}
+	}
+
+	if len(dirs.exif) == 0 && len(dirs.ifd0) == 0 {
+		return nil, errors.New("no EXIF directory found")
+	}
+	return dirs, nil
+}
+
+// readIFD reads the entries of the directory at base+offset with their values
+func readIFD(r io.ReaderAt, base, offset int64, order byteOrder) ([]tiffEntry, error) {
+	countBytes := make([]byte, 2)
+	if _, err := r.ReadAt(countBytes, base+offset); err != nil {
+		return nil, err
+	}
+	count := int(order.Uint16(countBytes))
+	if count == 0 || count > maxIFDEntries {
+		return nil, fmt.Errorf("invalid IFD entry count %d", count)
+	}
+
+	raw := make([]byte, 12*count)
+	if _, err := r.ReadAt(raw, base+offset+2); err != nil {
+		return nil, err
+	}
+
+	entries := make([]tiffEntry, 0, count)
+	for i := 0; i < count; i++ {
+		field := raw[12*i : 12*i+12]
+		entry := tiffEntry{
+			tag:   order.Uint16(field),
+			typ:   order.Uint16(field[2:]),
+			count: order.Uint32(field[4:]),
+		}
+
+		typeSize, known := tiffTypeSizes[entry.typ]
+		if !known || entry.tag == tagMakerNote {
+			continue
+		}
+		size := uint64(typeSize) * uint64(entry.count)
+		if size > maxTIFFValueSize && (entry.tag != tagXMLPacket || size > maxXMPSize) {
+			continue
+		}
+
+		if size <= 4 {
+			entry.value = append([]byte(nil), field[8:8+size]...)
+		} else {
+			entry.value = make([]byte, size)
+			if _, err := r.ReadAt(entry.value, base+int64(order.Uint32(field[8:]))); err != nil {
+				continue // Value outside the file: drop the field, keep the others
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// encode writes the directories as a standard TIFF, pointers rebuilt for the new layout
+func (d *exifDirs) encode() []byte {
+	var magic []byte
+	if d.order == binary.LittleEndian {
+		magic = []byte{'I', 'I', 0x2A, 0x00}
+	} else {
+		magic = []byte{'M', 'M', 0x00, 0x2A}
+	}
+
+	ifd0 := withoutPointers(d.ifd0)
+	exifIFD := withoutPointers(d.exif)
+	gpsIFD := withoutPointers(d.gps)
+
+	// Pointers are placeholders until the sub-directory offsets are known
+	if len(exifIFD) > 0 {
+		ifd0 = append(ifd0, tiffEntry{tag: tagExifIFD, typ: 4, count: 1, value: make([]byte, 4)})
+	}
+	if len(gpsIFD) > 0 {
+		ifd0 = append(ifd0, tiffEntry{tag: tagGPSIFD, typ: 4, count: 1, value: make([]byte, 4)})
+	}
+
+	out := append(magic, d.order.AppendUint32(nil, 8)...)
+	ifd0Start := len(out)
+	out = appendIFD(out, ifd0, d.order)
+
+	for _, sub := range []struct {
+		entries []tiffEntry
+		tag     uint16
+	}{{exifIFD, tagExifIFD}, {gpsIFD, tagGPSIFD}} {
+		if len(sub.entries) == 0 {
+			continue
+		}
+		patchPointer(out, ifd0Start, ifd0, sub.tag, uint32(len(out)), d.order) //nolint:gosec // compact TIFF is small
+		out = appendIFD(out, sub.entries, d.order)
+	}
+	return out
+}
+
+// withoutPointers drops sub-directory pointers that are meaningless in the compact TIFF
+func withoutPointers(entries []tiffEntry) []tiffEntry {
+	kept := make([]tiffEntry, 0, len(entries))
+	for _, entry := range entries {
+		switch entry.tag {
+		case tagExifIFD, tagGPSIFD, tagInteropIFD, tagSubIFDs:
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept
+}
+
+// appendIFD writes a directory (sorted by tag) followed by its out-of-line values
+func appendIFD(out []byte, entries []tiffEntry, order byteOrder) []byte {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	start := len(out)
+	valuesOffset := start + 2 + 12*len(entries) + 4
+
+	out = order.AppendUint16(out, uint16(len(entries))) //nolint:gosec // bounded by maxIFDEntries
+	var values []byte
+	for _, entry := range entries {
+		out = order.AppendUint16(out, entry.tag)
+		out = order.AppendUint16(out, entry.typ)
+		out = order.AppendUint32(out, entry.count)
+		if len(entry.value) <= 4 {
+			inline := make([]byte, 4)
+			copy(inline, entry.value)
+			out = append(out, inline...)
+			continue
+		}
+		out = order.AppendUint32(out, uint32(valuesOffset+len(values))) //nolint:gosec // compact TIFF is small
+		values = append(values, entry.value...)
+		if len(values)%2 == 1 {
+			values = append(values, 0) // Values start on word boundaries
+		}
+	}
+	out = order.AppendUint32(out, 0) // No next IFD
+	return append(out, values...)
+}
+
+// patchPointer sets the value of a pointer entry of the directory written at ifdStart
+func patchPointer(out []byte, ifdStart int, entries []tiffEntry, tag uint16, target uint32, order byteOrder) {
+	for i, entry := range entries {
+		if entry.tag == tag {
+			order.PutUint32(out[ifdStart+2+12*i+8:], target)
+			return
+		}
+	}
+}
+
+// readRAFEXIF returns the EXIF of a Fujifilm RAF file, stored in the embedded JPEG preview
+func readRAFEXIF(r io.ReaderAt) ([]byte, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, rafJPEGOffset); err != nil {
+		return nil, fmt.Errorf("failed to read RAF header: %w", err)
+	}
+	jpegOffset := int64(binary.BigEndian.Uint32(header))
+	jpegLength := int64(binary.BigEndian.Uint32(header[4:]))
+	if jpegOffset == 0 || jpegLength == 0 {
+		return nil, errors.New("RAF file without JPEG preview")
+	}
+
+	tiffStart, err := findJPEGExif(io.NewSectionReader(r, jpegOffset, jpegLength))
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := readTIFFDirs(r, jpegOffset+tiffStart)
+	if err != nil {
+		return nil, err
+	}
+	return dirs.encode(), nil
+}
+
+// findJPEGExif returns the position of the TIFF header of the EXIF APP1 segment of a JPEG
+func findJPEGExif(r io.ReaderAt) (int64, error) {
+	offset, _, err := findJPEGSegment(r, jpegExifSignature)
+	if err != nil {
+		return 0, fmt.Errorf("no EXIF in JPEG preview: %w", err)
+	}
+	return offset, nil
+}
+
+// findJPEGSegment returns the position and length of the data of the first APP1 segment
+// starting with signature, the signature excluded
+func findJPEGSegment(r io.ReaderAt, signature string) (int64, int64, error) {
+	marker := make([]byte, 4)
+	if _, err := r.ReadAt(marker[:2], 0); err != nil || marker[0] != 0xFF || marker[1] != 0xD8 {
+		return 0, 0, errors.New("invalid JPEG")
+	}
+
+	offset := int64(2)
+	for {
+		if _, err := r.ReadAt(marker, offset); err != nil {
+			return 0, 0, err
+		}
+		if marker[0] != 0xFF || marker[1] == 0xDA { // Start of scan: no metadata after
+			return 0, 0, errNoJPEGSegment
+		}
+		length := int64(binary.BigEndian.Uint16(marker[2:]))
+
+		if marker[1] == 0xE1 && length-2 >= int64(len(signature)) {
+			data := make([]byte, len(signature))
+			if _, err := r.ReadAt(data, offset+4); err == nil && string(data) == signature {
+				start := offset + 4 + int64(len(signature))
+				return start, length - 2 - int64(len(signature)), nil
+			}
+		}
+		offset += 2 + length
+	}
+}
+
+// readCR3EXIF returns the EXIF of a Canon CR3 file: CMT1 (IFD0), CMT2 (EXIF) and CMT4 (GPS)
+// are TIFF structures stored in the Canon uuid box of moov
+func readCR3EXIF(r io.ReadSeeker) ([]byte, error) {
+	boxes, err := mp4.ExtractBox(r, nil, mp4.BoxPath{mp4.BoxTypeMoov(), mp4.StrToBoxType("uuid")})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CR3 boxes: %w", err)
+	}
+
+	for _, box := range boxes {
+		payload, err := readBoxPayload(r, box)
+		if err != nil || len(payload) < 16 || !bytes.Equal(payload[:16], cr3MetadataUUID[:]) {
+			continue
+		}
+		return parseCR3Metadata(payload[16:])
+	}
+	return nil, errors.New("no Canon metadata box in CR3 file")
+}
+
+// parseCR3Metadata combines the CMT boxes found in the Canon uuid box
+func parseCR3Metadata(children []byte) ([]byte, error) {
+	var dirs *exifDirs
+	br := &boxReader{data: children}
+
+	for br.err == nil && br.remaining() >= 8 {
+		size := int(br.u32())
+		boxType := string(br.bytes(4))
+		if size < 8 || size-8 > br.remaining() {
+			break
+		}
+		payload := br.bytes(size - 8)
+
+		switch boxType {
+		case "CMT1", "CMT2", "CMT4":
+		default:
+			continue
+		}
+
+		cmt, err := readTIFFDirs(bytes.NewReader(payload), 0)
+		if err != nil {
+			continue
+		}
+		if dirs == nil {
+			dirs = &exifDirs{order: cmt.order}
+		}
+		if cmt.order != dirs.order {
+			continue // Never seen in practice: entries cannot be mixed across byte orders
+		}
+
+		switch boxType {
+		case "CMT1":
+			dirs.ifd0 = cmt.ifd0
+		case "CMT2":
+			dirs.exif = cmt.ifd0
+		case "CMT4":
+			dirs.gps = cmt.ifd0
+		}
+	}
+
+	if dirs == nil {
+		return nil, errors.New("no CMT box in CR3 metadata")
+	}
+	return dirs.encode(), nil
+}
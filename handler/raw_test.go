@@ -0,0 +1,268 @@
+package handler
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// asciiEntry builds a NUL-terminated ASCII TIFF field
+func asciiEntry(tag uint16, value string) tiffEntry {
+	return tiffEntry{tag: tag, typ: 2, count: uint32(len(value) + 1), value: []byte(value + "\x00")}
+}
+
+// gpsEntries builds the GPS IFD fields of a coordinate as degrees, minutes and seconds
+func gpsEntries(order byteOrder, coord *GPSCoord) []tiffEntry {
+	rationals := func(v float64) []byte {
+		v = math.Abs(v)
+		deg := math.Floor(v)
+		minutes := math.Floor((v - deg) * 60)
+		seconds := ((v-deg)*60 - minutes) * 60
+		b := order.AppendUint32(nil, uint32(deg))
+		b = order.AppendUint32(b, 1)
+		b = order.AppendUint32(b, uint32(minutes))
+		b = order.AppendUint32(b, 1)
+		b = order.AppendUint32(b, uint32(seconds*10000))
+		return order.AppendUint32(b, 10000)
+	}
+	latRef, lonRef := "N", "E"
+	if coord.Lat < 0 {
+		latRef = "S"
+	}
+	if coord.Lon < 0 {
+		lonRef = "W"
+	}
+	return []tiffEntry{
+		asciiEntry(1, latRef),
+		{tag: 2, typ: 5, count: 3, value: rationals(coord.Lat)},
+		asciiEntry(3, lonRef),
+		{tag: 4, typ: 5, count: 3, value: rationals(coord.Lon)},
+	}
+}
+
+// createRAWDirs builds the directories of a camera RAW: make and model in IFD0, the date
+// and a maker note in the EXIF IFD, and the position in the GPS IFD
+func createRAWDirs(order byteOrder, shot time.Time, gps *GPSCoord) *exifDirs {
+	dirs := &exifDirs{
+		order: order,
+		ifd0:  []tiffEntry{asciiEntry(0x010F, "TestMake"), asciiEntry(0x0110, "TestModel")},
+		exif: []tiffEntry{
+			asciiEntry(0x9003, shot.Format("2006:01:02 15:04:05")),
+			{tag: tagMakerNote, typ: 7, count: 128, value: make([]byte, 128)},
+		},
+	}
+	if gps != nil {
+		dirs.gps = gpsEntries(order, gps)
+	}
+	return dirs
+}
+
+// createRAWTIFF builds a TIFF-based RAW, magic replaces the two bytes of the TIFF magic number
+func createRAWTIFF(order byteOrder, magic string, shot time.Time, gps *GPSCoord) []byte {
+	data := createRAWDirs(order, shot, gps).encode()
+	if magic != "" {
+		copy(data[2:4], magic)
+	}
+	return data
+}
+
+// createRAF builds a Fujifilm RAF: the header points to a JPEG preview holding the EXIF
+func createRAF(shot time.Time, gps *GPSCoord) []byte {
+	tiff := createRAWDirs(binary.BigEndian, shot, gps).encode()
+
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10}
+	jpeg = append(jpeg, "JFIF\x00"...)
+	jpeg = append(jpeg, make([]byte, 9)...)
+	jpeg = append(jpeg, 0xFF, 0xE1)
+	jpeg = binary.BigEndian.AppendUint16(jpeg, uint16(2+6+len(tiff)))
+	jpeg = append(jpeg, "Exif\x00\x00"...)
+	jpeg = append(jpeg, tiff...)
+	jpeg = append(jpeg, 0xFF, 0xD9)
+
+	header := make([]byte, 160)
+	copy(header, rafMagic+"0201FF129502")
+	binary.BigEndian.PutUint32(header[rafJPEGOffset:], uint32(len(header)))
+	binary.BigEndian.PutUint32(header[rafJPEGOffset+4:], uint32(len(jpeg)))
+	return append(header, jpeg...)
+}
+
+// createCR3 builds a Canon CR3: CMT1, CMT2 and CMT4 TIFF structures in the Canon uuid box of moov
+func createCR3(shot time.Time, gps *GPSCoord) []byte {
+	dirs := createRAWDirs(binary.LittleEndian, shot, gps)
+	cmt := func(entries []tiffEntry) []byte {
+		return (&exifDirs{order: dirs.order, ifd0: entries}).encode()
+	}
+
+	children := [][]byte{
+		cr3MetadataUUID[:],
+		isoBox("CNCV", false, 0, []byte("CanonCR3_001/00.09.00/00.00.00")),
+		isoBox("CMT1", false, 0, cmt(dirs.ifd0)),
+		isoBox("CMT2", false, 0, cmt(dirs.exif)),
+	}
+	if gps != nil {
+		children = append(children, isoBox("CMT4", false, 0, cmt(dirs.gps)))
+	}
+
+	ftyp := isoBox("ftyp", false, 0, []byte(cr3Brand), []byte{0, 0, 0, 1}, []byte(cr3Brand), []byte("isom"))
+	moov := isoBox("moov", false, 0, isoBox("uuid", false, 0, children...))
+	file := append(ftyp, moov...)
+	return append(file, isoBox("mdat", false, 0, []byte("raw-data"))...)
+}
+
+func TestExtractMetadata_RAW(t *testing.T) {
+	shot := time.Date(2024, 6, 15, 14, 30, 12, 0, time.Local)
+	paris := &GPSCoord{Lat: 48.8566, Lon: 2.3522}
+	saoPaulo := &GPSCoord{Lat: -23.5505, Lon: -46.6333}
+
+	tests := []struct {
+		name     string
+		fileName string
+		data     []byte
+		gps      *GPSCoord
+	}{
+		{"NEF little endian", "DSC_0001.NEF", createRAWTIFF(binary.LittleEndian, "", shot, paris), paris},
+		{"DNG big endian", "IMG_0001.dng", createRAWTIFF(binary.BigEndian, "", shot, saoPaulo), saoPaulo},
+		{"ARW without GPS", "DSC01234.ARW", createRAWTIFF(binary.LittleEndian, "", shot, nil), nil},
+		{"ORF IIRO magic", "P1010001.ORF", createRAWTIFF(binary.LittleEndian, "RO", shot, paris), paris},
+		{"ORF MMOR magic", "P1010002.ORF", createRAWTIFF(binary.BigEndian, "OR", shot, nil), nil},
+		{"RW2 magic", "P1000001.RW2", createRAWTIFF(binary.LittleEndian, "U\x00", shot, paris), paris},
+		{"RAF JPEG preview", "DSCF0001.RAF", createRAF(shot, paris), paris},
+		{"CR3 CMT boxes", "IMG_0001.CR3", createCR3(shot, saoPaulo), saoPaulo},
+		{"CR3 without GPS", "IMG_0002.CR3", createCR3(shot, nil), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFileSystem()
+			path := "/photos/" + tt.fileName
+			writeMemFile(t, fsys, path, tt.data, time.Now())
+
+			ctx, err := newExecutionContext(&Config{FS: fsys})
+			if err != nil {
+				t.Fatal(err)
+			}
+			metadata, err := ExtractMetadata(ctx, path)
+			if err != nil {
+				t.Fatalf("ExtractMetadata() error: %v", err)
+			}
+
+			if metadata.Source != DateSourceEXIF || !metadata.DateTime.Equal(shot) {
+				t.Errorf("date = %v from %v, want %v from EXIF", metadata.DateTime, metadata.Source, shot)
+			}
+			if metadata.Make != "TestMake" || metadata.Model != "TestModel" {
+				t.Errorf("camera = %q %q, want TestMake TestModel", metadata.Make, metadata.Model)
+			}
+
+			if tt.gps == nil {
+				if metadata.GPS != nil {
+					t.Errorf("GPS = %v, want nil", metadata.GPS)
+				}
+				return
+			}
+			if metadata.GPS == nil {
+				t.Fatal("GPS = nil, want coordinates")
+			}
+			if math.Abs(metadata.GPS.Lat-tt.gps.Lat) > 1e-4 || math.Abs(metadata.GPS.Lon-tt.gps.Lon) > 1e-4 {
+				t.Errorf("GPS = %v, want %v", metadata.GPS, tt.gps)
+			}
+		})
+	}
+}
+
+func TestExtractMetadata_RAWFallsBackToJPEG(t *testing.T) {
+	shot := time.Date(2024, 6, 15, 14, 30, 12, 0, time.Local)
+	fsys := NewMemFileSystem()
+
+	// Corrupted RAW: IFD0 points past the end of the file
+	corrupted := createRAWTIFF(binary.LittleEndian, "", shot, nil)
+	binary.LittleEndian.PutUint32(corrupted[4:], uint32(len(corrupted)+100))
+	writeMemFile(t, fsys, "/photos/IMG_0001.CR2", corrupted, time.Now())
+
+	exifData := createMinimalEXIFData(shot.Add(time.Hour))
+	jpeg := binary.BigEndian.AppendUint16([]byte{0xFF, 0xD8, 0xFF, 0xE1}, uint16(len(exifData)+2))
+	jpeg = append(append(jpeg, exifData...), 0xFF, 0xD9)
+	writeMemFile(t, fsys, "/photos/IMG_0001.JPG", jpeg, time.Now())
+
+	ctx, err := newExecutionContext(&Config{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := ExtractMetadata(ctx, "/photos/IMG_0001.CR2")
+	if err != nil {
+		t.Fatalf("ExtractMetadata() error: %v", err)
+	}
+	if metadata.Source != DateSourceEXIF || !metadata.DateTime.Equal(shot.Add(time.Hour)) {
+		t.Errorf("date = %v from %v, want %v from the associated JPEG", metadata.DateTime, metadata.Source, shot.Add(time.Hour))
+	}
+}
+
+func TestReadRAWEXIF_Errors(t *testing.T) {
+	shot := time.Date(2024, 6, 15, 14, 30, 12, 0, time.Local)
+
+	noPreview := createRAF(shot, nil)
+	binary.BigEndian.PutUint32(noPreview[rafJPEGOffset:], 0)
+
+	withoutCMT := createCR3(shot, nil)
+	copy(withoutCMT[indexOf(withoutCMT, "CMT1"):], "XXX1")
+	copy(withoutCMT[indexOf(withoutCMT, "CMT2"):], "XXX2")
+
+	hugeIFD := createRAWTIFF(binary.LittleEndian, "", shot, nil)
+	binary.LittleEndian.PutUint16(hugeIFD[8:], maxIFDEntries+1)
+
+	tests := []struct {
+		name   string
+		format string
+		data   []byte
+	}{
+		{"RAF without JPEG preview", "raf", noPreview},
+		{"CR3 without CMT box", "cr3", withoutCMT},
+		{"TIFF with too many entries", "tiff", hugeIFD},
+		{"truncated TIFF", "tiff", []byte("II*\x00\x08\x00")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFileSystem()
+			writeMemFile(t, fsys, "/raw", tt.data, time.Now())
+			f, err := fsys.Open("/raw")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			if format := rawFormat(tt.data); format != tt.format {
+				t.Fatalf("rawFormat() = %q, want %q", format, tt.format)
+			}
+			if _, err := readRAWEXIF(f, tt.format); err == nil {
+				t.Error("readRAWEXIF() expected error")
+			}
+		})
+	}
+}
+
+func TestEncodeEXIFDirs_DropsMakerNote(t *testing.T) {
+	shot := time.Date(2024, 6, 15, 14, 30, 12, 0, time.Local)
+	data := createRAWTIFF(binary.BigEndian, "", shot, nil)
+
+	fsys := NewMemFileSystem()
+	writeMemFile(t, fsys, "/raw", data, time.Now())
+	f, err := fsys.Open("/raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	dirs, err := readTIFFDirs(f, 0)
+	if err != nil {
+		t.Fatalf("readTIFFDirs() error: %v", err)
+	}
+	for _, entry := range dirs.exif {
+		if entry.tag == tagMakerNote {
+			t.Error("maker note kept in the EXIF IFD")
+		}
+	}
+	if len(dirs.exif) != 1 {
+		t.Errorf("EXIF IFD has %d entries, want 1", len(dirs.exif))
+	}
+}
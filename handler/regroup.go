@@ -0,0 +1,648 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// regroupMove is a planned move of one file of the library (paths relative to BasePath)
+type regroupMove struct {
+	from string
+	to   string
+}
+
+// regroupPlan lists the files whose destination changes and the folder contents before and after
+type regroupPlan struct {
+	moves   []regroupMove
+	renamed int
+	before  map[string]int // event folder -> media files, before regroup
+	after   map[string]int // event folder -> media files, after regroup
+}
+
+// libraryFiles holds the media files and sidecars of an organized library
+// Media files carry their path relative to BasePath (FileMetadata.relPath)
+type libraryFiles struct {
+	media    []FileMetadata
+	sidecars []string
+}
+
+// Regroup recomputes the event folders of an already organized library with the current
+// settings (delta, GPS, min group size...) (v2.10.0+)
+// Files are gathered from event folders (including raw/, mov/, orphan/), regrouped, and only
+// the files whose destination changes are moved; folders left empty are removed
+// In dryrun and validate modes, nothing is moved: the result holds the planned before/after folder diff
+func Regroup(runCtx context.Context, cfg *Config) (*RegroupResult, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	ctx, err := newExecutionContext(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize extension context: %w", err)
+	}
+
+	startTime := time.Now()
+
+	library, err := collectLibraryFiles(cfg, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect library files: %w", err)
+	}
+	if len(library.media) == 0 {
+		ctx.log.Info("no media files found")
+		return &RegroupResult{}, nil
+	}
+
+	ctx.log.Info("library files collected", "count", len(library.media), "sidecars", len(library.sidecars))
+
+	groups, _, err := buildGroups(runCtx, cfg, ctx, library.media)
+	if err != nil {
+		return nil, err
+	}
+	plan := planRegroup(cfg, ctx, groups, library)
+
+	result := &RegroupResult{
+		Folders:      plan.folders(),
+		Files:        len(library.media),
+		FilesMoved:   len(plan.moves),
+		FilesRenamed: plan.renamed,
+	}
+
+	if cfg.Mode != ModeRun {
+		result.Duration = time.Since(startTime)
+		ctx.log.Info("DRY RUN completed - no files were actually moved")
+		return result, nil
+	}
+
+	result.FilesMoved, err = executeRegroup(runCtx, ctx, cfg.BasePath, plan.moves)
+	if err == nil {
+		refreshRegroupContactSheets(runCtx, cfg, ctx, plan)
+	}
+	result.FoldersRemoved = removeRegroupEmptyDirs(cfg, ctx, plan)
+	result.Duration = time.Since(startTime)
+
+	return result, err
+}
+
+// RegroupFolder is an event folder before and after regrouping (v2.10.0+)
+type RegroupFolder struct {
+	Folder  string // Event folder relative to BasePath
+	Before  int    // Media files before regrouping, 0 for a new folder
+	After   int    // Media files after regrouping, 0 for a removed folder
+	Changed bool   // The folder receives or loses files
+}
+
+// RegroupResult is the outcome of Regroup (v2.10.0+)
+type RegroupResult struct {
+	Folders        []RegroupFolder // Event folders before and after regrouping, sorted by path
+	Files          int             // Media files found in the library
+	FilesMoved     int             // Files moved (to move in dryrun and validate modes)
+	FilesRenamed   int             // Files renamed on a name collision
+	FoldersRemoved int             // Event folders left empty and removed (run mode)
+	Duration       time.Duration
+}
+
+// PrintDiff displays the event folders removed, created and changed
+func (r *RegroupResult) PrintDiff() {
+	fmt.Println()
+	slog.Info("=== Regroup Plan ===")
+
+	for _, folder := range r.Folders {
+		switch {
+		case folder.After == 0:
+			slog.Info("- folder removed", "folder", folder.Folder, "files", folder.Before)
+		case folder.Before == 0:
+			slog.Info("+ folder created", "folder", folder.Folder, "files", folder.After)
+		case folder.Changed:
+			slog.Info("~ folder changed", "folder", folder.Folder, "files_before", folder.Before, "files_after", folder.After)
+		default:
+			slog.Info("  folder unchanged", "folder", folder.Folder, "files", folder.After)
+		}
+	}
+}
+
+// PrintSummary displays the regroup statistics
+func (r *RegroupResult) PrintSummary() {
+	before, after := 0, 0
+	for _, folder := range r.Folders {
+		if folder.Before > 0 {
+			before++
+		}
+		if folder.After > 0 {
+			after++
+		}
+	}
+
+	fmt.Println()
+	slog.Info("=== Regroup Summary ===")
+	slog.Info("regroup statistics",
+		"files", r.Files,
+		"files_moved", r.FilesMoved,
+		"files_renamed", r.FilesRenamed,
+		"folders_before", before,
+		"folders_after", after,
+		"folders_removed", r.FoldersRemoved,
+		"duration", r.Duration.Round(time.Millisecond))
+}
+
+// collectLibraryFiles gathers media files and sidecars from the root, location folders
+// and event folders of the library, with their raw/, mov/ and orphan/ subfolders
+func collectLibraryFiles(cfg *Config, ctx *executionContext) (*libraryFiles, error) {
+	entries, err := ctx.fs.ReadDir(cfg.BasePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	// Root files and small groups left at root
+	containers := []string{""}
+	for _, entry := range entries {
+		if !entry.IsDir() || isSpecialFolder(entry.Name()) {
+			continue
+		}
+		if isDateFolderName(entry.Name()) {
+			containers = append(containers, entry.Name())
+			continue
+		}
+
+		// Location folder (GPS mode): its event folders and its own files
+		subEntries, err := ctx.fs.ReadDir(filepath.Join(cfg.BasePath, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var events []string
+		for _, sub := range subEntries {
+			if sub.IsDir() && isDateFolderName(sub.Name()) {
+				events = append(events, filepath.Join(entry.Name(), sub.Name()))
+			}
+		}
+		if len(events) > 0 {
+			containers = append(containers, entry.Name())
+			containers = append(containers, events...)
+		}
+	}
+
+	library := &libraryFiles{}
+	for _, container := range containers {
+		for _, dir := range eventFolderDirs("", container) {
+			collectLibraryDir(cfg, ctx, library, dir)
+		}
+	}
+
+	alignRawDates(ctx, library)
+
+	return library, nil
+}
+
+// collectLibraryDir adds the media files and sidecars of one directory (relative to BasePath)
+func collectLibraryDir(cfg *Config, ctx *executionContext, library *libraryFiles, relDir string) {
+	entries, err := ctx.fs.ReadDir(filepath.Join(cfg.BasePath, relDir))
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		relPath := filepath.Join(relDir, name)
+
+		if ctx.isSidecar(name) {
+			library.sidecars = append(library.sidecars, relPath)
+			continue
+		}
+		if isContactSheet(name) || (!ctx.isPhoto(name) && !ctx.isMovie(name)) {
+			continue
+		}
+
+		filePath := filepath.Join(cfg.BasePath, relPath)
+		metadata, err := groupingMetadata(cfg, ctx, filePath)
+		if err != nil {
+			ctx.log.Warn("failed to read file metadata", "file", relPath, "error", err)
+			continue
+		}
+
+		metadata.relPath = relPath
+		library.media = append(library.media, *metadata)
+	}
+}
+
+// alignRawDates gives RAW files stored in raw/ or orphan/ the date and GPS of their JPEG/HEIC
+// in the parent event folder, so pairs are never split by the new grouping
+func alignRawDates(ctx *executionContext, library *libraryFiles) {
+	photos := make(map[string]FileMetadata)
+	for _, file := range library.media {
+		name := file.FileInfo.Name()
+		if ctx.isPhoto(name) && !ctx.isRaw(name) {
+			relPath := file.relPath
+			photos[filepath.Join(filepath.Dir(relPath), strings.TrimSuffix(name, filepath.Ext(name)))] = file
+		}
+	}
+
+	for i := range library.media {
+		file := &library.media[i]
+		name := file.FileInfo.Name()
+		if !ctx.isRaw(name) {
+			continue
+		}
+
+		relDir := filepath.Dir(file.relPath)
+		if base := filepath.Base(relDir); base == rawFolderName || base == orphanFolderName {
+			relDir = filepath.Dir(relDir)
+		}
+		if photo, ok := photos[filepath.Join(relDir, strings.TrimSuffix(name, filepath.Ext(name)))]; ok {
+			file.DateTime = photo.DateTime
+			file.GPS = photo.GPS
+		}
+	}
+}
+
+// planRegroup computes the destination of every file for the new groups
+// Files already at their destination keep their place; moved files are renamed
+// (DSC_0001 -> DSC_0001_1) when their destination name is taken
+func planRegroup(cfg *Config, ctx *executionContext, groups []Group, library *libraryFiles) *regroupPlan {
+	plan := &regroupPlan{before: make(map[string]int), after: make(map[string]int)}
+
+	// Desired destinations
+	type placement struct {
+		from, to string
+		family   string // group index + basename, shared by RAW/JPEG pairs
+	}
+	var placements []placement
+
+	for i, group := range groups {
+		folder := group.Folder
+		if len(group.Files) < cfg.MinGroupSize {
+			// Small group: files go to the (location) root, as in Split
+			folder = ""
+			if cfg.UseGPS && filepath.Dir(group.Folder) != "." {
+				folder = filepath.Dir(group.Folder)
+			}
+		}
+		paired := pairedBasenames(ctx, group.Files)
+
+		for _, file := range group.Files {
+			name := file.FileInfo.Name()
+			from := file.relPath
+			to := filepath.Join(regroupDestDir(cfg, ctx, folder, name, paired), name)
+
+			placements = append(placements, placement{
+				from:   from,
+				to:     to,
+				family: fmt.Sprintf("%d/%s", i, strings.TrimSuffix(name, filepath.Ext(name))),
+			})
+			plan.before[eventFolderOf(from)]++
+		}
+	}
+
+	// Files staying in place keep their names
+	sources := make(map[string]bool, len(placements))
+	taken := make(map[string]bool, len(placements))
+	for _, p := range placements {
+		sources[strings.ToLower(p.from)] = true
+		if p.from == p.to {
+			taken[strings.ToLower(p.to)] = true
+		}
+	}
+
+	// isTaken reports whether a destination is used by another planned file or by a file
+	// outside the plan (e.g., a non-media file left in an event folder)
+	isTaken := func(relPath string) bool {
+		if taken[strings.ToLower(relPath)] {
+			return true
+		}
+		if sources[strings.ToLower(relPath)] {
+			return false
+		}
+		_, err := ctx.fs.Stat(filepath.Join(cfg.BasePath, relPath))
+		return err == nil
+	}
+
+	familyBases := make(map[string]string)
+	mediaDest := make(map[string]string, len(placements)) // from -> to
+	for _, p := range placements {
+		to := p.to
+		if p.from != p.to {
+			ext := filepath.Ext(to)
+			base := strings.TrimSuffix(filepath.Base(to), ext)
+			if assigned, ok := familyBases[p.family]; ok {
+				base = assigned
+			}
+
+			candidate := filepath.Join(filepath.Dir(to), base+ext)
+			if isTaken(candidate) {
+				for counter := 1; isTaken(candidate); counter++ {
+					candidate = filepath.Join(filepath.Dir(to), fmt.Sprintf("%s_%d%s", base, counter, ext))
+				}
+				plan.renamed++
+				ctx.log.Debug("destination name taken, renaming", "file", p.from, "dest", candidate)
+			}
+			to = candidate
+			familyBases[p.family] = strings.TrimSuffix(filepath.Base(to), ext)
+
+			plan.moves = append(plan.moves, regroupMove{from: p.from, to: to})
+		}
+
+		taken[strings.ToLower(to)] = true
+		mediaDest[p.from] = to
+		plan.after[eventFolderOf(to)]++
+	}
+
+	sidecarMoves := planSidecarMoves(ctx, library.sidecars, mediaDest)
+	for _, move := range sidecarMoves {
+		if isTaken(move.to) {
+			ctx.log.Warn("sidecar destination exists, leaving sidecar in place", "sidecar", move.from, "dest", move.to)
+			continue
+		}
+		taken[strings.ToLower(move.to)] = true
+		plan.moves = append(plan.moves, move)
+	}
+
+	return plan
+}
+
+// regroupDestDir returns the directory of a file in its new group, following Split rules
+// (raw/ or orphan/ for RAW files, mov/ for videos)
+func regroupDestDir(cfg *Config, ctx *executionContext, folder, name string, paired map[string]bool) string {
+	switch {
+	case ctx.isRaw(name) && !cfg.NoMoveRaw:
+		if cfg.SeparateOrphanRaw && !paired[strings.TrimSuffix(name, filepath.Ext(name))] {
+			return filepath.Join(folder, orphanFolderName)
+		}
+		return filepath.Join(folder, rawFolderName)
+	case ctx.isMovie(name) && !cfg.NoMoveMovie:
+		return filepath.Join(folder, movFolderName)
+	default:
+		return folder
+	}
+}
+
+// pairedBasenames returns the basenames of JPEG/HEIC files of a group (RAW pairing)
+func pairedBasenames(ctx *executionContext, files []FileMetadata) map[string]bool {
+	paired := make(map[string]bool)
+	for _, file := range files {
+		name := file.FileInfo.Name()
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".jpg", ".jpeg", ".heic":
+			if !ctx.isRaw(name) {
+				paired[strings.TrimSuffix(name, filepath.Ext(name))] = true
+			}
+		}
+	}
+	return paired
+}
+
+// planSidecarMoves moves sidecars along with their media file (DSC_0001.NEF.xmp with
+// DSC_0001.NEF, DSC_0001.xmp with the RAW of the shot or its only media file)
+func planSidecarMoves(ctx *executionContext, sidecars []string, mediaDest map[string]string) []regroupMove {
+	var moves []regroupMove
+
+	// Media files by directory and basename
+	byBase := make(map[string][]string)
+	for from := range mediaDest {
+		name := filepath.Base(from)
+		key := filepath.Join(filepath.Dir(from), strings.TrimSuffix(name, filepath.Ext(name)))
+		byBase[key] = append(byBase[key], from)
+	}
+
+	for _, sidecar := range sidecars {
+		dir := filepath.Dir(sidecar)
+		owner := strings.TrimSuffix(filepath.Base(sidecar), filepath.Ext(sidecar))
+
+		// name.ext.xmp belongs to name.ext, name.xmp to the RAW named name.* (or the first media file)
+		ownerPath := filepath.Join(dir, owner)
+		if _, ok := mediaDest[ownerPath]; !ok {
+			ownerPath = basenameOwner(ctx, byBase[filepath.Join(dir, owner)])
+		}
+		if ownerPath == "" {
+			continue
+		}
+
+		to := mediaDest[ownerPath]
+		if to == ownerPath {
+			continue
+		}
+
+		// Follow a renamed owner: DSC_0001.xmp -> DSC_0001_1.xmp
+		ownerName, newName := filepath.Base(ownerPath), filepath.Base(to)
+		sidecarName := filepath.Base(sidecar)
+		if owner == ownerName {
+			sidecarName = newName + filepath.Ext(sidecar)
+		} else {
+			sidecarName = strings.TrimSuffix(newName, filepath.Ext(newName)) + filepath.Ext(sidecar)
+		}
+
+		moves = append(moves, regroupMove{from: sidecar, to: filepath.Join(filepath.Dir(to), sidecarName)})
+	}
+
+	return moves
+}
+
+// basenameOwner picks the media file owning a basename sidecar: the RAW if any,
+// otherwise the first file by name
+func basenameOwner(ctx *executionContext, candidates []string) string {
+	sort.Strings(candidates)
+	for _, candidate := range candidates {
+		if ctx.isRaw(candidate) {
+			return candidate
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	return ""
+}
+
+// eventFolderOf returns the event folder of a file path ("." for the root)
+// "2024 - 0616 - 1900/raw/DSC_0001.NEF" -> "2024 - 0616 - 1900"
+func eventFolderOf(relPath string) string {
+	dir := filepath.Dir(relPath)
+	switch filepath.Base(dir) {
+	case rawFolderName, movFolderName, orphanFolderName:
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// folders lists the event folders before and after the plan, sorted by path
+func (p *regroupPlan) folders() []RegroupFolder {
+	folders := make(map[string]bool)
+	for folder := range p.before {
+		folders[folder] = true
+	}
+	for folder := range p.after {
+		folders[folder] = true
+	}
+
+	names := make([]string, 0, len(folders))
+	for folder := range folders {
+		names = append(names, folder)
+	}
+	sort.Strings(names)
+
+	// Folders receiving or losing files
+	touched := make(map[string]bool)
+	for _, move := range p.moves {
+		touched[eventFolderOf(move.from)] = true
+		touched[eventFolderOf(move.to)] = true
+	}
+
+	diff := make([]RegroupFolder, 0, len(names))
+	for _, folder := range names {
+		diff = append(diff, RegroupFolder{
+			Folder:  folder,
+			Before:  p.before[folder],
+			After:   p.after[folder],
+			Changed: touched[folder],
+		})
+	}
+	return diff
+}
+
+// executeRegroup performs the planned moves
+// A move whose destination is still occupied by a file moving elsewhere waits for it;
+// cycles (files swapping folders) are broken through a temporary name
+// Cancelling runCtx stops before the next move
+func executeRegroup(runCtx context.Context, ctx *executionContext, basePath string, moves []regroupMove) (int, error) {
+	pending := append([]regroupMove(nil), moves...)
+	moved := 0
+
+	for len(pending) > 0 {
+		var blocked []regroupMove
+		for _, move := range pending {
+			if err := runCtx.Err(); err != nil {
+				return moved, err
+			}
+
+			dst := filepath.Join(basePath, move.to)
+			if _, err := ctx.fs.Stat(dst); err == nil {
+				blocked = append(blocked, move)
+				continue
+			}
+
+			if err := ctx.fs.MkdirAll(filepath.Dir(dst), permDirectory); err != nil {
+				return moved, fmt.Errorf("failed to create folder %s: %w", filepath.Dir(dst), err)
+			}
+			if err := moveFileAs(ctx, basePath, move.from, filepath.Dir(move.to), filepath.Base(move.to), false); err != nil {
+				return moved, err
+			}
+			moved++
+			ctx.reportProgress(StageMove, moved, len(moves))
+		}
+
+		if len(blocked) > 0 && len(blocked) == len(pending) {
+			// No progress: park one file under a temporary name, unless its destination
+			// is held by a file that is not moving
+			move := &blocked[0]
+			if !pendingSource(blocked, move.to) {
+				return moved, &PicsplitError{
+					Type: ErrTypeIO,
+					Op:   "regroup",
+					Path: filepath.Join(basePath, move.to),
+					Err:  ErrDestinationExists,
+				}
+			}
+			tmp := move.from + ".picsplit-tmp"
+			if err := ctx.fs.Rename(filepath.Join(basePath, move.from), filepath.Join(basePath, tmp)); err != nil {
+				return moved, fmt.Errorf("failed to move %s: %w", move.from, err)
+			}
+			move.from = tmp
+		}
+		pending = blocked
+	}
+
+	return moved, nil
+}
+
+// pendingSource checks if relPath is the source of one of the moves
+func pendingSource(moves []regroupMove, relPath string) bool {
+	for _, move := range moves {
+		if move.from == relPath {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshRegroupContactSheets rewrites the contact sheets of the event folders receiving or losing files
+// Folders without a sheet get one only with --contact-sheet; emptied folders lose theirs
+func refreshRegroupContactSheets(runCtx context.Context, cfg *Config, ctx *executionContext, plan *regroupPlan) {
+	touched := make(map[string]bool)
+	var folders []string
+	for _, move := range plan.moves {
+		for _, folder := range []string{eventFolderOf(move.from), eventFolderOf(move.to)} {
+			if !touched[folder] {
+				touched[folder] = true
+				folders = append(folders, folder)
+			}
+		}
+	}
+	sort.Strings(folders)
+
+	for _, folder := range folders {
+		dir := filepath.Join(cfg.BasePath, folder)
+		if !cfg.ContactSheet && !hasContactSheet(ctx, dir) {
+			continue
+		}
+		if _, err := refreshContactSheet(runCtx, ctx, dir); err != nil {
+			if runCtx.Err() != nil {
+				return
+			}
+			ctx.log.Warn("failed to write contact sheet", "folder", folder, "error", err)
+		}
+	}
+}
+
+// removeRegroupEmptyDirs removes the folders emptied by the moves, deepest first
+// Returns the number of event folders removed: their raw/, mov/ and orphan/ subfolders and
+// the location folders left empty are removed too but not counted, like folders_before/after
+func removeRegroupEmptyDirs(cfg *Config, ctx *executionContext, plan *regroupPlan) int {
+	ignored := append(append([]string{}, ignoredFiles...), cfg.CleanupIgnore...)
+
+	dirs := make(map[string]bool)
+	for _, move := range plan.moves {
+		for dir := filepath.Dir(move.from); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	ordered := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		ordered = append(ordered, dir)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return strings.Count(ordered[i], string(filepath.Separator)) > strings.Count(ordered[j], string(filepath.Separator))
+	})
+
+	removed := 0
+	for _, dir := range ordered {
+		dirPath := filepath.Join(cfg.BasePath, dir)
+		empty, err := isDirEmptyWithIgnored(ctx.fs, dirPath, ignored)
+		if err != nil || !empty {
+			continue
+		}
+		if err := removeIgnoredFiles(ctx.fs, dirPath, ignored); err != nil {
+			continue
+		}
+		if err := ctx.fs.Remove(dirPath); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				ctx.log.Warn("failed to remove empty folder", "folder", dir, "error", err)
+			}
+			continue
+		}
+		if _, event := plan.before[dir]; !event {
+			ctx.log.Debug("removed empty folder", "folder", dir)
+			continue
+		}
+		ctx.log.Info("removed empty folder", "folder", dir)
+		removed++
+	}
+
+	return removed
+}
@@ -0,0 +1,288 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// organizedLibrary creates two events split with a 30m delta:
+// 19:00 (JPEG+NEF pair, video) and 20:10 (two JPEG, one with the same name as in the first event)
+func organizedLibrary(t *testing.T) (string, string, string) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	base := time.Date(2024, 6, 16, 19, 0, 0, 0, time.Local)
+	eventA := base.Format(dateFormatPattern)
+	eventB := base.Add(70 * time.Minute).Format(dateFormatPattern)
+
+	files := []struct {
+		path    string
+		content string
+		modTime time.Time
+	}{
+		{filepath.Join(eventA, "DSC_0001.JPG"), "a jpeg", base},
+		{filepath.Join(eventA, rawFolderName, "DSC_0001.NEF"), "a raw", base},
+		{filepath.Join(eventA, rawFolderName, "DSC_0001.xmp"), "a sidecar", base},
+		{filepath.Join(eventA, movFolderName, "MOV_0001.MOV"), "a video", base.Add(10 * time.Minute)},
+		{filepath.Join(eventB, "DSC_0001.JPG"), "b jpeg", base.Add(70 * time.Minute)},
+		{filepath.Join(eventB, "IMG_0002.JPG"), "b other", base.Add(80 * time.Minute)},
+	}
+	for _, f := range files {
+		writeTestFile(t, tmpDir, f.path, f.content)
+		setModTime(t, filepath.Join(tmpDir, f.path), f.modTime)
+	}
+
+	return tmpDir, eventA, eventB
+}
+
+func TestRegroup_LargerDeltaMergesEvents(t *testing.T) {
+	tmpDir, eventA, eventB := organizedLibrary(t)
+
+	cfg := &Config{
+		BasePath:          tmpDir,
+		Delta:             2 * time.Hour,
+		Mode:              ModeRun,
+		MinGroupSize:      1,
+		SeparateOrphanRaw: true,
+	}
+	var progress []ProgressEvent
+	cfg.Progress = func(event ProgressEvent) {
+		if event.Stage == StageMove {
+			progress = append(progress, event)
+		}
+	}
+
+	result, err := Regroup(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Regroup() error: %v", err)
+	}
+	if result.FilesMoved != 2 || result.FilesRenamed != 1 || result.FoldersRemoved != 1 {
+		t.Errorf("result = %+v, want 2 moved, 1 renamed, 1 folder removed", result)
+	}
+	if len(progress) != 2 || progress[1].Current != 2 || progress[1].Total != 2 {
+		t.Errorf("move progress = %v, want 2 events up to 2/2", progress)
+	}
+
+	// Files of the first event did not move
+	assertExists(t, filepath.Join(tmpDir, eventA, "DSC_0001.JPG"))
+	assertExists(t, filepath.Join(tmpDir, eventA, rawFolderName, "DSC_0001.NEF"))
+	assertExists(t, filepath.Join(tmpDir, eventA, rawFolderName, "DSC_0001.xmp"))
+	assertExists(t, filepath.Join(tmpDir, eventA, movFolderName, "MOV_0001.MOV"))
+
+	// Second event joined the first one, name collision renamed
+	assertExists(t, filepath.Join(tmpDir, eventA, "DSC_0001_1.JPG"))
+	assertExists(t, filepath.Join(tmpDir, eventA, "IMG_0002.JPG"))
+	assertNotExists(t, filepath.Join(tmpDir, eventB))
+}
+
+func TestRegroup_SmallerDeltaSplitsEvent(t *testing.T) {
+	tmpDir, eventA, _ := organizedLibrary(t)
+
+	cfg := &Config{
+		BasePath:          tmpDir,
+		Delta:             5 * time.Minute,
+		Mode:              ModeRun,
+		MinGroupSize:      1,
+		SeparateOrphanRaw: true,
+	}
+
+	if _, err := Regroup(context.Background(), cfg); err != nil {
+		t.Fatalf("Regroup() error: %v", err)
+	}
+
+	// The video 10 minutes after the pair now has its own event
+	videoEvent := time.Date(2024, 6, 16, 19, 10, 0, 0, time.Local).Format(dateFormatPattern)
+	assertExists(t, filepath.Join(tmpDir, videoEvent, movFolderName, "MOV_0001.MOV"))
+	assertNotExists(t, filepath.Join(tmpDir, eventA, movFolderName))
+
+	// RAW stays paired with its JPEG
+	assertExists(t, filepath.Join(tmpDir, eventA, rawFolderName, "DSC_0001.NEF"))
+}
+
+func TestRegroup_DryRunMovesNothing(t *testing.T) {
+	tmpDir, eventA, eventB := organizedLibrary(t)
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        2 * time.Hour,
+		Mode:         ModeDryRun,
+		MinGroupSize: 1,
+	}
+
+	result, err := Regroup(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Regroup() error: %v", err)
+	}
+
+	want := []RegroupFolder{
+		{Folder: eventA, Before: 3, After: 5, Changed: true},
+		{Folder: eventB, Before: 2, After: 0, Changed: true},
+	}
+	if !reflect.DeepEqual(result.Folders, want) {
+		t.Errorf("Folders = %+v, want %+v", result.Folders, want)
+	}
+	if result.FilesMoved != 2 || result.FoldersRemoved != 0 {
+		t.Errorf("result = %+v, want 2 files to move and no folder removed", result)
+	}
+
+	assertExists(t, filepath.Join(tmpDir, eventB, "DSC_0001.JPG"))
+	assertNotExists(t, filepath.Join(tmpDir, eventA, "DSC_0001_1.JPG"))
+}
+
+func TestPlanRegroup_Diff(t *testing.T) {
+	tmpDir, eventA, eventB := organizedLibrary(t)
+
+	cfg := &Config{BasePath: tmpDir, Delta: 2 * time.Hour, MinGroupSize: 1, SeparateOrphanRaw: true}
+	ctx := newDefaultExecutionContext()
+
+	library, err := collectLibraryFiles(cfg, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(library.media) != 5 || len(library.sidecars) != 1 {
+		t.Fatalf("collected %d media, %d sidecars, want 5 and 1", len(library.media), len(library.sidecars))
+	}
+
+	groups, _, err := buildGroups(context.Background(), cfg, ctx, library.media)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := planRegroup(cfg, ctx, groups, library)
+
+	if len(plan.moves) != 2 {
+		t.Errorf("moves = %v, want 2 (second event files only)", plan.moves)
+	}
+	if plan.renamed != 1 {
+		t.Errorf("renamed = %d, want 1", plan.renamed)
+	}
+	if plan.before[eventA] != 3 || plan.before[eventB] != 2 {
+		t.Errorf("before = %v", plan.before)
+	}
+	if plan.after[eventA] != 5 || plan.after[eventB] != 0 {
+		t.Errorf("after = %v", plan.after)
+	}
+}
+
+func TestExecuteRegroup_Swap(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestFile(t, tmpDir, filepath.Join("a", "IMG.JPG"), "from a")
+	writeTestFile(t, tmpDir, filepath.Join("b", "IMG.JPG"), "from b")
+
+	moves := []regroupMove{
+		{from: filepath.Join("a", "IMG.JPG"), to: filepath.Join("b", "IMG.JPG")},
+		{from: filepath.Join("b", "IMG.JPG"), to: filepath.Join("a", "IMG.JPG")},
+	}
+
+	moved, err := executeRegroup(context.Background(), newDefaultExecutionContext(), tmpDir, moves)
+	if err != nil {
+		t.Fatalf("executeRegroup() error: %v", err)
+	}
+	if moved != 2 {
+		t.Errorf("moved = %d, want 2", moved)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "a", "IMG.JPG"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "from b" {
+		t.Errorf("a/IMG.JPG = %q, want %q", content, "from b")
+	}
+}
+
+func TestRemoveRegroupEmptyDirs_CountsEventFolders(t *testing.T) {
+	tmpDir, eventA, eventB := organizedLibrary(t)
+	pairTime := time.Date(2024, 6, 16, 20, 20, 0, 0, time.Local)
+	writeTestFile(t, tmpDir, filepath.Join(eventB, "DSC_0003.JPG"), "b pair")
+	writeTestFile(t, tmpDir, filepath.Join(eventB, rawFolderName, "DSC_0003.NEF"), "b raw")
+	setModTime(t, filepath.Join(tmpDir, eventB, "DSC_0003.JPG"), pairTime)
+	setModTime(t, filepath.Join(tmpDir, eventB, rawFolderName, "DSC_0003.NEF"), pairTime)
+
+	cfg := &Config{BasePath: tmpDir, Delta: 2 * time.Hour, Mode: ModeRun, MinGroupSize: 1, SeparateOrphanRaw: true}
+	ctx := newDefaultExecutionContext()
+
+	library, err := collectLibraryFiles(cfg, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, _, err := buildGroups(context.Background(), cfg, ctx, library.media)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := planRegroup(cfg, ctx, groups, library)
+	if _, err := executeRegroup(context.Background(), ctx, tmpDir, plan.moves); err != nil {
+		t.Fatalf("executeRegroup() error: %v", err)
+	}
+
+	// eventB and its raw/ subfolder are removed, only the event folder is counted
+	if removed := removeRegroupEmptyDirs(cfg, ctx, plan); removed != 1 {
+		t.Errorf("removed = %d, want 1", removed)
+	}
+	assertNotExists(t, filepath.Join(tmpDir, eventB))
+	assertExists(t, filepath.Join(tmpDir, eventA, rawFolderName, "DSC_0003.NEF"))
+}
+
+func TestRegroup_ContactSheets(t *testing.T) {
+	tmpDir, eventA, eventB := organizedLibrary(t)
+	for _, event := range []string{eventA, eventB} {
+		writeTestFile(t, tmpDir, filepath.Join(event, contactSheetName), "stale sheet")
+	}
+
+	cfg := &Config{BasePath: tmpDir, Delta: 2 * time.Hour, Mode: ModeRun, MinGroupSize: 1, SeparateOrphanRaw: true}
+	result, err := Regroup(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Regroup() error: %v", err)
+	}
+
+	// A folder left with only its contact sheet is empty
+	if result.FoldersRemoved != 1 {
+		t.Errorf("FoldersRemoved = %d, want 1", result.FoldersRemoved)
+	}
+	assertNotExists(t, filepath.Join(tmpDir, eventB))
+
+	// The sheet of the extended folder is rewritten: none of its test files has a thumbnail
+	assertNotExists(t, filepath.Join(tmpDir, eventA, contactSheetName))
+}
+
+func TestRegroup_MemFileSystemSameNameFiles(t *testing.T) {
+	// Same name, size and date in two event folders: the files only differ by their folder
+	fsys := NewMemFileSystem()
+	shot := time.Date(2024, 6, 16, 19, 0, 0, 0, time.Local)
+	eventA := shot.Format(dateFormatPattern)
+	eventB := shot.Add(70 * time.Minute).Format(dateFormatPattern)
+	writeMemFile(t, fsys, filepath.Join("/lib", eventA, "IMG_0001.JPG"), []byte("photo a"), shot)
+	writeMemFile(t, fsys, filepath.Join("/lib", eventB, "IMG_0001.JPG"), []byte("photo b"), shot)
+	writeMemFile(t, fsys, filepath.Join("/lib", eventB, "IMG_0002.JPG"), []byte("photo c"), shot.Add(70*time.Minute))
+
+	cfg := &Config{BasePath: "/lib", Delta: 30 * time.Minute, Mode: ModeRun, MinGroupSize: 1, FS: fsys}
+	result, err := Regroup(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Regroup() error: %v", err)
+	}
+	if result.FilesMoved != 1 || result.FilesRenamed != 1 {
+		t.Errorf("result = %+v, want 1 moved and renamed", result)
+	}
+
+	// The photo of eventB taken at 19:00 joins eventA, the other stays
+	assertMemExists(t, fsys, filepath.Join("/lib", eventA, "IMG_0001.JPG"))
+	assertMemExists(t, fsys, filepath.Join("/lib", eventA, "IMG_0001_1.JPG"))
+	assertMemExists(t, fsys, filepath.Join("/lib", eventB, "IMG_0002.JPG"))
+}
+
+func TestRegroup_CancelledMovesNothing(t *testing.T) {
+	tmpDir, _, eventB := organizedLibrary(t)
+
+	cfg := &Config{BasePath: tmpDir, Delta: 2 * time.Hour, Mode: ModeRun, MinGroupSize: 1}
+	runCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Regroup(runCtx, cfg); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Regroup() error = %v, want context.Canceled", err)
+	}
+	assertExists(t, filepath.Join(tmpDir, eventB, "DSC_0001.JPG"))
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// renameManifestName records the original name of every file renamed by --rename-template (v2.10.0+)
+	renameManifestName = "picsplit-renames.csv"
+
+	defaultRenameDateLayout = "20060102_150405"
+	defaultRenameSeqWidth   = 4
+	unknownCameraName       = "unknown"
+
+	// Template tokens
+	tokenDate   = "date"   // {date} or {date:20060102} - Go time layout applied to the file date
+	tokenCamera = "camera" // {camera} - EXIF Model without spaces (e.g., "iPhone15")
+	tokenMake   = "make"   // {make} - EXIF Make without spaces (e.g., "Apple")
+	tokenName   = "name"   // {name} - original basename
+	tokenSeq    = "seq"    // {seq} or {seq:4} - zero-padded sequence number within the destination folder
+	tokenExt    = "ext"    // {ext} - lowercase extension, must end the template
+)
+
+// renameTemplate is a parsed --rename-template
+// Example: "{date:20060102_150405}_{camera}_{seq:4}.{ext}" turns IMG_4821.HEIC
+// into 20240615_143012_iPhone15_0001.heic
+type renameTemplate struct {
+	parts []templatePart
+}
+
+// templatePart is either a literal text or a token with its optional argument
+type templatePart struct {
+	literal string
+	token   string
+	arg     string
+}
+
+// renameRecord maps an original file name to its new location (relative to BasePath)
+type renameRecord struct {
+	original string
+	renamed  string
+}
+
+// parseRenameTemplate parses and validates a rename template
+func parseRenameTemplate(tmpl string) (*renameTemplate, error) {
+	extSuffix := ".{" + tokenExt + "}"
+	if !strings.HasSuffix(tmpl, extSuffix) {
+		return nil, fmt.Errorf("rename template must end with %s", extSuffix)
+	}
+	body := strings.TrimSuffix(tmpl, extSuffix)
+	if body == "" {
+		return nil, fmt.Errorf("rename template has no basename before %s", extSuffix)
+	}
+	if strings.ContainsAny(body, `/\`) {
+		return nil, fmt.Errorf("rename template cannot contain path separators")
+	}
+
+	parts, err := parseTemplateParts(body, "rename template", func(token, arg string) error {
+		switch token {
+		case tokenDate, tokenCamera, tokenMake, tokenName:
+		case tokenSeq:
+			if arg != "" {
+				if width, err := strconv.Atoi(arg); err != nil || width < 1 || width > 9 {
+					return fmt.Errorf("invalid {seq} width: %s (must be 1-9)", arg)
+				}
+			}
+		case tokenExt:
+			return fmt.Errorf("{ext} is only allowed at the end of the rename template")
+		default:
+			return fmt.Errorf("unknown rename template token: {%s}", token)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &renameTemplate{parts: parts}, nil
+}
+
+// parseTemplateParts splits a template into literals and {token:arg} parts
+// checkToken validates each token for the kind of template being parsed
+func parseTemplateParts(body, kind string, checkToken func(token, arg string) error) ([]templatePart, error) {
+	var parts []templatePart
+	for len(body) > 0 {
+		start := strings.IndexByte(body, '{')
+		if start < 0 {
+			if strings.IndexByte(body, '}') >= 0 {
+				return nil, fmt.Errorf("unbalanced '}' in %s", kind)
+			}
+			parts = append(parts, templatePart{literal: body})
+			break
+		}
+		if start > 0 {
+			literal := body[:start]
+			if strings.IndexByte(literal, '}') >= 0 {
+				return nil, fmt.Errorf("unbalanced '}' in %s", kind)
+			}
+			parts = append(parts, templatePart{literal: literal})
+		}
+
+		end := strings.IndexByte(body[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed '{' in %s", kind)
+		}
+		token, arg, _ := strings.Cut(body[start+1:start+end], ":")
+		if err := checkToken(token, arg); err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, templatePart{token: token, arg: arg})
+		body = body[start+end+1:]
+	}
+
+	return parts, nil
+}
+
+// basename renders the template (without extension) for a file
+func (t *renameTemplate) basename(file FileMetadata, seq int) string {
+	var sb strings.Builder
+
+	for _, part := range t.parts {
+		switch part.token {
+		case "":
+			sb.WriteString(part.literal)
+		case tokenDate:
+			layout := part.arg
+			if layout == "" {
+				layout = defaultRenameDateLayout
+			}
+			sb.WriteString(sanitizeFileNamePart(file.DateTime.Format(layout)))
+		case tokenCamera:
+			sb.WriteString(cameraNamePart(file.Model))
+		case tokenMake:
+			sb.WriteString(cameraNamePart(file.Make))
+		case tokenName:
+			name := file.FileInfo.Name()
+			sb.WriteString(strings.TrimSuffix(name, filepath.Ext(name)))
+		case tokenSeq:
+			width := defaultRenameSeqWidth
+			if part.arg != "" {
+				width, _ = strconv.Atoi(part.arg)
+			}
+			sb.WriteString(fmt.Sprintf("%0*d", width, seq))
+		}
+	}
+
+	return sb.String()
+}
+
+// renameExtension returns the extension used by {ext}: lowercase, with leading dot
+func renameExtension(fileName string) string {
+	return strings.ToLower(filepath.Ext(fileName))
+}
+
+// cameraNamePart formats a camera make/model for a file name ("iPhone 15" -> "iPhone15")
+func cameraNamePart(value string) string {
+	value = strings.ReplaceAll(sanitizeFileNamePart(value), " ", "")
+	if value == "" {
+		return unknownCameraName
+	}
+	return value
+}
+
+// sanitizeFileNamePart removes characters that are invalid in file names
+func sanitizeFileNamePart(value string) string {
+	return strings.ReplaceAll(sanitizeFolderName(value), ".", "")
+}
+
+// templateBasename returns the next templated basename for a file placed in destDir
+// The sequence counter is shared by the group folder and its raw/, orphan/, mov/ subfolders
+// and skips names already used in the folder (files appended to an existing event)
+func (ctx *executionContext) templateBasename(file FileMetadata, basePath, destDir string) string {
+	group := destDir
+	switch filepath.Base(destDir) {
+	case rawFolderName, orphanFolderName, movFolderName:
+		group = filepath.Dir(destDir)
+	}
+
+	if ctx.renameSeq == nil {
+		ctx.renameSeq = make(map[string]int)
+	}
+
+	familyDirs := collisionFamilyDirs(basePath, destDir)
+	for {
+		ctx.renameSeq[group]++
+		base := ctx.renamer.basename(file, ctx.renameSeq[group])
+		if !ctx.renamer.hasSeq() || !basenameTaken(ctx.fs, familyDirs, base) {
+			return base
+		}
+	}
+}
+
+// hasSeq reports whether the template contains a {seq} token
+func (t *renameTemplate) hasSeq() bool {
+	for _, part := range t.parts {
+		if part.token == tokenSeq {
+			return true
+		}
+	}
+	return false
+}
+
+// recordRename remembers a renamed file for the manifest
+func (ctx *executionContext) recordRename(original, destDir, destName string) {
+	if original == destName {
+		return
+	}
+	ctx.renames = append(ctx.renames, renameRecord{
+		original: original,
+		renamed:  filepath.Join(destDir, destName),
+	})
+}
+
+// writeRenameManifest appends the rename records of the run to the CSV manifest in basePath
+// Columns: renamed_at, original_name, new_path (relative to basePath)
+func (ctx *executionContext) writeRenameManifest(basePath string) error {
+	records := ctx.renames
+	if len(records) == 0 {
+		return nil
+	}
+
+	manifestPath := filepath.Join(basePath, renameManifestName)
+
+	// Append to the existing manifest (read then rewrite: not every FileSystem can append)
+	existing, err := readFile(ctx.fs, manifestPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to open rename manifest: %w", err)
+	}
+
+	buf := bytes.NewBuffer(existing)
+	w := csv.NewWriter(buf)
+	if len(existing) == 0 {
+		if err := w.Write([]string{"renamed_at", "original_name", "new_path"}); err != nil {
+			return fmt.Errorf("failed to write rename manifest: %w", err)
+		}
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	for _, record := range records {
+		if err := w.Write([]string{now, record.original, filepath.ToSlash(record.renamed)}); err != nil {
+			return fmt.Errorf("failed to write rename manifest: %w", err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write rename manifest: %w", err)
+	}
+	if err := ctx.fs.WriteFile(manifestPath, buf.Bytes(), 0644); err != nil { //nolint:gosec // manifest is meant to be readable
+		return fmt.Errorf("failed to write rename manifest: %w", err)
+	}
+
+	ctx.log.Info("rename manifest updated", "path", manifestPath, "entries", len(records))
+	return nil
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRenameTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    string
+		wantErr bool
+	}{
+		{"date camera seq", "{date:20060102_150405}_{camera}_{seq:4}.{ext}", false},
+		{"default layouts", "{date}_{seq}.{ext}", false},
+		{"name and make", "{make}-{name}.{ext}", false},
+		{"literal only", "photo.{ext}", false},
+		{"missing ext", "{date}_{seq}", true},
+		{"ext not at end", "{ext}_{seq}.{ext}", true},
+		{"empty basename", ".{ext}", true},
+		{"unknown token", "{date}_{lens}.{ext}", true},
+		{"bad seq width", "{seq:x}.{ext}", true},
+		{"seq width too large", "{seq:12}.{ext}", true},
+		{"path separator", "{date:2006}/{seq}.{ext}", true},
+		{"unclosed brace", "{date_{seq}.{ext}", true},
+		{"unbalanced brace", "date}_{seq}.{ext}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRenameTemplate(tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseRenameTemplate(%q) error = %v, wantErr %v", tt.tmpl, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenameTemplate_Basename(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestFile(t, tmpDir, "IMG_4821.HEIC", "content")
+
+	file := statMetadata(t, tmpDir, "IMG_4821.HEIC")
+	file.DateTime = time.Date(2024, 6, 15, 14, 30, 12, 0, time.Local)
+	file.Make = "Apple"
+	file.Model = "iPhone 15"
+
+	tests := []struct {
+		tmpl string
+		seq  int
+		want string
+	}{
+		{"{date:20060102_150405}_{camera}_{seq:4}.{ext}", 1, "20240615_143012_iPhone15_0001"},
+		{"{date}_{seq}.{ext}", 12, "20240615_143012_0012"},
+		{"{make}_{name}.{ext}", 1, "Apple_IMG_4821"},
+		{"{date:2006-01-02}_{seq:2}.{ext}", 3, "2024-06-15_03"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			tmpl, err := parseRenameTemplate(tt.tmpl)
+			if err != nil {
+				t.Fatalf("parseRenameTemplate() error: %v", err)
+			}
+			if got := tmpl.basename(file, tt.seq); got != tt.want {
+				t.Errorf("basename() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Missing camera information
+	file.Model = ""
+	tmpl, _ := parseRenameTemplate("{camera}.{ext}")
+	if got := tmpl.basename(file, 1); got != unknownCameraName {
+		t.Errorf("basename() without model = %q, want %q", got, unknownCameraName)
+	}
+}
+
+func TestSplit_RenameTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	group := baseTime.Format(dateFormatPattern)
+
+	createTestFile(t, tmpDir, "DSC_0001.JPG", baseTime)
+	createTestFile(t, tmpDir, "DSC_0001.NEF", baseTime)
+	createTestFile(t, tmpDir, "DSC_0001.xmp", baseTime)
+	createTestFile(t, tmpDir, "DSC_0002.JPG", baseTime.Add(time.Minute))
+
+	cfg := &Config{
+		BasePath:       tmpDir,
+		Delta:          30 * time.Minute,
+		UseEXIF:        false,
+		Mode:           ModeRun,
+		MinGroupSize:   1,
+		RenameTemplate: "{date:20060102}_{seq:3}.{ext}",
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	// The whole family shares the first sequence number
+	assertExists(t, filepath.Join(tmpDir, group, "20240615_001.jpg"))
+	assertExists(t, filepath.Join(tmpDir, group, rawFolderName, "20240615_001.nef"))
+	assertExists(t, filepath.Join(tmpDir, group, rawFolderName, "20240615_001.xmp"))
+	assertExists(t, filepath.Join(tmpDir, group, "20240615_002.jpg"))
+
+	manifest, err := os.ReadFile(filepath.Join(tmpDir, renameManifestName))
+	if err != nil {
+		t.Fatalf("rename manifest not written: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(manifest)), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("manifest has %d lines, want 5 (header + 4 files):\n%s", len(lines), manifest)
+	}
+	if !strings.Contains(string(manifest), "DSC_0001.NEF,"+group+"/raw/20240615_001.nef") {
+		t.Errorf("manifest missing NEF entry:\n%s", manifest)
+	}
+}
+
+func TestSplit_RenameTemplateDryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+
+	createTestFile(t, tmpDir, "IMG_0001.JPG", baseTime)
+
+	cfg := &Config{
+		BasePath:       tmpDir,
+		Delta:          30 * time.Minute,
+		Mode:           ModeDryRun,
+		MinGroupSize:   1,
+		RenameTemplate: "{seq}.{ext}",
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	assertExists(t, filepath.Join(tmpDir, "IMG_0001.JPG"))
+	assertNotExists(t, filepath.Join(tmpDir, renameManifestName))
+}
+
+func TestTemplateBasename_SkipsTakenSequence(t *testing.T) {
+	tmpDir := t.TempDir()
+	group := "2024 - 0615 - 1200"
+
+	// Files from a previous run already use 0001 and 0002
+	writeTestFile(t, tmpDir, filepath.Join(group, "photo_0001.jpg"), "a")
+	writeTestFile(t, tmpDir, filepath.Join(group, rawFolderName, "photo_0002.nef"), "b")
+	writeTestFile(t, tmpDir, "IMG_0001.JPG", "c")
+
+	renamer, err := parseRenameTemplate("photo_{seq}.{ext}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := newDefaultExecutionContext()
+	ctx.renamer = renamer
+
+	file := statMetadata(t, tmpDir, "IMG_0001.JPG")
+	if got := ctx.templateBasename(file, tmpDir, group); got != "photo_0003" {
+		t.Errorf("templateBasename() = %q, want photo_0003", got)
+	}
+}
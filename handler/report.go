@@ -0,0 +1,463 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ReportVersion is the version of the JSON run report schema (v2.10.0+)
+// It is increased when a field is renamed, removed or changes meaning; adding fields keeps the version
+const ReportVersion = 1
+
+// Report statuses
+const (
+	ReportStatusCompleted   = "completed"   // Every file was handled (some may have non-critical errors)
+	ReportStatusInterrupted = "interrupted" // The run was canceled, see Checkpoint
+	ReportStatusFailed      = "failed"      // The run stopped on an error, see Error
+)
+
+// Report is the machine-readable outcome of a run, written by --report (v2.10.0+)
+// Exactly one of Split, Validation or MergeValidation is set
+type Report struct {
+	GeneratedAt     time.Time                 `json:"generated_at"`
+	Command         string                    `json:"command"` // "split" or "merge"
+	Mode            ExecutionMode             `json:"mode"`
+	Status          string                    `json:"status"`
+	Error           string                    `json:"error,omitempty"` // Error that stopped the run
+	Split           *SplitRunReport           `json:"split,omitempty"`
+	Validation      *ValidationRunReport      `json:"validation,omitempty"`
+	MergeValidation *MergeValidationRunReport `json:"merge_validation,omitempty"`
+	Version         int                       `json:"version"`
+}
+
+// SplitRunReport is the outcome of a split in dryrun or run mode
+type SplitRunReport struct {
+	StartTime          time.Time                 `json:"start_time"`
+	EndTime            time.Time                 `json:"end_time"`
+	Duplicates         map[string]string         `json:"duplicates"` // Duplicate path -> original path
+	EmptyDirsFailed    map[string]string         `json:"empty_dirs_failed"`
+	Checkpoint         string                    `json:"checkpoint,omitempty"`
+	Groups             []ReportGroup             `json:"groups"`
+	OrphanRawFiles     []string                  `json:"orphan_raw_files"`
+	EmptyDirsRemoved   []string                  `json:"empty_dirs_removed"`
+	Errors             []ReportError             `json:"errors"`
+	RuleHits           []ReportRuleHit           `json:"rule_hits"`           // Files matched by each routing rule (v2.10.0+)
+	DateConflicts      []ReportDateConflict      `json:"date_conflicts"`      // Files whose dates disagree (v2.10.0+)
+	LocationInferences []ReportLocationInference `json:"location_inferences"` // Files without GPS placed with a geotagged neighbour (v2.10.0+)
+	Files              ReportFiles               `json:"files"`
+	Collisions         ReportCollisions          `json:"collisions"`
+	DurationSeconds    float64                   `json:"duration_seconds"`
+	ThroughputMBs      float64                   `json:"throughput_mb_s"`
+	SuccessRate        float64                   `json:"success_rate"`
+	Bytes              int64                     `json:"bytes"`
+	GroupsCreated      int                       `json:"groups_created"`
+	GroupsExtended     int                       `json:"groups_extended"`
+	EventsMerged       int                       `json:"events_merged"`
+	SmallGroups        int                       `json:"small_groups"`
+	FilesAtRoot        int                       `json:"files_at_root"`
+	PairedRaw          int                       `json:"paired_raw"`
+	OrphanRaw          int                       `json:"orphan_raw"`
+	DuplicatesSkipped  int                       `json:"duplicates_skipped"`
+	FilesRenamed       int                       `json:"files_renamed"`
+	ScreenshotFiles    int                       `json:"screenshot_files"` // Files sent to screenshots/ (v2.10.0+)
+	MessagingFiles     int                       `json:"messaging_files"`  // Files sent to messaging/<app>/ (v2.10.0+)
+	ContactSheets      int                       `json:"contact_sheets"`   // Event folders that received a contact.jpg (v2.10.0+)
+	OrphanRefresh      bool                      `json:"orphan_refresh"`   // The folder was already organized: only orphan RAW files were separated
+}
+
+// ReportFiles counts the files of a run by type
+type ReportFiles struct {
+	Total           int `json:"total"`
+	Processed       int `json:"processed"`
+	Photos          int `json:"photos"`
+	Videos          int `json:"videos"`
+	Raw             int `json:"raw"`
+	ModTimeFallback int `json:"modtime_fallback"`
+}
+
+// ReportCollisions counts how destination name collisions were resolved
+type ReportCollisions struct {
+	Renamed          int `json:"renamed"`
+	Skipped          int `json:"skipped"`
+	Overwritten      int `json:"overwritten"`
+	IdenticalSkipped int `json:"identical_skipped"`
+}
+
+// ReportGroup is an event folder created or extended by the run
+type ReportGroup struct {
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Folder   string        `json:"folder"`
+	Files    []string      `json:"files"`
+	Media    []ReportMedia `json:"media"` // Metadata of Files, in the same order (v2.10.0+)
+	Existing bool          `json:"existing"`
+	Split    string        `json:"split,omitempty"` // Why the group was split from the previous one (adaptive grouping, v2.10.0+)
+}
+
+// ReportMedia is the metadata extracted from a file, empty values are omitted (v2.10.0+)
+type ReportMedia struct {
+	DateTime        time.Time  `json:"date_time"`
+	GPS             *ReportGPS `json:"gps,omitempty"`
+	Name            string     `json:"name"`
+	DateSource      string     `json:"date_source"` // "EXIF", "VideoMeta", "Filename", "XMP", "ModTime" or "BirthTime"
+	Make            string     `json:"make,omitempty"`
+	Model           string     `json:"model,omitempty"`
+	SerialNumber    string     `json:"serial_number,omitempty"`
+	LensModel       string     `json:"lens_model,omitempty"`
+	VideoCodec      string     `json:"video_codec,omitempty"`
+	Label           string     `json:"label,omitempty"`
+	UserComment     string     `json:"user_comment,omitempty"`
+	Keywords        []string   `json:"keywords,omitempty"`
+	FocalLength     float64    `json:"focal_length,omitempty"`
+	DurationSeconds float64    `json:"duration_seconds,omitempty"`
+	ISO             int        `json:"iso,omitempty"`
+	Width           int        `json:"width,omitempty"`
+	Height          int        `json:"height,omitempty"`
+	Orientation     int        `json:"orientation,omitempty"`
+	Rating          int        `json:"rating,omitempty"`
+}
+
+// ReportRuleHit counts the files matched by a routing rule (v2.10.0+)
+type ReportRuleHit struct {
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	Files  int    `json:"files"`
+}
+
+// ReportDateConflict is a file whose dates disagree by more than --date-conflict-threshold (v2.10.0+)
+type ReportDateConflict struct {
+	Dates         map[string]time.Time `json:"dates"` // Date source -> date
+	File          string               `json:"file"`
+	Chosen        string               `json:"chosen"` // Date source kept by the conflict policy
+	SpreadSeconds float64              `json:"spread_seconds"`
+}
+
+// ReportLocationInference is a file without GPS placed in the location of the geotagged file closest in time (v2.10.0+)
+type ReportLocationInference struct {
+	File       string  `json:"file"`
+	From       string  `json:"from"` // Geotagged neighbour
+	Location   string  `json:"location"`
+	GapSeconds float64 `json:"gap_seconds"`
+}
+
+// ReportGPS is a position in decimal degrees
+type ReportGPS struct {
+	Lat          float64 `json:"lat"`
+	Lon          float64 `json:"lon"`
+	Interpolated bool    `json:"interpolated,omitempty"` // Interpolated from a --gpx track (v2.10.0+)
+}
+
+// ReportError is a PicsplitError with its corrective action
+type ReportError struct {
+	Details    map[string]string `json:"details,omitempty"`
+	Type       ErrorType         `json:"type"`
+	Op         string            `json:"op"`
+	Path       string            `json:"path"`
+	Message    string            `json:"message"`
+	Suggestion string            `json:"suggestion"`
+	Critical   bool              `json:"critical"`
+}
+
+// ValidationRunReport is the outcome of a split in validate mode
+type ValidationRunReport struct {
+	StartTime       time.Time       `json:"start_time"`
+	EndTime         time.Time       `json:"end_time"`
+	Errors          []ReportError   `json:"errors"`
+	Warnings        []string        `json:"warnings"`
+	RuleHits        []ReportRuleHit `json:"rule_hits"` // Files matched by each routing rule (v2.10.0+)
+	Files           ReportFiles     `json:"files"`
+	DurationSeconds float64         `json:"duration_seconds"`
+	Bytes           int64           `json:"bytes"`
+	CriticalErrors  int             `json:"critical_errors"`
+}
+
+// MergeValidationRunReport is the outcome of a merge in validate mode
+type MergeValidationRunReport struct {
+	SourceBreakdown map[string]int `json:"source_breakdown"` // Files per source folder
+	TargetFolder    string         `json:"target_folder"`
+	SourceFolders   []string       `json:"source_folders"`
+	Errors          []string       `json:"errors"`
+	Warnings        []string       `json:"warnings"`
+	TotalFiles      int            `json:"total_files"`
+	Bytes           int64          `json:"bytes"`
+	Conflicts       int            `json:"conflicts"`
+}
+
+// NewSplitReport builds the report of a split from its result and returned error
+// result may be nil when the run failed before processing or found no media file
+func NewSplitReport(mode ExecutionMode, result *SplitResult, runErr error) *Report {
+	report := newReport("split", mode, runErr)
+	if result == nil {
+		return report
+	}
+
+	if result.Validation != nil {
+		report.Validation = newValidationRunReport(result.Validation)
+	}
+	if result.Stats != nil {
+		report.Split = newSplitRunReport(result.Stats)
+		report.Split.OrphanRefresh = result.OrphanRefresh
+		report.Status = runStatus(result.Stats, runErr)
+	}
+	return report
+}
+
+// NewMergeReport builds the report of a merge validation
+func NewMergeReport(validation *MergeValidationReport, runErr error) *Report {
+	report := newReport("merge", ModeValidate, runErr)
+	if validation == nil {
+		return report
+	}
+
+	report.MergeValidation = &MergeValidationRunReport{
+		SourceFolders:   append([]string{}, validation.SourceFolders...),
+		TargetFolder:    validation.TargetFolder,
+		TotalFiles:      validation.TotalFiles,
+		Bytes:           validation.TotalBytes,
+		Conflicts:       validation.Conflicts,
+		SourceBreakdown: make(map[string]int, len(validation.SourceBreakdown)),
+		Errors:          make([]string, 0, len(validation.Errors)),
+		Warnings:        append([]string{}, validation.Warnings...),
+	}
+	for source, count := range validation.SourceBreakdown {
+		report.MergeValidation.SourceBreakdown[source] = count
+	}
+	for _, err := range validation.Errors {
+		report.MergeValidation.Errors = append(report.MergeValidation.Errors, err.Error())
+	}
+	return report
+}
+
+// newReport creates a report header, the status follows the error returned by the run
+func newReport(command string, mode ExecutionMode, runErr error) *Report {
+	report := &Report{
+		Version:     ReportVersion,
+		GeneratedAt: time.Now(),
+		Command:     command,
+		Mode:        mode,
+		Status:      ReportStatusCompleted,
+	}
+
+	if runErr != nil {
+		report.Error = runErr.Error()
+		report.Status = runStatus(nil, runErr)
+	}
+	return report
+}
+
+// runStatus derives the status of a run from its statistics and returned error
+func runStatus(stats *ProcessingStats, runErr error) string {
+	switch {
+	case errors.Is(runErr, context.Canceled), stats != nil && stats.Interrupted:
+		return ReportStatusInterrupted
+	case runErr != nil:
+		return ReportStatusFailed
+	default:
+		return ReportStatusCompleted
+	}
+}
+
+// newSplitRunReport converts processing statistics, empty collections are kept as [] and {}
+func newSplitRunReport(s *ProcessingStats) *SplitRunReport {
+	report := &SplitRunReport{
+		StartTime:       s.StartTime,
+		EndTime:         s.EndTime,
+		DurationSeconds: s.Duration().Seconds(),
+		ThroughputMBs:   s.Throughput(),
+		SuccessRate:     s.SuccessRate(),
+		Bytes:           s.TotalBytes,
+		Files: ReportFiles{
+			Total:           s.TotalFiles,
+			Processed:       s.ProcessedFiles,
+			Photos:          s.PhotoCount,
+			Videos:          s.VideoCount,
+			Raw:             s.RawCount,
+			ModTimeFallback: s.ModTimeFallbackCount,
+		},
+		GroupsCreated:     s.GroupsCreated,
+		GroupsExtended:    s.GroupsExtended,
+		EventsMerged:      s.EventsMerged,
+		SmallGroups:       s.SmallGroupsCount,
+		FilesAtRoot:       s.RootFilesCount,
+		Groups:            make([]ReportGroup, 0, len(s.Groups)),
+		PairedRaw:         s.PairedRaw,
+		OrphanRaw:         s.OrphanRaw,
+		OrphanRawFiles:    append([]string{}, s.OrphanRawFiles...),
+		Duplicates:        make(map[string]string, len(s.DuplicatesDetected)),
+		DuplicatesSkipped: s.DuplicatesSkipped,
+		Collisions: ReportCollisions{
+			Renamed:          s.CollisionsRenamed,
+			Skipped:          s.CollisionsSkipped,
+			Overwritten:      s.CollisionsOverwritten,
+			IdenticalSkipped: s.IdenticalSkipped,
+		},
+		FilesRenamed:       s.FilesRenamed,
+		ScreenshotFiles:    s.ScreenshotFiles,
+		MessagingFiles:     s.MessagingFiles,
+		ContactSheets:      s.ContactSheets,
+		EmptyDirsRemoved:   append([]string{}, s.EmptyDirsRemoved...),
+		EmptyDirsFailed:    make(map[string]string, len(s.EmptyDirsFailed)),
+		Errors:             newReportErrors(s.Errors),
+		Checkpoint:         s.CheckpointPath,
+		RuleHits:           newReportRuleHits(s.RuleHits),
+		DateConflicts:      newReportDateConflicts(s.DateConflicts),
+		LocationInferences: newReportLocationInferences(s.LocationInferences),
+	}
+
+	for _, group := range s.Groups {
+		reportGroup := ReportGroup{
+			Folder:   group.Folder,
+			Files:    append([]string{}, group.Files...),
+			Media:    make([]ReportMedia, 0, len(group.Metadata)),
+			Start:    group.Start,
+			End:      group.End,
+			Existing: group.Existing,
+			Split:    group.Split,
+		}
+		for i := range group.Metadata {
+			reportGroup.Media = append(reportGroup.Media, newReportMedia(&group.Metadata[i]))
+		}
+		report.Groups = append(report.Groups, reportGroup)
+	}
+	for duplicate, original := range s.DuplicatesDetected {
+		report.Duplicates[duplicate] = original
+	}
+	for dir, reason := range s.EmptyDirsFailed {
+		report.EmptyDirsFailed[dir] = reason
+	}
+	return report
+}
+
+// newReportMedia converts the metadata of a file
+func newReportMedia(m *FileMetadata) ReportMedia {
+	media := ReportMedia{
+		DateTime:        m.DateTime,
+		DateSource:      m.Source.String(),
+		Make:            m.Make,
+		Model:           m.Model,
+		SerialNumber:    m.SerialNumber,
+		LensModel:       m.LensModel,
+		FocalLength:     m.FocalLength,
+		ISO:             m.ISO,
+		Width:           m.Width,
+		Height:          m.Height,
+		Orientation:     m.Orientation,
+		UserComment:     m.UserComment,
+		DurationSeconds: m.Duration.Seconds(),
+		VideoCodec:      m.VideoCodec,
+		Rating:          m.Rating,
+		Label:           m.Label,
+		Keywords:        append([]string(nil), m.Keywords...),
+	}
+	if m.FileInfo != nil {
+		media.Name = m.FileInfo.Name()
+	}
+	if m.GPS != nil {
+		media.GPS = &ReportGPS{Lat: m.GPS.Lat, Lon: m.GPS.Lon, Interpolated: m.GPSInterpolated}
+	}
+	return media
+}
+
+// newValidationRunReport converts a split validation report
+func newValidationRunReport(r *ValidationReport) *ValidationRunReport {
+	return &ValidationRunReport{
+		StartTime:       r.StartTime,
+		EndTime:         r.EndTime,
+		DurationSeconds: r.Duration().Seconds(),
+		Files: ReportFiles{
+			Total:  r.TotalFiles,
+			Photos: r.PhotoCount,
+			Videos: r.VideoCount,
+			Raw:    r.RawCount,
+		},
+		Bytes:          r.TotalBytes,
+		Errors:         newReportErrors(r.Errors),
+		Warnings:       append([]string{}, r.Warnings...),
+		CriticalErrors: r.CriticalErrorCount(),
+		RuleHits:       newReportRuleHits(r.RuleHits),
+	}
+}
+
+// newReportRuleHits converts rule hit counts, an empty list without rules
+func newReportRuleHits(hits []RuleHit) []ReportRuleHit {
+	reportHits := make([]ReportRuleHit, 0, len(hits))
+	for _, hit := range hits {
+		reportHits = append(reportHits, ReportRuleHit(hit))
+	}
+	return reportHits
+}
+
+// newReportDateConflicts converts date conflicts, an empty list without conflict
+func newReportDateConflicts(conflicts []DateConflict) []ReportDateConflict {
+	result := make([]ReportDateConflict, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		dates := make(map[string]time.Time, len(conflict.Dates))
+		for _, candidate := range conflict.Dates {
+			dates[candidate.Source.String()] = candidate.Date
+		}
+		result = append(result, ReportDateConflict{
+			File:          conflict.File,
+			Dates:         dates,
+			Chosen:        conflict.Chosen.String(),
+			SpreadSeconds: conflict.Spread.Seconds(),
+		})
+	}
+	return result
+}
+
+// newReportLocationInferences converts location inferences, an empty list without inference
+func newReportLocationInferences(inferences []LocationInference) []ReportLocationInference {
+	result := make([]ReportLocationInference, 0, len(inferences))
+	for _, inference := range inferences {
+		result = append(result, ReportLocationInference{
+			File:       inference.File,
+			From:       inference.From,
+			Location:   inference.Location,
+			GapSeconds: inference.Gap.Seconds(),
+		})
+	}
+	return result
+}
+
+// newReportErrors converts errors with their suggestion
+func newReportErrors(errs []*PicsplitError) []ReportError {
+	result := make([]ReportError, 0, len(errs))
+	for _, err := range errs {
+		result = append(result, ReportError{
+			Type:       err.Type,
+			Op:         err.Op,
+			Path:       err.Path,
+			Message:    err.Error(),
+			Details:    err.Details,
+			Suggestion: err.Suggestion(),
+			Critical:   err.IsCritical(),
+		})
+	}
+	return result
+}
+
+// Encode writes the report as indented JSON
+func (r *Report) Encode(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteFile writes the report to path on the local file system
+func (r *Report) WriteFile(path string) error {
+	file, err := os.Create(path) //nolint:gosec // path is given by the user
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+
+	if err := r.Encode(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewSplitReport(t *testing.T) {
+	fsys := NewMemFileSystem()
+	start := time.Date(2024, 6, 15, 9, 0, 0, 0, time.Local)
+	writeMemFile(t, fsys, "/photos/a.jpg", []byte("a"), start)
+	writeMemFile(t, fsys, "/photos/b.jpg", []byte("a"), start.Add(10*time.Minute))
+	writeMemFile(t, fsys, "/photos/c.jpg", []byte("c"), start.Add(20*time.Minute))
+	writeMemFile(t, fsys, "/photos/d.nef", []byte("raw"), start.Add(25*time.Minute))
+
+	cfg := &Config{
+		BasePath:          "/photos",
+		Delta:             30 * time.Minute,
+		Mode:              ModeRun,
+		MinGroupSize:      1,
+		DetectDuplicates:  true,
+		SeparateOrphanRaw: true,
+		FS:                fsys,
+	}
+	result, err := Split(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+	result.Stats.AddError(&PicsplitError{Type: ErrTypePermission, Op: "read_file", Path: "/photos/x.jpg", Err: errors.New("denied")})
+
+	var buf bytes.Buffer
+	if err := NewSplitReport(cfg.Mode, result, nil).Encode(&buf); err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	if report.Version != ReportVersion || report.Command != "split" || report.Status != ReportStatusCompleted {
+		t.Fatalf("header = version %d, command %q, status %q", report.Version, report.Command, report.Status)
+	}
+
+	split := report.Split
+	if split == nil {
+		t.Fatal("split section missing")
+	}
+	folder := start.Format(dateFormatPattern)
+	if len(split.Groups) != 1 || split.Groups[0].Folder != folder || len(split.Groups[0].Files) != 4 {
+		t.Fatalf("groups = %+v, want one group %q with 4 files", split.Groups, folder)
+	}
+	if !split.Groups[0].Start.Equal(start) || !split.Groups[0].End.Equal(start.Add(25*time.Minute)) {
+		t.Errorf("group range = %v - %v", split.Groups[0].Start, split.Groups[0].End)
+	}
+	if media := split.Groups[0].Media; len(media) != 4 || media[0].Name != "a.jpg" || media[0].DateSource != "ModTime" || !media[3].DateTime.Equal(start.Add(25*time.Minute)) {
+		t.Errorf("group media = %+v, want the metadata of the 4 files", media)
+	}
+	if split.Duplicates[filepath.Join("/photos", "b.jpg")] != filepath.Join("/photos", "a.jpg") {
+		t.Errorf("duplicates = %v, want b.jpg -> a.jpg", split.Duplicates)
+	}
+	if split.OrphanRaw != 1 || len(split.OrphanRawFiles) != 1 || split.OrphanRawFiles[0] != filepath.Join(folder, orphanFolderName, "d.nef") {
+		t.Errorf("orphan RAW = %d %v", split.OrphanRaw, split.OrphanRawFiles)
+	}
+	if len(split.Errors) != 1 || split.Errors[0].Suggestion != "chmod +r /photos/x.jpg" || !split.Errors[0].Critical {
+		t.Errorf("errors = %+v, want one critical error with its suggestion", split.Errors)
+	}
+	if split.Files.Total != 4 || split.Bytes != 6 {
+		t.Errorf("files = %+v, bytes = %d", split.Files, split.Bytes)
+	}
+}
+
+func TestNewSplitReport_Status(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *SplitResult
+		err    error
+		want   string
+	}{
+		{"completed", &SplitResult{Stats: &ProcessingStats{}}, nil, ReportStatusCompleted},
+		{"failed", nil, errors.New("boom"), ReportStatusFailed},
+		{"canceled", nil, context.Canceled, ReportStatusInterrupted},
+		{"interrupted stats", &SplitResult{Stats: &ProcessingStats{Interrupted: true}}, context.Canceled, ReportStatusInterrupted},
+		{"validation", &SplitResult{Validation: &ValidationReport{}}, nil, ReportStatusCompleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewSplitReport(ModeRun, tt.result, tt.err)
+			if report.Status != tt.want {
+				t.Errorf("Status = %q, want %q", report.Status, tt.want)
+			}
+			if (tt.err != nil) != (report.Error != "") {
+				t.Errorf("Error = %q for run error %v", report.Error, tt.err)
+			}
+		})
+	}
+}
+
+func TestNewMergeReport(t *testing.T) {
+	fsys := NewMemFileSystem()
+	writeMemFile(t, fsys, "/lib/a/photo.jpg", []byte("a"), time.Now())
+	writeMemFile(t, fsys, "/lib/b/photo.jpg", []byte("b"), time.Now())
+
+	validation, err := ValidateMerge(&MergeConfig{
+		SourceFolders: []string{"/lib/a"},
+		TargetFolder:  "/lib/b",
+		Mode:          ModeValidate,
+		FS:            fsys,
+	})
+	if err != nil {
+		t.Fatalf("ValidateMerge() error: %v", err)
+	}
+
+	report := NewMergeReport(validation, nil)
+	merge := report.MergeValidation
+	if report.Command != "merge" || merge == nil {
+		t.Fatalf("report = %+v, want merge validation", report)
+	}
+	if merge.TotalFiles != 1 || merge.Conflicts != 1 || merge.SourceBreakdown["/lib/a"] != 1 {
+		t.Errorf("merge validation = %+v, want 1 file, 1 conflict", merge)
+	}
+}
@@ -0,0 +1,570 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Routing rules send some files elsewhere before they are grouped into events (v2.10.0+)
+// Example rules file (JSON):
+//
+//	{"rules": [
+//	  {"name": "screenshots", "match": {"ext": ["png"], "exif": {"make": ""}}, "action": "route", "folder": "Screenshots"},
+//	  {"name": "whatsapp", "match": {"name": "IMG-*-WA*"}, "action": "route", "folder": "WhatsApp"},
+//	  {"name": "drone", "match": {"exif": {"make": "DJI"}}, "action": "group", "folder": "Drone", "delta": "2h"},
+//	  {"name": "thumbnails", "match": {"max_size": "50KB"}, "action": "skip"}
+//	]}
+//
+// Rules are evaluated in order, the first matching rule wins. Files matching no rule are grouped as usual.
+
+// Rule actions
+const (
+	RuleActionRoute   = "route"   // Move the files to a fixed folder, whatever their count
+	RuleActionGroup   = "group"   // Group the files into events with the rule's own delta and folder template
+	RuleActionExclude = "exclude" // Keep the files out of event grouping: they stay at the root
+	RuleActionSkip    = "skip"    // Leave the files untouched
+)
+
+// Fields of FileMetadata usable in a rule "exif" condition
+const (
+	ruleFieldMake         = "make"
+	ruleFieldModel        = "model"
+	ruleFieldSerialNumber = "serial_number"
+	ruleFieldLensModel    = "lens_model"
+	ruleFieldVideoCodec   = "video_codec"
+	ruleFieldLabel        = "label"
+	ruleFieldKeywords     = "keywords" // Matches when one of the keywords matches
+)
+
+// ruleDateLayouts are the accepted formats of "after" and "before", date only values use local time
+var ruleDateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// RuleHit counts the files matched by a routing rule (v2.10.0+)
+type RuleHit struct {
+	Rule   string // Rule name
+	Action string // route, group, exclude or skip
+	Files  int    // Files matched by the rule (and by no rule before it)
+}
+
+// rulesFile is the JSON document read from --rules
+type rulesFile struct {
+	Rules []routingRule `json:"rules"`
+}
+
+// routingRule is a match condition and the action applied to the matching files
+type routingRule struct {
+	template []templatePart // Parsed Template (group action)
+
+	Match    ruleMatch `json:"match"`
+	Name     string    `json:"name"`
+	Action   string    `json:"action"`
+	Folder   string    `json:"folder,omitempty"`   // route: destination folder, group: parent folder of the events
+	Template string    `json:"template,omitempty"` // group: event folder name (tokens: date, camera, make)
+	Delta    string    `json:"delta,omitempty"`    // group: gap starting a new event (default: --delta)
+
+	delta time.Duration // Parsed Delta (group action)
+}
+
+// ruleMatch holds the conditions of a rule, all set conditions must match
+type ruleMatch struct {
+	after  time.Time // Parsed After
+	before time.Time // Parsed Before
+
+	EXIF map[string]string `json:"exif,omitempty"` // Field -> glob, "" matches a missing value
+	Ext  []string          `json:"ext,omitempty"`  // Extensions without dot, case-insensitive
+	Name string            `json:"name,omitempty"` // Glob on the file name, case-insensitive
+
+	MinResolution string `json:"min_resolution,omitempty"` // "WIDTHxHEIGHT", whatever the orientation
+	MaxResolution string `json:"max_resolution,omitempty"`
+	After         string `json:"after,omitempty"`  // Files taken at or after this date
+	Before        string `json:"before,omitempty"` // Files taken before this date
+
+	MinSize byteSize `json:"min_size,omitempty"` // Bytes, or a string like "10MB"
+	MaxSize byteSize `json:"max_size,omitempty"`
+
+	minResolution resolution
+	maxResolution resolution
+}
+
+// resolution is an image size, sides are stored long side first to ignore orientation
+type resolution struct {
+	long, short int
+}
+
+// byteSize is a file size read from a JSON number or a string like "512KB", "10MB", "1.5GB"
+type byteSize int64
+
+// printRuleHits logs the files matched by each rule, rules matching no file included
+func printRuleHits(hits []RuleHit) {
+	for _, hit := range hits {
+		slog.Info("rule matched",
+			"rule", hit.Rule,
+			"action", hit.Action,
+			"files", hit.Files)
+	}
+}
+
+// routingRules is a parsed rules file
+type routingRules struct {
+	rules []routingRule
+}
+
+// routing is the outcome of the rules on the scanned files
+type routing struct {
+	fixed    []Group        // route action: one folder per rule, created whatever MinGroupSize says
+	events   []Group        // group action: events built with the rule's delta and template
+	excluded []Group        // exclude action: files left at the root
+	files    []FileMetadata // Files matched by no rule, grouped as usual
+	hits     []RuleHit      // Files matched by each rule, in rules order
+}
+
+// loadRules reads and validates a rules file from fsys
+func loadRules(fsys FileSystem, path string) (*routingRules, error) {
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	return parseRules(data)
+}
+
+// parseRules parses and validates the JSON content of a rules file
+func parseRules(data []byte) (*routingRules, error) {
+	var file rulesFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid rules file: %w", err)
+	}
+	if len(file.Rules) == 0 {
+		return nil, errors.New("rules file has no rule")
+	}
+
+	names := make(map[string]bool, len(file.Rules))
+	for i := range file.Rules {
+		rule := &file.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate rule name: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+	}
+
+	return &routingRules{rules: file.Rules}, nil
+}
+
+// compile validates a rule and parses its values
+func (r *routingRule) compile() error {
+	if err := r.Match.compile(); err != nil {
+		return err
+	}
+
+	switch r.Action {
+	case RuleActionRoute:
+		if r.Folder == "" {
+			return errors.New("route action requires a folder")
+		}
+	case RuleActionGroup:
+		if r.Delta != "" {
+			delta, err := time.ParseDuration(r.Delta)
+			if err != nil || delta <= 0 {
+				return fmt.Errorf("invalid delta: %s", r.Delta)
+			}
+			r.delta = delta
+		}
+		if r.Template != "" {
+			parts, err := parseFolderTemplate(r.Template)
+			if err != nil {
+				return err
+			}
+			r.template = parts
+		}
+	case RuleActionExclude, RuleActionSkip:
+	case "":
+		return errors.New("missing action")
+	default:
+		return fmt.Errorf("unknown action: %s (must be: route, group, exclude, or skip)", r.Action)
+	}
+
+	if r.Action != RuleActionGroup && (r.Delta != "" || r.Template != "") {
+		return errors.New("delta and template are only allowed with the group action")
+	}
+	if r.Folder != "" {
+		if r.Action != RuleActionRoute && r.Action != RuleActionGroup {
+			return fmt.Errorf("folder is not allowed with the %s action", r.Action)
+		}
+		if !filepath.IsLocal(r.Folder) {
+			return fmt.Errorf("folder must be relative to the processed folder: %s", r.Folder)
+		}
+	}
+	return nil
+}
+
+// compile validates the conditions and parses their values
+func (m *ruleMatch) compile() error {
+	if m.Name != "" {
+		if _, err := filepath.Match(strings.ToLower(m.Name), ""); err != nil {
+			return fmt.Errorf("invalid name pattern: %s", m.Name)
+		}
+	}
+
+	for i, ext := range m.Ext {
+		m.Ext[i] = strings.ToLower(strings.TrimPrefix(ext, "."))
+	}
+
+	for field, pattern := range m.EXIF {
+		switch field {
+		case ruleFieldMake, ruleFieldModel, ruleFieldSerialNumber, ruleFieldLensModel,
+			ruleFieldVideoCodec, ruleFieldLabel, ruleFieldKeywords:
+		default:
+			return fmt.Errorf("unknown exif field: %s", field)
+		}
+		if _, err := filepath.Match(strings.ToLower(pattern), ""); err != nil {
+			return fmt.Errorf("invalid %s pattern: %s", field, pattern)
+		}
+	}
+
+	if m.MinSize < 0 || m.MaxSize < 0 || (m.MaxSize > 0 && m.MinSize > m.MaxSize) {
+		return errors.New("invalid size range")
+	}
+
+	var err error
+	if m.minResolution, err = parseResolution(m.MinResolution); err != nil {
+		return err
+	}
+	if m.maxResolution, err = parseResolution(m.MaxResolution); err != nil {
+		return err
+	}
+
+	if m.after, err = parseRuleDate(m.After); err != nil {
+		return err
+	}
+	if m.before, err = parseRuleDate(m.Before); err != nil {
+		return err
+	}
+	if !m.after.IsZero() && !m.before.IsZero() && !m.after.Before(m.before) {
+		return errors.New("after must be earlier than before")
+	}
+	return nil
+}
+
+// needsMetadata reports whether the conditions read EXIF or video metadata
+func (m *ruleMatch) needsMetadata() bool {
+	return len(m.EXIF) > 0 || m.MinResolution != "" || m.MaxResolution != "" || m.After != "" || m.Before != ""
+}
+
+// matches checks a file against every set condition
+func (m *ruleMatch) matches(file *FileMetadata) bool {
+	name := strings.ToLower(file.FileInfo.Name())
+
+	if m.Name != "" {
+		if ok, _ := filepath.Match(strings.ToLower(m.Name), name); !ok {
+			return false
+		}
+	}
+	if len(m.Ext) > 0 && !slices.Contains(m.Ext, strings.TrimPrefix(filepath.Ext(name), ".")) {
+		return false
+	}
+
+	size := file.FileInfo.Size()
+	if (m.MinSize > 0 && size < int64(m.MinSize)) || (m.MaxSize > 0 && size > int64(m.MaxSize)) {
+		return false
+	}
+
+	if m.MinResolution != "" || m.MaxResolution != "" {
+		if file.Width == 0 || file.Height == 0 {
+			return false // Unknown resolution
+		}
+		res := newResolution(file.Width, file.Height)
+		if m.MinResolution != "" && (res.long < m.minResolution.long || res.short < m.minResolution.short) {
+			return false
+		}
+		if m.MaxResolution != "" && (res.long > m.maxResolution.long || res.short > m.maxResolution.short) {
+			return false
+		}
+	}
+
+	if !m.after.IsZero() && file.DateTime.Before(m.after) {
+		return false
+	}
+	if !m.before.IsZero() && !file.DateTime.Before(m.before) {
+		return false
+	}
+
+	for field, pattern := range m.EXIF {
+		if !matchField(file, field, strings.ToLower(pattern)) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchField checks a metadata field against a lowercase glob, "" matches a missing value
+func matchField(file *FileMetadata, field, pattern string) bool {
+	var value string
+	switch field {
+	case ruleFieldMake:
+		value = file.Make
+	case ruleFieldModel:
+		value = file.Model
+	case ruleFieldSerialNumber:
+		value = file.SerialNumber
+	case ruleFieldLensModel:
+		value = file.LensModel
+	case ruleFieldVideoCodec:
+		value = file.VideoCodec
+	case ruleFieldLabel:
+		value = file.Label
+	case ruleFieldKeywords:
+		if pattern == "" {
+			return len(file.Keywords) == 0
+		}
+		for _, keyword := range file.Keywords {
+			if ok, _ := filepath.Match(pattern, strings.ToLower(keyword)); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	if pattern == "" {
+		return strings.TrimSpace(value) == ""
+	}
+	ok, _ := filepath.Match(pattern, strings.ToLower(strings.TrimSpace(value)))
+	return ok
+}
+
+// needsMetadata reports whether a rule reads EXIF or video metadata
+func (r *routingRules) needsMetadata() bool {
+	for i := range r.rules {
+		if r.rules[i].Match.needsMetadata() {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the index of the first rule matching a file, -1 when none does
+func (r *routingRules) match(file *FileMetadata) int {
+	for i := range r.rules {
+		if r.rules[i].Match.matches(file) {
+			return i
+		}
+	}
+	return -1
+}
+
+// count returns the files matched by each rule, without routing them
+func (r *routingRules) count(files []FileMetadata) []RuleHit {
+	hits := r.newHits()
+	for i := range files {
+		if idx := r.match(&files[i]); idx >= 0 {
+			hits[idx].Files++
+		}
+	}
+	return hits
+}
+
+// newHits returns an empty hit count for every rule
+func (r *routingRules) newHits() []RuleHit {
+	hits := make([]RuleHit, len(r.rules))
+	for i, rule := range r.rules {
+		hits[i] = RuleHit{Rule: rule.Name, Action: rule.Action}
+	}
+	return hits
+}
+
+// route applies the rules to the scanned files; without rules every file is left for grouping
+func (r *routingRules) route(cfg *Config, ctx *executionContext, files []FileMetadata) *routing {
+	if r == nil {
+		return &routing{files: files}
+	}
+
+	result := &routing{hits: r.newHits()}
+	matched := make([][]FileMetadata, len(r.rules))
+	for _, file := range files {
+		idx := r.match(&file)
+		if idx < 0 {
+			result.files = append(result.files, file)
+			continue
+		}
+		matched[idx] = append(matched[idx], file)
+		result.hits[idx].Files++
+	}
+
+	for i, rule := range r.rules {
+		ruleFiles := matched[i]
+		if len(ruleFiles) == 0 {
+			continue
+		}
+		sortFilesByDateTime(ruleFiles)
+
+		ctx.log.Info("files matched rule",
+			"rule", rule.Name,
+			"action", rule.Action,
+			"files", len(ruleFiles))
+
+		switch rule.Action {
+		case RuleActionRoute:
+			result.fixed = append(result.fixed, Group{Folder: rule.Folder, Files: ruleFiles})
+		case RuleActionGroup:
+			result.events = append(result.events, rule.groups(cfg, ruleFiles)...)
+		case RuleActionExclude:
+			result.excluded = append(result.excluded, Group{Files: ruleFiles, AtRoot: true})
+		case RuleActionSkip:
+			for _, file := range ruleFiles {
+				ctx.log.Debug("file skipped by rule", "file", file.FileInfo.Name(), "rule", rule.Name)
+			}
+		}
+	}
+	return result
+}
+
+// groups splits the files of a group rule into events, sorted files expected
+func (r *routingRule) groups(cfg *Config, files []FileMetadata) []Group {
+	delta := r.delta
+	if delta == 0 {
+		delta = cfg.Delta
+	}
+
+	groups := groupFilesByGaps(files, delta)
+	for i := range groups {
+		if r.template != nil {
+			groups[i].Folder = renderFolderTemplate(r.template, groups[i].Files[0])
+		}
+		groups[i].Folder = filepath.Join(r.Folder, groups[i].Folder)
+	}
+	return groups
+}
+
+// parseFolderTemplate parses an event folder template, "/" creates subfolders
+// Example: "{make}/{date:2006-01-02}" puts a DJI flight of June 15 in DJI/2024-06-15
+func parseFolderTemplate(tmpl string) ([]templatePart, error) {
+	if !filepath.IsLocal(tmpl) {
+		return nil, fmt.Errorf("folder template must be relative to the processed folder: %s", tmpl)
+	}
+
+	return parseTemplateParts(tmpl, "folder template", func(token, _ string) error {
+		switch token {
+		case tokenDate, tokenCamera, tokenMake:
+			return nil
+		default:
+			return fmt.Errorf("unknown folder template token: {%s}", token)
+		}
+	})
+}
+
+// renderFolderTemplate renders a folder template for the first file of an event
+func renderFolderTemplate(parts []templatePart, file FileMetadata) string {
+	var sb strings.Builder
+
+	for _, part := range parts {
+		switch part.token {
+		case "":
+			sb.WriteString(part.literal)
+		case tokenDate:
+			layout := part.arg
+			if layout == "" {
+				layout = dateFormatPattern
+			}
+			sb.WriteString(sanitizeFolderName(file.DateTime.Format(layout)))
+		case tokenCamera:
+			sb.WriteString(cameraNamePart(file.Model))
+		case tokenMake:
+			sb.WriteString(cameraNamePart(file.Make))
+		}
+	}
+
+	return filepath.Clean(sb.String())
+}
+
+// parseResolution parses "WIDTHxHEIGHT", an empty value gives a zero resolution
+func parseResolution(value string) (resolution, error) {
+	if value == "" {
+		return resolution{}, nil
+	}
+
+	w, h, ok := strings.Cut(strings.ToLower(value), "x")
+	width, errW := strconv.Atoi(strings.TrimSpace(w))
+	height, errH := strconv.Atoi(strings.TrimSpace(h))
+	if !ok || errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return resolution{}, fmt.Errorf("invalid resolution: %s (expected WIDTHxHEIGHT, e.g. 1920x1080)", value)
+	}
+	return newResolution(width, height), nil
+}
+
+// newResolution orders the sides of an image size
+func newResolution(width, height int) resolution {
+	if width < height {
+		width, height = height, width
+	}
+	return resolution{long: width, short: height}
+}
+
+// parseRuleDate parses an "after" or "before" date, an empty value gives the zero time
+func parseRuleDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range ruleDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %s (expected 2006-01-02 or RFC 3339)", value)
+}
+
+// UnmarshalJSON reads a size from a number of bytes or a string with a B, KB, MB, GB or TB unit (1024-based)
+func (s *byteSize) UnmarshalJSON(data []byte) error {
+	var bytes int64
+	if err := json.Unmarshal(data, &bytes); err == nil {
+		*s = byteSize(bytes)
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("invalid size: %s", data)
+	}
+	size, err := parseByteSize(text)
+	if err != nil {
+		return err
+	}
+	*s = size
+	return nil
+}
+
+// parseByteSize parses sizes like "512", "512KB", "10 MB" or "1.5GB"
+func parseByteSize(text string) (byteSize, error) {
+	value := strings.ToUpper(strings.TrimSpace(text))
+	multiplier := 1.0
+	for i, unit := range []string{"TB", "GB", "MB", "KB"} {
+		if strings.HasSuffix(value, unit) {
+			multiplier = float64(int64(1) << (10 * (4 - i)))
+			value = strings.TrimSuffix(value, unit)
+			break
+		}
+	}
+	if multiplier == 1 {
+		value = strings.TrimSuffix(value, "B")
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size: %s (e.g. 512KB, 10MB)", text)
+	}
+	return byteSize(number * multiplier), nil
+}
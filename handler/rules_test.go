@@ -0,0 +1,341 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeRulesFile writes a rules file in a temporary directory and returns its path
+func writeRulesFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write rules file: %v", err)
+	}
+	return path
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		wantErr string
+	}{
+		{"route", `{"rules": [{"match": {"ext": ["png"]}, "action": "route", "folder": "Screenshots"}]}`, ""},
+		{"group with delta and template", `{"rules": [{"match": {"exif": {"make": "DJI"}}, "action": "group", "folder": "Drone", "delta": "2h", "template": "{date:2006-01-02}"}]}`, ""},
+		{"exclude and skip", `{"rules": [{"match": {"max_size": "50KB"}, "action": "skip"}, {"match": {"name": "*.tmp.jpg"}, "action": "exclude"}]}`, ""},
+		{"no rule", `{"rules": []}`, "no rule"},
+		{"unknown field", `{"rules": [{"match": {"colour": "red"}, "action": "skip"}]}`, "unknown field"},
+		{"missing action", `{"rules": [{"match": {}}]}`, "missing action"},
+		{"unknown action", `{"rules": [{"action": "delete"}]}`, "unknown action"},
+		{"route without folder", `{"rules": [{"action": "route"}]}`, "requires a folder"},
+		{"folder outside base path", `{"rules": [{"action": "route", "folder": "../elsewhere"}]}`, "relative"},
+		{"folder with skip", `{"rules": [{"action": "skip", "folder": "Trash"}]}`, "not allowed"},
+		{"delta with route", `{"rules": [{"action": "route", "folder": "A", "delta": "1h"}]}`, "only allowed with the group action"},
+		{"invalid delta", `{"rules": [{"action": "group", "delta": "soon"}]}`, "invalid delta"},
+		{"invalid template token", `{"rules": [{"action": "group", "template": "{seq}"}]}`, "unknown folder template token"},
+		{"unknown exif field", `{"rules": [{"match": {"exif": {"shutter": "1/100"}}, "action": "skip"}]}`, "unknown exif field"},
+		{"invalid name pattern", `{"rules": [{"match": {"name": "[a"}, "action": "skip"}]}`, "invalid name pattern"},
+		{"invalid size", `{"rules": [{"match": {"min_size": "big"}, "action": "skip"}]}`, "invalid size"},
+		{"invalid size range", `{"rules": [{"match": {"min_size": "2MB", "max_size": "1MB"}, "action": "skip"}]}`, "invalid size range"},
+		{"invalid resolution", `{"rules": [{"match": {"min_resolution": "4K"}, "action": "skip"}]}`, "invalid resolution"},
+		{"invalid date", `{"rules": [{"match": {"after": "yesterday"}, "action": "skip"}]}`, "invalid date"},
+		{"empty date range", `{"rules": [{"match": {"after": "2024-06-15", "before": "2024-06-01"}, "action": "skip"}]}`, "after must be earlier"},
+		{"duplicate name", `{"rules": [{"name": "a", "action": "skip"}, {"name": "a", "action": "exclude"}]}`, "duplicate rule name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRules([]byte(tt.rules))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("parseRules() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseRules() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    byteSize
+		wantErr bool
+	}{
+		{"512", 512, false},
+		{"512B", 512, false},
+		{"50KB", 50 << 10, false},
+		{"10 mb", 10 << 20, false},
+		{"1.5GB", 3 << 29, false},
+		{"2TB", 2 << 40, false},
+		{"", 0, true},
+		{"-1MB", 0, true},
+		{"ten", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseByteSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseByteSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseByteSize(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	shot := time.Date(2024, 6, 15, 14, 30, 0, 0, time.Local)
+	photo := FileMetadata{
+		FileInfo: memFileInfo{name: "DJI_0042.JPG", size: 8 << 20},
+		DateTime: shot,
+		Make:     "DJI",
+		Model:    "FC3582",
+		Width:    4032,
+		Height:   3024,
+		Keywords: []string{"Beach", "Summer"},
+	}
+	screenshot := FileMetadata{
+		FileInfo: memFileInfo{name: "Screenshot 2024-06-15.png", size: 300 << 10},
+		DateTime: shot,
+	}
+
+	tests := []struct {
+		name  string
+		match string
+		file  FileMetadata
+		want  bool
+	}{
+		{"empty match", `{}`, photo, true},
+		{"name glob, case-insensitive", `{"name": "dji_*"}`, photo, true},
+		{"name glob mismatch", `{"name": "IMG-*-WA*"}`, photo, false},
+		{"extension", `{"ext": ["PNG", ".heic"]}`, screenshot, true},
+		{"extension mismatch", `{"ext": ["png"]}`, photo, false},
+		{"make", `{"exif": {"make": "dji"}}`, photo, true},
+		{"missing make", `{"exif": {"make": ""}}`, screenshot, true},
+		{"missing make mismatch", `{"exif": {"make": ""}}`, photo, false},
+		{"model glob", `{"exif": {"model": "FC*"}}`, photo, true},
+		{"keyword", `{"exif": {"keywords": "beach"}}`, photo, true},
+		{"keyword mismatch", `{"exif": {"keywords": "winter"}}`, photo, false},
+		{"min size", `{"min_size": "5MB"}`, photo, true},
+		{"max size", `{"max_size": "1MB"}`, photo, false},
+		{"min resolution, rotated", `{"min_resolution": "3000x4000"}`, photo, true},
+		{"max resolution", `{"max_resolution": "1920x1080"}`, photo, false},
+		{"unknown resolution", `{"min_resolution": "640x480"}`, screenshot, false},
+		{"after", `{"after": "2024-06-15"}`, photo, true},
+		{"before is exclusive", `{"before": "2024-06-15T14:30:00"}`, photo, false},
+		{"date range", `{"after": "2024-06-01", "before": "2024-07-01"}`, photo, true},
+		{"all conditions", `{"ext": ["png"], "exif": {"make": ""}, "max_size": "1MB"}`, screenshot, true},
+		{"one condition fails", `{"ext": ["png"], "exif": {"make": "Apple"}}`, screenshot, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := parseRules([]byte(`{"rules": [{"match": ` + tt.match + `, "action": "skip"}]}`))
+			if err != nil {
+				t.Fatalf("parseRules() error: %v", err)
+			}
+			if got := rules.rules[0].Match.matches(&tt.file); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderFolderTemplate(t *testing.T) {
+	file := FileMetadata{
+		DateTime: time.Date(2024, 6, 15, 14, 30, 0, 0, time.Local),
+		Make:     "DJI",
+		Model:    "Mini 4 Pro",
+	}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{date}", "2024 - 0615 - 1430"},
+		{"{date:2006-01-02}", "2024-06-15"},
+		{"{make}/{camera}", filepath.Join("DJI", "Mini4Pro")},
+		{"Flight {date:02.01.2006}", "Flight 15.06.2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			parts, err := parseFolderTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("parseFolderTemplate() error: %v", err)
+			}
+			if got := renderFolderTemplate(parts, file); got != tt.want {
+				t.Errorf("renderFolderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit_Rules(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+
+	// Regular event
+	createTestFile(t, tmpDir, "IMG_0001.JPG", baseTime)
+	createTestFile(t, tmpDir, "IMG_0002.JPG", baseTime.Add(5*time.Minute))
+	// Messaging app images spread over days: one folder whatever the dates
+	createTestFile(t, tmpDir, "IMG-20240601-WA0001.jpg", baseTime.AddDate(0, 0, -14))
+	createTestFile(t, tmpDir, "IMG-20240615-WA0002.jpg", baseTime.Add(time.Minute))
+	// Screenshots stay at root, thumbnails are skipped
+	createTestFile(t, tmpDir, "Screenshot_1.png", baseTime.Add(2*time.Minute))
+	createTestFile(t, tmpDir, "thumb_0001.jpg", baseTime.Add(3*time.Minute))
+	// Timelapse: its own delta splits two sessions
+	createTestFile(t, tmpDir, "TL_0001.JPG", baseTime)
+	createTestFile(t, tmpDir, "TL_0002.JPG", baseTime.Add(20*time.Minute))
+	createTestFile(t, tmpDir, "TL_0003.JPG", baseTime.Add(3*time.Hour))
+
+	rulesPath := writeRulesFile(t, `{"rules": [
+		{"name": "whatsapp", "match": {"name": "IMG-*-WA*"}, "action": "route", "folder": "WhatsApp"},
+		{"name": "screenshots", "match": {"ext": ["png"], "exif": {"make": ""}}, "action": "exclude"},
+		{"name": "thumbnails", "match": {"name": "thumb_*"}, "action": "skip"},
+		{"name": "timelapse", "match": {"name": "TL_*"}, "action": "group", "folder": "Timelapse", "delta": "1h", "template": "{date:2006-01-02 1504}"},
+		{"name": "unused", "match": {"ext": ["mkv"]}, "action": "skip"}
+	]}`)
+
+	cfg := &Config{
+		BasePath:        tmpDir,
+		Delta:           30 * time.Minute,
+		Mode:            ModeRun,
+		MinGroupSize:    1,
+		CustomPhotoExts: []string{"png"},
+		RulesFile:       rulesPath,
+	}
+
+	result, err := Split(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	assertExists(t, filepath.Join(tmpDir, baseTime.Format(dateFormatPattern), "IMG_0001.JPG"))
+	assertExists(t, filepath.Join(tmpDir, baseTime.Format(dateFormatPattern), "IMG_0002.JPG"))
+	assertExists(t, filepath.Join(tmpDir, "WhatsApp", "IMG-20240601-WA0001.jpg"))
+	assertExists(t, filepath.Join(tmpDir, "WhatsApp", "IMG-20240615-WA0002.jpg"))
+	assertExists(t, filepath.Join(tmpDir, "Screenshot_1.png"))
+	assertExists(t, filepath.Join(tmpDir, "thumb_0001.jpg"))
+	assertExists(t, filepath.Join(tmpDir, "Timelapse", "2024-06-15 1200", "TL_0001.JPG"))
+	assertExists(t, filepath.Join(tmpDir, "Timelapse", "2024-06-15 1200", "TL_0002.JPG"))
+	assertExists(t, filepath.Join(tmpDir, "Timelapse", "2024-06-15 1500", "TL_0003.JPG"))
+
+	wantHits := []RuleHit{
+		{Rule: "whatsapp", Action: RuleActionRoute, Files: 2},
+		{Rule: "screenshots", Action: RuleActionExclude, Files: 1},
+		{Rule: "thumbnails", Action: RuleActionSkip, Files: 1},
+		{Rule: "timelapse", Action: RuleActionGroup, Files: 3},
+		{Rule: "unused", Action: RuleActionSkip, Files: 0},
+	}
+	stats := result.Stats
+	if len(stats.RuleHits) != len(wantHits) {
+		t.Fatalf("RuleHits = %+v, want %+v", stats.RuleHits, wantHits)
+	}
+	for i, want := range wantHits {
+		if stats.RuleHits[i] != want {
+			t.Errorf("RuleHits[%d] = %+v, want %+v", i, stats.RuleHits[i], want)
+		}
+	}
+	if stats.TotalFiles != 8 {
+		t.Errorf("TotalFiles = %d, want 8 (skipped file excluded)", stats.TotalFiles)
+	}
+}
+
+func TestSplit_RouteIgnoresMinGroupSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	createTestFile(t, tmpDir, "DJI_0001.MP4", baseTime)
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		MinGroupSize: 5,
+		NoMoveMovie:  true,
+		RulesFile:    writeRulesFile(t, `{"rules": [{"match": {"name": "DJI_*"}, "action": "route", "folder": "Drone"}]}`),
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+	assertExists(t, filepath.Join(tmpDir, "Drone", "DJI_0001.MP4"))
+}
+
+func TestSplit_RulesOnMemFileSystem(t *testing.T) {
+	// The rules file is read from the injected file system, like the media
+	fsys := NewMemFileSystem()
+	baseTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	writeMemFile(t, fsys, "/photos/DJI_0001.JPG", []byte("drone"), baseTime)
+	writeMemFile(t, fsys, "/config/rules.json",
+		[]byte(`{"rules": [{"match": {"name": "DJI_*"}, "action": "route", "folder": "Drone"}]}`), baseTime)
+
+	cfg := &Config{
+		BasePath:     "/photos",
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		MinGroupSize: 1,
+		RulesFile:    "/config/rules.json",
+		FS:           fsys,
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+	assertMemExists(t, fsys, "/photos/Drone/DJI_0001.JPG")
+}
+
+func TestValidate_RuleHits(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"IMG-20240615-WA0001.jpg", "IMG-20240615-WA0002.jpg", "IMG_0001.JPG"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("test content"), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	cfg := &Config{
+		BasePath:  tmpDir,
+		Delta:     30 * time.Minute,
+		Mode:      ModeValidate,
+		UseEXIF:   true,
+		RulesFile: writeRulesFile(t, `{"rules": [{"name": "whatsapp", "match": {"name": "IMG-*-WA*"}, "action": "route", "folder": "WhatsApp"}]}`),
+	}
+
+	report, err := Validate(cfg)
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	want := RuleHit{Rule: "whatsapp", Action: RuleActionRoute, Files: 2}
+	if len(report.RuleHits) != 1 || report.RuleHits[0] != want {
+		t.Errorf("RuleHits = %+v, want [%+v]", report.RuleHits, want)
+	}
+
+	// No file was moved
+	assertExists(t, filepath.Join(tmpDir, "IMG-20240615-WA0001.jpg"))
+}
+
+func TestConfigValidate_Rules(t *testing.T) {
+	cfg := DefaultConfig(t.TempDir())
+
+	cfg.RulesFile = filepath.Join(t.TempDir(), "missing.json")
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should fail with a missing rules file")
+	}
+
+	cfg.RulesFile = writeRulesFile(t, `{"rules": [{"action": "move"}]}`)
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "unknown action") {
+		t.Errorf("Validate() error = %v, want an unknown action error", err)
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -20,24 +21,23 @@ const (
 	dateFormatPattern    = "2006 - 0102 - 1504"
 )
 
-// fileGroup represents a group of files detected as an event
-type fileGroup struct {
-	folderName string
-	firstFile  FileMetadata
-	files      []FileMetadata
-}
-
 var (
 	// Custom errors
 	ErrNotDirectory = errors.New("path is not a directory")
 	ErrInvalidDelta = errors.New("delta must be positive")
+
+	// ErrDestinationExists is returned when the destination name is taken and --on-collision is fail
+	ErrDestinationExists = errors.New("destination file already exists")
+
+	// ErrNoCheckpoint is returned by --resume when no interrupted run left a checkpoint
+	ErrNoCheckpoint = errors.New("no checkpoint to resume")
 )
 
 // isOrganizedFolder checks if we're running picsplit on an already organized folder
 // Two cases:
 // 1. The folder itself has a date-formatted name (e.g., "2024 - 1220 - 0900")
 // 2. The folder contains subdirectories with date-formatted names
-func isOrganizedFolder(basePath string) bool {
+func isOrganizedFolder(fsys FileSystem, basePath string) bool {
 	// Resolve absolute path to get real folder name
 	absPath, err := filepath.Abs(basePath)
 	if err != nil {
@@ -52,7 +52,7 @@ func isOrganizedFolder(basePath string) bool {
 	if len(folderName) >= 18 && len(folderName) <= 19 {
 		slog.Debug("folder name length matches", "len", len(folderName))
 		if parsed, err := time.Parse(dateFormatPattern, folderName); err == nil {
-			slog.Info("current folder is date-formatted - using orphan refresh mode", "folder", folderName, "parsed", parsed)
+			slog.Debug("current folder is date-formatted - using orphan refresh mode", "folder", folderName, "parsed", parsed)
 			return true
 		} else {
 			slog.Debug("parse failed", "folder", folderName, "error", err)
@@ -60,7 +60,7 @@ func isOrganizedFolder(basePath string) bool {
 	}
 
 	// Case 2: Check if folder contains date-formatted subdirectories
-	entries, err := os.ReadDir(basePath)
+	entries, err := fsys.ReadDir(basePath)
 	if err != nil {
 		slog.Debug("failed to read directory for organized check", "path", basePath, "error", err)
 		return false
@@ -101,67 +101,66 @@ func isOrganizedFolder(basePath string) bool {
 }
 
 // collectMediaFilesWithMetadata retrieves all media files with their EXIF/video metadata
-func collectMediaFilesWithMetadata(cfg *Config, ctx *executionContext) ([]FileMetadata, error) {
-	entries, err := os.ReadDir(cfg.BasePath)
+func collectMediaFilesWithMetadata(runCtx context.Context, cfg *Config, ctx *executionContext) ([]FileMetadata, error) {
+	entries, err := ctx.fs.ReadDir(cfg.BasePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read directory: %w", err)
 	}
 
-	var mediaFiles []FileMetadata
-	var exifFailCount int
-
+	var candidates []os.DirEntry
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
 
-		info, err := entry.Info()
-		if err != nil {
-			slog.Warn("failed to get file info", "file", entry.Name(), "error", err)
-			continue
-		}
-
 		// Use context to check if file is a media file
-		if !ctx.isPhoto(info.Name()) && !ctx.isMovie(info.Name()) {
-			slog.Debug("skipping file with unknown extension", "file", info.Name())
+		if !ctx.isPhoto(entry.Name()) && !ctx.isMovie(entry.Name()) {
+			ctx.log.Debug("skipping file with unknown extension", "file", entry.Name())
 			continue
 		}
 
-		filePath := filepath.Join(cfg.BasePath, info.Name())
+		candidates = append(candidates, entry)
+	}
+
+	var mediaFiles []FileMetadata
+	var exifFailCount int
+
+	for i, entry := range candidates {
+		if err := runCtx.Err(); err != nil {
+			return nil, err
+		}
+		ctx.reportProgress(StageScan, i+1, len(candidates))
 
-		// Extract metadata (EXIF/video)
-		var metadata *FileMetadata
-		if cfg.UseEXIF {
-			metadata, err = ExtractMetadata(ctx, filePath)
-			if err != nil || metadata.Source == DateSourceModTime {
-				slog.Debug("failed to extract metadata, using ModTime", "file", info.Name())
+		// Extract metadata (EXIF/video), ModTime without EXIF
+		metadata, err := groupingMetadata(cfg, ctx, filepath.Join(cfg.BasePath, entry.Name()))
+		if err != nil {
+			ctx.log.Warn("failed to get file info", "file", entry.Name(), "error", err)
+			if cfg.UseEXIF {
 				exifFailCount++
 			}
-		} else {
-			// Mode without EXIF: use ModTime directly
-			metadata = &FileMetadata{
-				FileInfo: info,
-				DateTime: info.ModTime(),
-				GPS:      nil,
-				Source:   DateSourceModTime,
-			}
+			continue
 		}
-
-		if metadata != nil {
-			mediaFiles = append(mediaFiles, *metadata)
+		if cfg.UseEXIF && metadata.Source == DateSourceModTime {
+			ctx.log.Debug("failed to extract metadata, using ModTime", "file", entry.Name())
+			exifFailCount++
 		}
+
+		mediaFiles = append(mediaFiles, *metadata)
 	}
 
 	// Selective fallback: files without EXIF use ModTime, others keep their extracted metadata
 	// GPS coordinates are preserved even when DateTime falls back to ModTime
 	if cfg.UseEXIF && exifFailCount > 0 {
-		slog.Warn("files used ModTime fallback",
+		ctx.log.Warn("files used ModTime fallback",
 			"count", exifFailCount,
 			"reason", "EXIF metadata unavailable or corrupted")
 		// Each file keeps its individually extracted metadata (DateTime and GPS)
 		// No global reset - this allows GPS clustering to work with mixed file sets
 	}
 
+	// Files without GPS take their position from the --gpx track (v2.10.0+)
+	ctx.geotagFromTrack(cfg, mediaFiles)
+
 	return mediaFiles, nil
 }
 
@@ -178,16 +177,15 @@ func sortFilesByDateTime(files []FileMetadata) {
 
 // groupFilesByGaps groups files by time gaps
 // A new group starts when gap > delta
-func groupFilesByGaps(files []FileMetadata, delta time.Duration) []fileGroup {
+func groupFilesByGaps(files []FileMetadata, delta time.Duration) []Group {
 	if len(files) == 0 {
 		return nil
 	}
 
-	var groups []fileGroup
+	var groups []Group
 
-	currentGroup := fileGroup{
-		files:     []FileMetadata{files[0]},
-		firstFile: files[0],
+	currentGroup := Group{
+		Files: []FileMetadata{files[0]},
 	}
 
 	for i := 1; i < len(files); i++ {
@@ -195,7 +193,7 @@ func groupFilesByGaps(files []FileMetadata, delta time.Duration) []fileGroup {
 
 		if gap <= delta {
 			// Acceptable gap, continue the group
-			currentGroup.files = append(currentGroup.files, files[i])
+			currentGroup.Files = append(currentGroup.Files, files[i])
 		} else {
 			// Gap too large, finalize current group
 			slog.Debug("gap exceeds delta, creating new group",
@@ -205,106 +203,308 @@ func groupFilesByGaps(files []FileMetadata, delta time.Duration) []fileGroup {
 				"curr_time", files[i].DateTime,
 				"gap", gap,
 				"delta", delta)
-			currentGroup.folderName = currentGroup.firstFile.DateTime.Format(dateFormatPattern)
+			currentGroup.Folder = currentGroup.Files[0].DateTime.Format(dateFormatPattern)
 			groups = append(groups, currentGroup)
 
 			// Start new group
-			currentGroup = fileGroup{
-				files:     []FileMetadata{files[i]},
-				firstFile: files[i],
+			currentGroup = Group{
+				Files: []FileMetadata{files[i]},
 			}
 		}
 	}
 
 	// Add last group
-	currentGroup.folderName = currentGroup.firstFile.DateTime.Format(dateFormatPattern)
+	currentGroup.Folder = currentGroup.Files[0].DateTime.Format(dateFormatPattern)
 	groups = append(groups, currentGroup)
 
 	return groups
 }
 
+// buildGroups groups media files into events: GPS clustering then time gaps (or calendar
+// periods with --group-by) when cfg.UseGPS is set, time gaps or periods only otherwise
+// Also returns the files without GPS assigned to a location with --gps-infer-window
+func buildGroups(runCtx context.Context, cfg *Config, ctx *executionContext, mediaFiles []FileMetadata) ([]Group, []LocationInference, error) {
+	var groups []Group
+	var inferences []LocationInference
+
+	if cfg.UseGPS {
+		// Log GPS coverage analysis
+		var filesWithGPSCount int
+		for _, mf := range mediaFiles {
+			if mf.GPS != nil {
+				filesWithGPSCount++
+			}
+		}
+
+		gpsPercentage := 0.0
+		if len(mediaFiles) > 0 {
+			gpsPercentage = float64(filesWithGPSCount) / float64(len(mediaFiles)) * 100
+		}
+
+		ctx.log.Info("GPS coverage analysis",
+			"files_with_gps", filesWithGPSCount,
+			"total_files", len(mediaFiles),
+			"coverage_pct", fmt.Sprintf("%.1f%%", gpsPercentage))
+
+		if filesWithGPSCount == 0 {
+			ctx.log.Warn("GPS clustering disabled: no files with GPS coordinates",
+				"total_files", len(mediaFiles))
+		}
+
+		// GPS clustering: location FIRST, then time within each location
+		locationClusters, filesWithoutGPS := ClusterByLocation(mediaFiles, cfg.GPSRadius)
+
+		ctx.log.Info("GPS clustering completed",
+			"location_clusters", len(locationClusters),
+			"files_without_gps", len(filesWithoutGPS))
+
+		// Files without GPS shot next to a geotagged one join its location (v2.10.0+)
+		filesWithoutGPS, inferences = inferLocations(locationClusters, filesWithoutGPS, cfg.GPSInferWindow)
+		if len(inferences) > 0 {
+			ctx.log.Info("locations inferred",
+				"count", len(inferences),
+				"window", cfg.GPSInferWindow)
+		}
+
+		// Pre-geocode all locations if geocoding is enabled
+		// This shows progress and prepares location names before processing
+		locationNames := make(map[int]string)
+		if cfg.GPSUseGeocoding && len(locationClusters) > 0 {
+			for i, cluster := range locationClusters {
+				start := time.Now()
+				lat, lon := cluster.Centroid.Lat, cluster.Centroid.Lon
+				var cached bool
+				if cfg.GPSHierarchy {
+					// "Country/Region/City" folders (v2.10.0+)
+					cached = isLocationCached(locationCacheKey(lat, lon, nominatimZoomSuburb))
+					locationNames[i] = reverseGeocodePath(runCtx, cluster.Centroid.Lat, cluster.Centroid.Lon, cfg.GPSUseGeocoding)
+				} else {
+					_, cached = getCachedLocation(geocodeCacheKey(lat, lon))
+					locationNames[i] = reverseGeocode(runCtx, cluster.Centroid.Lat, cluster.Centroid.Lon, cfg.GPSUseGeocoding)
+				}
+				ctx.metrics.observeGeocode(time.Since(start), cached)
+				if err := runCtx.Err(); err != nil {
+					return nil, nil, err
+				}
+				ctx.reportProgress(StageGeocode, i+1, len(locationClusters))
+			}
+		}
+
+		// Name each location cluster (geocoding already done)
+		// Clusters with the same name, such as two clusters of one city with --gps-hierarchy, are merged (v2.10.0+)
+		var locations []LocationCluster
+		var names []string
+		locationIndex := make(map[string]int)
+		for i, cluster := range locationClusters {
+			var locationName string
+			if cfg.GPSUseGeocoding {
+				locationName = locationNames[i]
+			} else {
+				locationName = FormatLocationName(cluster.Centroid)
+			}
+			for j := range inferences {
+				if inferences[j].cluster == i {
+					inferences[j].Location = locationName
+				}
+			}
+
+			if j, ok := locationIndex[locationName]; ok {
+				locations[j].Files = append(locations[j].Files, cluster.Files...)
+				continue
+			}
+			locationIndex[locationName] = len(locations)
+			locations = append(locations, cluster)
+			names = append(names, locationName)
+		}
+		if len(locations) < len(locationClusters) {
+			ctx.log.Info("location clusters merged",
+				"clusters", len(locationClusters),
+				"locations", len(locations))
+		}
+
+		// Now process each location
+		for i, cluster := range locations {
+			locationName := names[i]
+			ctx.log.Debug("processing location cluster", "location", locationName, "files", len(cluster.Files))
+
+			// Group by time gaps or calendar period within this location
+			sortFilesByDateTime(cluster.Files)
+			timeGroups := groupFiles(cfg, ctx, cluster.Files)
+			ctx.log.Debug("location split into time groups", "location", locationName, "time_groups", len(timeGroups))
+
+			// Create a group for each time group
+			for _, timeGroup := range timeGroups {
+				groups = append(groups, Group{
+					Folder: filepath.Join(locationName, timeGroup.Folder),
+					Files:  timeGroup.Files,
+				})
+			}
+		}
+
+		// Process files without GPS
+		if len(filesWithoutGPS) > 0 {
+			// Sort and group by time
+			sortFilesByDateTime(filesWithoutGPS)
+			noGPSGroups := groupFiles(cfg, ctx, filesWithoutGPS)
+
+			// If location clusters exist, create "NoLocation" subfolder
+			// Otherwise, put directly at root (no need for segregation)
+			if len(locationClusters) > 0 {
+				ctx.log.Info("processing files without GPS in folder",
+					"count", len(filesWithoutGPS),
+					"folder", GetNoLocationFolderName())
+				for _, noGPSGroup := range noGPSGroups {
+					folderName := filepath.Join(GetNoLocationFolderName(), noGPSGroup.Folder)
+					groups = append(groups, Group{
+						Folder: folderName,
+						Files:  noGPSGroup.Files,
+					})
+				}
+			} else {
+				ctx.log.Info("processing files without GPS at root (no location clusters)",
+					"count", len(filesWithoutGPS))
+				groups = append(groups, noGPSGroups...)
+			}
+		}
+	} else {
+		// Classic time-based mode (backward compatible)
+		// 2. Sort chronologically
+		sortFilesByDateTime(mediaFiles)
+
+		// 3. Group by gaps or calendar period (v2.10.0+)
+		groups = groupFiles(cfg, ctx, mediaFiles)
+	}
+
+	return groups, inferences, nil
+}
+
 // processGroup processes all files in a group
-func processGroup(cfg *Config, ctx *executionContext, group fileGroup, stats *ProcessingStats, detector *DuplicateDetector) error {
+func processGroup(runCtx context.Context, cfg *Config, ctx *executionContext, group Group, stats *ProcessingStats, detector *DuplicateDetector) error {
 	// Create main folder (unless dry-run)
 	if cfg.Mode != ModeDryRun {
-		groupDir := filepath.Join(cfg.BasePath, group.folderName)
-		if err := os.MkdirAll(groupDir, permDirectory); err != nil {
+		groupDir := filepath.Join(cfg.BasePath, group.Folder)
+		if err := ctx.fs.MkdirAll(groupDir, permDirectory); err != nil {
 			return fmt.Errorf("failed to create folder %s: %w", groupDir, err)
 		}
 	}
 
 	// Process each file
-	for _, file := range group.files {
+	for _, file := range group.Files {
+		if err := runCtx.Err(); err != nil {
+			return err
+		}
+
 		fileName := file.FileInfo.Name()
-		filePath := filepath.Join(cfg.BasePath, fileName)
 
 		// Check if file is a duplicate
-		if cfg.DetectDuplicates {
-			isDup, original, err := detector.Check(filePath, file.FileInfo.Size())
-			if err != nil {
-				slog.Warn("failed to check duplicate", "file", fileName, "error", err)
-				// Continue processing even if duplicate detection fails
-			} else if isDup {
-				// Duplicate detected
-				stats.DuplicatesDetected[filePath] = original
-
-				if cfg.SkipDuplicates {
-					// Skip this file
-					slog.Info("skipping duplicate", "file", fileName, "original", filepath.Base(original))
-					stats.DuplicatesSkipped++
+		handled, err := handleDuplicate(runCtx, cfg, ctx, file, stats, detector)
+		if err != nil {
+			return err
+		}
+		if handled {
+			continue
+		}
+
+		// Process file normally
+		if ctx.isPhoto(fileName) {
+			if err := processPicture(cfg, ctx, file, group.Folder); err != nil {
+				if errors.Is(err, errCollisionSkipped) {
 					continue
-				} else if cfg.MoveDuplicates {
-					// Move to duplicates/ folder
-					duplicatesDir := filepath.Join(cfg.BasePath, duplicatesFolderName)
-					if cfg.Mode != ModeDryRun {
-						if err := os.MkdirAll(duplicatesDir, permDirectory); err != nil {
-							slog.Error("failed to create duplicates folder", "error", err)
-							if cfg.ContinueOnError {
-								stats.AddError(fmt.Errorf("failed to create duplicates folder: %w", err))
-								continue
-							}
-							return err
-						}
-					}
-
-					if err := moveFile(cfg.BasePath, fileName, duplicatesFolderName, cfg.Mode == ModeDryRun); err != nil {
-						slog.Error("failed to move duplicate", "file", fileName, "error", err)
-						if cfg.ContinueOnError {
-							stats.AddError(fmt.Errorf("failed to move duplicate %s: %w", fileName, err))
-							continue
-						}
-						return err
-					}
-					if cfg.Mode == ModeDryRun {
-						slog.Info("would move duplicate", "file", fileName, "to", duplicatesFolderName+"/", "original", filepath.Base(original))
-					} else {
-						slog.Info("moved duplicate", "file", fileName, "to", duplicatesFolderName+"/", "original", filepath.Base(original))
-					}
-					stats.DuplicatesSkipped++
+				}
+				if cfg.ContinueOnError {
+					stats.AddError(err)
+					ctx.log.Error("failed to process photo, continuing", "file", fileName, "error", err)
 					continue
-				} else {
-					// Just a warning, process anyway
-					slog.Warn("duplicate detected (processing anyway)", "file", fileName, "original", filepath.Base(original))
 				}
+				return err
 			}
+			stats.ProcessedFiles++
+		} else if ctx.isMovie(fileName) {
+			if err := processMovie(cfg, ctx, file, group.Folder); err != nil {
+				if errors.Is(err, errCollisionSkipped) {
+					continue
+				}
+				if cfg.ContinueOnError {
+					stats.AddError(err)
+					ctx.log.Error("failed to process video, continuing", "file", fileName, "error", err)
+					continue
+				}
+				return err
+			}
+			stats.ProcessedFiles++
 		}
+	}
 
-		// Process file normally
+	// Contact sheet of the whole folder, files of earlier runs included (v2.10.0+)
+	// The sheet of an extended folder is kept up to date even without --contact-sheet
+	folderPath := filepath.Join(cfg.BasePath, group.Folder)
+	if cfg.ContactSheet || (group.Existing && hasContactSheet(ctx, folderPath)) {
+		if cfg.Mode == ModeDryRun {
+			ctx.log.Debug("[DRY RUN] would write contact sheet", "folder", group.Folder)
+			return nil
+		}
+		thumbnails, err := refreshContactSheet(runCtx, ctx, folderPath)
+		switch {
+		case runCtx.Err() != nil:
+			return runCtx.Err()
+		case err != nil:
+			ctx.log.Warn("failed to write contact sheet", "folder", group.Folder, "error", err)
+		case thumbnails > 0:
+			stats.ContactSheets++
+		}
+	}
+
+	return nil
+}
+
+// processRootGroup processes the files of a group below MinGroupSize: they stay at root
+// For GPS mode: "Paris/2024-0615-1200" or "Paris/2024/06" → files go to "Paris"
+// For time mode: "2024-0615-1200" → files stay in basePath
+// For trips: "2024-06 Italy trip/2024-06-15/2024-0615-1200" → files go to the day folder (group.Root)
+func processRootGroup(runCtx context.Context, cfg *Config, ctx *executionContext, group Group, stats *ProcessingStats, detector *DuplicateDetector) error {
+	destinationRoot := group.Root
+	if destinationRoot == "" && cfg.UseGPS {
+		destinationRoot = groupRoot(group.Folder, cfg.GroupBy)
+	}
+
+	for _, file := range group.Files {
+		if err := runCtx.Err(); err != nil {
+			return err
+		}
+
+		fileName := file.FileInfo.Name()
+
+		// Check duplicates (same logic as processGroup)
+		handled, err := handleDuplicate(runCtx, cfg, ctx, file, stats, detector)
+		if err != nil {
+			return err
+		}
+		if handled {
+			continue
+		}
+
+		// Process file at root
 		if ctx.isPhoto(fileName) {
-			if err := processPicture(cfg, ctx, file.FileInfo, group.folderName); err != nil {
+			if err := processPictureAtRoot(cfg, ctx, file, destinationRoot); err != nil {
+				if errors.Is(err, errCollisionSkipped) {
+					continue
+				}
 				if cfg.ContinueOnError {
 					stats.AddError(err)
-					slog.Error("failed to process photo, continuing", "file", fileName, "error", err)
+					ctx.log.Error("failed to process photo at root, continuing", "file", fileName, "error", err)
 					continue
 				}
 				return err
 			}
 			stats.ProcessedFiles++
 		} else if ctx.isMovie(fileName) {
-			if err := processMovie(cfg, file.FileInfo, group.folderName); err != nil {
+			if err := processMovieAtRoot(cfg, ctx, file, destinationRoot); err != nil {
+				if errors.Is(err, errCollisionSkipped) {
+					continue
+				}
 				if cfg.ContinueOnError {
 					stats.AddError(err)
-					slog.Error("failed to process video, continuing", "file", fileName, "error", err)
+					ctx.log.Error("failed to process video at root, continuing", "file", fileName, "error", err)
 					continue
 				}
 				return err
@@ -316,10 +516,81 @@ func processGroup(cfg *Config, ctx *executionContext, group fileGroup, stats *Pr
 	return nil
 }
 
+// handleDuplicate checks a file against already seen files and applies the duplicate policy
+// Returns true when the file was skipped or moved to duplicates/ and must not be processed further
+func handleDuplicate(runCtx context.Context, cfg *Config, ctx *executionContext, file FileMetadata, stats *ProcessingStats, detector *DuplicateDetector) (bool, error) {
+	if !cfg.DetectDuplicates {
+		return false, nil
+	}
+
+	fileName := file.FileInfo.Name()
+	filePath := filepath.Join(cfg.BasePath, fileName)
+
+	isDup, original, err := detector.CheckContext(runCtx, filePath, file.FileInfo.Size())
+	if err != nil {
+		// Interrupted while hashing: the file is left for --resume
+		if runCtx.Err() != nil {
+			return false, runCtx.Err()
+		}
+		// Continue processing even if duplicate detection fails
+		ctx.log.Warn("failed to check duplicate", "file", fileName, "error", err)
+		return false, nil
+	}
+	if !isDup {
+		return false, nil
+	}
+
+	// Duplicate detected
+	stats.DuplicatesDetected[filePath] = original
+
+	switch {
+	case cfg.SkipDuplicates:
+		// Skip this file
+		ctx.log.Info("skipping duplicate", "file", fileName, "original", filepath.Base(original))
+		stats.DuplicatesSkipped++
+		return true, nil
+
+	case cfg.MoveDuplicates:
+		// Move to duplicates/ folder
+		duplicatesDir := filepath.Join(cfg.BasePath, duplicatesFolderName)
+		if cfg.Mode != ModeDryRun {
+			if err := ctx.fs.MkdirAll(duplicatesDir, permDirectory); err != nil {
+				ctx.log.Error("failed to create duplicates folder", "error", err)
+				if cfg.ContinueOnError {
+					stats.AddError(fmt.Errorf("failed to create duplicates folder: %w", err))
+					return true, nil
+				}
+				return true, err
+			}
+		}
+
+		if err := placeFile(cfg, ctx, file, duplicatesFolderName); err != nil && !errors.Is(err, errCollisionSkipped) {
+			ctx.log.Error("failed to move duplicate", "file", fileName, "error", err)
+			if cfg.ContinueOnError {
+				stats.AddError(fmt.Errorf("failed to move duplicate %s: %w", fileName, err))
+				return true, nil
+			}
+			return true, err
+		}
+		if cfg.Mode == ModeDryRun {
+			ctx.log.Info("would move duplicate", "file", fileName, "to", duplicatesFolderName+"/", "original", filepath.Base(original))
+		} else {
+			ctx.log.Info("moved duplicate", "file", fileName, "to", duplicatesFolderName+"/", "original", filepath.Base(original))
+		}
+		stats.DuplicatesSkipped++
+		return true, nil
+
+	default:
+		// Just a warning, process anyway
+		ctx.log.Warn("duplicate detected (processing anyway)", "file", fileName, "original", filepath.Base(original))
+		return false, nil
+	}
+}
+
 // refreshOrphanRAW scans organized folders and separates orphan RAW files
 // This is used when picsplit is run on an already organized directory
-func refreshOrphanRAW(cfg *Config, ctx *executionContext) error {
-	slog.Info("detected organized folder structure - refreshing orphan RAW separation")
+func refreshOrphanRAW(cfg *Config, ctx *executionContext) (*ProcessingStats, error) {
+	ctx.log.Info("detected organized folder structure - refreshing orphan RAW separation")
 
 	stats := &ProcessingStats{
 		StartTime:        time.Now(),
@@ -329,10 +600,10 @@ func refreshOrphanRAW(cfg *Config, ctx *executionContext) error {
 	defer func() {
 		// Cleanup empty directories if requested (after all file operations)
 		if cfg.CleanupEmptyDirs && cfg.Mode != ModeValidate {
-			slog.Info("cleaning up empty directories", "path", cfg.BasePath)
-			result, err := CleanupEmptyDirs(cfg.BasePath, cfg.Mode, cfg.Force, cfg.CleanupIgnore)
+			ctx.log.Info("cleaning up empty directories", "path", cfg.BasePath)
+			result, err := cleanupEmptyDirs(ctx, cfg.BasePath, cfg.Mode, cfg.Force, cfg.CleanupIgnore)
 			if err != nil {
-				slog.Warn("cleanup failed", "error", err)
+				ctx.log.Warn("cleanup failed", "error", err)
 			} else {
 				stats.EmptyDirsRemoved = result.RemovedDirs
 				for path, cleanupErr := range result.FailedDirs {
@@ -342,7 +613,6 @@ func refreshOrphanRAW(cfg *Config, ctx *executionContext) error {
 		}
 
 		stats.EndTime = time.Now()
-		stats.PrintSummary(cfg.Mode == ModeDryRun)
 	}()
 
 	// Check if we're IN a date-formatted folder (e.g., running picsplit inside "2024 - 1220 - 0900")
@@ -355,22 +625,22 @@ func refreshOrphanRAW(cfg *Config, ctx *executionContext) error {
 	if len(folderName) >= 18 && len(folderName) <= 19 {
 		if _, err := time.Parse(dateFormatPattern, folderName); err == nil {
 			// We're inside a date-formatted folder, process it directly
-			slog.Debug("processing current date-formatted folder", "folder", folderName)
+			ctx.log.Debug("processing current date-formatted folder", "folder", folderName)
 			if err := processOrganizedFolder(cfg, ctx, stats, cfg.BasePath, folderName); err != nil {
-				return err
+				return stats, err
 			}
 			// Fix stats before returning
 			stats.TotalFiles = stats.RawCount
 			stats.PhotoCount = 0
 			stats.VideoCount = 0
-			return nil
+			return stats, nil
 		}
 	}
 
 	// Otherwise, scan for date-formatted subfolders
-	entries, err := os.ReadDir(cfg.BasePath)
+	entries, err := ctx.fs.ReadDir(cfg.BasePath)
 	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
+		return stats, fmt.Errorf("failed to read directory: %w", err)
 	}
 
 	// Process each organized subfolder
@@ -388,13 +658,13 @@ func refreshOrphanRAW(cfg *Config, ctx *executionContext) error {
 
 		// Only process date-formatted folders
 		if _, err := time.Parse(dateFormatPattern, subFolderName); err != nil {
-			slog.Debug("skipping non-date folder", "folder", subFolderName)
+			ctx.log.Debug("skipping non-date folder", "folder", subFolderName)
 			continue
 		}
 
 		folderPath := filepath.Join(cfg.BasePath, subFolderName)
 		if err := processOrganizedFolder(cfg, ctx, stats, folderPath, subFolderName); err != nil {
-			slog.Warn("failed to process folder", "folder", subFolderName, "error", err)
+			ctx.log.Warn("failed to process folder", "folder", subFolderName, "error", err)
 		}
 	}
 
@@ -404,22 +674,22 @@ func refreshOrphanRAW(cfg *Config, ctx *executionContext) error {
 	stats.PhotoCount = 0 // We don't process photos in orphan mode
 	stats.VideoCount = 0 // We don't process videos in orphan mode
 
-	return nil
+	return stats, nil
 }
 
 // processOrganizedFolder processes a single organized folder to separate orphan RAW files
 func processOrganizedFolder(cfg *Config, ctx *executionContext, stats *ProcessingStats, folderPath, folderName string) error {
-	slog.Debug("processing organized folder", "folder", folderName)
+	ctx.log.Debug("processing organized folder", "folder", folderName)
 
 	// Check if there's a raw/ subfolder
 	rawPath := filepath.Join(folderPath, rawFolderName)
-	if _, err := os.Stat(rawPath); os.IsNotExist(err) {
-		slog.Debug("no raw folder found", "folder", folderName)
+	if _, err := ctx.fs.Stat(rawPath); os.IsNotExist(err) {
+		ctx.log.Debug("no raw folder found", "folder", folderName)
 		return nil
 	}
 
 	// Scan RAW files in raw/ subfolder
-	rawEntries, err := os.ReadDir(rawPath)
+	rawEntries, err := ctx.fs.ReadDir(rawPath)
 	if err != nil {
 		return fmt.Errorf("failed to read raw folder: %w", err)
 	}
@@ -439,38 +709,39 @@ func processOrganizedFolder(cfg *Config, ctx *executionContext, stats *Processin
 		rawFilePath := filepath.Join(rawPath, rawFileName)
 
 		// Check if RAW has associated JPEG/HEIC in parent folder
-		if !isRawPaired(rawFilePath, folderPath, folderPath) {
+		if !isRawPaired(ctx.fs, rawFilePath, folderPath, folderPath) {
 			// Orphan RAW - move to orphan/ folder
 			stats.OrphanRaw++
+			stats.OrphanRawFiles = append(stats.OrphanRawFiles, filepath.Join(folderName, orphanFolderName, rawFileName))
 
 			orphanPath := filepath.Join(folderPath, orphanFolderName)
 			if cfg.Mode != ModeDryRun {
-				if err := os.MkdirAll(orphanPath, permDirectory); err != nil {
-					slog.Error("failed to create orphan folder", "folder", folderName, "error", err)
+				if err := ctx.fs.MkdirAll(orphanPath, permDirectory); err != nil {
+					ctx.log.Error("failed to create orphan folder", "folder", folderName, "error", err)
 					stats.ProcessedFiles-- // Decrement if we couldn't process
 					continue
 				}
 			}
 
 			destPath := filepath.Join(orphanPath, rawFileName)
-			slog.Info("moving orphan RAW", "from", rawFilePath, "to", destPath, "dryrun", cfg.Mode == ModeDryRun)
+			ctx.log.Info("moving orphan RAW", "from", rawFilePath, "to", destPath, "dryrun", cfg.Mode == ModeDryRun)
 
 			if cfg.Mode != ModeDryRun {
-				if err := os.Rename(rawFilePath, destPath); err != nil {
+				if err := ctx.fs.Rename(rawFilePath, destPath); err != nil {
 					stats.Errors = append(stats.Errors, &PicsplitError{
 						Type: ErrTypeIO,
 						Op:   "move_orphan",
 						Path: rawFilePath,
 						Err:  err,
 					})
-					slog.Error("failed to move orphan RAW", "file", rawFileName, "error", err)
+					ctx.log.Error("failed to move orphan RAW", "file", rawFileName, "error", err)
 					stats.ProcessedFiles-- // Decrement on error
 				}
 			}
 		} else {
 			// Paired RAW - keep in raw/
 			stats.PairedRaw++
-			slog.Debug("keeping paired RAW", "file", rawFileName)
+			ctx.log.Debug("keeping paired RAW", "file", rawFileName)
 		}
 	}
 
@@ -478,10 +749,11 @@ func processOrganizedFolder(cfg *Config, ctx *executionContext, stats *Processin
 }
 
 // Split is the main function that moves files to dated folders according to configuration
-func Split(cfg *Config) error {
+// It chains the Scan, BuildPlan and Execute stages; statistics are returned, not printed
+func Split(runCtx context.Context, cfg *Config) (*SplitResult, error) {
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
-		return fmt.Errorf("invalid configuration: %w", err)
+		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
 	// Handle execution modes
@@ -490,54 +762,188 @@ func Split(cfg *Config) error {
 		// Fast validation without EXIF extraction
 		report, err := Validate(cfg)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		report.Print()
+		result := &SplitResult{Validation: report}
 		if report.HasCriticalErrors() {
-			return fmt.Errorf("validation found %d critical error(s)", report.CriticalErrorCount())
+			return result, fmt.Errorf("validation found %d critical error(s)", report.CriticalErrorCount())
 		}
-		return nil
+		return result, nil
 
 	case ModeDryRun, ModeRun:
 		// Continue with split processing
-		return splitInternal(cfg)
+		result, err := splitInternal(runCtx, cfg)
+		var stats *ProcessingStats
+		if result != nil {
+			stats = result.Stats
+		}
+		cfg.Metrics.ObserveRun(cfg.Mode, stats, err)
+		return result, err
 
 	default:
-		return fmt.Errorf("unknown execution mode: %v", cfg.Mode)
+		return nil, fmt.Errorf("unknown execution mode: %v", cfg.Mode)
 	}
 }
 
 // splitInternal is the internal implementation of Split
-func splitInternal(cfg *Config) error {
+func splitInternal(runCtx context.Context, cfg *Config) (*SplitResult, error) {
 	// Create execution context with custom extensions
 	ctx, err := newExecutionContext(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to initialize extension context: %w", err)
+		return nil, fmt.Errorf("failed to initialize extension context: %w", err)
+	}
+
+	// Continue an interrupted run with the plan it saved (v2.10.0+)
+	if cfg.Resume {
+		plan, err := loadCheckpointPlan(cfg, ctx)
+		if err != nil {
+			return nil, err
+		}
+		stats, err := executePlan(runCtx, cfg, ctx, plan)
+		return &SplitResult{Stats: stats}, err
+	}
+	if hasCheckpoint(cfg, ctx) {
+		ctx.log.Warn("an interrupted run left a checkpoint, use --resume to continue it",
+			"checkpoint", filepath.Join(cfg.BasePath, checkpointName))
 	}
 
 	// Check if we're in an already organized folder
-	if cfg.SeparateOrphanRaw && isOrganizedFolder(cfg.BasePath) {
-		slog.Info("detected organized folder - running orphan refresh mode")
-		return refreshOrphanRAW(cfg, ctx)
+	// Incremental mode expects organized folders and adds new files to them
+	if cfg.SeparateOrphanRaw && !cfg.Incremental && isOrganizedFolder(ctx.fs, cfg.BasePath) {
+		ctx.log.Info("detected organized folder - running orphan refresh mode")
+		stats, err := refreshOrphanRAW(cfg, ctx)
+		return &SplitResult{Stats: stats, OrphanRefresh: true}, err
 	}
 
 	// 1. Collect media files with metadata
-	mediaFiles, err := collectMediaFilesWithMetadata(cfg, ctx)
+	mediaFiles, err := collectMediaFilesWithMetadata(runCtx, cfg, ctx)
 	if err != nil {
-		return fmt.Errorf("failed to collect media files: %w", err)
+		return nil, fmt.Errorf("failed to collect media files: %w", err)
 	}
 
 	if len(mediaFiles) == 0 {
-		slog.Info("no media files found")
-		return nil
+		ctx.log.Info("no media files found")
+		return &SplitResult{}, nil
+	}
+
+	ctx.log.Info("media files collected", "count", len(mediaFiles))
+
+	// 2. Group files into events
+	plan, err := buildPlan(runCtx, cfg, ctx, mediaFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Move files
+	stats, err := executePlan(runCtx, cfg, ctx, plan)
+	return &SplitResult{Stats: stats}, err
+}
+
+// buildPlan routes files matching a rule, groups the others into events and filters groups by MinGroupSize
+func buildPlan(runCtx context.Context, cfg *Config, ctx *executionContext, mediaFiles []FileMetadata) (*Plan, error) {
+	if err := runCtx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Files whose dates disagree are reported, the skip policy leaves them at the root (v2.10.0+)
+	conflicts, mediaFiles, conflicting := dateConflicts(cfg, mediaFiles)
+
+	// Routing rules take their files out of event grouping (v2.10.0+)
+	routed := ctx.rules.route(cfg, ctx, mediaFiles)
+
+	// Screenshots and messaging app images do not form events (v2.10.0+)
+	classified := classifyFiles(cfg, ctx, routed.files)
+
+	// Multi-day trips get their own folder with day subfolders (v2.10.0+)
+	var groups []Group
+	var inferences []LocationInference
+	files := classified.files
+	if cfg.Trips {
+		sortFilesByDateTime(files)
+		var trips []trip
+		trips, files = detectTrips(cfg, ctx, files)
+		tripGroups, inferred, err := buildTripGroups(runCtx, cfg, ctx, trips)
+		if err != nil {
+			return nil, err
+		}
+		if err := runCtx.Err(); err != nil {
+			return nil, err
+		}
+		groups = tripGroups
+		inferences = inferred
+	}
+
+	// GPS clustering mode or classic time-based mode
+	if len(files) > 0 {
+		eventGroups, inferred, err := buildGroups(runCtx, cfg, ctx, files)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, eventGroups...)
+		inferences = append(inferences, inferred...)
+	}
+
+	ctx.log.Info("event groups detected",
+		"count", len(groups),
+		"delta", cfg.Delta)
+
+	plan := &Plan{
+		ruleHits:      routed.hits,
+		dateConflicts: conflicts,
+		inferences:    inferences,
+		screenshots:   classified.screenshots,
+		messaging:     classified.messaging,
 	}
 
-	slog.Info("media files collected", "count", len(mediaFiles))
+	// Incremental mode: extend existing event folders instead of creating new ones next to them (v2.10.0+)
+	if cfg.Incremental {
+		plan.events = scanEventFolders(cfg, ctx)
+		appendToExistingEvents(ctx, groups, plan.events, cfg.Delta)
+	}
+
+	// Events of group rules follow MinGroupSize like the others
+	groups = append(groups, routed.events...)
+
+	// Filter groups by MinGroupSize (v2.9.0+)
+	// Groups below threshold will have files left at root instead of creating folder
+	var smallGroups []Group
+	var rootFiles int
+	for _, group := range groups {
+		if len(group.Files) >= cfg.MinGroupSize || group.Existing {
+			plan.Groups = append(plan.Groups, group)
+		} else {
+			group.AtRoot = true
+			smallGroups = append(smallGroups, group)
+			rootFiles += len(group.Files)
+		}
+	}
 
+	if len(smallGroups) > 0 {
+		ctx.log.Info("filtered small groups",
+			"small_groups", len(smallGroups),
+			"files_at_root", rootFiles,
+			"threshold", cfg.MinGroupSize)
+	}
+
+	// Route rules and classified folders are filled whatever the file count, exclude rules leave files at root
+	plan.Groups = append(plan.Groups, routed.fixed...)
+	plan.Groups = append(plan.Groups, classified.groups...)
+	plan.Groups = append(plan.Groups, smallGroups...)
+	plan.Groups = append(plan.Groups, routed.excluded...)
+	if conflicting != nil {
+		plan.Groups = append(plan.Groups, *conflicting)
+	}
+
+	return plan, nil
+}
+
+// executePlan moves the files of every group and returns the processing statistics
+//
+//nolint:gocyclo // Sequential pipeline with per-group error handling
+func executePlan(runCtx context.Context, cfg *Config, ctx *executionContext, plan *Plan) (*ProcessingStats, error) {
 	// Initialize processing statistics
 	stats := &ProcessingStats{
 		StartTime:          time.Now(),
-		TotalFiles:         len(mediaFiles),
 		EmptyDirsFailed:    make(map[string]string),
 		EmptyDirsRemoved:   []string{},
 		DuplicatesDetected: make(map[string]string),
@@ -546,10 +952,10 @@ func splitInternal(cfg *Config) error {
 	defer func() {
 		// Cleanup empty directories if requested (after all file operations)
 		if cfg.CleanupEmptyDirs && cfg.Mode != ModeValidate {
-			slog.Info("cleaning up empty directories", "path", cfg.BasePath)
-			result, err := CleanupEmptyDirs(cfg.BasePath, cfg.Mode, cfg.Force, cfg.CleanupIgnore)
+			ctx.log.Info("cleaning up empty directories", "path", cfg.BasePath)
+			result, err := cleanupEmptyDirs(ctx, cfg.BasePath, cfg.Mode, cfg.Force, cfg.CleanupIgnore)
 			if err != nil {
-				slog.Warn("cleanup failed", "error", err)
+				ctx.log.Warn("cleanup failed", "error", err)
 			} else {
 				stats.EmptyDirsRemoved = result.RemovedDirs
 				for path, cleanupErr := range result.FailedDirs {
@@ -558,352 +964,227 @@ func splitInternal(cfg *Config) error {
 			}
 		}
 
-		stats.EndTime = time.Now()
-		stats.PrintSummary(cfg.Mode == ModeDryRun)
-	}()
-
-	// Create duplicate detector if enabled
-	detector := NewDuplicateDetector(cfg.DetectDuplicates)
+		// Name collision outcomes (v2.10.0+)
+		stats.CollisionsRenamed = ctx.collisions.renamed
+		stats.CollisionsSkipped = ctx.collisions.skipped
+		stats.CollisionsOverwritten = ctx.collisions.overwritten
+		stats.IdenticalSkipped = ctx.collisions.identical
 
-	// Count file types and ModTime fallback
-	// AND pre-fill duplicate detector by size
-	for _, mf := range mediaFiles {
-		fileName := mf.FileInfo.Name()
-		if ctx.isPhoto(fileName) {
-			if ctx.isRaw(fileName) {
-				stats.RawCount++
-			} else {
-				stats.PhotoCount++
-			}
-		} else if ctx.isMovie(fileName) {
-			stats.VideoCount++
-		}
+		// Orphan RAW files separated during the run (v2.10.0+)
+		stats.OrphanRaw += len(ctx.orphanRaws)
+		stats.OrphanRawFiles = append(stats.OrphanRawFiles, ctx.orphanRaws...)
 
-		// Track ModTime fallback
-		if mf.Source == DateSourceModTime {
-			stats.ModTimeFallbackCount++
-		}
+		// Routing rules (v2.10.0+)
+		stats.RuleHits = plan.ruleHits
 
-		// Track total bytes
-		stats.TotalBytes += mf.FileInfo.Size()
+		// Files whose dates disagree (v2.10.0+)
+		stats.DateConflicts = plan.dateConflicts
 
-		// Add to size pre-filtering (duplicates optimization)
-		if cfg.DetectDuplicates {
-			filePath := filepath.Join(cfg.BasePath, mf.FileInfo.Name())
-			detector.AddFile(filePath, mf.FileInfo.Size())
-		}
-	}
+		// Files without GPS placed with a geotagged neighbour (v2.10.0+)
+		stats.LocationInferences = plan.inferences
 
-	var groups []fileGroup
+		// Screenshot and messaging app classification (v2.10.0+)
+		stats.ScreenshotFiles = plan.screenshots
+		stats.MessagingFiles = plan.messaging
 
-	// 2. GPS clustering mode or classic time-based mode
-	if cfg.UseGPS {
-		// Log GPS coverage analysis
-		var filesWithGPSCount int
-		for _, mf := range mediaFiles {
-			if mf.GPS != nil {
-				filesWithGPSCount++
+		// Original names of renamed files (v2.10.0+)
+		stats.FilesRenamed = len(ctx.renames)
+		if cfg.Mode == ModeRun {
+			if err := ctx.writeRenameManifest(cfg.BasePath); err != nil {
+				ctx.log.Warn("failed to write rename manifest", "error", err)
 			}
 		}
 
-		gpsPercentage := 0.0
-		if len(mediaFiles) > 0 {
-			gpsPercentage = float64(filesWithGPSCount) / float64(len(mediaFiles)) * 100
-		}
-
-		slog.Info("GPS coverage analysis",
-			"files_with_gps", filesWithGPSCount,
-			"total_files", len(mediaFiles),
-			"coverage_pct", fmt.Sprintf("%.1f%%", gpsPercentage))
-
-		// GPS clustering: location FIRST, then time within each location
-		locationClusters, filesWithoutGPS := ClusterByLocation(mediaFiles, cfg.GPSRadius)
+		stats.EndTime = time.Now()
+	}()
 
-		slog.Info("GPS clustering completed",
-			"location_clusters", len(locationClusters),
-			"files_without_gps", len(filesWithoutGPS))
+	// Create duplicate detector if enabled
+	detector := newDuplicateDetector(cfg.DetectDuplicates, ctx.fs)
 
-		// Pre-geocode all locations if geocoding is enabled
-		// This shows progress and prepares location names before processing
-		locationNames := make(map[int]string)
-		if cfg.GPSUseGeocoding && len(locationClusters) > 0 {
-			geocodingBar := createProgressBar(len(locationClusters), "Geocoding locations", cfg.LogLevel, cfg.LogFormat)
+	// Count file types and ModTime fallback
+	// AND pre-fill duplicate detector by size
+	var eventGroups []Group
+	for _, group := range plan.Groups {
+		switch {
+		case group.AtRoot:
+			stats.SmallGroupsCount++
+			stats.RootFilesCount += len(group.Files)
+		case group.Existing:
+			stats.GroupsExtended++
+			eventGroups = append(eventGroups, group)
+			stats.Groups = append(stats.Groups, newGroupSummary(group))
+		default:
+			// Only large groups create folders, appended groups reuse one
+			stats.GroupsCreated++
+			eventGroups = append(eventGroups, group)
+			stats.Groups = append(stats.Groups, newGroupSummary(group))
+		}
 
-			for i, cluster := range locationClusters {
-				locationNames[i] = ReverseGeocode(cluster.Centroid.Lat, cluster.Centroid.Lon, cfg.GPSUseGeocoding)
+		for _, mf := range group.Files {
+			stats.TotalFiles++
 
-				if geocodingBar != nil {
-					_ = geocodingBar.Add(1)
+			fileName := mf.FileInfo.Name()
+			if ctx.isPhoto(fileName) {
+				if ctx.isRaw(fileName) {
+					stats.RawCount++
+				} else {
+					stats.PhotoCount++
 				}
+			} else if ctx.isMovie(fileName) {
+				stats.VideoCount++
 			}
-		}
 
-		// Now process each location cluster (geocoding already done)
-		for i, cluster := range locationClusters {
-			var locationName string
-			if cfg.GPSUseGeocoding {
-				locationName = locationNames[i]
-			} else {
-				locationName = FormatLocationName(cluster.Centroid)
+			// Track ModTime fallback
+			if mf.Source == DateSourceModTime {
+				stats.ModTimeFallbackCount++
 			}
 
-			slog.Debug("processing location cluster", "location", locationName, "files", len(cluster.Files))
-
-			// Group by time within this location
-			timeGroups := GroupLocationByTime(cluster, cfg.Delta)
-			slog.Debug("location split into time groups", "location", locationName, "time_groups", len(timeGroups))
+			// Track total bytes
+			stats.TotalBytes += mf.FileInfo.Size()
 
-			// Create fileGroup for each time group
-			for _, timeGroup := range timeGroups {
-				if len(timeGroup) == 0 {
-					continue
-				}
-
-				folderName := filepath.Join(locationName, timeGroup[0].DateTime.Format(dateFormatPattern))
-				groups = append(groups, fileGroup{
-					folderName: folderName,
-					firstFile:  timeGroup[0],
-					files:      timeGroup,
-				})
+			// Add to size pre-filtering (duplicates optimization)
+			if cfg.DetectDuplicates {
+				filePath := filepath.Join(cfg.BasePath, mf.FileInfo.Name())
+				detector.AddFile(filePath, mf.FileInfo.Size())
 			}
 		}
+	}
 
-		// Process files without GPS
-		if len(filesWithoutGPS) > 0 {
-			// Sort and group by time
-			sortFilesByDateTime(filesWithoutGPS)
-			noGPSGroups := groupFilesByGaps(filesWithoutGPS, cfg.Delta)
+	// Positions from the --gpx track are only written once the plan is executed (v2.10.0+)
+	writeTrackSidecars(cfg, ctx, plan.Groups)
 
-			// If location clusters exist, create "NoLocation" subfolder
-			// Otherwise, put directly at root (no need for segregation)
-			if len(locationClusters) > 0 {
-				slog.Info("processing files without GPS in folder",
-					"count", len(filesWithoutGPS),
-					"folder", GetNoLocationFolderName())
-				for _, noGPSGroup := range noGPSGroups {
-					folderName := filepath.Join(GetNoLocationFolderName(), noGPSGroup.folderName)
-					groups = append(groups, fileGroup{
-						folderName: folderName,
-						firstFile:  noGPSGroup.firstFile,
-						files:      noGPSGroup.files,
-					})
-				}
-			} else {
-				slog.Info("processing files without GPS at root (no location clusters)",
-					"count", len(filesWithoutGPS))
-				for _, noGPSGroup := range noGPSGroups {
-					groups = append(groups, fileGroup{
-						folderName: noGPSGroup.folderName,
-						firstFile:  noGPSGroup.firstFile,
-						files:      noGPSGroup.files,
-					})
-				}
+	// Files moved before an interruption are the originals of their duplicates left (v2.10.0+)
+	for _, relPath := range ctx.placed {
+		filePath := filepath.Join(cfg.BasePath, relPath)
+		info, err := ctx.fs.Stat(filePath)
+		if err != nil {
+			continue
+		}
+		if err := detector.addPlacedFile(runCtx, filePath, info.Size()); err != nil {
+			if runCtx.Err() != nil {
+				return stats, interruptPlan(runCtx, cfg, ctx, stats, plan.Groups)
 			}
+			ctx.log.Warn("failed to check duplicate", "file", relPath, "error", err)
 		}
-	} else {
-		// Classic time-based mode (backward compatible)
-		// 2. Sort chronologically
-		sortFilesByDateTime(mediaFiles)
-
-		// 3. Group by gaps
-		groups = groupFilesByGaps(mediaFiles, cfg.Delta)
 	}
 
-	slog.Info("event groups detected",
-		"count", len(groups),
-		"delta", cfg.Delta)
-
-	// 3. Filter groups by MinGroupSize (v2.9.0+)
-	// Groups below threshold will have files left at root instead of creating folder
-	largeGroups := make([]fileGroup, 0, len(groups))
-	smallGroups := make([]fileGroup, 0)
-
-	for _, group := range groups {
-		if len(group.files) >= cfg.MinGroupSize {
-			largeGroups = append(largeGroups, group)
-		} else {
-			smallGroups = append(smallGroups, group)
-			stats.SmallGroupsCount++
-			stats.RootFilesCount += len(group.files)
+	// Process ALL groups (event folders first, then small groups left at root)
+	totalGroups := len(plan.Groups)
+	for i, group := range plan.Groups {
+		if runCtx.Err() != nil {
+			return stats, interruptPlan(runCtx, cfg, ctx, stats, plan.Groups[i:])
 		}
-	}
 
-	if stats.SmallGroupsCount > 0 {
-		slog.Info("filtered small groups",
-			"small_groups", stats.SmallGroupsCount,
-			"files_at_root", stats.RootFilesCount,
-			"threshold", cfg.MinGroupSize)
-	}
-
-	// Track groups created (only large groups create folders)
-	stats.GroupsCreated = len(largeGroups)
-
-	// 4. Process ALL groups (large + small) with combined progress bar
-	totalGroups := len(largeGroups) + len(smallGroups)
-	bar := createProgressBar(totalGroups, "Processing groups", cfg.LogLevel, cfg.LogFormat)
+		// Use Debug level when progress is reported to avoid visual interference with a progress bar
+		logGroup := ctx.log.Info
+		if ctx.progress != nil {
+			logGroup = ctx.log.Debug
+		}
 
-	// Process large groups (create folders)
-	for i, group := range largeGroups {
-		// Use Debug level when progress bar is active to avoid visual interference
-		if bar != nil {
-			slog.Debug("processing group",
+		var err error
+		if group.AtRoot {
+			logGroup("processing small group at root",
 				"current", i+1,
 				"total", totalGroups,
-				"folder", group.folderName,
-				"files", len(group.files))
+				"files", len(group.Files))
+			err = processRootGroup(runCtx, cfg, ctx, group, stats, detector)
 		} else {
-			slog.Info("processing group",
+			logGroup("processing group",
 				"current", i+1,
 				"total", totalGroups,
-				"folder", group.folderName,
-				"files", len(group.files))
+				"folder", group.Folder,
+				"files", len(group.Files))
+			err = processGroup(runCtx, cfg, ctx, group, stats, detector)
 		}
 
-		if err := processGroup(cfg, ctx, group, stats, detector); err != nil {
+		if err != nil {
+			// Cancellation stops the run, whatever ContinueOnError says
+			if runCtx.Err() != nil {
+				return stats, interruptPlan(runCtx, cfg, ctx, stats, plan.Groups[i:])
+			}
+
 			// Track error at group level
 			stats.AddError(&PicsplitError{
 				Type:    ErrTypeIO,
 				Op:      "process_group",
-				Path:    group.folderName,
+				Path:    group.Folder,
 				Err:     err,
-				Details: map[string]string{"file_count": fmt.Sprintf("%d", len(group.files))},
+				Details: map[string]string{"file_count": fmt.Sprintf("%d", len(group.Files))},
 			})
 
 			if !cfg.ContinueOnError {
-				slog.Error("failed to process group, stopping", "folder", group.folderName, "error", err)
-				return err
+				ctx.log.Error("failed to process group, stopping", "folder", group.Folder, "error", err)
+				return stats, err
 			}
 
-			slog.Error("failed to process group, continuing", "folder", group.folderName, "error", err)
+			ctx.log.Error("failed to process group, continuing", "folder", group.Folder, "error", err)
 		}
 
-		if bar != nil {
-			_ = bar.Add(1)
-		}
+		ctx.reportProgress(StageProcess, i+1, totalGroups)
 	}
 
-	// 5. Process small groups (leave files at root)
-	if len(smallGroups) > 0 {
-		if bar == nil {
-			slog.Info("processing small groups at root",
-				"count", len(smallGroups),
-				"total_files", stats.RootFilesCount)
-		}
-
-		for i, group := range smallGroups {
-			// Extract destination root from folder name
-			// For GPS mode: "Paris/2024-0615-1200" → root = "Paris"
-			// For time mode: "2024-0615-1200" → root = "" (basePath root)
-			destinationRoot := ""
-			if cfg.UseGPS && filepath.Dir(group.folderName) != "." {
-				destinationRoot = filepath.Dir(group.folderName)
-			}
-
-			if bar != nil {
-				slog.Debug("processing small group at root",
-					"current", len(largeGroups)+i+1,
-					"total", totalGroups,
-					"files", len(group.files))
-			}
-
-			// Process each file in small group
-			for _, file := range group.files {
-				fileName := file.FileInfo.Name()
-				filePath := filepath.Join(cfg.BasePath, fileName)
-
-				// Check duplicates (same logic as processGroup)
-				if cfg.DetectDuplicates {
-					isDup, original, err := detector.Check(filePath, file.FileInfo.Size())
-					if err != nil {
-						slog.Warn("failed to check duplicate", "file", fileName, "error", err)
-					} else if isDup {
-						stats.DuplicatesDetected[filePath] = original
-
-						if cfg.SkipDuplicates {
-							slog.Info("skipping duplicate", "file", fileName, "original", filepath.Base(original))
-							stats.DuplicatesSkipped++
-							continue
-						} else if cfg.MoveDuplicates {
-							duplicatesDir := filepath.Join(cfg.BasePath, duplicatesFolderName)
-							if cfg.Mode != ModeDryRun {
-								if err := os.MkdirAll(duplicatesDir, permDirectory); err != nil {
-									slog.Error("failed to create duplicates folder", "error", err)
-									if cfg.ContinueOnError {
-										stats.AddError(fmt.Errorf("failed to create duplicates folder: %w", err))
-										continue
-									}
-									return err
-								}
-							}
-
-							if err := moveFile(cfg.BasePath, fileName, duplicatesFolderName, cfg.Mode == ModeDryRun); err != nil {
-								slog.Error("failed to move duplicate", "file", fileName, "error", err)
-								if cfg.ContinueOnError {
-									stats.AddError(fmt.Errorf("failed to move duplicate %s: %w", fileName, err))
-									continue
-								}
-								return err
-							}
-							if cfg.Mode == ModeDryRun {
-								slog.Info("would move duplicate", "file", fileName, "to", duplicatesFolderName+"/", "original", filepath.Base(original))
-							} else {
-								slog.Info("moved duplicate", "file", fileName, "to", duplicatesFolderName+"/", "original", filepath.Base(original))
-							}
-							stats.DuplicatesSkipped++
-							continue
-						} else {
-							slog.Warn("duplicate detected (processing anyway)", "file", fileName, "original", filepath.Base(original))
-						}
-					}
-				}
-
-				// Process file at root
-				if ctx.isPhoto(fileName) {
-					if err := processPictureAtRoot(cfg, ctx, file.FileInfo, destinationRoot); err != nil {
-						if cfg.ContinueOnError {
-							stats.AddError(err)
-							slog.Error("failed to process photo at root, continuing", "file", fileName, "error", err)
-							continue
-						}
-						return err
-					}
-					stats.ProcessedFiles++
-				} else if ctx.isMovie(fileName) {
-					if err := processMovieAtRoot(cfg, file.FileInfo, destinationRoot); err != nil {
-						if cfg.ContinueOnError {
-							stats.AddError(err)
-							slog.Error("failed to process video at root, continuing", "file", fileName, "error", err)
-							continue
-						}
-						return err
-					}
-					stats.ProcessedFiles++
-				}
+	// Incremental mode: merge events that now touch and update the event index (v2.10.0+)
+	if cfg.Incremental {
+		merged, err := finishIncremental(runCtx, cfg, ctx, plan.events, eventGroups)
+		stats.EventsMerged = merged
+		if err != nil {
+			// Every file is in place: a new --incremental run finishes the merge
+			if runCtx.Err() != nil {
+				stats.Interrupted = true
+				return stats, runCtx.Err()
 			}
-
-			// Update progress bar after processing each small group
-			if bar != nil {
-				_ = bar.Add(1)
+			stats.AddError(&PicsplitError{
+				Type: ErrTypeIO,
+				Op:   "merge_events",
+				Path: cfg.BasePath,
+				Err:  err,
+			})
+			if !cfg.ContinueOnError {
+				return stats, err
 			}
 		}
 	}
 
+	// The interrupted run this one resumed (or superseded) is complete
+	if cfg.Mode == ModeRun {
+		removeCheckpoint(cfg, ctx)
+	}
+
 	// Return error if critical errors occurred
 	if stats.HasCriticalErrors() {
-		return fmt.Errorf("processing completed with %d critical error(s)", len(stats.Errors))
+		return stats, fmt.Errorf("processing completed with %d critical error(s)", len(stats.Errors))
 	}
 
-	return nil
+	return stats, nil
+}
+
+// interruptPlan records a canceled run: remaining groups are saved to a checkpoint (run mode)
+// so --resume can finish them; returns the cancellation cause
+func interruptPlan(runCtx context.Context, cfg *Config, ctx *executionContext, stats *ProcessingStats, remaining []Group) error {
+	stats.Interrupted = true
+	ctx.log.Warn("run interrupted, stopping after the current file",
+		"processed", stats.ProcessedFiles,
+		"total", stats.TotalFiles)
+
+	if cfg.Mode == ModeRun {
+		path, err := writeCheckpoint(cfg, ctx, remaining)
+		if err != nil {
+			ctx.log.Error("failed to save checkpoint", "error", err)
+		}
+		stats.CheckpointPath = path
+	}
+
+	return runCtx.Err()
 }
 
 // processPicture handles the processing of picture files
-func processPicture(cfg *Config, ctx *executionContext, fi os.FileInfo, datedFolder string) error {
-	slog.Debug("processing picture", "file", fi.Name(), "dest_folder", datedFolder)
+func processPicture(cfg *Config, ctx *executionContext, file FileMetadata, datedFolder string) error {
+	ctx.log.Debug("processing picture", "file", file.FileInfo.Name(), "dest_folder", datedFolder)
 
 	destDir := datedFolder
 
 	// Special handling for RAW files
-	if ctx.isRaw(fi.Name()) && !cfg.NoMoveRaw {
+	if ctx.isRaw(file.FileInfo.Name()) && !cfg.NoMoveRaw {
 		baseRawDir := filepath.Join(cfg.BasePath, datedFolder)
 
 		// Determine if RAW goes to raw/ or orphan/
@@ -913,44 +1194,45 @@ func processPicture(cfg *Config, ctx *executionContext, fi os.FileInfo, datedFol
 			// Check if RAW has associated JPEG/HEIC
 			// Search in source (basePath) AND in destination (datedFolder)
 			// because JPEG may have already been moved
-			rawFilePath := filepath.Join(cfg.BasePath, fi.Name())
+			rawFilePath := pairingPath(cfg, ctx, file.FileInfo.Name())
 			destFolder := filepath.Join(cfg.BasePath, datedFolder)
-			if !isRawPaired(rawFilePath, cfg.BasePath, destFolder) {
+			if !isRawPaired(ctx.fs, rawFilePath, cfg.BasePath, destFolder) {
 				targetFolder = orphanFolderName
-				slog.Debug("orphan RAW file (no JPEG/HEIC)", "file", fi.Name(), "dest", orphanFolderName)
+				ctx.orphanRaws = append(ctx.orphanRaws, filepath.Join(datedFolder, orphanFolderName, file.FileInfo.Name()))
+				ctx.log.Debug("orphan RAW file (no JPEG/HEIC)", "file", file.FileInfo.Name(), "dest", orphanFolderName)
 			}
 		}
 
-		rawDir, err := findOrCreateFolder(baseRawDir, targetFolder, cfg.Mode == ModeDryRun)
+		rawDir, err := findOrCreateFolder(ctx.fs, baseRawDir, targetFolder, cfg.Mode == ModeDryRun)
 		if err != nil {
 			return err
 		}
 		destDir = filepath.Join(datedFolder, rawDir)
 	}
 
-	return moveFile(cfg.BasePath, fi.Name(), destDir, cfg.Mode == ModeDryRun)
+	return placeFile(cfg, ctx, file, destDir)
 }
 
 // processMovie handles the processing of movie files
-func processMovie(cfg *Config, fi os.FileInfo, datedFolder string) error {
-	slog.Debug("processing movie", "file", fi.Name(), "dest_folder", datedFolder)
+func processMovie(cfg *Config, ctx *executionContext, file FileMetadata, datedFolder string) error {
+	ctx.log.Debug("processing movie", "file", file.FileInfo.Name(), "dest_folder", datedFolder)
 
 	destDir := datedFolder
 
 	// Move to separate mov folder if needed
 	if !cfg.NoMoveMovie {
 		baseMovieDir := filepath.Join(cfg.BasePath, datedFolder)
-		movieDir, err := findOrCreateFolder(baseMovieDir, movFolderName, cfg.Mode == ModeDryRun)
+		movieDir, err := findOrCreateFolder(ctx.fs, baseMovieDir, movFolderName, cfg.Mode == ModeDryRun)
 		if err != nil {
 			return err
 		}
 		destDir = filepath.Join(datedFolder, movieDir)
 	}
 
-	return moveFile(cfg.BasePath, fi.Name(), destDir, cfg.Mode == ModeDryRun)
+	return placeFile(cfg, ctx, file, destDir)
 }
 
-func findOrCreateFolder(basedir, name string, dryRun bool) (string, error) {
+func findOrCreateFolder(fsys FileSystem, basedir, name string, dryRun bool) (string, error) {
 	dirCreate := filepath.Join(basedir, name)
 
 	slog.Debug("finding or creating folder", "path", dirCreate)
@@ -959,15 +1241,15 @@ func findOrCreateFolder(basedir, name string, dryRun bool) (string, error) {
 		return name, nil
 	}
 
-	fi, err := os.Stat(dirCreate)
+	fi, err := fsys.Stat(dirCreate)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Create the folder
-			if err := os.Mkdir(dirCreate, permDirectory); err != nil {
+			if err := fsys.MkdirAll(dirCreate, permDirectory); err != nil {
 				return "", fmt.Errorf("failed to create folder %s: %w", dirCreate, err)
 			}
 
-			fi, err = os.Stat(dirCreate)
+			fi, err = fsys.Stat(dirCreate)
 			if err != nil {
 				return "", fmt.Errorf("failed to stat created folder: %w", err)
 			}
@@ -981,18 +1263,28 @@ func findOrCreateFolder(basedir, name string, dryRun bool) (string, error) {
 	return fi.Name(), nil
 }
 
-func moveFile(basedir, src, dest string, dryRun bool) error {
+func moveFile(ctx *executionContext, basedir, src, dest string, dryRun bool) error {
+	return moveFileAs(ctx, basedir, src, dest, src, dryRun)
+}
+
+// moveFileAs moves basedir/src to basedir/dest/destName
+func moveFileAs(ctx *executionContext, basedir, src, dest, destName string, dryRun bool) error {
 	srcPath := filepath.Join(basedir, src)
-	dstPath := filepath.Join(basedir, dest, src)
+	dstPath := filepath.Join(basedir, dest, destName)
+
+	// Already in place (e.g. small group left at root)
+	if srcPath == dstPath {
+		return nil
+	}
 
 	if dryRun {
-		slog.Info("[DRY RUN] would move file", "source", srcPath, "dest", dstPath)
+		ctx.log.Info("[DRY RUN] would move file", "source", srcPath, "dest", dstPath)
 		return nil
 	}
 
-	slog.Info("moving file", "source", srcPath, "dest", dstPath)
+	ctx.log.Info("moving file", "source", srcPath, "dest", dstPath)
 
-	if err := os.Rename(srcPath, dstPath); err != nil {
+	if err := ctx.fs.Rename(srcPath, dstPath); err != nil {
 		return fmt.Errorf("failed to move %s to %s: %w", srcPath, dstPath, err)
 	}
 
@@ -1002,7 +1294,7 @@ func moveFile(basedir, src, dest string, dryRun bool) error {
 // isRawPaired checks if a RAW file has an associated JPEG or HEIC
 // Searches in the source directory and optionally in the destination folder
 // (since JPEG may have already been moved during processing)
-func isRawPaired(rawPath string, basePath string, destFolder string) bool {
+func isRawPaired(fsys FileSystem, rawPath string, basePath string, destFolder string) bool {
 	baseName := strings.TrimSuffix(filepath.Base(rawPath), filepath.Ext(rawPath))
 
 	// Extensions to search for (JPEG and HEIC for iPhone)
@@ -1011,7 +1303,7 @@ func isRawPaired(rawPath string, basePath string, destFolder string) bool {
 	// 1. Search in source folder (basePath)
 	for _, ext := range photoExtensions {
 		photoPath := filepath.Join(basePath, baseName+ext)
-		if _, err := os.Stat(photoPath); err == nil {
+		if _, err := fsys.Stat(photoPath); err == nil {
 			slog.Debug("found paired photo in source", "photo", photoPath, "raw", filepath.Base(rawPath))
 			return true
 		}
@@ -1021,7 +1313,7 @@ func isRawPaired(rawPath string, basePath string, destFolder string) bool {
 	if destFolder != "" {
 		for _, ext := range photoExtensions {
 			photoPath := filepath.Join(destFolder, baseName+ext)
-			if _, err := os.Stat(photoPath); err == nil {
+			if _, err := fsys.Stat(photoPath); err == nil {
 				slog.Debug("found paired photo in destination", "photo", photoPath, "raw", filepath.Base(rawPath))
 				return true
 			}
@@ -1031,22 +1323,33 @@ func isRawPaired(rawPath string, basePath string, destFolder string) bool {
 	return false // Orphelin
 }
 
+// pairingPath returns the path used to look up the JPEG/HEIC paired with a RAW file
+// When the JPEG was renamed on collision, the RAW is looked up under its new basename
+func pairingPath(cfg *Config, ctx *executionContext, rawName string) string {
+	ext := filepath.Ext(rawName)
+	base := strings.TrimSuffix(rawName, ext)
+	if assigned, ok := ctx.assignedBase(filepath.Join(cfg.BasePath, base)); ok && assigned != base {
+		return filepath.Join(cfg.BasePath, assigned+ext)
+	}
+	return filepath.Join(cfg.BasePath, rawName)
+}
+
 // processPictureAtRoot handles the processing of picture files for small groups (left at root)
-func processPictureAtRoot(cfg *Config, ctx *executionContext, fi os.FileInfo, destinationRoot string) error {
-	slog.Debug("processing picture at root", "file", fi.Name(), "dest_root", destinationRoot)
+func processPictureAtRoot(cfg *Config, ctx *executionContext, file FileMetadata, destinationRoot string) error {
+	ctx.log.Debug("processing picture at root", "file", file.FileInfo.Name(), "dest_root", destinationRoot)
 
 	destDir := destinationRoot
 
 	// Create destination root folder if it doesn't exist (GPS mode)
 	if destinationRoot != "" && cfg.Mode != ModeDryRun {
 		destRootPath := filepath.Join(cfg.BasePath, destinationRoot)
-		if err := os.MkdirAll(destRootPath, permDirectory); err != nil {
+		if err := ctx.fs.MkdirAll(destRootPath, permDirectory); err != nil {
 			return fmt.Errorf("failed to create location folder %s: %w", destRootPath, err)
 		}
 	}
 
 	// Special handling for RAW files
-	if ctx.isRaw(fi.Name()) && !cfg.NoMoveRaw {
+	if ctx.isRaw(file.FileInfo.Name()) && !cfg.NoMoveRaw {
 		baseRawDir := cfg.BasePath
 		if destinationRoot != "" {
 			baseRawDir = filepath.Join(cfg.BasePath, destinationRoot)
@@ -1056,15 +1359,16 @@ func processPictureAtRoot(cfg *Config, ctx *executionContext, fi os.FileInfo, de
 		targetFolder := rawFolderName
 
 		if cfg.SeparateOrphanRaw {
-			rawFilePath := filepath.Join(cfg.BasePath, fi.Name())
+			rawFilePath := pairingPath(cfg, ctx, file.FileInfo.Name())
 			destFolder := baseRawDir
-			if !isRawPaired(rawFilePath, cfg.BasePath, destFolder) {
+			if !isRawPaired(ctx.fs, rawFilePath, cfg.BasePath, destFolder) {
 				targetFolder = orphanFolderName
-				slog.Debug("orphan RAW file (no JPEG/HEIC)", "file", fi.Name(), "dest", orphanFolderName)
+				ctx.orphanRaws = append(ctx.orphanRaws, filepath.Join(destinationRoot, orphanFolderName, file.FileInfo.Name()))
+				ctx.log.Debug("orphan RAW file (no JPEG/HEIC)", "file", file.FileInfo.Name(), "dest", orphanFolderName)
 			}
 		}
 
-		rawDir, err := findOrCreateFolder(baseRawDir, targetFolder, cfg.Mode == ModeDryRun)
+		rawDir, err := findOrCreateFolder(ctx.fs, baseRawDir, targetFolder, cfg.Mode == ModeDryRun)
 		if err != nil {
 			return err
 		}
@@ -1075,19 +1379,19 @@ func processPictureAtRoot(cfg *Config, ctx *executionContext, fi os.FileInfo, de
 		}
 	}
 
-	return moveFile(cfg.BasePath, fi.Name(), destDir, cfg.Mode == ModeDryRun)
+	return placeFile(cfg, ctx, file, destDir)
 }
 
 // processMovieAtRoot handles the processing of movie files for small groups (left at root)
-func processMovieAtRoot(cfg *Config, fi os.FileInfo, destinationRoot string) error {
-	slog.Debug("processing movie at root", "file", fi.Name(), "dest_root", destinationRoot)
+func processMovieAtRoot(cfg *Config, ctx *executionContext, file FileMetadata, destinationRoot string) error {
+	ctx.log.Debug("processing movie at root", "file", file.FileInfo.Name(), "dest_root", destinationRoot)
 
 	destDir := destinationRoot
 
 	// Create destination root folder if it doesn't exist (GPS mode)
 	if destinationRoot != "" && cfg.Mode != ModeDryRun {
 		destRootPath := filepath.Join(cfg.BasePath, destinationRoot)
-		if err := os.MkdirAll(destRootPath, permDirectory); err != nil {
+		if err := ctx.fs.MkdirAll(destRootPath, permDirectory); err != nil {
 			return fmt.Errorf("failed to create location folder %s: %w", destRootPath, err)
 		}
 	}
@@ -1099,7 +1403,7 @@ func processMovieAtRoot(cfg *Config, fi os.FileInfo, destinationRoot string) err
 			baseMovieDir = filepath.Join(cfg.BasePath, destinationRoot)
 		}
 
-		movieDir, err := findOrCreateFolder(baseMovieDir, movFolderName, cfg.Mode == ModeDryRun)
+		movieDir, err := findOrCreateFolder(ctx.fs, baseMovieDir, movFolderName, cfg.Mode == ModeDryRun)
 		if err != nil {
 			return err
 		}
@@ -1110,5 +1414,5 @@ func processMovieAtRoot(cfg *Config, fi os.FileInfo, destinationRoot string) err
 		}
 	}
 
-	return moveFile(cfg.BasePath, fi.Name(), destDir, cfg.Mode == ModeDryRun)
+	return placeFile(cfg, ctx, file, destDir)
 }
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -205,6 +206,10 @@ func TestIsRaw(t *testing.T) {
 		{"ARW Sony", "photo.arw", true},
 		{"ORF Olympus", "photo.orf", true},
 		{"RAF Fujifilm", "photo.raf", true},
+		{"CR3 Canon", "photo.cr3", true},
+		{"PEF Pentax", "photo.pef", true},
+		{"SRW Samsung", "photo.srw", true},
+		{"3FR Hasselblad", "photo.3fr", true},
 
 		// Non-raw files
 		{"JPG file", "photo.jpg", false},
@@ -245,7 +250,7 @@ func TestFindOrCreateFolder(t *testing.T) {
 	t.Run("create new folder", func(t *testing.T) {
 		tmpDir := t.TempDir()
 
-		folderName, err := findOrCreateFolder(tmpDir, "raw", false)
+		folderName, err := findOrCreateFolder(OSFileSystem{}, tmpDir, "raw", false)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -271,7 +276,7 @@ func TestFindOrCreateFolder(t *testing.T) {
 		}
 
 		// Should return existing folder
-		folderName, err := findOrCreateFolder(tmpDir, "raw", false)
+		folderName, err := findOrCreateFolder(OSFileSystem{}, tmpDir, "raw", false)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -285,7 +290,7 @@ func TestFindOrCreateFolder(t *testing.T) {
 		tmpDir := t.TempDir()
 
 		// In dry run, folder should NOT be created
-		folderName, err := findOrCreateFolder(tmpDir, "mov", true)
+		folderName, err := findOrCreateFolder(OSFileSystem{}, tmpDir, "mov", true)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -321,7 +326,7 @@ func TestMoveFile(t *testing.T) {
 		}
 
 		// Move file
-		err := moveFile(tmpDir, srcFile, destDir, false)
+		err := moveFile(newDefaultExecutionContext(), tmpDir, srcFile, destDir, false)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -360,7 +365,7 @@ func TestMoveFile(t *testing.T) {
 		destDir := "2024 - 0101 - 1000"
 
 		// In dry run, file should NOT be moved
-		err := moveFile(tmpDir, srcFile, destDir, true)
+		err := moveFile(newDefaultExecutionContext(), tmpDir, srcFile, destDir, true)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -398,7 +403,7 @@ func TestCollectMediaFiles(t *testing.T) {
 		}
 
 		cfg := &Config{BasePath: tmpDir, UseEXIF: false}
-		files, err := collectMediaFilesWithMetadata(cfg, newDefaultExecutionContext())
+		files, err := collectMediaFilesWithMetadata(context.Background(), cfg, newDefaultExecutionContext())
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -412,7 +417,7 @@ func TestCollectMediaFiles(t *testing.T) {
 		tmpDir := t.TempDir()
 
 		cfg := &Config{BasePath: tmpDir, UseEXIF: false}
-		files, err := collectMediaFilesWithMetadata(cfg, newDefaultExecutionContext())
+		files, err := collectMediaFilesWithMetadata(context.Background(), cfg, newDefaultExecutionContext())
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -430,7 +435,7 @@ func TestCollectMediaFiles(t *testing.T) {
 		createTestFile(t, tmpDir, "data.json", baseTime)
 
 		cfg := &Config{BasePath: tmpDir, UseEXIF: false}
-		files, err := collectMediaFilesWithMetadata(cfg, newDefaultExecutionContext())
+		files, err := collectMediaFilesWithMetadata(context.Background(), cfg, newDefaultExecutionContext())
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -519,13 +524,13 @@ func TestGroupFilesByGaps(t *testing.T) {
 			t.Errorf("expected 1 group, got %d", len(groups))
 		}
 
-		if len(groups[0].files) != 5 {
-			t.Errorf("expected 5 files in group, got %d", len(groups[0].files))
+		if len(groups[0].Files) != 5 {
+			t.Errorf("expected 5 files in group, got %d", len(groups[0].Files))
 		}
 
 		expectedFolder := baseTime.Format(dateFormatPattern)
-		if groups[0].folderName != expectedFolder {
-			t.Errorf("expected folder %q, got %q", expectedFolder, groups[0].folderName)
+		if groups[0].Folder != expectedFolder {
+			t.Errorf("expected folder %q, got %q", expectedFolder, groups[0].Folder)
 		}
 	})
 
@@ -554,12 +559,12 @@ func TestGroupFilesByGaps(t *testing.T) {
 			t.Errorf("expected 2 groups, got %d", len(groups))
 		}
 
-		if len(groups[0].files) != 3 {
-			t.Errorf("expected 3 files in first group, got %d", len(groups[0].files))
+		if len(groups[0].Files) != 3 {
+			t.Errorf("expected 3 files in first group, got %d", len(groups[0].Files))
 		}
 
-		if len(groups[1].files) != 2 {
-			t.Errorf("expected 2 files in second group, got %d", len(groups[1].files))
+		if len(groups[1].Files) != 2 {
+			t.Errorf("expected 2 files in second group, got %d", len(groups[1].Files))
 		}
 	})
 
@@ -585,8 +590,8 @@ func TestGroupFilesByGaps(t *testing.T) {
 		}
 
 		for i, group := range groups {
-			if len(group.files) != 1 {
-				t.Errorf("group %d: expected 1 file, got %d", i, len(group.files))
+			if len(group.Files) != 1 {
+				t.Errorf("group %d: expected 1 file, got %d", i, len(group.Files))
 			}
 		}
 	})
@@ -629,8 +634,8 @@ func TestGroupFilesByGaps(t *testing.T) {
 			t.Errorf("expected 1 group, got %d", len(groups))
 		}
 
-		if len(groups[0].files) != 1 {
-			t.Errorf("expected 1 file in group, got %d", len(groups[0].files))
+		if len(groups[0].Files) != 1 {
+			t.Errorf("expected 1 file in group, got %d", len(groups[0].Files))
 		}
 	})
 
@@ -679,7 +684,7 @@ func TestSplit_Integration(t *testing.T) {
 			Mode:        ModeRun,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -726,7 +731,7 @@ func TestSplit_Integration(t *testing.T) {
 			Mode:        ModeDryRun,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -760,7 +765,7 @@ func TestSplit_Integration(t *testing.T) {
 			Mode:        ModeRun,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -796,7 +801,7 @@ func TestSplit_Integration(t *testing.T) {
 			Mode:        ModeRun,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -842,7 +847,7 @@ func TestSplit_Integration(t *testing.T) {
 			Mode:        ModeRun,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -934,7 +939,7 @@ func TestSplit_Integration(t *testing.T) {
 			Mode:        ModeRun,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -1058,7 +1063,7 @@ func TestIsOrganizedFolder(t *testing.T) {
 			t.Fatalf("failed to create test folder: %v", err)
 		}
 
-		if !isOrganizedFolder(dateFolder) {
+		if !isOrganizedFolder(OSFileSystem{}, dateFolder) {
 			t.Error("should detect date-formatted folder name")
 		}
 	})
@@ -1082,7 +1087,7 @@ func TestIsOrganizedFolder(t *testing.T) {
 			t.Fatalf("failed to create subdir: %v", err)
 		}
 
-		result := isOrganizedFolder(tempDir)
+		result := isOrganizedFolder(OSFileSystem{}, tempDir)
 		if !result {
 			// Debug: list directory contents
 			entries, _ := os.ReadDir(tempDir)
@@ -1105,7 +1110,7 @@ func TestIsOrganizedFolder(t *testing.T) {
 			t.Fatalf("failed to create subdir: %v", err)
 		}
 
-		if isOrganizedFolder(tempDir) {
+		if isOrganizedFolder(OSFileSystem{}, tempDir) {
 			t.Error("should not detect regular folder as organized")
 		}
 	})
@@ -1124,7 +1129,7 @@ func TestIsOrganizedFolder(t *testing.T) {
 			t.Fatalf("failed to create subdir: %v", err)
 		}
 
-		if isOrganizedFolder(tempDir) {
+		if isOrganizedFolder(OSFileSystem{}, tempDir) {
 			t.Error("should not detect folder with <50% date subdirs as organized")
 		}
 	})
@@ -1132,7 +1137,7 @@ func TestIsOrganizedFolder(t *testing.T) {
 	t.Run("empty folder", func(t *testing.T) {
 		tempDir := t.TempDir()
 
-		if isOrganizedFolder(tempDir) {
+		if isOrganizedFolder(OSFileSystem{}, tempDir) {
 			t.Error("empty folder should not be considered organized")
 		}
 	})
@@ -1195,7 +1200,7 @@ func TestSplit_ValidateMode(t *testing.T) {
 		UseGPS:   false,
 	}
 
-	err := Split(cfg)
+	_, err := Split(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Split() in validate mode failed: %v", err)
 	}
@@ -1225,7 +1230,7 @@ func TestSplit_DryRunMode(t *testing.T) {
 		UseGPS:   false,
 	}
 
-	err := Split(cfg)
+	_, err := Split(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Split() in dry-run mode failed: %v", err)
 	}
@@ -1427,7 +1432,7 @@ func TestRefreshOrphanRAW(t *testing.T) {
 
 		ctx, _ := newExecutionContext(cfg)
 
-		err := refreshOrphanRAW(cfg, ctx)
+		_, err := refreshOrphanRAW(cfg, ctx)
 		if err != nil {
 			t.Fatalf("refreshOrphanRAW failed: %v", err)
 		}
@@ -1465,7 +1470,7 @@ func TestRefreshOrphanRAW(t *testing.T) {
 
 		ctx, _ := newExecutionContext(cfg)
 
-		err := refreshOrphanRAW(cfg, ctx)
+		_, err := refreshOrphanRAW(cfg, ctx)
 		if err != nil {
 			t.Fatalf("refreshOrphanRAW failed: %v", err)
 		}
@@ -1500,7 +1505,7 @@ func TestRefreshOrphanRAW(t *testing.T) {
 
 		ctx, _ := newExecutionContext(cfg)
 
-		err := refreshOrphanRAW(cfg, ctx)
+		_, err := refreshOrphanRAW(cfg, ctx)
 		if err != nil {
 			t.Fatalf("refreshOrphanRAW failed: %v", err)
 		}
@@ -1532,7 +1537,7 @@ func TestRefreshOrphanRAW(t *testing.T) {
 		ctx, _ := newExecutionContext(cfg)
 
 		// Should not error on special folders
-		err := refreshOrphanRAW(cfg, ctx)
+		_, err := refreshOrphanRAW(cfg, ctx)
 		if err != nil {
 			t.Fatalf("refreshOrphanRAW should skip special folders: %v", err)
 		}
@@ -1560,7 +1565,7 @@ func TestSplit_NoMediaFiles(t *testing.T) {
 		UseGPS:      false,
 	}
 
-	err := Split(cfg)
+	_, err := Split(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Split() should not error with no media files, got: %v", err)
 	}
@@ -1590,7 +1595,7 @@ func TestSplit_GPSMode_AllFilesWithGPS(t *testing.T) {
 	// Note: This test will process files but won't create GPS clusters
 	// because we can't inject GPS coordinates without EXIF
 	// When NO location clusters exist, files should be at root (no NoLocation folder)
-	err := Split(cfg)
+	_, err := Split(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Split() GPS mode error: %v", err)
 	}
@@ -1625,7 +1630,7 @@ func TestSplit_ValidationError(t *testing.T) {
 		Delta:    30 * time.Minute,
 	}
 
-	err := Split(cfg)
+	_, err := Split(context.Background(), cfg)
 	if err == nil {
 		t.Error("Split() should error on invalid configuration")
 	}
@@ -1637,7 +1642,7 @@ func TestSplit_InvalidBasePath(t *testing.T) {
 		Delta:    30 * time.Minute,
 	}
 
-	err := Split(cfg)
+	_, err := Split(context.Background(), cfg)
 	if err == nil {
 		t.Error("Split() should error when base path doesn't exist")
 	}
@@ -1652,7 +1657,7 @@ func TestCollectMediaFilesWithMetadata_EmptyDirectory(t *testing.T) {
 		UseEXIF:  true,
 	}
 
-	files, err := collectMediaFilesWithMetadata(cfg, newDefaultExecutionContext())
+	files, err := collectMediaFilesWithMetadata(context.Background(), cfg, newDefaultExecutionContext())
 	if err != nil {
 		t.Fatalf("collectMediaFilesWithMetadata(, newDefaultExecutionContext()) error: %v", err)
 	}
@@ -1677,7 +1682,7 @@ func TestProcessGroup_DryRunMode(t *testing.T) {
 		UseEXIF:     false,
 	}
 
-	err := Split(cfg)
+	_, err := Split(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Split() dry-run error: %v", err)
 	}
@@ -1709,7 +1714,7 @@ func TestSplit_MixedMediaTypes(t *testing.T) {
 		UseEXIF:     false, // Use ModTime
 	}
 
-	err := Split(cfg)
+	_, err := Split(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Split() error: %v", err)
 	}
@@ -1764,7 +1769,7 @@ func TestSplit_NoMoveMovieAndRaw(t *testing.T) {
 		UseEXIF:     false,
 	}
 
-	err := Split(cfg)
+	_, err := Split(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Split() error: %v", err)
 	}
@@ -1833,7 +1838,7 @@ func TestSplit_MultipleGroups(t *testing.T) {
 		UseEXIF:     false,
 	}
 
-	err := Split(cfg)
+	_, err := Split(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Split() error: %v", err)
 	}
@@ -1873,7 +1878,7 @@ func TestCollectMediaFilesWithMetadata_OnlyNonMedia(t *testing.T) {
 		UseEXIF:  true,
 	}
 
-	files, err := collectMediaFilesWithMetadata(cfg, newDefaultExecutionContext())
+	files, err := collectMediaFilesWithMetadata(context.Background(), cfg, newDefaultExecutionContext())
 	if err != nil {
 		t.Fatalf("collectMediaFilesWithMetadata(, newDefaultExecutionContext()) error: %v", err)
 	}
@@ -1896,7 +1901,7 @@ func TestIsRawPaired(t *testing.T) {
 		createTestFile(t, tmpDir, "PHOTO_01.JPG", time.Now())
 
 		rawPath := filepath.Join(tmpDir, "PHOTO_01.NEF")
-		if !isRawPaired(rawPath, tmpDir, "") {
+		if !isRawPaired(OSFileSystem{}, rawPath, tmpDir, "") {
 			t.Error("RAW should be paired with JPEG in same folder")
 		}
 	})
@@ -1909,7 +1914,7 @@ func TestIsRawPaired(t *testing.T) {
 		createTestFile(t, tmpDir, "IMG_1234.HEIC", time.Now())
 
 		rawPath := filepath.Join(tmpDir, "IMG_1234.DNG")
-		if !isRawPaired(rawPath, tmpDir, "") {
+		if !isRawPaired(OSFileSystem{}, rawPath, tmpDir, "") {
 			t.Error("RAW should be paired with HEIC")
 		}
 	})
@@ -1921,7 +1926,7 @@ func TestIsRawPaired(t *testing.T) {
 		createTestFile(t, tmpDir, "PHOTO_02.NEF", time.Now())
 
 		rawPath := filepath.Join(tmpDir, "PHOTO_02.NEF")
-		if isRawPaired(rawPath, tmpDir, "") {
+		if isRawPaired(OSFileSystem{}, rawPath, tmpDir, "") {
 			t.Error("RAW should be orphan (no JPEG/HEIC)")
 		}
 	})
@@ -1934,7 +1939,7 @@ func TestIsRawPaired(t *testing.T) {
 		createTestFile(t, tmpDir, "PHOTO_03.jpeg", time.Now())
 
 		rawPath := filepath.Join(tmpDir, "PHOTO_03.CR2")
-		if !isRawPaired(rawPath, tmpDir, "") {
+		if !isRawPaired(OSFileSystem{}, rawPath, tmpDir, "") {
 			t.Error("RAW should be paired with .jpeg (case insensitive)")
 		}
 	})
@@ -1955,7 +1960,7 @@ func TestIsRawPaired(t *testing.T) {
 		createTestFile(t, tmpDir, "PHOTO_04.NEF", time.Now())
 
 		rawPath := filepath.Join(tmpDir, "PHOTO_04.NEF")
-		if !isRawPaired(rawPath, tmpDir, destFolder) {
+		if !isRawPaired(OSFileSystem{}, rawPath, tmpDir, destFolder) {
 			t.Error("RAW should be paired with JPEG in destination folder")
 		}
 	})
@@ -1983,7 +1988,7 @@ func TestSplit_OrphanRawSeparation(t *testing.T) {
 			SeparateOrphanRaw: true, // Enabled
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error: %v", err)
 		}
@@ -2026,7 +2031,7 @@ func TestSplit_OrphanRawSeparation(t *testing.T) {
 			SeparateOrphanRaw: false, // Disabled
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error: %v", err)
 		}
@@ -2071,7 +2076,7 @@ func TestSplit_OrphanRawSeparation(t *testing.T) {
 			SeparateOrphanRaw: true,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error: %v", err)
 		}
@@ -2109,7 +2114,7 @@ func TestSplit_OrphanRawSeparation(t *testing.T) {
 			SeparateOrphanRaw: true,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error: %v", err)
 		}
@@ -2152,7 +2157,7 @@ func TestSplit_OrphanRawSeparation(t *testing.T) {
 			SeparateOrphanRaw: true,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error: %v", err)
 		}
@@ -2205,7 +2210,7 @@ func TestSplit_ContinueOnError(t *testing.T) {
 		}
 
 		// Verify Split() doesn't error with valid files
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Errorf("Split() should succeed with valid files: %v", err)
 		}
@@ -2309,7 +2314,7 @@ func TestSplit_MoveDuplicates(t *testing.T) {
 			MoveDuplicates:   true,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error = %v, want nil", err)
 		}
@@ -2384,7 +2389,7 @@ func TestSplit_MoveDuplicates(t *testing.T) {
 			MoveDuplicates:   true,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error = %v, want nil", err)
 		}
@@ -2427,7 +2432,7 @@ func TestSplit_MoveDuplicates(t *testing.T) {
 			ContinueOnError:  true,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error = %v, want nil (continue-on-error)", err)
 		}
@@ -2472,7 +2477,7 @@ func TestSplit_GPSMode_ZeroCoverage(t *testing.T) {
 		GPSRadius:   2000.0,
 	}
 
-	err := Split(cfg)
+	_, err := Split(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Split() GPS mode with 0 coverage error: %v", err)
 	}
@@ -2521,7 +2526,7 @@ func TestCollectMediaFiles_MixedExtensions(t *testing.T) {
 	}
 
 	ctx := newDefaultExecutionContext()
-	files, err := collectMediaFilesWithMetadata(cfg, ctx)
+	files, err := collectMediaFilesWithMetadata(context.Background(), cfg, ctx)
 	if err != nil {
 		t.Fatalf("collectMediaFilesWithMetadata() error: %v", err)
 	}
@@ -2574,7 +2579,7 @@ func TestSplit_MinGroupSize(t *testing.T) {
 			MinGroupSize: 5, // Default threshold
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error: %v", err)
 		}
@@ -2630,7 +2635,7 @@ func TestSplit_MinGroupSize(t *testing.T) {
 			MinGroupSize: 3, // Custom threshold
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error: %v", err)
 		}
@@ -2679,7 +2684,7 @@ func TestSplit_MinGroupSize(t *testing.T) {
 			MinGroupSize: 0, // No filtering
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error: %v", err)
 		}
@@ -2725,7 +2730,7 @@ func TestSplit_MinGroupSize(t *testing.T) {
 			MinGroupSize: 5,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error: %v", err)
 		}
@@ -2773,7 +2778,7 @@ func TestSplit_MinGroupSize(t *testing.T) {
 			MinGroupSize: 5,
 		}
 
-		err := Split(cfg)
+		_, err := Split(context.Background(), cfg)
 		if err != nil {
 			t.Fatalf("Split() error: %v", err)
 		}
@@ -2860,7 +2865,7 @@ func TestSplit_MinGroupSize_GPSMode(t *testing.T) {
 		MinGroupSize: 5,
 	}
 
-	err := Split(cfg)
+	_, err := Split(context.Background(), cfg)
 	if err != nil {
 		t.Fatalf("Split() error: %v", err)
 	}
@@ -2905,7 +2910,7 @@ func TestProcessPictureAtRoot(t *testing.T) {
 	ctx := newDefaultExecutionContext()
 
 	// Process photo at root (no destination root)
-	err = processPictureAtRoot(cfg, ctx, fi, "")
+	err = processPictureAtRoot(cfg, ctx, fileInfoToMetadata(fi), "")
 	if err != nil {
 		t.Fatalf("processPictureAtRoot() error: %v", err)
 	}
@@ -2934,8 +2939,10 @@ func TestProcessMovieAtRoot(t *testing.T) {
 		NoMoveMovie: false,
 	}
 
+	ctx := newDefaultExecutionContext()
+
 	// Process video at root with NoMoveMovie=false (should create mov/ folder)
-	err = processMovieAtRoot(cfg, fi, "")
+	err = processMovieAtRoot(cfg, ctx, fileInfoToMetadata(fi), "")
 	if err != nil {
 		t.Fatalf("processMovieAtRoot() error: %v", err)
 	}
@@ -2975,7 +2982,7 @@ func TestProcessPictureAtRoot_RAW(t *testing.T) {
 	ctx := newDefaultExecutionContext()
 
 	// Process RAW at root - should go to raw/ folder (paired with JPEG)
-	err = processPictureAtRoot(cfg, ctx, fiRAW, "")
+	err = processPictureAtRoot(cfg, ctx, fileInfoToMetadata(fiRAW), "")
 	if err != nil {
 		t.Fatalf("processPictureAtRoot() RAW error: %v", err)
 	}
@@ -3018,7 +3025,7 @@ func TestProcessPictureAtRoot_WithDestinationRoot(t *testing.T) {
 	ctx := newDefaultExecutionContext()
 
 	// Process photo at location root
-	err = processPictureAtRoot(cfg, ctx, fi, locationRoot)
+	err = processPictureAtRoot(cfg, ctx, fileInfoToMetadata(fi), locationRoot)
 	if err != nil {
 		t.Fatalf("processPictureAtRoot() with destination root error: %v", err)
 	}
@@ -3054,8 +3061,10 @@ func TestProcessMovieAtRoot_WithDestinationRoot(t *testing.T) {
 		NoMoveMovie: false,
 	}
 
+	ctx := newDefaultExecutionContext()
+
 	// Process video at location root - should go to Paris/mov/
-	err = processMovieAtRoot(cfg, fi, locationRoot)
+	err = processMovieAtRoot(cfg, ctx, fileInfoToMetadata(fi), locationRoot)
 	if err != nil {
 		t.Fatalf("processMovieAtRoot() with destination root error: %v", err)
 	}
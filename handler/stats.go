@@ -40,11 +40,71 @@ type ProcessingStats struct {
 	SmallGroupsCount int // Number of groups below MinGroupSize threshold
 	RootFilesCount   int // Number of files left at root (from small groups)
 
+	// Name collisions (v2.10.0+)
+	CollisionsRenamed     int // Files renamed because the destination name was taken
+	CollisionsSkipped     int // Files left in place because the destination name was taken
+	CollisionsOverwritten int // Files that replaced an existing destination file
+	IdenticalSkipped      int // Files left in place because an identical copy is already at destination
+
+	// File renaming (v2.10.0+)
+	FilesRenamed int // Files and sidecars whose name changed (recorded in the rename manifest)
+
+	// Incremental split (v2.10.0+)
+	GroupsExtended int // Groups appended to an existing event folder
+	EventsMerged   int // Event folders merged into an earlier one because new files made them touch
+
+	// Interruption (v2.10.0+)
+	CheckpointPath string // Checkpoint to continue from with --resume, empty when nothing is left
+	Interrupted    bool   // The run was canceled before all files were processed
+
+	// Routing rules (v2.10.0+)
+	RuleHits []RuleHit // Files matched by each rule of --rules, in rules order
+
+	// Classification (v2.10.0+)
+	ScreenshotFiles int // Files sent to screenshots/
+	MessagingFiles  int // Files sent to messaging/<app>/
+
+	// Contact sheets (v2.10.0+)
+	ContactSheets int // Event folders that received a contact.jpg
+
+	// Run report details (v2.10.0+)
+	Groups         []GroupSummary // Event folders created or extended, in plan order
+	OrphanRawFiles []string       // Orphan RAW files moved to orphan/, relative to BasePath
+
+	// Location inference (v2.10.0+)
+	LocationInferences []LocationInference // Files without GPS placed in the location of a geotagged neighbour
+
 	// Issues
-	ModTimeFallbackCount int // Files that fell back to ModTime
+	ModTimeFallbackCount int            // Files that fell back to ModTime
+	DateConflicts        []DateConflict // Files whose dates disagree by more than DateConflictThreshold (v2.10.0+)
 	Errors               []*PicsplitError
 }
 
+// GroupSummary describes an event folder filled by a run (v2.10.0+)
+type GroupSummary struct {
+	Start    time.Time      // Date of the earliest file
+	End      time.Time      // Date of the latest file
+	Folder   string         // Folder relative to BasePath
+	Files    []string       // Source file names
+	Metadata []FileMetadata // Extracted metadata, in the order of Files
+	Existing bool           // Files were appended to an already organized event folder
+	Split    string         // Why the group was split from the previous one (adaptive grouping, v2.10.0+)
+}
+
+// newGroupSummary summarizes a planned group, files are in chronological order
+func newGroupSummary(group Group) GroupSummary {
+	summary := GroupSummary{Folder: group.Folder, Existing: group.Existing, Split: group.Split}
+	for _, file := range group.Files {
+		summary.Files = append(summary.Files, file.FileInfo.Name())
+	}
+	summary.Metadata = append(summary.Metadata, group.Files...)
+	if len(group.Files) > 0 {
+		summary.Start = group.Files[0].DateTime
+		summary.End = group.Files[len(group.Files)-1].DateTime
+	}
+	return summary
+}
+
 // AddError adds an error to the statistics
 // If the error is a PicsplitError, it's added to the Errors slice
 // If it's a generic error, it's wrapped as a PicsplitError with ErrTypeIO
@@ -131,8 +191,15 @@ func (s *ProcessingStats) PrintSummary(dryRun bool) {
 
 	// Duration
 	duration := s.Duration()
-	slog.Info("processing completed",
-		"duration", fmt.Sprintf("%dm %ds", int(duration.Minutes()), int(duration.Seconds())%60))
+	durationText := fmt.Sprintf("%dm %ds", int(duration.Minutes()), int(duration.Seconds())%60)
+	if s.Interrupted {
+		slog.Warn("processing interrupted", "status", "interrupted", "duration", durationText)
+		if s.CheckpointPath != "" {
+			slog.Warn("remaining files saved, run again with --resume to continue", "checkpoint", s.CheckpointPath)
+		}
+	} else {
+		slog.Info("processing completed", "duration", durationText)
+	}
 
 	// Files processed
 	slog.Info("files processed",
@@ -163,6 +230,23 @@ func (s *ProcessingStats) PrintSummary(dryRun bool) {
 	// Groups created
 	slog.Info("groups created", "count", s.GroupsCreated)
 
+	// Adaptive grouping explains its splits before anything is moved (v2.10.0+)
+	if dryRun {
+		for _, group := range s.Groups {
+			if group.Split != "" {
+				slog.Info("event split", "folder", group.Folder, "reason", group.Split)
+			}
+		}
+	}
+
+	// Incremental split (v2.10.0+)
+	if s.GroupsExtended > 0 {
+		slog.Info("existing event folders extended", "count", s.GroupsExtended)
+	}
+	if s.EventsMerged > 0 {
+		slog.Info("event folders merged", "count", s.EventsMerged)
+	}
+
 	// MinGroupSize stats (v2.9.0+)
 	if s.SmallGroupsCount > 0 {
 		slog.Info("small groups filtered",
@@ -170,6 +254,37 @@ func (s *ProcessingStats) PrintSummary(dryRun bool) {
 			"files_at_root", s.RootFilesCount)
 	}
 
+	// Routing rules (v2.10.0+)
+	printRuleHits(s.RuleHits)
+
+	// Classification (v2.10.0+)
+	if s.ScreenshotFiles > 0 || s.MessagingFiles > 0 {
+		slog.Info("files classified",
+			"screenshots", s.ScreenshotFiles,
+			"messaging", s.MessagingFiles)
+	}
+
+	// Name collisions (v2.10.0+)
+	if s.CollisionsRenamed > 0 || s.CollisionsSkipped > 0 || s.CollisionsOverwritten > 0 || s.IdenticalSkipped > 0 {
+		slog.Info("name collisions",
+			"renamed", s.CollisionsRenamed,
+			"skipped", s.CollisionsSkipped,
+			"overwritten", s.CollisionsOverwritten,
+			"identical_skipped", s.IdenticalSkipped)
+	}
+
+	// File renaming (v2.10.0+)
+	if s.FilesRenamed > 0 {
+		slog.Info("files renamed",
+			"count", s.FilesRenamed,
+			"manifest", renameManifestName)
+	}
+
+	// Contact sheets (v2.10.0+)
+	if s.ContactSheets > 0 {
+		slog.Info("contact sheets written", "count", s.ContactSheets)
+	}
+
 	// RAW organization
 	if s.PairedRaw > 0 || s.OrphanRaw > 0 {
 		slog.Info("RAW organization",
@@ -230,6 +345,20 @@ func (s *ProcessingStats) PrintSummary(dryRun bool) {
 			"reason", "EXIF metadata unavailable or corrupted")
 	}
 
+	// Inferred locations (v2.10.0+)
+	if len(s.LocationInferences) > 0 {
+		fmt.Println()
+		printLocationInferences(s.LocationInferences)
+		slog.Info("locations inferred", "count", len(s.LocationInferences))
+	}
+
+	// Date conflicts (v2.10.0+)
+	if len(s.DateConflicts) > 0 {
+		fmt.Println()
+		printDateConflicts(s.DateConflicts)
+		slog.Warn("files with conflicting dates", "count", len(s.DateConflicts))
+	}
+
 	// Duplicates summary
 	if len(s.DuplicatesDetected) > 0 || s.DuplicatesSkipped > 0 {
 		fmt.Println()
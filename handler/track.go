@@ -0,0 +1,472 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cameras without GPS are geotagged from the track recorded by a watch or phone (v2.10.0+)
+// The position of a file is interpolated between the track points around its date, shifted by --gpx-offset
+
+// DefaultGPXMaxGap is the longest gap used when Config.GPXMaxGap is zero
+const DefaultGPXMaxGap = 10 * time.Minute
+
+const (
+	// maxTrackSize bounds a track file read in memory (a day of 1 s points is a few MB)
+	maxTrackSize = 256 << 20
+
+	// fitRecordMessage is the global number of FIT record messages, holding timestamp and position
+	fitRecordMessage = 20
+
+	fitFieldLatitude  = 0
+	fitFieldLongitude = 1
+	fitFieldTimestamp = 253
+
+	// fitInvalidSint32 marks a missing latitude or longitude
+	fitInvalidSint32 = 0x7FFFFFFF
+)
+
+// fitEpoch is the origin of FIT timestamps
+var fitEpoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// errUnknownTrackFormat is returned for files that are not GPX, KML or FIT tracks
+var errUnknownTrackFormat = errors.New("unknown track format (expected .gpx, .kml or .fit)")
+
+// trackPoint is a position recorded at a given time
+type trackPoint struct {
+	time time.Time
+	pos  GPSCoord
+}
+
+// gpsTrack holds the points of every loaded track, sorted by time
+type gpsTrack struct {
+	points []trackPoint
+}
+
+// gpxMaxGap returns the configured longest gap, or the default one
+func (c *Config) gpxMaxGap() time.Duration {
+	if c.GPXMaxGap > 0 {
+		return c.GPXMaxGap
+	}
+	return DefaultGPXMaxGap
+}
+
+// isTrackFile checks if a file name has a track extension
+func isTrackFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gpx", ".kml", ".fit":
+		return true
+	default:
+		return false
+	}
+}
+
+// loadTracks loads a GPX, KML or FIT file, or every track of a folder and its subfolders, from fsys
+func loadTracks(fsys FileSystem, path string) (*gpsTrack, error) {
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read track: %w", err)
+	}
+
+	var files []string
+	if info.IsDir() {
+		err := walkDir(fsys, path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && isTrackFile(d.Name()) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tracks: %w", err)
+		}
+	} else {
+		files = []string{path}
+	}
+
+	track := &gpsTrack{}
+	for _, file := range files {
+		points, err := readTrackFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+		track.points = append(track.points, points...)
+	}
+	if len(track.points) == 0 {
+		return nil, fmt.Errorf("no timed track point in %s", path)
+	}
+
+	sort.SliceStable(track.points, func(i, j int) bool {
+		return track.points[i].time.Before(track.points[j].time)
+	})
+	return track, nil
+}
+
+// readTrackFile reads the timed points of a track file
+func readTrackFile(fsys FileSystem, path string) ([]trackPoint, error) {
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxTrackSize {
+		return nil, fmt.Errorf("track too large: %d bytes", info.Size())
+	}
+
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gpx":
+		return parseGPX(data)
+	case ".kml":
+		return parseKML(data)
+	case ".fit":
+		return parseFIT(data)
+	default:
+		return nil, errUnknownTrackFormat
+	}
+}
+
+// parseGPX reads the timed track, route and waypoints of a GPX file
+func parseGPX(data []byte) ([]trackPoint, error) {
+	var points []trackPoint
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return points, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPX: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || (start.Name.Local != "trkpt" && start.Name.Local != "rtept" && start.Name.Local != "wpt") {
+			continue
+		}
+
+		var point struct {
+			Lat  float64 `xml:"lat,attr"`
+			Lon  float64 `xml:"lon,attr"`
+			Time string  `xml:"time"`
+		}
+		if err := decoder.DecodeElement(&point, &start); err != nil {
+			return nil, fmt.Errorf("invalid GPX point: %w", err)
+		}
+		if point.Time == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(point.Time))
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPX time %q: %w", point.Time, err)
+		}
+		points = append(points, trackPoint{time: t, pos: GPSCoord{Lat: point.Lat, Lon: point.Lon}})
+	}
+}
+
+// parseKML reads the timed points of a KML file: gx:Track when/gx:coord pairs
+// and placemarks with a TimeStamp and a Point
+func parseKML(data []byte) ([]trackPoint, error) {
+	var points []trackPoint
+	var whens, coords []string
+	var stampWhen, pointCoords string
+	var path []string
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid KML: %w", err)
+		}
+
+		switch tok := token.(type) {
+		case xml.StartElement:
+			path = append(path, tok.Name.Local)
+		case xml.CharData:
+			if len(path) == 0 {
+				continue
+			}
+			text := strings.TrimSpace(string(tok))
+			parent := ""
+			if len(path) > 1 {
+				parent = path[len(path)-2]
+			}
+			switch element := path[len(path)-1]; {
+			case element == "when" && parent == "Track":
+				whens = append(whens, text)
+			case element == "coord" && parent == "Track":
+				coords = append(coords, text)
+			case element == "when" && parent == "TimeStamp":
+				stampWhen = text
+			case element == "coordinates" && parent == "Point":
+				pointCoords = text
+			}
+		case xml.EndElement:
+			path = path[:len(path)-1]
+			switch tok.Name.Local {
+			case "Track":
+				for i := range min(len(whens), len(coords)) {
+					point, err := kmlPoint(whens[i], strings.Fields(coords[i]))
+					if err != nil {
+						return nil, err
+					}
+					points = append(points, point)
+				}
+				whens, coords = nil, nil
+			case "Placemark":
+				if stampWhen != "" && pointCoords != "" {
+					point, err := kmlPoint(stampWhen, strings.Split(pointCoords, ","))
+					if err != nil {
+						return nil, err
+					}
+					points = append(points, point)
+				}
+				stampWhen, pointCoords = "", ""
+			}
+		}
+	}
+	return points, nil
+}
+
+// kmlPoint builds a point from a KML time and "longitude latitude [altitude]" values
+func kmlPoint(when string, values []string) (trackPoint, error) {
+	t, err := time.Parse(time.RFC3339Nano, when)
+	if err != nil {
+		return trackPoint{}, fmt.Errorf("invalid KML time %q: %w", when, err)
+	}
+	if len(values) < 2 {
+		return trackPoint{}, fmt.Errorf("invalid KML coordinates %q", strings.Join(values, " "))
+	}
+	lon, errLon := strconv.ParseFloat(strings.TrimSpace(values[0]), 64)
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(values[1]), 64)
+	if err := errors.Join(errLon, errLat); err != nil {
+		return trackPoint{}, fmt.Errorf("invalid KML coordinates: %w", err)
+	}
+	return trackPoint{time: t, pos: GPSCoord{Lat: lat, Lon: lon}}, nil
+}
+
+// fitField is a field of a FIT definition message
+type fitField struct {
+	num  byte
+	size int
+}
+
+// fitDefinition describes the data messages of a local message type
+type fitDefinition struct {
+	global    uint16
+	order     binary.ByteOrder
+	fields    []fitField
+	devFields int // Total size of developer fields, skipped
+}
+
+// parseFIT reads the positions of the record messages of a FIT activity (Garmin, Coros, Suunto, Wahoo)
+func parseFIT(data []byte) ([]trackPoint, error) {
+	if len(data) < 12 || string(data[8:12]) != ".FIT" {
+		return nil, errors.New("invalid FIT: missing .FIT signature")
+	}
+	headerSize := int(data[0])
+	end := headerSize + int(binary.LittleEndian.Uint32(data[4:8]))
+	if headerSize < 12 || end > len(data) {
+		return nil, errors.New("invalid FIT: truncated file")
+	}
+
+	var points []trackPoint
+	definitions := make(map[byte]*fitDefinition)
+	var lastTimestamp uint32
+	errTruncated := errors.New("invalid FIT: truncated record")
+
+	for pos := headerSize; pos < end; {
+		header := data[pos]
+		pos++
+
+		// Compressed timestamp header: data message with a 5-bit offset on the last timestamp
+		var local byte
+		compressed := header&0x80 != 0
+		if compressed {
+			local = (header >> 5) & 0x03
+			offset := uint32(header & 0x1F)
+			if offset >= lastTimestamp&0x1F {
+				lastTimestamp = lastTimestamp&^0x1F + offset
+			} else {
+				lastTimestamp = lastTimestamp&^0x1F + offset + 0x20
+			}
+		} else {
+			local = header & 0x0F
+		}
+
+		// Definition message
+		if !compressed && header&0x40 != 0 {
+			if pos+5 > end {
+				return nil, errTruncated
+			}
+			def := &fitDefinition{order: binary.LittleEndian}
+			if data[pos+1] == 1 {
+				def.order = binary.BigEndian
+			}
+			def.global = def.order.Uint16(data[pos+2 : pos+4])
+			count := int(data[pos+4])
+			pos += 5
+			if pos+3*count > end {
+				return nil, errTruncated
+			}
+			for i := range count {
+				def.fields = append(def.fields, fitField{num: data[pos+3*i], size: int(data[pos+3*i+1])})
+			}
+			pos += 3 * count
+			if header&0x20 != 0 {
+				if pos >= end {
+					return nil, errTruncated
+				}
+				devCount := int(data[pos])
+				pos++
+				if pos+3*devCount > end {
+					return nil, errTruncated
+				}
+				for i := range devCount {
+					def.devFields += int(data[pos+3*i+1])
+				}
+				pos += 3 * devCount
+			}
+			definitions[local] = def
+			continue
+		}
+
+		// Data message
+		def, ok := definitions[local]
+		if !ok {
+			return nil, fmt.Errorf("invalid FIT: data message without definition (local type %d)", local)
+		}
+		lat, lon := int32(fitInvalidSint32), int32(fitInvalidSint32)
+		for _, field := range def.fields {
+			if pos+field.size > end {
+				return nil, errTruncated
+			}
+			value := data[pos : pos+field.size]
+			pos += field.size
+			switch {
+			case field.num == fitFieldTimestamp && field.size == 4:
+				lastTimestamp = def.order.Uint32(value)
+			case def.global == fitRecordMessage && field.num == fitFieldLatitude && field.size == 4:
+				lat = int32(def.order.Uint32(value)) //nolint:gosec // FIT sint32 semicircles
+			case def.global == fitRecordMessage && field.num == fitFieldLongitude && field.size == 4:
+				lon = int32(def.order.Uint32(value)) //nolint:gosec // FIT sint32 semicircles
+			}
+		}
+		pos += def.devFields
+
+		if def.global == fitRecordMessage && lat != fitInvalidSint32 && lon != fitInvalidSint32 && lastTimestamp != 0 {
+			points = append(points, trackPoint{
+				time: fitEpoch.Add(time.Duration(lastTimestamp) * time.Second),
+				pos:  GPSCoord{Lat: semicirclesToDegrees(lat), Lon: semicirclesToDegrees(lon)},
+			})
+		}
+	}
+	return points, nil
+}
+
+// semicirclesToDegrees converts a FIT position to decimal degrees
+func semicirclesToDegrees(semicircles int32) float64 {
+	return float64(semicircles) * 180 / math.Pow(2, 31)
+}
+
+// position returns the position at a given time, interpolated between the surrounding points
+// when they are at most maxGap apart, or the nearest point when it is at most maxGap away
+func (t *gpsTrack) position(at time.Time, maxGap time.Duration) (GPSCoord, bool) {
+	i := sort.Search(len(t.points), func(i int) bool { return !t.points[i].time.Before(at) })
+
+	if i < len(t.points) && t.points[i].time.Equal(at) {
+		return t.points[i].pos, true
+	}
+
+	var prev, next *trackPoint
+	if i > 0 {
+		prev = &t.points[i-1]
+	}
+	if i < len(t.points) {
+		next = &t.points[i]
+	}
+
+	if prev != nil && next != nil && next.time.Sub(prev.time) <= maxGap {
+		ratio := float64(at.Sub(prev.time)) / float64(next.time.Sub(prev.time))
+		return GPSCoord{
+			Lat: prev.pos.Lat + (next.pos.Lat-prev.pos.Lat)*ratio,
+			Lon: prev.pos.Lon + (next.pos.Lon-prev.pos.Lon)*ratio,
+		}, true
+	}
+
+	switch {
+	case prev != nil && at.Sub(prev.time) <= maxGap && (next == nil || at.Sub(prev.time) <= next.time.Sub(at)):
+		return prev.pos, true
+	case next != nil && next.time.Sub(at) <= maxGap:
+		return next.pos, true
+	default:
+		return GPSCoord{}, false
+	}
+}
+
+// geotagFromTrack sets the position of the files without GPS from the --gpx track
+// Nothing is written here: the scan is shared with dry runs, validation and the library report
+func (ctx *executionContext) geotagFromTrack(cfg *Config, files []FileMetadata) {
+	if ctx.track == nil {
+		return
+	}
+
+	var tagged, missed int
+	for i := range files {
+		if files[i].GPS != nil {
+			continue
+		}
+
+		pos, ok := ctx.track.position(files[i].DateTime.Add(cfg.GPXOffset), cfg.gpxMaxGap())
+		if !ok {
+			missed++
+			continue
+		}
+		files[i].GPS = &pos
+		files[i].GPSInterpolated = true
+		tagged++
+	}
+
+	ctx.log.Info("files geotagged from GPS track",
+		"geotagged", tagged,
+		"outside_track", missed,
+		"offset", cfg.GPXOffset)
+}
+
+// writeTrackSidecars writes the positions interpolated from the track to new XMP sidecars
+// with --gpx-write-xmp in run mode, before the files are moved so the sidecars follow them
+func writeTrackSidecars(cfg *Config, ctx *executionContext, groups []Group) {
+	if !cfg.GPXWriteXMP || cfg.Mode != ModeRun {
+		return
+	}
+
+	for _, group := range groups {
+		for _, file := range group.Files {
+			if !file.GPSInterpolated || file.GPS == nil {
+				continue
+			}
+			if err := writeXMPPosition(ctx.fs, filepath.Join(cfg.BasePath, file.FileInfo.Name()), *file.GPS); err != nil {
+				ctx.log.Warn("failed to write XMP sidecar", "file", file.FileInfo.Name(), "error", err)
+			}
+		}
+	}
+}
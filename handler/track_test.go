@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// degreesToSemicircles converts decimal degrees to a FIT position
+func degreesToSemicircles(degrees float64) int32 {
+	return int32(math.Round(degrees * math.Pow(2, 31) / 180))
+}
+
+// createFIT builds a FIT activity: one record with a timestamp field, then one with a compressed timestamp header 5 s later
+func createFIT(start time.Time, first, second GPSCoord) []byte {
+	le := binary.LittleEndian
+	var records []byte
+
+	// Local type 0: record with timestamp, latitude and longitude
+	records = append(records, 0x40, 0, 0)
+	records = le.AppendUint16(records, fitRecordMessage)
+	records = append(records, 3, fitFieldTimestamp, 4, 0x86, fitFieldLatitude, 4, 0x85, fitFieldLongitude, 4, 0x85)
+	records = append(records, 0x00)
+	timestamp := uint32(start.Sub(fitEpoch) / time.Second)
+	records = le.AppendUint32(records, timestamp)
+	records = le.AppendUint32(records, uint32(degreesToSemicircles(first.Lat)))
+	records = le.AppendUint32(records, uint32(degreesToSemicircles(first.Lon)))
+
+	// Local type 1: record without timestamp, with a developer field, sent with a compressed timestamp header
+	records = append(records, 0x61, 0, 0)
+	records = le.AppendUint16(records, fitRecordMessage)
+	records = append(records, 2, fitFieldLatitude, 4, 0x85, fitFieldLongitude, 4, 0x85)
+	records = append(records, 1, 0, 2, 0) // One 2-byte developer field
+	records = append(records, 0x80|1<<5|byte((timestamp+5)&0x1F))
+	records = le.AppendUint32(records, uint32(degreesToSemicircles(second.Lat)))
+	records = le.AppendUint32(records, uint32(degreesToSemicircles(second.Lon)))
+	records = append(records, 0xAA, 0xBB)
+
+	data := []byte{14, 0x20, 0x08, 0x08}
+	data = le.AppendUint32(data, uint32(len(records)))
+	data = append(data, ".FIT"...)
+	data = append(data, 0, 0)
+	data = append(data, records...)
+	return append(data, 0, 0) // CRC, not checked
+}
+
+func TestParseTracks(t *testing.T) {
+	start := time.Date(2024, 6, 15, 8, 0, 0, 0, time.UTC)
+
+	gpx := `<?xml version="1.0"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1">
+  <wpt lat="45.0" lon="6.0"><name>No time</name></wpt>
+  <trk><trkseg>
+    <trkpt lat="48.8566" lon="2.3522"><ele>35</ele><time>2024-06-15T08:00:00Z</time></trkpt>
+    <trkpt lat="48.8606" lon="2.3376"><time>2024-06-15T10:00:05+02:00</time></trkpt>
+  </trkseg></trk>
+</gpx>`
+
+	kml := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+<Document>
+  <Placemark><gx:Track>
+    <when>2024-06-15T08:00:00Z</when><when>2024-06-15T08:00:05Z</when>
+    <gx:coord>2.3522 48.8566 35</gx:coord><gx:coord>2.3376 48.8606 40</gx:coord>
+  </gx:Track></Placemark>
+  <Placemark><TimeStamp><when>2024-06-15T08:00:10Z</when></TimeStamp><Point><coordinates>2.2945,48.8584,0</coordinates></Point></Placemark>
+</Document>
+</kml>`
+
+	tests := []struct {
+		name  string
+		parse func([]byte) ([]trackPoint, error)
+		data  []byte
+		want  int
+	}{
+		{"GPX", parseGPX, []byte(gpx), 2},
+		{"KML", parseKML, []byte(kml), 3},
+		{"FIT", parseFIT, createFIT(start, GPSCoord{Lat: 48.8566, Lon: 2.3522}, GPSCoord{Lat: 48.8606, Lon: 2.3376}), 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := tt.parse(tt.data)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			if len(points) != tt.want {
+				t.Fatalf("got %d points, want %d", len(points), tt.want)
+			}
+			if !points[0].time.Equal(start) || math.Abs(points[0].pos.Lat-48.8566) > 1e-6 || math.Abs(points[0].pos.Lon-2.3522) > 1e-6 {
+				t.Errorf("first point = %v %v, want %v 48.8566,2.3522", points[0].time, points[0].pos, start)
+			}
+			if want := start.Add(5 * time.Second); !points[1].time.Equal(want) || math.Abs(points[1].pos.Lat-48.8606) > 1e-6 {
+				t.Errorf("second point = %v %v, want %v 48.8606,2.3376", points[1].time, points[1].pos, want)
+			}
+		})
+	}
+
+	if _, err := parseFIT([]byte("not a fit file")); err == nil {
+		t.Error("parseFIT() should reject a file without signature")
+	}
+	if _, err := parseGPX([]byte(`<gpx><trk><trkseg><trkpt lat="1" lon="2"><time>yesterday</time></trkpt></trkseg></trk></gpx>`)); err == nil {
+		t.Error("parseGPX() should reject an invalid time")
+	}
+}
+
+func TestLoadTracks_MemFileSystem(t *testing.T) {
+	fsys := NewMemFileSystem()
+	gpx := `<gpx><trk><trkseg><trkpt lat="48.85" lon="2.35"><time>2024-06-15T10:00:00Z</time></trkpt></trkseg></trk></gpx>`
+	writeMemFile(t, fsys, "/tracks/day1/walk.gpx", []byte(gpx), time.Now())
+	writeMemFile(t, fsys, "/tracks/notes.txt", []byte("not a track"), time.Now())
+
+	track, err := loadTracks(fsys, "/tracks")
+	if err != nil {
+		t.Fatalf("loadTracks() error: %v", err)
+	}
+	if len(track.points) != 1 || track.points[0].pos != (GPSCoord{Lat: 48.85, Lon: 2.35}) {
+		t.Errorf("points = %+v, want the point of walk.gpx", track.points)
+	}
+}
+
+func TestTrackPosition(t *testing.T) {
+	start := time.Date(2024, 6, 15, 8, 0, 0, 0, time.UTC)
+	track := &gpsTrack{points: []trackPoint{
+		{time: start, pos: GPSCoord{Lat: 45, Lon: 6}},
+		{time: start.Add(4 * time.Minute), pos: GPSCoord{Lat: 45.1, Lon: 6.2}},
+		{time: start.Add(time.Hour), pos: GPSCoord{Lat: 46, Lon: 7}}, // The watch lost the signal
+	}}
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		want   GPSCoord
+		wantOK bool
+	}{
+		{"exact point", start, GPSCoord{Lat: 45, Lon: 6}, true},
+		{"interpolated", start.Add(time.Minute), GPSCoord{Lat: 45.025, Lon: 6.05}, true},
+		{"nearest across a long gap", start.Add(8 * time.Minute), GPSCoord{Lat: 45.1, Lon: 6.2}, true},
+		{"too far from both points", start.Add(30 * time.Minute), GPSCoord{}, false},
+		{"shortly before the track", start.Add(-5 * time.Minute), GPSCoord{Lat: 45, Lon: 6}, true},
+		{"after the track", start.Add(2 * time.Hour), GPSCoord{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := track.position(tt.at, 10*time.Minute)
+			if ok != tt.wantOK {
+				t.Fatalf("position() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if math.Abs(got.Lat-tt.want.Lat) > 1e-9 || math.Abs(got.Lon-tt.want.Lon) > 1e-9 {
+				t.Errorf("position() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXMPPosition(t *testing.T) {
+	fsys := NewMemFileSystem()
+	writeMemFile(t, fsys, "/photos/DSC_0001.NEF", []byte("raw"), time.Now())
+	writeMemFile(t, fsys, "/photos/DSC_0002.NEF", []byte("raw"), time.Now())
+	writeMemFile(t, fsys, "/photos/DSC_0002.xmp", []byte("<x:xmpmeta/>"), time.Now())
+
+	pos := GPSCoord{Lat: -33.8688, Lon: 151.2093}
+	if err := writeXMPPosition(fsys, "/photos/DSC_0001.NEF", pos); err != nil {
+		t.Fatalf("writeXMPPosition() error: %v", err)
+	}
+	if err := writeXMPPosition(fsys, "/photos/DSC_0002.NEF", pos); err == nil {
+		t.Error("writeXMPPosition() should leave an existing sidecar untouched")
+	}
+
+	xmp, err := readXMPSidecar(fsys, "/photos/DSC_0001.NEF")
+	if err != nil {
+		t.Fatalf("readXMPSidecar() error: %v", err)
+	}
+	metadata := &FileMetadata{}
+	newDefaultExecutionContext().applyXMP(metadata, xmp)
+	if metadata.GPS == nil || math.Abs(metadata.GPS.Lat-pos.Lat) > 1e-6 || math.Abs(metadata.GPS.Lon-pos.Lon) > 1e-6 {
+		t.Errorf("GPS read back = %v, want %v", metadata.GPS, pos)
+	}
+
+	for value, want := range map[string]float64{"48,51.396N": 48.8566, "2,21,7.92E": 2.3522, "33,52.128S": -33.8688} {
+		if got, ok := parseXMPCoordinate(value); !ok || math.Abs(got-want) > 1e-4 {
+			t.Errorf("parseXMPCoordinate(%q) = %v, %v, want %v", value, got, ok, want)
+		}
+	}
+}
+
+func TestSplit_GPXGeotagging(t *testing.T) {
+	tmpDir := t.TempDir()
+	trackDir := t.TempDir()
+	shot := time.Date(2024, 6, 15, 10, 0, 0, 0, time.Local)
+
+	// The camera clock is 2 minutes late: the files were shot 2 minutes after their EXIF date
+	for i, name := range []string{"DSC_0001.JPG", "DSC_0002.JPG", "DSC_0003.JPG"} {
+		createJPEGWithEXIF(t, filepath.Join(tmpDir, name), shot.Add(time.Duration(i)*time.Minute))
+	}
+	createJPEGWithEXIF(t, filepath.Join(tmpDir, "DSC_0100.JPG"), shot.Add(5*time.Hour)) // Outside the track
+
+	utc := shot.Add(2 * time.Minute).UTC()
+	gpx := `<gpx><trk><trkseg>
+<trkpt lat="41.9028" lon="12.4964"><time>` + utc.Format(time.RFC3339) + `</time></trkpt>
+<trkpt lat="41.9030" lon="12.4966"><time>` + utc.Add(2*time.Minute).Format(time.RFC3339) + `</time></trkpt>
+</trkseg></trk></gpx>`
+	if err := os.WriteFile(filepath.Join(trackDir, "walk.GPX"), []byte(gpx), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		BasePath:    tmpDir,
+		Delta:       30 * time.Minute,
+		Mode:        ModeRun,
+		UseEXIF:     true,
+		UseGPS:      true,
+		GPSRadius:   2000,
+		GPXPath:     trackDir,
+		GPXOffset:   2 * time.Minute,
+		GPXWriteXMP: true,
+	}
+
+	// Scanning alone writes nothing, even in run mode
+	if _, err := Scan(context.Background(), cfg); err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	assertNotExists(t, filepath.Join(tmpDir, "DSC_0002.JPG.xmp"))
+
+	result, err := Split(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	event := filepath.Join(tmpDir, "41.9029N-12.4965E", "2024 - 0615 - 1000")
+	assertExists(t, filepath.Join(event, "DSC_0002.JPG"))
+	assertExists(t, filepath.Join(event, "DSC_0002.JPG.xmp")) // Written, then moved with the file
+	assertExists(t, filepath.Join(tmpDir, GetNoLocationFolderName(), "2024 - 0615 - 1500", "DSC_0100.JPG"))
+
+	for _, group := range result.Stats.Groups {
+		for _, m := range group.Metadata {
+			if m.GPS != nil && !m.GPSInterpolated {
+				t.Errorf("%s should be marked as interpolated", m.FileInfo.Name())
+			}
+		}
+	}
+}
+
+func TestConfigValidate_GPX(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"missing track", func(c *Config) { c.GPXPath = filepath.Join(c.BasePath, "missing.gpx") }, "invalid GPS track"},
+		{"write without track", func(c *Config) { c.GPXWriteXMP = true }, "requires --gpx"},
+		{"negative max gap", func(c *Config) { c.GPXMaxGap = -time.Minute }, "max gap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig(t.TempDir())
+			tt.modify(cfg)
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A 10-day vacation is one trip folder rather than 40 sibling events (v2.10.0+)
+// Runs of consecutive days with activity away from --home become "2024-06 Italy trip/2024-06-15/<events>"
+
+const (
+	// DefaultTripMinDays is the shortest trip used when Config.TripMinDays is zero
+	DefaultTripMinDays = 3
+
+	// DefaultHomeRadius is the home radius in meters used when Config.HomeRadius is zero
+	DefaultHomeRadius = 50000.0
+
+	tripDayFormat = "2006-01-02"
+)
+
+// dayPresence tells where a day was spent, from the GPS coordinates of its files
+type dayPresence int
+
+const (
+	presenceUnknown dayPresence = iota // No geotagged file
+	presenceHome                       // Every geotagged file is within the home radius
+	presenceAway                       // At least one geotagged file is outside the home radius
+)
+
+// tripDay is a day with activity, before the day boundary files belong to the previous day
+type tripDay struct {
+	date     time.Time
+	files    []FileMetadata
+	presence dayPresence
+}
+
+// trip is a run of consecutive days away from home
+type trip struct {
+	days []tripDay
+}
+
+// files returns the files of every day of the trip, in chronological order
+func (t trip) files() []FileMetadata {
+	var files []FileMetadata
+	for _, day := range t.days {
+		files = append(files, day.files...)
+	}
+	return files
+}
+
+// tripMinDays returns the configured shortest trip, or the default one
+func (c *Config) tripMinDays() int {
+	if c.TripMinDays > 0 {
+		return c.TripMinDays
+	}
+	return DefaultTripMinDays
+}
+
+// homeRadius returns the configured home radius, or the default one
+func (c *Config) homeRadius() float64 {
+	if c.HomeRadius > 0 {
+		return c.HomeRadius
+	}
+	return DefaultHomeRadius
+}
+
+// parseHome parses the --home coordinates ("48.8566,2.3522"), "" is no home
+func parseHome(value string) (*GPSCoord, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	lat, lon, ok := strings.Cut(value, ",")
+	if !ok {
+		return nil, fmt.Errorf("invalid home %q (expected latitude,longitude)", value)
+	}
+	coord := GPSCoord{}
+	var errLat, errLon error
+	coord.Lat, errLat = strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	coord.Lon, errLon = strconv.ParseFloat(strings.TrimSpace(lon), 64)
+	if err := errors.Join(errLat, errLon); err != nil {
+		return nil, fmt.Errorf("invalid home %q: %w", value, err)
+	}
+	if coord.Lat < -90 || coord.Lat > 90 || coord.Lon < -180 || coord.Lon > 180 {
+		return nil, fmt.Errorf("invalid home %q: coordinates out of range", value)
+	}
+	return &coord, nil
+}
+
+// isAwayFromHome checks if a geotagged file was taken outside the home radius
+func (ctx *executionContext) isAwayFromHome(cfg *Config, file FileMetadata) bool {
+	if file.GPS == nil || ctx.home == nil {
+		return false
+	}
+	return CalculateDistance(ctx.home.Lat, ctx.home.Lon, file.GPS.Lat, file.GPS.Lon) > cfg.homeRadius()
+}
+
+// activeDays splits sorted files by day
+func activeDays(cfg *Config, ctx *executionContext, files []FileMetadata) []tripDay {
+	var days []tripDay
+	for _, file := range files {
+		shifted := file.DateTime.Add(-ctx.dayBoundary)
+		date := time.Date(shifted.Year(), shifted.Month(), shifted.Day(), 0, 0, 0, 0, shifted.Location())
+		if len(days) == 0 || !days[len(days)-1].date.Equal(date) {
+			days = append(days, tripDay{date: date})
+		}
+
+		day := &days[len(days)-1]
+		day.files = append(day.files, file)
+		switch {
+		case ctx.isAwayFromHome(cfg, file):
+			day.presence = presenceAway
+		case file.GPS != nil && day.presence == presenceUnknown:
+			day.presence = presenceHome
+		}
+	}
+	return days
+}
+
+// detectTrips finds the runs of at least TripMinDays consecutive days with activity away from home
+// Days without geotagged files join a trip between away days, they cannot start or end one:
+// without GPS data (or without --home), no trip is detected
+// Returns the trips and the files left out of them, both in chronological order
+func detectTrips(cfg *Config, ctx *executionContext, files []FileMetadata) ([]trip, []FileMetadata) {
+	var trips []trip
+	var rest []FileMetadata
+
+	days := activeDays(cfg, ctx, files)
+	flush := func(run []tripDay) {
+		// Trips start and end on a day away
+		start, end := 0, len(run)
+		for start < end && run[start].presence != presenceAway {
+			start++
+		}
+		for end > start && run[end-1].presence != presenceAway {
+			end--
+		}
+
+		isTrip := end-start >= cfg.tripMinDays()
+		if isTrip {
+			trips = append(trips, trip{days: run[start:end]})
+		}
+		for i, day := range run {
+			if !isTrip || i < start || i >= end {
+				rest = append(rest, day.files...)
+			}
+		}
+	}
+
+	var run []tripDay
+	for _, day := range days {
+		consecutive := len(run) > 0 && run[len(run)-1].date.AddDate(0, 0, 1).Equal(day.date)
+		if day.presence == presenceHome || (len(run) > 0 && !consecutive) {
+			flush(run)
+			run = nil
+		}
+		if day.presence == presenceHome {
+			rest = append(rest, day.files...)
+			continue
+		}
+		run = append(run, day)
+	}
+	flush(run)
+
+	sortFilesByDateTime(rest)
+	return trips, rest
+}
+
+// tripName names a trip after its month and the place most of its away photos were taken: "2024-06 Italy trip"
+// The place is the country with --gps-geocoding, the coordinates of the main location otherwise
+func tripName(runCtx context.Context, cfg *Config, ctx *executionContext, t trip) string {
+	month := t.days[0].date.Format("2006-01")
+
+	var away []FileMetadata
+	for _, file := range t.files() {
+		if ctx.isAwayFromHome(cfg, file) {
+			away = append(away, file)
+		}
+	}
+
+	radius := cfg.GPSRadius
+	if radius <= 0 {
+		radius = defaultGPSRadiusMeters
+	}
+	clusters, _ := ClusterByLocation(away, radius)
+	if len(clusters) == 0 {
+		return month + " trip"
+	}
+
+	largest := clusters[0]
+	for _, cluster := range clusters[1:] {
+		if len(cluster.Files) > len(largest.Files) {
+			largest = cluster
+		}
+	}
+
+	place := FormatLocationName(largest.Centroid)
+	if cfg.GPSUseGeocoding {
+		// Geocoded names are "coordinates - country - city"
+		parts := strings.Split(reverseGeocode(runCtx, largest.Centroid.Lat, largest.Centroid.Lon, true), " - ")
+		if len(parts) > 1 {
+			place = parts[1]
+		}
+	}
+	return fmt.Sprintf("%s %s trip", month, place)
+}
+
+// buildTripGroups groups the files of each trip day into events, inside "<trip>/<day>" folders
+// Days are grouped like a split: by location then time with --gps ("<trip>/<day>/<location>/<event>")
+// Small groups of a day stay in its day folder
+func buildTripGroups(runCtx context.Context, cfg *Config, ctx *executionContext, trips []trip) ([]Group, []LocationInference, error) {
+	var groups []Group
+	var inferences []LocationInference
+	for _, t := range trips {
+		name := tripName(runCtx, cfg, ctx, t)
+		ctx.log.Info("trip detected",
+			"folder", name,
+			"days", len(t.days),
+			"files", len(t.files()))
+
+		for _, day := range t.days {
+			dayFolder := filepath.Join(name, day.date.Format(tripDayFormat))
+			dayGroups, inferred, err := buildGroups(runCtx, cfg, ctx, day.files)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, group := range dayGroups {
+				group.Folder = filepath.Join(dayFolder, group.Folder)
+				group.Root = dayFolder
+				groups = append(groups, group)
+			}
+			for _, inference := range inferred {
+				inference.Location = filepath.Join(dayFolder, inference.Location)
+				inferences = append(inferences, inference)
+			}
+		}
+	}
+	return groups, inferences, nil
+}
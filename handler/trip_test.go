@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	paris = GPSCoord{Lat: 48.8566, Lon: 2.3522}
+	rome  = GPSCoord{Lat: 41.9028, Lon: 12.4964}
+)
+
+// createJPEGWithGPS creates a JPEG whose EXIF holds a DateTimeOriginal and GPS coordinates
+func createJPEGWithGPS(t *testing.T, filePath string, shot time.Time, gps *GPSCoord) {
+	t.Helper()
+
+	app1 := append([]byte("Exif\x00\x00"), createTIFFEXIF(shot, gps)...)
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1, byte((len(app1) + 2) >> 8), byte((len(app1) + 2) & 0xFF)}
+	data = append(data, app1...)
+	data = append(data, 0xFF, 0xD9)
+
+	if err := os.WriteFile(filePath, data, 0600); err != nil {
+		t.Fatalf("failed to create JPEG with GPS: %v", err)
+	}
+}
+
+// filesOnDays returns one file per day at noon, geotagged when gps is set
+func filesOnDays(gps *GPSCoord, days ...int) []FileMetadata {
+	var files []FileMetadata
+	for _, day := range days {
+		files = append(files, FileMetadata{
+			FileInfo: memFileInfo{name: fmt.Sprintf("IMG_%02d.JPG", day)},
+			DateTime: time.Date(2024, 6, day, 12, 0, 0, 0, time.Local),
+			GPS:      gps,
+		})
+	}
+	return files
+}
+
+func TestParseHome(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    *GPSCoord
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"48.8566,2.3522", &paris, false},
+		{" -33.8688 , 151.2093 ", &GPSCoord{Lat: -33.8688, Lon: 151.2093}, false},
+		{"48.8566", nil, true},
+		{"north,east", nil, true},
+		{"91,0", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseHome(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHome() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("parseHome() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectTrips(t *testing.T) {
+	var withHome []FileMetadata
+	withHome = append(withHome, filesOnDays(&paris, 1)...)
+	withHome = append(withHome, filesOnDays(nil, 2)...) // Leaving: no GPS, does not start the trip
+	withHome = append(withHome, filesOnDays(&rome, 3, 4)...)
+	withHome = append(withHome, filesOnDays(nil, 5)...) // Between away days: part of the trip
+	withHome = append(withHome, filesOnDays(&rome, 6)...)
+	withHome = append(withHome, filesOnDays(&paris, 7)...)
+	withHome = append(withHome, filesOnDays(&rome, 8, 9)...) // Too short
+
+	tests := []struct {
+		name     string
+		files    []FileMetadata
+		home     *GPSCoord
+		minDays  int
+		wantDays [][]int
+		wantRest int
+	}{
+		{"consecutive days away", filesOnDays(&rome, 1, 2, 3, 4, 10, 20, 21), &paris, 0, [][]int{{1, 2, 3, 4}}, 3},
+		{"missing day breaks the run", filesOnDays(&rome, 1, 2, 4, 5), &paris, 0, nil, 4},
+		{"shorter minimum", filesOnDays(&rome, 1, 2, 4, 5), &paris, 2, [][]int{{1, 2}, {4, 5}}, 0},
+		{"away from home", withHome, &paris, 0, [][]int{{3, 4, 5, 6}}, 5},
+		{"no GPS data", filesOnDays(nil, 1, 2, 3, 4), &paris, 0, nil, 4},
+		{"no home", filesOnDays(&rome, 1, 2, 3, 4), nil, 0, nil, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Trips: true, TripMinDays: tt.minDays}
+			ctx := newDefaultExecutionContext()
+			ctx.home = tt.home
+
+			trips, rest := detectTrips(cfg, ctx, tt.files)
+
+			if len(trips) != len(tt.wantDays) {
+				t.Fatalf("got %d trips, want %d", len(trips), len(tt.wantDays))
+			}
+			for i, trip := range trips {
+				var days []int
+				for _, day := range trip.days {
+					days = append(days, day.date.Day())
+				}
+				if fmt.Sprint(days) != fmt.Sprint(tt.wantDays[i]) {
+					t.Errorf("trip %d days = %v, want %v", i, days, tt.wantDays[i])
+				}
+			}
+			if len(rest) != tt.wantRest {
+				t.Errorf("got %d files out of trips, want %d", len(rest), tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestTripName(t *testing.T) {
+	ctx := newDefaultExecutionContext()
+	ctx.home = &paris
+	cfg := &Config{GPSRadius: 2000}
+
+	days := activeDays(cfg, ctx, filesOnDays(nil, 10, 11, 12))
+	if got := tripName(context.Background(), cfg, ctx, trip{days: days}); got != "2024-06 trip" {
+		t.Errorf("tripName() = %q, want %q", got, "2024-06 trip")
+	}
+
+	files := append(filesOnDays(&paris, 10), filesOnDays(&rome, 11, 12)...)
+	days = activeDays(cfg, ctx, files)
+	if got, want := tripName(context.Background(), cfg, ctx, trip{days: days}), "2024-06 41.9028N-12.4964E trip"; got != want {
+		t.Errorf("tripName() = %q, want %q (home photos are left out)", got, want)
+	}
+}
+
+func TestSplit_Trips(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// A morning at home, then three days in Rome with a lone evening photo on the second day
+	for i := range 3 {
+		createJPEGWithGPS(t, filepath.Join(tmpDir, fmt.Sprintf("HOME_%d.JPG", i)), time.Date(2024, 6, 10, 9, i, 0, 0, time.Local), &paris)
+	}
+	for day := 12; day <= 14; day++ {
+		for i := range 3 {
+			createJPEGWithGPS(t, filepath.Join(tmpDir, fmt.Sprintf("ROME_%d_%d.JPG", day, i)), time.Date(2024, 6, day, 10, i, 0, 0, time.Local), &rome)
+		}
+	}
+	createJPEGWithGPS(t, filepath.Join(tmpDir, "ROME_13_night.JPG"), time.Date(2024, 6, 13, 22, 0, 0, 0, time.Local), &rome)
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		UseEXIF:      true,
+		MinGroupSize: 2,
+		Trips:        true,
+		Home:         "48.8566,2.3522",
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	trip := filepath.Join(tmpDir, "2024-06 41.9028N-12.4964E trip")
+	assertExists(t, filepath.Join(tmpDir, "2024 - 0610 - 0900", "HOME_0.JPG"))
+	assertExists(t, filepath.Join(trip, "2024-06-12", "2024 - 0612 - 1000", "ROME_12_0.JPG"))
+	assertExists(t, filepath.Join(trip, "2024-06-14", "2024 - 0614 - 1000", "ROME_14_2.JPG"))
+	assertExists(t, filepath.Join(trip, "2024-06-13", "ROME_13_night.JPG")) // Below MinGroupSize: stays in the day folder
+}
+
+func TestSplit_TripsClusterLocations(t *testing.T) {
+	tmpDir := t.TempDir()
+	tivoli := GPSCoord{Lat: 41.9637, Lon: 12.7980}
+
+	// Three days around Rome: the second day visits two places
+	for day := 12; day <= 14; day++ {
+		for i := range 2 {
+			createJPEGWithGPS(t, filepath.Join(tmpDir, fmt.Sprintf("ROME_%d_%d.JPG", day, i)), time.Date(2024, 6, day, 10, i, 0, 0, time.Local), &rome)
+		}
+	}
+	for i := range 2 {
+		createJPEGWithGPS(t, filepath.Join(tmpDir, fmt.Sprintf("TIVOLI_%d.JPG", i)), time.Date(2024, 6, 13, 15, i, 0, 0, time.Local), &tivoli)
+	}
+
+	cfg := &Config{
+		BasePath:     tmpDir,
+		Delta:        30 * time.Minute,
+		Mode:         ModeRun,
+		UseEXIF:      true,
+		UseGPS:       true,
+		GPSRadius:    5000,
+		MinGroupSize: 1,
+		Trips:        true,
+		Home:         "48.8566,2.3522",
+	}
+
+	if _, err := Split(context.Background(), cfg); err != nil {
+		t.Fatalf("Split() error: %v", err)
+	}
+
+	// Trip named after the main location, each day split by location
+	day := filepath.Join(tmpDir, "2024-06 41.9028N-12.4964E trip", "2024-06-13")
+	assertExists(t, filepath.Join(day, FormatLocationName(rome), "2024 - 0613 - 1000", "ROME_13_0.JPG"))
+	assertExists(t, filepath.Join(day, FormatLocationName(tivoli), "2024 - 0613 - 1500", "TIVOLI_0.JPG"))
+}
+
+func TestConfigValidate_Trips(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"home", func(c *Config) { c.Trips = true; c.Home = "48.8566,2.3522" }, false},
+		{"adaptive", func(c *Config) { c.Trips = true; c.Home = "48.8566,2.3522"; c.GroupBy = GroupByAdaptive }, false},
+		{"missing home", func(c *Config) { c.Trips = true }, true},
+		{"invalid home", func(c *Config) { c.Trips = true; c.Home = "paris" }, true},
+		{"negative min days", func(c *Config) { c.Trips = true; c.Home = "48.8566,2.3522"; c.TripMinDays = -1 }, true},
+		{"negative home radius", func(c *Config) { c.Trips = true; c.HomeRadius = -1 }, true},
+		{"calendar grouping", func(c *Config) { c.Trips = true; c.GroupBy = GroupByMonth }, true},
+		{"incremental", func(c *Config) { c.Trips = true; c.Incremental = true }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig(t.TempDir())
+			tt.modify(cfg)
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
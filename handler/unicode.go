@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Place names come precomposed from geocoders ("São Paulo") but decomposed from macOS file systems
+// ("Sa\u0303o Paulo"): both must give the same folder, so names are composed like Unicode NFC (v2.10.0+)
+// Only Latin letters with combining diacritics are composed, which covers place names written in Latin script
+
+// latinCompositions lists, for each combining mark, the base letters it composes with and the composed letters
+var latinCompositions = []struct {
+	mark     rune
+	bases    string
+	composed string
+}{
+	// Grave accent
+	{0x0300, "AEIOUaeiouÜüNnĒēŌōWwÂâĂăÊêÔôƠơƯưYy", "ÀÈÌÒÙàèìòùǛǜǸǹḔḕṐṑẀẁẦầẰằỀềỒồỜờỪừỲỳ"},
+	// Acute accent
+	{0x0301, "AEIOUYaeiouyCcLlNnRrSsZzÜüGgÅåÆæØøÇçĒēÏïKkMmÕõŌōPpŨũWwÂâĂăÊêÔôƠơƯư", "ÁÉÍÓÚÝáéíóúýĆćĹĺŃńŔŕŚśŹźǗǘǴǵǺǻǼǽǾǿḈḉḖḗḮḯḰḱḾḿṌṍṒṓṔṕṸṹẂẃẤấẮắẾếỐốỚớỨứ"},
+	// Circumflex accent
+	{0x0302, "AEIOUaeiouCcGgHhJjSsWwYyZzẠạẸẹỌọ", "ÂÊÎÔÛâêîôûĈĉĜĝĤĥĴĵŜŝŴŵŶŷẐẑẬậỆệỘộ"},
+	// Tilde
+	{0x0303, "ANOanoIiUuVvÂâĂăEeÊêÔôƠơƯưYy", "ÃÑÕãñõĨĩŨũṼṽẪẫẴẵẼẽỄễỖỗỠỡỮữỸỹ"},
+	// Macron
+	{0x0304, "AaEeIiOoUuÜüÄäȦȧÆæǪǫÖöÕõȮȯYyGgḶḷṚṛ", "ĀāĒēĪīŌōŪūǕǖǞǟǠǡǢǣǬǭȪȫȬȭȰȱȲȳḠḡḸḹṜṝ"},
+	// Breve
+	{0x0306, "AaEeGgIiOoUuȨȩẠạ", "ĂăĔĕĞğĬĭŎŏŬŭḜḝẶặ"},
+	// Dot above
+	{0x0307, "CcEeGgIZzAaOoBbDdFfHhMmNnPpRrSsŚśŠšṢṣTtWwXxYyſ", "ĊċĖėĠġİŻżȦȧȮȯḂḃḊḋḞḟḢḣṀṁṄṅṖṗṘṙṠṡṤṥṦṧṨṩṪṫẆẇẊẋẎẏẛ"},
+	// Diaeresis
+	{0x0308, "AEIOUaeiouyYHhÕõŪūWwXxt", "ÄËÏÖÜäëïöüÿŸḦḧṎṏṺṻẄẅẌẍẗ"},
+	// Hook above
+	{0x0309, "AaÂâĂăEeÊêIiOoÔôƠơUuƯưYy", "ẢảẨẩẲẳẺẻỂểỈỉỎỏỔổỞởỦủỬửỶỷ"},
+	// Ring above
+	{0x030A, "AaUuwy", "ÅåŮůẘẙ"},
+	// Double acute accent
+	{0x030B, "OoUu", "ŐőŰű"},
+	// Caron
+	{0x030C, "CcDdEeLlNnRrSsTtZzAaIiOoUuÜüGgKkƷʒjHh", "ČčĎďĚěĽľŇňŘřŠšŤťŽžǍǎǏǐǑǒǓǔǙǚǦǧǨǩǮǯǰȞȟ"},
+	// Double grave accent
+	{0x030F, "AaEeIiOoRrUu", "ȀȁȄȅȈȉȌȍȐȑȔȕ"},
+	// Inverted breve
+	{0x0311, "AaEeIiOoRrUu", "ȂȃȆȇȊȋȎȏȒȓȖȗ"},
+	// Horn
+	{0x031B, "OoUu", "ƠơƯư"},
+	// Dot below
+	{0x0323, "BbDdHhKkLlMmNnRrSsTtVvWwZzAaEeIiOoƠơUuƯưYy", "ḄḅḌḍḤḥḲḳḶḷṂṃṆṇṚṛṢṣṬṭṾṿẈẉẒẓẠạẸẹỊịỌọỢợỤụỰựỴỵ"},
+	// Diaeresis below
+	{0x0324, "Uu", "Ṳṳ"},
+	// Ring below
+	{0x0325, "Aa", "Ḁḁ"},
+	// Comma below
+	{0x0326, "SsTt", "ȘșȚț"},
+	// Cedilla
+	{0x0327, "CcGgKkLlNnRrSsTtEeDdHh", "ÇçĢģĶķĻļŅņŖŗŞşŢţȨȩḐḑḨḩ"},
+	// Ogonek
+	{0x0328, "AaEeIiUuOo", "ĄąĘęĮįŲųǪǫ"},
+	// Circumflex accent below
+	{0x032D, "DdEeLlNnTtUu", "ḒḓḘḙḼḽṊṋṰṱṶṷ"},
+	// Breve below
+	{0x032E, "Hh", "Ḫḫ"},
+	// Tilde below
+	{0x0330, "EeIiUu", "ḚḛḬḭṴṵ"},
+	// Macron below
+	{0x0331, "BbDdKkLlNnRrTtZzh", "ḆḇḎḏḴḵḺḻṈṉṞṟṮṯẔẕẖ"},
+}
+
+// compositions maps a base letter and a combining mark to the composed letter
+var compositions = buildCompositions()
+
+// buildCompositions indexes latinCompositions by base letter and mark
+func buildCompositions() map[[2]rune]rune {
+	table := make(map[[2]rune]rune)
+	for _, row := range latinCompositions {
+		bases, composed := []rune(row.bases), []rune(row.composed)
+		for i, base := range bases {
+			table[[2]rune{base, row.mark}] = composed[i]
+		}
+	}
+	return table
+}
+
+// composeUnicode composes letters followed by combining diacritics into precomposed letters
+// "e\u0301" becomes "é"; marks without a precomposed letter are kept
+func composeUnicode(s string) string {
+	// Fast path: nothing to compose without a combining mark
+	hasMark := false
+	for _, r := range s {
+		if unicode.Is(unicode.Mn, r) {
+			hasMark = true
+			break
+		}
+	}
+	if !hasMark {
+		return s
+	}
+
+	result := make([]rune, 0, utf8.RuneCountInString(s))
+	for _, r := range s {
+		if n := len(result); n > 0 && unicode.Is(unicode.Mn, r) {
+			if composed, ok := compositions[[2]rune{result[n-1], r}]; ok {
+				result[n-1] = composed
+				continue
+			}
+		}
+		result = append(result, r)
+	}
+	return string(result)
+}
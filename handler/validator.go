@@ -20,6 +20,7 @@ type ValidationReport struct {
 	TotalBytes int64
 	Errors     []*PicsplitError
 	Warnings   []string
+	RuleHits   []RuleHit // Files matched by each rule of --rules (v2.10.0+)
 }
 
 // Duration returns the validation duration
@@ -81,6 +82,9 @@ func (r *ValidationReport) Print() {
 	// Estimated disk space
 	slog.Info("estimated disk space", "size", FormatBytes(r.TotalBytes))
 
+	// Routing rules (v2.10.0+)
+	printRuleHits(r.RuleHits)
+
 	// Critical errors
 	criticalCount := r.CriticalErrorCount()
 	if criticalCount > 0 {
@@ -128,12 +132,13 @@ func Validate(cfg *Config) (*ValidationReport, error) {
 	}
 
 	// Fast scan without EXIF extraction
-	entries, err := os.ReadDir(cfg.BasePath)
+	entries, err := ctx.fs.ReadDir(cfg.BasePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read directory: %w", err)
 	}
 
 	var unknownExts = make(map[string]bool) // Track unknown extensions (deduplicated)
+	var mediaInfos []os.FileInfo            // Readable media files, matched against the rules
 
 	for _, entry := range entries {
 		if entry.IsDir() {
@@ -163,6 +168,9 @@ func Validate(cfg *Config) (*ValidationReport, error) {
 			report.VideoCount++
 			report.TotalBytes += info.Size()
 			isMediaFile = true
+		} else if ctx.isSidecar(info.Name()) || info.Name() == renameManifestName || info.Name() == eventIndexName {
+			// Sidecars (.xmp, .aae, .thm) are moved along with their media file
+			continue
 		} else if ext != "" {
 			// Unknown extension
 			unknownExts[ext] = true
@@ -171,7 +179,7 @@ func Validate(cfg *Config) (*ValidationReport, error) {
 		// Check permissions (basic read access)
 		if isMediaFile {
 			filePath := filepath.Join(cfg.BasePath, info.Name())
-			file, err := os.Open(filePath)
+			file, err := ctx.fs.Open(filePath)
 			if err != nil {
 				report.Errors = append(report.Errors, &PicsplitError{
 					Type: ErrTypePermission,
@@ -181,10 +189,16 @@ func Validate(cfg *Config) (*ValidationReport, error) {
 				})
 			} else {
 				file.Close()
+				mediaInfos = append(mediaInfos, info)
 			}
 		}
 	}
 
+	// Count the files matched by each rule (v2.10.0+)
+	if ctx.rules != nil {
+		report.RuleHits = ctx.rules.count(ruleCandidates(cfg, ctx, mediaInfos))
+	}
+
 	// Report unknown extensions as validation errors
 	for ext := range unknownExts {
 		report.Errors = append(report.Errors, &PicsplitError{
@@ -202,3 +216,25 @@ func Validate(cfg *Config) (*ValidationReport, error) {
 
 	return report, nil
 }
+
+// ruleCandidates builds the metadata the rules are matched against in validate mode
+// EXIF is only read when a rule has a metadata condition (exif, resolution, date)
+func ruleCandidates(cfg *Config, ctx *executionContext, infos []os.FileInfo) []FileMetadata {
+	extract := cfg.UseEXIF && ctx.rules.needsMetadata()
+
+	files := make([]FileMetadata, 0, len(infos))
+	for _, info := range infos {
+		if extract {
+			if metadata, err := ExtractMetadata(ctx, filepath.Join(cfg.BasePath, info.Name())); err == nil {
+				files = append(files, *metadata)
+				continue
+			}
+		}
+		files = append(files, FileMetadata{
+			FileInfo: info,
+			DateTime: info.ModTime(),
+			Source:   DateSourceModTime,
+		})
+	}
+	return files
+}
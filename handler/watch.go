@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	defaultWatchQuietPeriod    = 30 * time.Second
+	defaultWatchStableInterval = 2 * time.Second
+)
+
+// WatchConfig holds configuration for watch mode (v2.10.0+)
+type WatchConfig struct {
+	Split          *Config       // Split profile applied to every batch (BasePath is the watched folder)
+	QuietPeriod    time.Duration // A batch is processed once no file event occurred for this long (default: 30s)
+	StableInterval time.Duration // Delay between two checks of files still being written (default: 2s)
+}
+
+// hotFolder tracks the files dropped in a watched folder until they are ready to be split
+type hotFolder struct {
+	basePath string
+	ctx      *executionContext
+	pending  map[string]int64 // file name -> size at last check
+}
+
+// Watch monitors cfg.Split.BasePath and runs Split on new media files
+// Files must be fully written (size stable and no open writer) and the batch quiet
+// for QuietPeriod before Split runs; new files close to an existing event folder are appended to it
+// Calendar grouping, trips and --gps-hierarchy are not supported: they cannot extend existing folders
+// Watch returns when the context is canceled
+func Watch(runCtx context.Context, cfg *WatchConfig) error {
+	if cfg.Split == nil {
+		return errors.New("watch requires a split configuration")
+	}
+	if err := cfg.Split.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if cfg.Split.Mode == ModeValidate {
+		return errors.New("watch does not support validate mode (use dryrun or run)")
+	}
+
+	// Every batch is an incremental split: reject the modes it cannot extend here rather than
+	// with an error about --incremental, which the user did not pass
+	switch {
+	case isCalendarGrouping(cfg.Split.GroupBy):
+		return fmt.Errorf("watch does not support --group-by %s (use gap or adaptive)", cfg.Split.GroupBy)
+	case cfg.Split.Trips:
+		return errors.New("watch does not support --trips (run a split on the folder instead)")
+	case cfg.Split.GPSHierarchy:
+		return errors.New("watch does not support --gps-hierarchy (use --gps-geocoding location folders)")
+	}
+	if _, local := cfg.Split.fileSystem().(OSFileSystem); !local {
+		return errors.New("watch requires the local file system (file events come from the OS)")
+	}
+
+	quietPeriod := cfg.QuietPeriod
+	if quietPeriod <= 0 {
+		quietPeriod = defaultWatchQuietPeriod
+	}
+	stableInterval := cfg.StableInterval
+	if stableInterval <= 0 {
+		stableInterval = defaultWatchStableInterval
+	}
+
+	// Every batch extends the events created by previous ones
+	// A daemon cannot answer prompts: cleanup confirmation is implied
+	splitCfg := *cfg.Split
+	splitCfg.Incremental = true
+	splitCfg.Force = true
+
+	ctx, err := newExecutionContext(&splitCfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize extension context: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(splitCfg.BasePath); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", splitCfg.BasePath, err)
+	}
+
+	folder := &hotFolder{
+		basePath: splitCfg.BasePath,
+		ctx:      ctx,
+		pending:  make(map[string]int64),
+	}
+
+	// Files dropped while picsplit was not running form the first batch
+	folder.trackExisting()
+
+	timer := time.NewTimer(quietPeriod)
+	if len(folder.pending) == 0 {
+		timer.Stop()
+	}
+	defer timer.Stop()
+
+	ctx.log.Info("watching folder for new media",
+		"path", splitCfg.BasePath,
+		"quiet_period", quietPeriod,
+		"mode", splitCfg.Mode)
+
+	for {
+		select {
+		case <-runCtx.Done():
+			ctx.log.Info("watch stopped", "path", splitCfg.BasePath, "pending_files", len(folder.pending))
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			if folder.track(filepath.Base(event.Name)) {
+				timer.Reset(quietPeriod)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			ctx.log.Warn("file watcher error", "error", err)
+
+		case <-timer.C:
+			if len(folder.pending) == 0 {
+				continue
+			}
+			if !folder.ready() {
+				ctx.log.Debug("files still being written, waiting", "pending_files", len(folder.pending))
+				timer.Reset(stableInterval)
+				continue
+			}
+
+			ctx.log.Info("processing batch", "files", len(folder.pending))
+			batch := folder.pending
+			folder.pending = make(map[string]int64)
+
+			result, err := Split(runCtx, &splitCfg)
+			if result != nil && result.Stats != nil {
+				result.Stats.PrintSummary(splitCfg.Mode == ModeDryRun)
+			}
+			if err != nil && runCtx.Err() == nil {
+				// Keep watching: files of the batch left in place are retried after a quiet period
+				for name := range batch {
+					folder.track(name)
+				}
+				ctx.log.Error("batch failed, retrying", "error", err, "pending_files", len(folder.pending), "retry_in", quietPeriod)
+				if len(folder.pending) > 0 {
+					timer.Reset(quietPeriod)
+				}
+			}
+		}
+	}
+}
+
+// trackExisting adds the media files already in the folder to the pending batch
+func (h *hotFolder) trackExisting() {
+	entries, err := h.ctx.fs.ReadDir(h.basePath)
+	if err != nil {
+		h.ctx.log.Warn("failed to read watched folder", "path", h.basePath, "error", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			h.track(entry.Name())
+		}
+	}
+}
+
+// track adds a media file to the pending batch and records its current size
+// Returns false for directories and files picsplit does not handle
+func (h *hotFolder) track(name string) bool {
+	if !h.ctx.isPhoto(name) && !h.ctx.isMovie(name) && !h.ctx.isSidecar(name) {
+		return false
+	}
+
+	info, err := h.ctx.fs.Stat(filepath.Join(h.basePath, name))
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	h.pending[name] = info.Size()
+	return true
+}
+
+// ready checks that every pending file is fully written: same size as at the
+// previous check and not opened for writing by any process
+// Files that disappeared are dropped from the batch
+func (h *hotFolder) ready() bool {
+	writing := filesOpenForWriting()
+	allReady := true
+
+	for name, size := range h.pending {
+		filePath := filepath.Join(h.basePath, name)
+		info, err := h.ctx.fs.Stat(filePath)
+		if err != nil {
+			delete(h.pending, name)
+			continue
+		}
+
+		if info.Size() != size {
+			h.pending[name] = info.Size()
+			allReady = false
+			continue
+		}
+
+		if absPath, err := filepath.Abs(filePath); err == nil && writing[absPath] {
+			allReady = false
+		}
+	}
+
+	return allReady && len(h.pending) > 0
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package handler
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// filesOpenForWriting returns the absolute paths of files currently opened for writing
+// by any process visible in /proc (processes of other users are skipped without permission)
+func filesOpenForWriting() map[string]bool {
+	writing := make(map[string]bool)
+
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return writing
+	}
+
+	for _, proc := range procs {
+		if _, err := strconv.Atoi(proc.Name()); err != nil {
+			continue
+		}
+
+		fdDir := filepath.Join("/proc", proc.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !filepath.IsAbs(target) {
+				continue
+			}
+			if fdOpenForWriting(filepath.Join("/proc", proc.Name(), "fdinfo", fd.Name())) {
+				writing[target] = true
+			}
+		}
+	}
+
+	return writing
+}
+
+// fdOpenForWriting reads the open flags of a file descriptor from /proc/<pid>/fdinfo/<fd>
+func fdOpenForWriting(fdInfoPath string) bool {
+	f, err := os.Open(fdInfoPath) //nolint:gosec // path built from /proc entries
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "flags:")
+		if !found {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimSpace(value), 8, 64)
+		if err != nil {
+			return false
+		}
+		accessMode := flags & syscall.O_ACCMODE
+		return accessMode == syscall.O_WRONLY || accessMode == syscall.O_RDWR
+	}
+
+	return false
+}
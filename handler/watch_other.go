@@ -0,0 +1,9 @@
+//go:build !linux
+
+package handler
+
+// filesOpenForWriting is only implemented on Linux (/proc); other platforms
+// rely on the file size being stable between two checks
+func filesOpenForWriting() map[string]bool {
+	return nil
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForFile polls until path exists or the timeout expires
+func waitForFile(t *testing.T, path string, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", path)
+}
+
+func TestHotFolder_Ready(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestFile(t, tmpDir, "IMG_0001.JPG", "partial")
+	writeTestFile(t, tmpDir, "notes.txt", "not media")
+
+	folder := &hotFolder{
+		basePath: tmpDir,
+		ctx:      newDefaultExecutionContext(),
+		pending:  make(map[string]int64),
+	}
+
+	if folder.track("notes.txt") {
+		t.Error("track() accepted a non-media file")
+	}
+	if !folder.track("IMG_0001.JPG") {
+		t.Fatal("track() rejected a media file")
+	}
+
+	// File grows after being tracked: not ready yet
+	writeTestFile(t, tmpDir, "IMG_0001.JPG", "partial content, now complete")
+	if folder.ready() {
+		t.Error("ready() = true while file size changed")
+	}
+
+	// Size stable since previous check
+	if !folder.ready() {
+		t.Error("ready() = false with a stable file")
+	}
+
+	// Vanished files are dropped from the batch
+	if err := os.Remove(filepath.Join(tmpDir, "IMG_0001.JPG")); err != nil {
+		t.Fatal(err)
+	}
+	if folder.ready() || len(folder.pending) != 0 {
+		t.Errorf("ready() with removed file: pending = %v", folder.pending)
+	}
+}
+
+func TestHotFolder_OpenWriterNotReady(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("open writer detection is only implemented on Linux")
+	}
+
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "IMG_0001.JPG")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	folder := &hotFolder{
+		basePath: tmpDir,
+		ctx:      newDefaultExecutionContext(),
+		pending:  make(map[string]int64),
+	}
+	folder.track("IMG_0001.JPG")
+
+	if folder.ready() {
+		t.Error("ready() = true while the file is open for writing")
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !folder.ready() {
+		t.Error("ready() = false after the writer closed the file")
+	}
+}
+
+func TestWatch_SplitsBatchesAndAppends(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Date(2024, 6, 16, 19, 0, 0, 0, time.Local)
+	event := base.Format(dateFormatPattern)
+
+	// File waiting before watch starts
+	createTestFile(t, tmpDir, "IMG_0001.JPG", base)
+
+	cfg := &WatchConfig{
+		Split: &Config{
+			BasePath:     tmpDir,
+			Delta:        30 * time.Minute,
+			Mode:         ModeRun,
+			MinGroupSize: 1,
+		},
+		QuietPeriod:    100 * time.Millisecond,
+		StableInterval: 50 * time.Millisecond,
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(runCtx, cfg)
+	}()
+
+	// First batch: existing file
+	waitForFile(t, filepath.Join(tmpDir, event, "IMG_0001.JPG"), 5*time.Second)
+
+	// Second batch: later photo of the same event is appended
+	writeTestFile(t, tmpDir, "IMG_0002.JPG", "second batch")
+	setModTime(t, filepath.Join(tmpDir, "IMG_0002.JPG"), base.Add(20*time.Minute))
+	waitForFile(t, filepath.Join(tmpDir, event, "IMG_0002.JPG"), 5*time.Second)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Watch() error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() did not stop after cancel")
+	}
+
+	assertNotExists(t, filepath.Join(tmpDir, base.Add(20*time.Minute).Format(dateFormatPattern)))
+}
+
+func TestWatch_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *WatchConfig
+	}{
+		{"missing split config", &WatchConfig{}},
+		{"validate mode", &WatchConfig{Split: &Config{BasePath: t.TempDir(), Delta: time.Minute, Mode: ModeValidate}}},
+		{"invalid delta", &WatchConfig{Split: &Config{BasePath: t.TempDir(), Mode: ModeRun}}},
+		{"calendar grouping", &WatchConfig{Split: &Config{BasePath: t.TempDir(), Delta: time.Minute, Mode: ModeRun, GroupBy: GroupByDay}}},
+		{"trips", &WatchConfig{Split: &Config{BasePath: t.TempDir(), Delta: time.Minute, Mode: ModeRun, Trips: true}}},
+		{"gps hierarchy", &WatchConfig{Split: &Config{BasePath: t.TempDir(), Delta: time.Minute, Mode: ModeRun, UseGPS: true, GPSUseGeocoding: true, GPSHierarchy: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Watch(context.Background(), tt.cfg)
+			if err == nil {
+				t.Fatal("Watch() expected error")
+			}
+			if strings.Contains(err.Error(), "incremental") {
+				t.Errorf("Watch() error = %v, want a watch-specific message", err)
+			}
+		})
+	}
+}
+
+func TestWatch_RetriesFailedBatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Date(2024, 6, 16, 19, 0, 0, 0, time.Local)
+	event := base.Format(dateFormatPattern)
+
+	// A different photo with the same name already in the event: the batch fails
+	writeTestFile(t, tmpDir, filepath.Join(event, "IMG_0001.JPG"), "already organized")
+	setModTime(t, filepath.Join(tmpDir, event, "IMG_0001.JPG"), base)
+	writeTestFile(t, tmpDir, "IMG_0001.JPG", "new photo")
+	setModTime(t, filepath.Join(tmpDir, "IMG_0001.JPG"), base.Add(time.Minute))
+
+	cfg := &WatchConfig{
+		Split: &Config{
+			BasePath:     tmpDir,
+			Delta:        30 * time.Minute,
+			Mode:         ModeRun,
+			MinGroupSize: 1,
+			OnCollision:  conflictFail,
+		},
+		QuietPeriod:    100 * time.Millisecond,
+		StableInterval: 50 * time.Millisecond,
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(runCtx, cfg)
+	}()
+
+	// Let the first batch fail, then free the name without any event in the watched folder
+	time.Sleep(500 * time.Millisecond)
+	assertExists(t, filepath.Join(tmpDir, "IMG_0001.JPG"))
+	if err := os.Rename(filepath.Join(tmpDir, event, "IMG_0001.JPG"), filepath.Join(tmpDir, event, "IMG_0000.JPG")); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForFile(t, filepath.Join(tmpDir, event, "IMG_0001.JPG"), 5*time.Second)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Watch() error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() did not stop after cancel")
+	}
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Coarse land outlines for the offline map of picsplit report --html (v2.10.0+)
+// Points are longitude, latitude pairs, a few degrees apart: enough to recognize where clusters are,
+// small enough to embed in every report
+
+// worldOutlines lists land polygons, then inland seas drawn over them as water
+var worldOutlines = []struct {
+	name   string
+	water  bool
+	points []float64
+}{
+	{"North America", false, []float64{
+		-168, 66, -162, 70, -156, 71.3, -141, 69.6, -128, 70, -115, 68.5, -95, 68, -85, 70, -80, 63, -93, 59,
+		-92, 57, -82, 55, -79, 52, -78, 58, -77, 62, -69, 59, -64, 60, -61, 56, -56, 52, -60, 47,
+		-66, 45, -70, 43, -70, 41.5, -74, 40.5, -76, 37, -75.5, 35.3, -81, 31.5, -80, 27, -80.5, 25.2, -82, 26.5,
+		-83, 29.5, -86, 30.3, -89.5, 30.2, -94, 29.6, -97.3, 27.5, -97.5, 24, -97.5, 21.5, -96, 19, -94.5, 18.2, -91, 18.8,
+		-90.5, 21, -87, 21.5, -88, 16, -84, 15.8, -83.5, 11, -81.5, 9, -79.5, 9.5, -77.5, 8.5, -78, 7.2, -80, 7.3,
+		-83, 8.3, -86, 11, -88, 13.2, -92, 14.5, -95, 16, -97.8, 16, -102, 18, -105.5, 20.5, -105.5, 23, -109.5, 26.5,
+		-112.5, 29.5, -114.7, 31.7, -114.5, 30, -112, 27, -110, 23, -112, 24.8, -114.5, 28, -117, 32.5, -118.5, 34, -120.6, 34.6,
+		-122.5, 37.5, -124, 40.5, -124.5, 43, -124, 46.5, -124.7, 48.4, -123, 49, -127.5, 50.5, -131, 54, -134, 57.5, -139, 59.5,
+		-146, 60.8, -152, 59, -158, 57, -164, 54.5, -158.5, 58.5, -162, 60, -165.5, 62, -164.5, 64.5,
+	}},
+	{"Baffin Island", false, []float64{-80, 73.5, -72, 71.5, -62, 66.5, -65, 63, -73, 65, -78, 64.5, -73, 68, -85, 70, -90, 73}},
+	{"Newfoundland", false, []float64{-59.3, 47.6, -56, 51.6, -53, 49, -52.6, 47, -56, 47.6}},
+	{"Greenland", false, []float64{
+		-73, 78, -66, 80.5, -55, 82.3, -35, 83.5, -20, 82.5, -18, 79.5, -20, 75, -22, 71, -25, 69.5, -32, 68,
+		-40, 65, -43, 60, -48, 61, -51, 64, -53.5, 67, -55, 70.5, -58, 75.5, -66, 76.2,
+	}},
+	{"Cuba", false, []float64{-85, 21.9, -81, 23.1, -77, 22, -74.2, 20.2, -77.5, 19.9, -80, 21.6, -84, 21.7}},
+	{"Hispaniola", false, []float64{-74.4, 18.4, -72.8, 19.9, -69.5, 19.7, -68.4, 18.6, -71.4, 17.6}},
+	{"South America", false, []float64{
+		-77.5, 8.5, -75, 11, -72, 12, -71.5, 10.5, -68, 10.6, -63, 10.7, -60, 8.5, -57, 6, -52, 5, -50, 1.8,
+		-48, -1, -44, -2.5, -39, -3.5, -35, -5.5, -35, -9, -38.5, -13, -39, -18, -40.5, -21.5, -44, -23, -48.5, -26,
+		-48.7, -28.5, -51, -31, -53.5, -34, -56, -34.8, -57.5, -36.5, -57.5, -38.5, -62, -39, -62.5, -41, -65, -42.5, -65.5, -45,
+		-67.5, -46.5, -66, -48, -68.5, -50.5, -68.5, -52.5, -66.5, -55, -70, -55, -74, -52, -75, -48, -74, -44, -73.5, -39,
+		-73.2, -37, -71.5, -32, -71.4, -28, -70.5, -23.5, -70.3, -18.5, -75, -15.5, -76.5, -13.5, -79.5, -7.5, -81.2, -5.5, -80.3, -3.5,
+		-80, -1, -80, 1, -78.8, 1.8, -77.5, 3.8, -77.5, 6.5,
+	}},
+	{"Iceland", false, []float64{-22, 64, -24, 65.5, -22.5, 66.4, -16, 66.5, -13.5, 65, -15, 64.3, -18.5, 63.4}},
+	{"Ireland", false, []float64{-6, 52, -6.2, 53.9, -5.5, 54.6, -7, 55.3, -8.5, 55, -10, 54.2, -10, 51.6, -8, 51.6}},
+	{"Great Britain", false, []float64{
+		-5.7, 50, 1.4, 51.2, 1.7, 52.7, 0, 53.5, -1.5, 55.5, -2, 57.7, -3.5, 58.6, -5, 58.6, -6.2, 57, -5, 55.8,
+		-4.8, 54.8, -3.2, 54, -3, 53.4, -4.5, 53.4, -4.2, 52.3, -5.2, 51.7, -3, 51.3,
+	}},
+	{"Eurasia", false, []float64{
+		-9, 37, -8.9, 42.5, -8, 43.7, -1.5, 43.4, -1.3, 46, -4.5, 48, -1.5, 48.7, 1.5, 50, 3, 51.2, 4.5, 52.5,
+		7, 53.5, 8.5, 53.8, 8.5, 55.5, 8.2, 57, 10.5, 57.7, 10.5, 56.2, 12.5, 56, 12.5, 54.5, 14, 54, 18, 54.8,
+		21, 55, 21, 57, 24, 57.3, 23.5, 59.2, 28, 59.7, 30, 60, 23, 60, 21.5, 61, 21.5, 63.5, 25.3, 65,
+		22, 65.8, 17.5, 62.5, 19, 60, 18.5, 59.3, 16.5, 57, 14.5, 56, 12.8, 56, 11.5, 58, 10.5, 59.5, 8, 58.1,
+		5.5, 58.8, 5, 61.5, 7, 63, 10, 64, 13, 66.5, 15.5, 68.3, 19, 70, 25, 71, 29, 70.7, 31, 70,
+		33, 69.3, 41, 67.5, 40, 66, 35, 66.3, 33, 65.5, 35, 64.3, 37.5, 64.5, 40, 64.5, 44, 66.2, 44, 68.5,
+		46, 68, 53, 68.5, 60, 69, 68, 69, 68, 72.5, 73, 72.8, 74, 68.5, 73, 71.5, 76, 72, 80, 73.5,
+		87, 74.5, 98, 76, 105, 77.7, 112, 76, 113, 73.5, 120, 73, 128, 72, 132, 71.2, 140, 72.5, 150, 71.5,
+		160, 70, 170, 70, 180, 69, 180, 65, 178, 64.5, 177, 62.5, 173, 61, 170, 60, 164, 59.8, 163, 57.5,
+		162, 55, 160, 53, 156.5, 51, 156, 57, 160, 61, 156, 61.5, 152, 59, 143, 59.3, 140, 57.8, 137, 54,
+		140.5, 53, 141, 48, 139, 46, 135, 43, 132, 42.9, 129.5, 41, 129.5, 40, 128, 38.3, 129.4, 37, 129, 35.2,
+		126.5, 34.4, 126.2, 36.8, 126, 38, 125, 39.5, 121.5, 38.8, 122.5, 40.5, 121, 40.9, 118.5, 39, 117.5, 38.5, 119, 37,
+		122.5, 37, 120, 35, 121, 32, 122, 30, 121, 28, 119.5, 25.5, 116.5, 23, 113, 22.2, 110, 21, 108.5, 21.6,
+		106, 19.5, 106.5, 18, 109, 15, 109, 12, 107, 10.5, 105, 8.6, 104.8, 10.3, 103, 11, 100, 13.5, 99.2, 10,
+		100.3, 7, 101, 6.8, 103.5, 3.5, 104.2, 1.4, 103, 1.6, 101, 2.8, 100, 4.5, 98.3, 8, 98.5, 12.5, 97.5, 16.5,
+		94.5, 16, 94, 19, 92, 21.5, 90.5, 22, 88, 21.5, 86.5, 20, 84, 17.8, 80.3, 15.5, 80, 10.5, 78, 8.2,
+		76.5, 8.7, 74.8, 12.8, 73, 17, 72.8, 21, 70, 20.8, 68.5, 23.5, 66.5, 25.4, 61.5, 25.2, 57.3, 25.8, 56.3, 27.1,
+		54, 26.7, 51.5, 27.9, 50, 30, 48, 30, 48.5, 28, 50.2, 26, 51.5, 24.5, 54, 24, 56.3, 26.2, 56.5, 24.5,
+		58.7, 23.5, 59.8, 22.3, 57.8, 19, 55, 17, 52, 15.5, 48.5, 14, 45, 12.8, 43.3, 12.7, 42.7, 15.7, 41, 19,
+		39, 21.5, 37, 25.5, 35, 28.1, 34.5, 29.5, 32.5, 29.8, 34.2, 31.3, 35, 33, 36, 35, 36, 36.7, 32.5, 36.1,
+		30.5, 36.5, 28, 36.7, 26.5, 38.5, 26.2, 39.5, 26, 40.8, 24, 40.7, 23, 40.3, 24, 38, 22.5, 36.5, 21.5, 37,
+		21, 38.5, 19.5, 40, 19.5, 41.8, 16, 43.5, 13.7, 45.6, 12.3, 45.3, 12.5, 44, 14, 42.5, 16.5, 41, 18.5, 40.2,
+		16.6, 38.5, 15.7, 38, 16.2, 39, 15.5, 40, 14, 40.8, 12, 41.9, 10.5, 43, 8.8, 44.4, 7.5, 43.8, 5, 43.3,
+		3.2, 43, 3.2, 41.8, 0.5, 40.5, -0.5, 38.5, -2, 36.7, -5, 36.4, -6.3, 36.8, -7.5, 37.2,
+	}},
+	{"Corsica", false, []float64{8.6, 41.4, 9.4, 41.4, 9.5, 43, 8.6, 42.4}},
+	{"Sardinia", false, []float64{8.4, 39, 9.6, 39.2, 9.8, 41, 8.2, 41}},
+	{"Sicily", false, []float64{12.4, 38, 15.6, 38.3, 15.1, 36.7}},
+	{"Crete", false, []float64{23.5, 35.3, 26.3, 35.2, 24.5, 35}},
+	{"Svalbard", false, []float64{11, 78.5, 16, 80, 27, 80.2, 22, 77.5, 16, 76.5}},
+	{"Novaya Zemlya", false, []float64{52, 71, 56, 74.5, 69, 76.8, 58, 74, 55, 71}},
+	{"Sakhalin", false, []float64{142, 46, 143.5, 49, 144.5, 53, 143, 54.5, 142, 51.5}},
+	{"Hokkaido", false, []float64{140, 41.5, 141.5, 42.5, 143.3, 42, 145.5, 43.3, 144, 44.2, 141.8, 45.4, 141.4, 43.4}},
+	{"Honshu", false, []float64{
+		130, 31.5, 131.5, 31.5, 132, 33.8, 135, 33.5, 136.8, 34.3, 139, 34.7, 140.8, 35.7, 141, 38.3, 142, 40, 141.3, 41.4,
+		140, 40.5, 139.8, 38.5, 138.5, 37.4, 136.7, 37, 136, 35.7, 133, 35.5, 131, 34.4, 130, 33.5,
+	}},
+	{"Taiwan", false, []float64{120.1, 23, 121, 25.2, 122, 25, 121, 22}},
+	{"Luzon", false, []float64{120.5, 18.5, 122.2, 18.5, 122, 16, 124, 13.5, 121, 13.8, 120.5, 14.7, 119.8, 16.3}},
+	{"Mindanao", false, []float64{122, 7, 125.5, 9.7, 126.5, 7, 125.5, 5.8, 124, 6.5}},
+	{"Sri Lanka", false, []float64{79.8, 8, 80.2, 9.8, 81.9, 7.5, 81, 6}},
+	{"Sumatra", false, []float64{95.3, 5.6, 98, 4, 104, -1.5, 106, -5.8, 104.5, -5.8, 101, -2.5, 98.5, 1.5}},
+	{"Java", false, []float64{105.5, -6.8, 111, -6.5, 114.5, -7.7, 111, -8.2, 106, -7.5}},
+	{"Borneo", false, []float64{109, 1.5, 111, 2, 113, 3.2, 115.5, 5, 117.5, 7, 119, 5, 118, 1, 117.5, -1, 116, -3.9, 113, -3.2, 110, -2.8, 109, -0.5}},
+	{"Sulawesi", false, []float64{119.5, -5.5, 120.5, -2, 119, 0.5, 121, 1.3, 125, 1.5, 121.5, 0.5, 123, -1, 121.5, -1.8, 122.8, -4.7, 121, -2.9, 120.5, -5.6}},
+	{"New Guinea", false, []float64{131, -1, 135, -3.3, 138, -1.7, 141, -2.6, 145, -4.5, 148, -8, 150.5, -10.5, 147, -10, 143.5, -9, 142, -9.3, 140, -8, 138, -8.4, 137.5, -5, 135, -4.5, 132, -2.8}},
+	{"Africa", false, []float64{
+		-17, 21, -16, 24, -13, 27.5, -9.8, 30, -9.5, 33, -6, 35.8, -2, 35.1, 3, 36.8, 10, 37.2, 11, 35,
+		10.2, 33.8, 12, 32.8, 15.5, 31.5, 19, 30.3, 20.5, 32.5, 25, 31.8, 29, 30.9, 32.3, 31.3, 34, 27.5, 35.5, 24,
+		37.3, 21, 39, 16, 41.5, 13.5, 43.3, 12.5, 45, 10.5, 51, 11.8, 51, 10, 48.5, 5, 46, 2, 43, -1,
+		40, -3, 39.2, -8, 40.5, -11, 40.5, -15, 37, -17.5, 35.3, -22.5, 32.8, -26, 32.5, -28.5, 30, -31.5, 27, -33.7,
+		22.5, -34, 20, -34.8, 18.4, -34, 18, -32, 16.5, -28.5, 15, -27, 14.4, -23, 12, -18, 11.8, -15.5, 13.6, -11,
+		13, -8.5, 12, -5, 9, -1, 9.5, 3.5, 8.5, 4.5, 5.5, 4.2, 2, 6.3, -2, 4.8, -4.5, 5.2, -7.5, 4.4,
+		-10, 6, -13, 7.8, -15, 10.8, -16.7, 12.5, -17.5, 14.7, -16.3, 19.5,
+	}},
+	{"Madagascar", false, []float64{49.3, -12, 50.5, -15.5, 49.5, -17.5, 47, -25, 45, -25.5, 43.3, -22, 44.2, -18, 44, -16.5, 46.5, -15.7, 48, -13.5}},
+	{"Australia", false, []float64{
+		113.5, -22, 114, -26.5, 115, -30, 115, -34, 117.8, -35, 123.5, -33.9, 129, -31.7, 131.2, -31.5, 134, -32.8, 137.8, -35.5,
+		138.5, -34.5, 140, -37.8, 143.5, -38.8, 146.3, -39, 150, -37.5, 151.5, -33, 153.5, -28.5, 153, -25, 150.8, -22.6, 149, -20.5,
+		146, -18.8, 145.3, -15, 143.5, -14, 142.5, -10.7, 141.5, -13.5, 141.5, -17, 139.5, -17.5, 136, -15.8, 136.9, -12.3, 132.5, -11.3,
+		130, -12.8, 128, -15, 125, -14.5, 122, -17.5, 121, -19.5, 117, -20.6,
+	}},
+	{"Tasmania", false, []float64{144.6, -40.7, 148.3, -40.9, 148, -43.2, 146, -43.6}},
+	{"New Zealand North Island", false, []float64{172.7, -34.4, 174.5, -36.5, 175.8, -36.8, 178.5, -37.7, 177, -39.5, 175, -41.5, 174.6, -39.9, 173.8, -39.2, 174.5, -37.3}},
+	{"New Zealand South Island", false, []float64{172.7, -40.5, 174.3, -41.7, 172.8, -43.7, 171, -45, 169, -46.6, 166.5, -46, 168.5, -44, 171.5, -41.8}},
+	{"Antarctica", false, []float64{
+		-180, -90, 180, -90, 180, -78, 165, -78, 170, -71.5, 140, -66.5, 100, -66, 70, -68, 40, -69, 10, -70,
+		-20, -72, -60, -64, -58, -63.3, -65, -69, -75, -72.5, -100, -73.5, -130, -74.5, -160, -78, -180, -78,
+	}},
+	{"Black Sea", true, []float64{28, 41.5, 29, 41.2, 33, 42, 36, 41.7, 38.5, 41, 41.5, 41.5, 41.5, 42.5, 39.5, 44, 37, 45.3, 35, 45, 33.5, 44.5, 32.5, 45.4, 30.5, 46.5, 29.5, 45, 28.5, 43.5}},
+	{"Caspian Sea", true, []float64{47, 45, 49, 46.5, 51, 47, 53, 46.5, 53, 45, 51, 44.5, 52.5, 42, 53, 40, 54, 37.5, 51, 36.7, 49, 37.5, 49.5, 40.3, 48, 42.5}},
+}
+
+// mapOutline is one polygon of the map, named for the tooltip
+type mapOutline struct {
+	Name string
+	Path string
+}
+
+// outlinePaths returns one SVG path per polygon (x = longitude, y = -latitude)
+// Polygons are kept apart so that overlapping ones do not cancel each other when filled
+func outlinePaths(water bool) []mapOutline {
+	var paths []mapOutline
+	for _, outline := range worldOutlines {
+		if outline.water != water {
+			continue
+		}
+		var sb strings.Builder
+		for i := 0; i+1 < len(outline.points); i += 2 {
+			if i == 0 {
+				sb.WriteString("M")
+			} else {
+				sb.WriteString("L")
+			}
+			sb.WriteString(strconv.FormatFloat(outline.points[i], 'f', -1, 64))
+			sb.WriteString(",")
+			sb.WriteString(strconv.FormatFloat(-outline.points[i+1], 'f', -1, 64))
+		}
+		sb.WriteString("Z")
+		paths = append(paths, mapOutline{Name: outline.name, Path: sb.String()})
+	}
+	return paths
+}
@@ -0,0 +1,338 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// XMP carries what photo managers (Lightroom, darktable, digiKam, Capture One) write about a shot:
+// rating, color label, keywords and capture date, in a sidecar next to the file or embedded in the photo (v2.10.0+)
+
+const (
+	// maxXMPSize bounds the XMP packet read in memory (packets are a few KB)
+	maxXMPSize = 1 << 20
+
+	// jpegXMPSignature starts the APP1 segment holding the XMP packet of a JPEG
+	jpegXMPSignature = "http://ns.adobe.com/xap/1.0/\x00"
+
+	xmpNamespace       = "http://ns.adobe.com/xap/1.0/"
+	dcNamespace        = "http://purl.org/dc/elements/1.1/"
+	rdfNamespace       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	exifNamespace      = "http://ns.adobe.com/exif/1.0/"
+	photoshopNamespace = "http://ns.adobe.com/photoshop/1.0/"
+)
+
+// xmpDateProperties hold the capture date, most reliable first
+var xmpDateProperties = []xml.Name{
+	{Space: exifNamespace, Local: "DateTimeOriginal"},
+	{Space: photoshopNamespace, Local: "DateCreated"},
+	{Space: xmpNamespace, Local: "CreateDate"},
+}
+
+// xmpDateLayouts are the ISO 8601 forms allowed by XMP, a missing time zone means local time
+var xmpDateLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// errNoXMP is returned when a file has no XMP sidecar or packet
+var errNoXMP = errors.New("no XMP metadata")
+
+// xmpSidecarExtensions are tried in order, after the full name (DSC_0001.NEF.xmp) then the basename (DSC_0001.xmp)
+var xmpSidecarExtensions = []string{".xmp", ".XMP"}
+
+// xmpMetadata holds the XMP properties picsplit uses
+type xmpMetadata struct {
+	dates     map[string]time.Time // Capture dates by property name (xmpDateProperties)
+	lat, lon  *float64             // exif:GPSLatitude and exif:GPSLongitude
+	label     string
+	keywords  []string
+	rating    int
+	hasRating bool // 0 is a valid rating: distinguishes "unrated" from "not set"
+}
+
+// applyXMP copies the properties set in an XMP packet, leaving the others untouched
+func (ctx *executionContext) applyXMP(m *FileMetadata, xmp *xmpMetadata) {
+	if date, ok := xmp.date(); ok {
+		ctx.addDateCandidate(m, DateSourceXMP, date)
+	}
+	if xmp.hasRating {
+		m.Rating = xmp.rating
+	}
+	if xmp.label != "" {
+		m.Label = xmp.label
+	}
+	if len(xmp.keywords) > 0 {
+		m.Keywords = xmp.keywords
+	}
+	if m.GPS == nil && xmp.lat != nil && xmp.lon != nil {
+		m.GPS = &GPSCoord{Lat: *xmp.lat, Lon: *xmp.lon}
+	}
+}
+
+// findXMPSidecar returns the path and size of the XMP sidecar of a media file (DSC_0001.NEF.xmp or DSC_0001.xmp)
+func findXMPSidecar(fsys FileSystem, filePath string) (string, int64, bool) {
+	base := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+
+	for _, prefix := range []string{filePath, base} {
+		for _, ext := range xmpSidecarExtensions {
+			sidecar := prefix + ext
+			if info, err := fsys.Stat(sidecar); err == nil && !info.IsDir() {
+				return sidecar, info.Size(), true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// readXMPSidecar reads the XMP sidecar of a media file (DSC_0001.NEF.xmp or DSC_0001.xmp)
+func readXMPSidecar(fsys FileSystem, filePath string) (*xmpMetadata, error) {
+	sidecar, size, ok := findXMPSidecar(fsys, filePath)
+	if !ok {
+		return nil, errNoXMP
+	}
+	if size > maxXMPSize {
+		return nil, fmt.Errorf("XMP sidecar too large: %d bytes", size)
+	}
+
+	data, err := readFile(fsys, sidecar)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read XMP sidecar: %w", err)
+	}
+	return parseXMP(data)
+}
+
+// writeXMPPosition writes a position to a new XMP sidecar (DSC_0001.NEF.xmp) (v2.10.0+)
+// Existing sidecars are left untouched: they may hold edits of a photo manager
+func writeXMPPosition(fsys FileSystem, filePath string, pos GPSCoord) error {
+	if sidecar, _, ok := findXMPSidecar(fsys, filePath); ok {
+		return fmt.Errorf("XMP sidecar already exists: %s", filepath.Base(sidecar))
+	}
+
+	packet := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="%s">
+  <rdf:Description rdf:about="" xmlns:exif="%s"
+   exif:GPSVersionID="2.2.0.0"
+   exif:GPSLatitude="%s"
+   exif:GPSLongitude="%s"/>
+ </rdf:RDF>
+</x:xmpmeta>
+`, rdfNamespace, exifNamespace, formatXMPCoordinate(pos.Lat, "N", "S"), formatXMPCoordinate(pos.Lon, "E", "W"))
+
+	return fsys.WriteFile(filePath+xmpSidecarExtensions[0], []byte(packet), 0644) //nolint:gosec // sidecar is meant to be readable
+}
+
+// formatXMPCoordinate formats a coordinate as XMP GPS degrees and decimal minutes: "48,51.396000N"
+func formatXMPCoordinate(value float64, positive, negative string) string {
+	ref := positive
+	if value < 0 {
+		ref = negative
+	}
+	value = math.Abs(value)
+	degrees := math.Floor(value)
+	return fmt.Sprintf("%d,%.6f%s", int(degrees), (value-degrees)*60, ref)
+}
+
+// parseXMPCoordinate parses an XMP GPS coordinate: "48,51.396N" or "48,51,23.76N"
+func parseXMPCoordinate(value string) (float64, bool) {
+	if len(value) < 2 {
+		return 0, false
+	}
+	ref := strings.ToUpper(value[len(value)-1:])
+	parts := strings.Split(value[:len(value)-1], ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+
+	coordinate := 0.0
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return 0, false
+		}
+		coordinate += number / math.Pow(60, float64(i))
+	}
+
+	switch ref {
+	case "N", "E":
+		return coordinate, true
+	case "S", "W":
+		return -coordinate, true
+	default:
+		return 0, false
+	}
+}
+
+// readEmbeddedXMP reads the XMP packet of a photo: the XMLPacket TIFF tag (TIFF-based RAW, DNG)
+// or the XMP APP1 segment of a JPEG
+func readEmbeddedXMP(fsys FileSystem, filePath string, x *exif.Exif) (*xmpMetadata, error) {
+	if x != nil {
+		if tag, err := x.Get(exifXMLPacket); err == nil && len(tag.Val) > 0 {
+			return parseXMP(tag.Val)
+		}
+	}
+
+	f, err := fsys.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	offset, length, err := findJPEGSegment(f, jpegXMPSignature)
+	if err != nil {
+		return nil, errNoXMP
+	}
+
+	data := make([]byte, length)
+	if _, err := f.ReadAt(data, offset); err != nil {
+		return nil, fmt.Errorf("failed to read XMP packet: %w", err)
+	}
+	return parseXMP(data)
+}
+
+// parseXMP extracts xmp:Rating, xmp:Label and dc:subject from an XMP packet
+// Properties are written as attributes of rdf:Description or as elements, both are read
+func parseXMP(data []byte) (*xmpMetadata, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // UTF-8 BOM written by some Windows tools
+	data = bytes.TrimRight(data, "\x00")
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	xmp := &xmpMetadata{}
+
+	var (
+		path []xml.Name // Open elements
+		text strings.Builder
+	)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XMP: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name)
+			text.Reset()
+			for _, attr := range t.Attr {
+				xmp.setProperty(attr.Name, attr.Value)
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			value := strings.TrimSpace(text.String())
+			text.Reset()
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+
+			if t.Name.Space == rdfNamespace && t.Name.Local == "li" && xmlPathContains(path, dcNamespace, "subject") {
+				xmp.addKeyword(value)
+				continue
+			}
+			xmp.setProperty(t.Name, value)
+		}
+	}
+	return xmp, nil
+}
+
+// date returns the most reliable capture date of the packet
+func (x *xmpMetadata) date() (time.Time, bool) {
+	for _, name := range xmpDateProperties {
+		if date, ok := x.dates[name.Local]; ok {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// setProperty records a simple XMP property, unknown properties and invalid values are ignored
+func (x *xmpMetadata) setProperty(name xml.Name, value string) {
+	if value == "" {
+		return
+	}
+	if slices.Contains(xmpDateProperties, name) {
+		if date, err := parseXMPDate(value); err == nil {
+			if x.dates == nil {
+				x.dates = make(map[string]time.Time)
+			}
+			x.dates[name.Local] = date
+		}
+		return
+	}
+	if name.Space == exifNamespace && (name.Local == "GPSLatitude" || name.Local == "GPSLongitude") {
+		if coordinate, ok := parseXMPCoordinate(value); ok {
+			if name.Local == "GPSLatitude" {
+				x.lat = &coordinate
+			} else {
+				x.lon = &coordinate
+			}
+		}
+		return
+	}
+	if name.Space != xmpNamespace {
+		return
+	}
+
+	switch name.Local {
+	case "Rating":
+		rating, err := strconv.ParseFloat(value, 64)
+		if err != nil || rating < -1 || rating > 5 {
+			return
+		}
+		x.rating = int(math.Round(rating))
+		x.hasRating = true
+	case "Label":
+		x.label = value
+	}
+}
+
+// parseXMPDate parses an XMP date (2024-06-15T14:30:12+02:00, 2024-06-15T14:30, 2024-06-15...)
+func parseXMPDate(value string) (time.Time, error) {
+	for _, layout := range xmpDateLayouts {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid XMP date: %s", value)
+}
+
+// addKeyword appends a keyword once, in packet order
+func (x *xmpMetadata) addKeyword(keyword string) {
+	if keyword == "" {
+		return
+	}
+	for _, existing := range x.keywords {
+		if existing == keyword {
+			return
+		}
+	}
+	x.keywords = append(x.keywords, keyword)
+}
+
+// xmlPathContains checks if an element with the given name is open
+func xmlPathContains(path []xml.Name, space, local string) bool {
+	for _, name := range path {
+		if name.Space == space && name.Local == local {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// createXMP builds an XMP packet with the rating and label as attributes and keywords in dc:subject
+func createXMP(rating, label string, keywords ...string) string {
+	packet := `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/"`
+	if rating != "" {
+		packet += ` xmp:Rating="` + rating + `"`
+	}
+	if label != "" {
+		packet += ` xmp:Label="` + label + `"`
+	}
+	packet += ">\n   <dc:subject><rdf:Bag>"
+	for _, keyword := range keywords {
+		packet += "<rdf:li>" + keyword + "</rdf:li>"
+	}
+	return packet + "</rdf:Bag></dc:subject>\n  </rdf:Description>\n </rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>"
+}
+
+func TestParseXMP(t *testing.T) {
+	tests := []struct {
+		name      string
+		packet    string
+		want      xmpMetadata
+		wantError bool
+	}{
+		{
+			name:   "attributes and keywords",
+			packet: createXMP("4", "Red", "Paris", "Family", "Paris"),
+			want:   xmpMetadata{rating: 4, hasRating: true, label: "Red", keywords: []string{"Paris", "Family"}},
+		},
+		{
+			name: "properties as elements",
+			packet: `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/"><xmp:Rating>-1</xmp:Rating><xmp:Label> Green </xmp:Label></rdf:Description>
+</rdf:RDF></x:xmpmeta>`,
+			want: xmpMetadata{rating: -1, hasRating: true, label: "Green"},
+		},
+		{
+			name:   "unrated with BOM",
+			packet: "\xef\xbb\xbf" + createXMP("0", ""),
+			want:   xmpMetadata{rating: 0, hasRating: true},
+		},
+		{
+			name:   "out of range rating ignored",
+			packet: createXMP("9", "", "Beach"),
+			want:   xmpMetadata{keywords: []string{"Beach"}},
+		},
+		{
+			name:      "invalid XML",
+			packet:    "<x:xmpmeta><rdf:RDF>",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseXMP([]byte(tt.packet))
+			if tt.wantError {
+				if err == nil {
+					t.Error("parseXMP() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseXMP() error: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("parseXMP() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadXMPSidecar(t *testing.T) {
+	fsys := NewMemFileSystem()
+	writeMemFile(t, fsys, "/photos/DSC_0001.xmp", []byte(createXMP("2", "")), time.Now())
+	writeMemFile(t, fsys, "/photos/DSC_0001.NEF.xmp", []byte(createXMP("5", "")), time.Now())
+	writeMemFile(t, fsys, "/photos/DSC_0002.XMP", []byte(createXMP("3", "")), time.Now())
+
+	tests := []struct {
+		path       string
+		wantRating int
+		wantErr    error
+	}{
+		{"/photos/DSC_0001.NEF", 5, nil}, // Full name sidecar first
+		{"/photos/DSC_0001.JPG", 2, nil},
+		{"/photos/DSC_0002.NEF", 3, nil},
+		{"/photos/DSC_0003.NEF", 0, errNoXMP},
+	}
+
+	for _, tt := range tests {
+		xmp, err := readXMPSidecar(fsys, tt.path)
+		if err != tt.wantErr {
+			t.Errorf("readXMPSidecar(%s) error = %v, want %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if err == nil && xmp.rating != tt.wantRating {
+			t.Errorf("readXMPSidecar(%s) rating = %d, want %d", tt.path, xmp.rating, tt.wantRating)
+		}
+	}
+}
+
+func TestExtractMetadata_Details(t *testing.T) {
+	shot := time.Date(2024, 6, 15, 14, 30, 12, 0, time.Local)
+	le := binary.LittleEndian
+	short := func(tag, value uint16) tiffEntry {
+		return tiffEntry{tag: tag, typ: 3, count: 1, value: le.AppendUint16(nil, value)}
+	}
+	long := func(tag uint16, value uint32) tiffEntry {
+		return tiffEntry{tag: tag, typ: 4, count: 1, value: le.AppendUint32(nil, value)}
+	}
+
+	packet := []byte(createXMP("3", "Blue", "Wedding"))
+
+	dirs := createRAWDirs(le, shot, nil)
+	dirs.ifd0 = append(dirs.ifd0,
+		short(0x0112, 6), // Orientation: rotated 90° CW
+		tiffEntry{tag: 0x02BC, typ: 1, count: uint32(len(packet)), value: packet},
+	)
+	dirs.exif = append(dirs.exif,
+		asciiEntry(0xA431, "3012345"),
+		asciiEntry(0xA434, "NIKKOR Z 24-70mm f/4 S"),
+		tiffEntry{tag: 0x920A, typ: 5, count: 1, value: le.AppendUint32(le.AppendUint32(nil, 350), 10)},
+		short(0x8827, 800),
+		long(0xA002, 6048),
+		long(0xA003, 4024),
+	)
+
+	fsys := NewMemFileSystem()
+	writeMemFile(t, fsys, "/photos/DSC_0001.NEF", dirs.encode(), time.Now())
+	writeMemFile(t, fsys, "/photos/DSC_0002.NEF", dirs.encode(), time.Now())
+	// Sidecar overrides the embedded rating, keeps the embedded label and keywords
+	writeMemFile(t, fsys, "/photos/DSC_0002.NEF.xmp", []byte(createXMP("5", "")), time.Now())
+
+	ctx, err := newExecutionContext(&Config{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path       string
+		wantRating int
+	}{
+		{"/photos/DSC_0001.NEF", 3},
+		{"/photos/DSC_0002.NEF", 5},
+	}
+	for _, tt := range tests {
+		metadata, err := ExtractMetadata(ctx, tt.path)
+		if err != nil {
+			t.Fatalf("ExtractMetadata() error: %v", err)
+		}
+
+		got := *metadata
+		got.FileInfo = nil
+		want := FileMetadata{
+			DateTime:     shot,
+			Source:       DateSourceEXIF,
+			Make:         "TestMake",
+			Model:        "TestModel",
+			SerialNumber: "3012345",
+			LensModel:    "NIKKOR Z 24-70mm f/4 S",
+			FocalLength:  35,
+			ISO:          800,
+			Width:        6048,
+			Height:       4024,
+			Orientation:  6,
+			Rating:       tt.wantRating,
+			Label:        "Blue",
+			Keywords:     []string{"Wedding"},
+		}
+		if !got.DateTime.Equal(want.DateTime) {
+			t.Errorf("%s: date = %v, want %v", tt.path, got.DateTime, want.DateTime)
+		}
+		got.DateTime = want.DateTime
+		got.Dates = nil // Date candidates are checked by the date priority tests
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: metadata = %+v, want %+v", tt.path, got, want)
+		}
+	}
+}
+
+func TestExtractMetadata_JPEGEmbeddedXMP(t *testing.T) {
+	exifData := createMinimalEXIFData(time.Date(2024, 6, 15, 14, 30, 12, 0, time.Local))
+	packet := append([]byte(jpegXMPSignature), createXMP("1", "Yellow", "Trip")...)
+
+	jpeg := binary.BigEndian.AppendUint16([]byte{0xFF, 0xD8, 0xFF, 0xE1}, uint16(len(exifData)+2))
+	jpeg = append(jpeg, exifData...)
+	jpeg = binary.BigEndian.AppendUint16(append(jpeg, 0xFF, 0xE1), uint16(len(packet)+2))
+	jpeg = append(append(jpeg, packet...), 0xFF, 0xD9)
+
+	fsys := NewMemFileSystem()
+	writeMemFile(t, fsys, "/photos/IMG_0001.jpg", jpeg, time.Now())
+	ctx, err := newExecutionContext(&Config{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := ExtractMetadata(ctx, "/photos/IMG_0001.jpg")
+	if err != nil {
+		t.Fatalf("ExtractMetadata() error: %v", err)
+	}
+	if metadata.Rating != 1 || metadata.Label != "Yellow" || !reflect.DeepEqual(metadata.Keywords, []string{"Trip"}) {
+		t.Errorf("XMP = rating %d, label %q, keywords %v", metadata.Rating, metadata.Label, metadata.Keywords)
+	}
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/sebastienfr/picsplit/handler"
@@ -50,6 +55,12 @@ var (
 	// gpsUseGeocoding -gps-geocoding : use reverse geocoding for GPS location names (v2.9.0+)
 	gpsUseGeocoding = false
 
+	// gpsHierarchy -gps-hierarchy : "Country/Region/City" location folders from geocoding (v2.10.0+)
+	gpsHierarchy = false
+
+	// gpsInferWindow -gps-infer-window : files without GPS join the location of the closest geotagged file within this time (v2.10.0+)
+	gpsInferWindow time.Duration
+
 	// customPhotoExts -pext : additional photo extensions (v2.5.0+)
 	customPhotoExts string
 
@@ -86,6 +97,84 @@ var (
 	// minGroupSize -min-group-size : minimum group size to create folder (v2.9.0+)
 	minGroupSize = 5
 
+	// onCollision -on-collision : policy when the destination name is taken (v2.10.0+)
+	onCollision = "rename"
+
+	// renameTemplate -rename-template : template used to rename imported files (v2.10.0+)
+	renameTemplate = ""
+
+	// incremental -incremental : append new files to existing event folders (v2.10.0+)
+	incremental = false
+
+	// resume -resume : continue the run interrupted in the folder from its checkpoint (v2.10.0+)
+	resume = false
+
+	// rules -rules : JSON rules file routing files before event grouping (v2.10.0+)
+	rulesFile = ""
+
+	// classify -classify : send screenshots and messaging app images to their own folders (v2.10.0+)
+	classify = false
+
+	// classifyBy -classify-by : subfolder period of classified files (v2.10.0+)
+	classifyBy = "year"
+
+	// contactSheet -contact-sheet : write a contact.jpg of thumbnails into each event folder (v2.10.0+)
+	contactSheet = false
+
+	// datePriority -date-priority : date sources tried in order (v2.10.0+)
+	datePriority = strings.Join(handler.DefaultDatePriority, ",")
+
+	// dateConflictThreshold -date-conflict-threshold : report files whose dates are further apart (v2.10.0+)
+	dateConflictThreshold time.Duration
+
+	// dateConflictPolicy -date-conflict-policy : date kept when the dates of a file disagree (v2.10.0+)
+	dateConflictPolicy = "priority"
+
+	// minValidYear -min-valid-year : dates before this year are ignored (v2.10.0+)
+	minValidYear = 1990
+
+	// groupBy -group-by : grouping strategy, time gaps or calendar periods (v2.10.0+)
+	groupBy = "gap"
+
+	// adaptiveMaxGap -adaptive-max-gap : gaps longer than this always split with --group-by adaptive (v2.10.0+)
+	adaptiveMaxGap = handler.DefaultAdaptiveMaxGap
+
+	// dayBoundary -day-boundary : time the calendar day starts at for period grouping (v2.10.0+)
+	dayBoundary = ""
+
+	// gpxPath -gpx : GPX, KML or FIT track, or folder of tracks, geotagging files without GPS (v2.10.0+)
+	gpxPath = ""
+
+	// gpxOffset -gpx-offset : added to file dates before matching the track (v2.10.0+)
+	gpxOffset time.Duration
+
+	// gpxMaxGap -gpx-max-gap : longest time between a file and the track points it is positioned from (v2.10.0+)
+	gpxMaxGap = handler.DefaultGPXMaxGap
+
+	// gpxWriteXMP -gpx-write-xmp : write interpolated positions to new XMP sidecars (v2.10.0+)
+	gpxWriteXMP = false
+
+	// trips -trips : group runs of consecutive active days into trip folders with day subfolders (v2.10.0+)
+	trips = false
+
+	// tripMinDays -trip-min-days : shortest trip in days (v2.10.0+)
+	tripMinDays = handler.DefaultTripMinDays
+
+	// home -home : home coordinates, days spent there end trips (v2.10.0+)
+	home = ""
+
+	// homeRadius -home-radius : home radius in meters (v2.10.0+)
+	homeRadius = handler.DefaultHomeRadius
+
+	// report -report : JSON file receiving the run report (v2.10.0+)
+	reportPath = ""
+
+	// metrics-addr -metrics-addr : address serving Prometheus metrics on /metrics (v2.10.0+)
+	metricsAddr = ""
+
+	// metrics-file -metrics-file : node-exporter textfile collector output (v2.10.0+)
+	metricsFile = ""
+
 	header, _ = base64.StdEncoding.DecodeString("ICAgICAgIC5fXyAgICAgICAgICAgICAgICAgICAgICAuX18gIC5fXyAgX18KX19f" +
 		"X19fIHxfX3wgX19fXCAgIF9fX19fX19fX19fXyB8ICB8IHxfX3wvICB8XwpcX19fXyBcfCAgfC8gX19fXCAvICBfX18vXF9fX18gXHwgIHw" +
 		"gfCAgXCAgIF9fXAp8ICB8Xz4gPiAgXCAgXF9fXyBcX19fIFwgfCAgfF8+ID4gIHxffCAgfHwgIHwKfCAgIF9fL3xfX3xcX19fICA+X19fXy" +
@@ -94,10 +183,11 @@ var (
 
 const (
 	// Default configuration values
-	defaultPath      = "."
-	defaultDelta     = 45 * time.Minute
-	defaultLogLevel  = "info"
-	defaultLogFormat = "text"
+	defaultPath        = "."
+	defaultDelta       = 45 * time.Minute
+	defaultLogLevel    = "info"
+	defaultLogFormat   = "text"
+	defaultQuietPeriod = 30 * time.Second
 
 	// Application metadata
 	appName        = "picsplit"
@@ -106,12 +196,19 @@ const (
 	copyrightOwner = "sebastienfr"
 
 	// Command names
-	cmdMerge = "merge"
+	cmdMerge        = "merge"
+	cmdWatch        = "watch"
+	cmdRegroup      = "regroup"
+	cmdReport       = "report"
+	cmdContactSheet = "contact-sheet"
 
 	// Flag names
 	flagForce     = "force"
 	flagLogLevel  = "log-level"
 	flagLogFormat = "log-format"
+	flagReport    = "report"
+
+	flagFilenameDatePattern = "filename-date-pattern"
 )
 
 // parseExtensions parses comma-separated extension string into slice
@@ -173,6 +270,211 @@ func setupLogger(logLevel, logFormat string) {
 	slog.SetDefault(logger)
 }
 
+// buildSplitConfig creates the split configuration from the global command line flags
+func buildSplitConfig(c *cli.Context, path string) (*handler.Config, error) {
+	// Parse custom extensions
+	photoExts, err := parseExtensions(customPhotoExts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid photo extensions: %w", err)
+	}
+
+	videoExts, err := parseExtensions(customVideoExts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid video extensions: %w", err)
+	}
+
+	rawExts, err := parseExtensions(customRawExts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RAW extensions: %w", err)
+	}
+
+	// Parse cleanup ignore files
+	cleanupIgnoreFiles := []string{}
+	if cleanupIgnore != "" {
+		cleanupIgnoreFiles = strings.Split(cleanupIgnore, ",")
+		for i, file := range cleanupIgnoreFiles {
+			cleanupIgnoreFiles[i] = strings.TrimSpace(file)
+		}
+	}
+
+	slog.Debug("configuration",
+		"path", path,
+		"delta_minutes", durationDelta.Minutes(),
+		"mode", executionMode,
+		"no_move_movies", noMoveMovie,
+		"no_move_raw", noMoveRaw,
+		"use_exif", useEXIF,
+		"use_gps", useGPS,
+		"gps_radius_meters", gpsRadius,
+		"gps_hierarchy", gpsHierarchy,
+		"gps_infer_window", gpsInferWindow,
+		"separate_orphan_raw", separateOrphanRaw,
+		"on_collision", onCollision,
+		"rename_template", renameTemplate,
+		"incremental", incremental,
+		"resume", resume,
+		"rules", rulesFile,
+		"classify", classify,
+		"classify_by", classifyBy,
+		"contact_sheet", contactSheet,
+		"filename_date_patterns", c.StringSlice(flagFilenameDatePattern),
+		"date_priority", datePriority,
+		"date_conflict_threshold", dateConflictThreshold,
+		"date_conflict_policy", dateConflictPolicy,
+		"min_valid_year", minValidYear,
+		"group_by", groupBy,
+		"day_boundary", dayBoundary,
+		"adaptive_max_gap", adaptiveMaxGap,
+		"gpx", gpxPath,
+		"gpx_offset", gpxOffset,
+		"gpx_max_gap", gpxMaxGap,
+		"gpx_write_xmp", gpxWriteXMP,
+		"trips", trips,
+		"trip_min_days", tripMinDays,
+		"home", home,
+		"home_radius_meters", homeRadius,
+		"report", reportPath,
+		"metrics_addr", metricsAddr,
+		"metrics_file", metricsFile)
+	if len(photoExts) > 0 {
+		slog.Debug("custom photo extensions", "extensions", strings.Join(photoExts, ", "))
+	}
+	if len(videoExts) > 0 {
+		slog.Debug("custom video extensions", "extensions", strings.Join(videoExts, ", "))
+	}
+	if len(rawExts) > 0 {
+		slog.Debug("custom raw extensions", "extensions", strings.Join(rawExts, ", "))
+	}
+
+	// check path exists
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("provided path %s is not a directory", path)
+	}
+
+	// Handle execution mode
+	mode := handler.ExecutionMode(executionMode)
+
+	// Validate execution mode
+	validModes := map[handler.ExecutionMode]bool{
+		handler.ModeValidate: true,
+		handler.ModeDryRun:   true,
+		handler.ModeRun:      true,
+	}
+	if !validModes[mode] {
+		return nil, fmt.Errorf("invalid --mode value: %s (must be: validate, dryrun, or run)", mode)
+	}
+
+	cfg := &handler.Config{
+		BasePath:              path,
+		Delta:                 durationDelta,
+		NoMoveMovie:           noMoveMovie,
+		NoMoveRaw:             noMoveRaw,
+		UseEXIF:               useEXIF,
+		UseGPS:                useGPS,
+		GPSRadius:             gpsRadius,
+		GPSUseGeocoding:       gpsUseGeocoding,
+		GPSHierarchy:          gpsHierarchy,
+		GPSInferWindow:        gpsInferWindow,
+		CustomPhotoExts:       photoExts,
+		CustomVideoExts:       videoExts,
+		CustomRawExts:         rawExts,
+		SeparateOrphanRaw:     separateOrphanRaw,
+		ContinueOnError:       continueOnError,
+		Mode:                  mode,
+		CleanupEmptyDirs:      cleanupEmptyDirs,
+		CleanupIgnore:         cleanupIgnoreFiles,
+		Force:                 force,
+		DetectDuplicates:      detectDuplicates,
+		SkipDuplicates:        skipDuplicates,
+		MoveDuplicates:        moveDuplicates,
+		MinGroupSize:          minGroupSize,
+		OnCollision:           onCollision,
+		RenameTemplate:        renameTemplate,
+		Incremental:           incremental,
+		Resume:                resume,
+		RulesFile:             rulesFile,
+		Classify:              classify,
+		ClassifyBy:            classifyBy,
+		ContactSheet:          contactSheet,
+		FilenameDatePatterns:  c.StringSlice(flagFilenameDatePattern),
+		DatePriority:          strings.Split(datePriority, ","),
+		DateConflictThreshold: dateConflictThreshold,
+		DateConflictPolicy:    dateConflictPolicy,
+		MinValidYear:          minValidYear,
+		GroupBy:               groupBy,
+		DayBoundary:           dayBoundary,
+		AdaptiveMaxGap:        adaptiveMaxGap,
+		GPXPath:               gpxPath,
+		GPXOffset:             gpxOffset,
+		GPXMaxGap:             gpxMaxGap,
+		GPXWriteXMP:           gpxWriteXMP,
+		Trips:                 trips,
+		TripMinDays:           tripMinDays,
+		Home:                  home,
+		HomeRadius:            homeRadius,
+		LogLevel:              c.String(flagLogLevel),
+		LogFormat:             c.String(flagLogFormat),
+		Progress:              handler.NewTerminalProgress(c.String(flagLogLevel), c.String(flagLogFormat)),
+	}
+	return cfg, nil
+}
+
+// startMetrics creates the metrics registry when --metrics-addr or --metrics-file is set
+// The HTTP endpoint lives as long as the process, which suits watch mode
+func startMetrics() *handler.Metrics {
+	if metricsAddr == "" && metricsFile == "" {
+		return nil
+	}
+
+	metrics := handler.NewMetrics()
+	metrics.TextFile = metricsFile
+
+	if metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics)
+		server := &http.Server{Addr: metricsAddr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("metrics endpoint stopped", "addr", metricsAddr, "error", err)
+			}
+		}()
+		slog.Info("serving metrics", "url", "http://"+metricsAddr+"/metrics")
+	}
+	return metrics
+}
+
+// validateMergeWithReport runs a merge validation and writes its JSON report
+func validateMergeWithReport(cfg *handler.MergeConfig, path string) error {
+	validation, err := handler.ValidateMerge(cfg)
+	if validation != nil {
+		validation.Print()
+		if err == nil && len(validation.Errors) > 0 {
+			err = fmt.Errorf("validation found %d critical issue(s)", len(validation.Errors))
+		}
+	}
+
+	if reportErr := handler.NewMergeReport(validation, err).WriteFile(path); reportErr != nil {
+		slog.Error("failed to write report", "path", path, "error", reportErr)
+	} else {
+		slog.Info("report written", "path", path)
+	}
+	return err
+}
+
+// interruptContext returns a context canceled on Ctrl-C / SIGTERM
+// The run then stops after the current file; a second signal terminates immediately
+func interruptContext() (context.Context, context.CancelFunc) {
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(runCtx, stop)
+	return runCtx, stop
+}
+
 // getBuildInfo returns version information from build metadata
 // Version is injected via ldflags, VCS info comes from runtime/debug (Go 1.18+)
 func getBuildInfo() (string, string, string, string) {
@@ -247,6 +549,8 @@ func main() {
 			{Name: authorName},
 		},
 		Copyright: copyrightOwner + " " + strconv.Itoa(time.Now().Year()),
+		// Regexes hold commas ({1,2}): repeat --filename-date-pattern instead
+		DisableSliceFlagSeparator: true,
 		Commands: []*cli.Command{
 			{
 				Name:      cmdMerge,
@@ -312,6 +616,10 @@ func main() {
 						Aliases: []string{"rext"},
 						Usage:   "Additional RAW extensions (comma-separated, e.g., 'rwx,srw,3fr'). Max 8 chars, alphanumeric only",
 					},
+					&cli.StringFlag{
+						Name:  flagReport,
+						Usage: "Write the validation report as JSON to this file (--mode validate only)",
+					},
 				},
 				Action: func(c *cli.Context) error {
 					// Init logger
@@ -358,6 +666,10 @@ func main() {
 					if !validModes[mode] {
 						return fmt.Errorf("invalid --mode value: %s (must be: validate, dryrun, or run)", mode)
 					}
+					mergeReport := c.String(flagReport)
+					if mergeReport != "" && mode != handler.ModeValidate {
+						return fmt.Errorf("merge --report requires --mode validate")
+					}
 
 					// Debug info
 					slog.Debug("merge configuration",
@@ -386,7 +698,206 @@ func main() {
 						CustomRawExts:   rawExts,
 					}
 
-					return handler.Merge(cfg)
+					if mergeReport != "" {
+						return validateMergeWithReport(cfg, mergeReport)
+					}
+
+					runCtx, stop := interruptContext()
+					defer stop()
+
+					return handler.Merge(runCtx, cfg)
+				},
+			},
+			{
+				Name:      cmdWatch,
+				Usage:     "Watch a folder and split new media as it arrives",
+				ArgsUsage: "DIR",
+				Description: `Watch a hot folder (e.g., a shared incoming/ folder) and split new media automatically.
+   Global flags (--delta, --gps, --mode...) define the split profile applied to every batch.
+
+   A batch is processed once:
+   - no new file showed up for --quiet-period
+   - every file is fully written (size stable, no process writing to it)
+
+   New files within --delta of an existing event folder are appended to it
+   instead of creating a new folder. A failed batch is retried after --quiet-period.
+   Confirmation prompts are skipped. Stop with Ctrl-C.
+
+   Not supported: --group-by day/week/month/year, --trips, --gps-hierarchy.
+
+   Examples:
+      picsplit watch incoming/
+      picsplit --delta 1h --mode dryrun watch incoming/ --quiet-period 2m`,
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:    "quiet-period",
+						Aliases: []string{"qp"},
+						Value:   defaultQuietPeriod,
+						Usage:   "Time without new files before a batch is processed",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					// Init logger
+					setupLogger(c.String(flagLogLevel), c.String(flagLogFormat))
+
+					// Print header
+					fmt.Println(string(header))
+
+					if c.NArg() != 1 {
+						return fmt.Errorf("watch requires a unique folder argument")
+					}
+
+					cfg, err := buildSplitConfig(c, c.Args().Get(0))
+					if err != nil {
+						return err
+					}
+					cfg.Metrics = startMetrics()
+
+					// Stop watching on Ctrl-C / SIGTERM
+					runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+					defer stop()
+
+					return handler.Watch(runCtx, &handler.WatchConfig{
+						Split:       cfg,
+						QuietPeriod: c.Duration("quiet-period"),
+					})
+				},
+			},
+			{
+				Name:      cmdRegroup,
+				Usage:     "Reorganize an already split library with new settings",
+				ArgsUsage: "DIR",
+				Description: `Recompute the event folders of a library previously organized by picsplit.
+   Global flags (--delta, --gps, --min-group-size...) define the new settings.
+
+   Media in event folders (and their raw/, mov/ and orphan/ subfolders) are regrouped:
+   - RAW and JPEG pairs stay together, sidecars follow their media
+   - name collisions are renamed (IMG_0001_1.JPG)
+   - folders left empty are removed
+
+   In dryrun mode the folder diff is printed without moving anything.
+
+   Examples:
+      picsplit --delta 2h --mode dryrun regroup photos/
+      picsplit --delta 2h regroup photos/`,
+				Action: func(c *cli.Context) error {
+					// Init logger
+					setupLogger(c.String(flagLogLevel), c.String(flagLogFormat))
+
+					// Print header
+					fmt.Println(string(header))
+
+					if c.NArg() != 1 {
+						return fmt.Errorf("regroup requires a unique folder argument")
+					}
+
+					cfg, err := buildSplitConfig(c, c.Args().Get(0))
+					if err != nil {
+						return err
+					}
+
+					runCtx, stop := interruptContext()
+					defer stop()
+
+					result, err := handler.Regroup(runCtx, cfg)
+					if result != nil {
+						if cfg.Mode != handler.ModeRun {
+							result.PrintDiff()
+						}
+						result.PrintSummary()
+					}
+					return err
+				},
+			},
+			{
+				Name:      cmdReport,
+				Usage:     "Write an HTML map and timeline of a library, with its GeoJSON",
+				ArgsUsage: "DIR",
+				Description: `Write a self-contained HTML page (index.html) and a GeoJSON export (library.geojson)
+   of a library to the --html folder. Nothing is moved.
+   Global flags (--delta, --gps, --gps-radius...) define the split profile.
+
+   The page works offline (no script, no CDN) and holds:
+   - a map of the location clusters and geotagged files, over embedded land outlines
+   - a timeline of the event folders
+
+   Event folders already organized are shown as they are; media still at the root
+   are shown in the folders split would create (planned), to review a split before running it.
+   The GeoJSON holds a point per location cluster and per geotagged file.
+
+   Examples:
+      picsplit --gps report --html review/ incoming/
+      picsplit --gps report --html photos/.index photos/`,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "html",
+						Required: true,
+						Usage:    "Folder receiving index.html and library.geojson (created if missing)",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					// Init logger
+					setupLogger(c.String(flagLogLevel), c.String(flagLogFormat))
+
+					// Print header
+					fmt.Println(string(header))
+
+					if c.NArg() != 1 {
+						return fmt.Errorf("report requires a unique folder argument")
+					}
+
+					cfg, err := buildSplitConfig(c, c.Args().Get(0))
+					if err != nil {
+						return err
+					}
+
+					runCtx, stop := interruptContext()
+					defer stop()
+
+					outDir := c.String("html")
+					if err := handler.WriteLibraryReport(runCtx, &handler.LibraryReportConfig{Split: cfg, HTMLDir: outDir}); err != nil {
+						return err
+					}
+					slog.Info("report written", "path", outDir)
+					return nil
+				},
+			},
+			{
+				Name:      cmdContactSheet,
+				Usage:     "Write a contact sheet into each event folder of an organized library",
+				ArgsUsage: "DIR",
+				Description: `Write contact.jpg, a grid of photo thumbnails, into every event folder of a library
+   previously organized by picsplit (at root and in GPS location folders). Existing sheets are replaced.
+
+   Thumbnails come from the preview embedded in the file (EXIF thumbnail of JPEG and HEIC,
+   JPEG preview of RAW files); JPEG and PNG without one are decoded. Videos are left out.
+   Large events are sampled to 96 thumbnails.
+
+   In dryrun mode the folders are listed without writing anything.
+
+   Examples:
+      picsplit contact-sheet photos/
+      picsplit --mode dryrun contact-sheet photos/`,
+				Action: func(c *cli.Context) error {
+					// Init logger
+					setupLogger(c.String(flagLogLevel), c.String(flagLogFormat))
+
+					// Print header
+					fmt.Println(string(header))
+
+					if c.NArg() != 1 {
+						return fmt.Errorf("contact-sheet requires a unique folder argument")
+					}
+
+					cfg, err := buildSplitConfig(c, c.Args().Get(0))
+					if err != nil {
+						return err
+					}
+
+					runCtx, stop := interruptContext()
+					defer stop()
+
+					return handler.WriteContactSheets(runCtx, cfg)
 				},
 			},
 		},
@@ -458,6 +969,16 @@ func main() {
 			Destination: &gpsUseGeocoding,
 			Usage:       "Use reverse geocoding for GPS location names (requires internet, slower due to API rate limits)",
 		},
+		&cli.BoolFlag{
+			Name:        "gps-hierarchy",
+			Destination: &gpsHierarchy,
+			Usage:       "With --gps-geocoding, organize locations as Country/Region/City folders, merging the clusters of one city",
+		},
+		&cli.DurationFlag{
+			Name:        "gps-infer-window",
+			Destination: &gpsInferWindow,
+			Usage:       "Place files without GPS in the location of the closest geotagged file shot within this time (e.g. 15m), 0 disables",
+		},
 		&cli.StringFlag{
 			Name:        "photo-ext",
 			Aliases:     []string{"pext"},
@@ -526,6 +1047,153 @@ func main() {
 			Destination: &minGroupSize,
 			Usage:       "Minimum group size to create folder (default: 5). Groups below threshold stay at parent root",
 		},
+		&cli.StringFlag{
+			Name:        "on-collision",
+			Aliases:     []string{"oc"},
+			Value:       "rename",
+			Destination: &onCollision,
+			Usage:       "Policy when a file with the same name exists at destination: rename, skip, overwrite, fail (identical files are always skipped)",
+		},
+		&cli.StringFlag{
+			Name:        "rename-template",
+			Aliases:     []string{"rt"},
+			Destination: &renameTemplate,
+			Usage:       "Rename files on import, e.g. \"{date:20060102_150405}_{camera}_{seq:4}.{ext}\" (tokens: date, camera, make, name, seq, ext)",
+		},
+		&cli.BoolFlag{
+			Name:        "incremental",
+			Aliases:     []string{"inc"},
+			Destination: &incremental,
+			Usage:       "Append new files to existing event folders within --delta, merging events that now touch",
+		},
+		&cli.BoolFlag{
+			Name:        "resume",
+			Destination: &resume,
+			Usage:       "Continue the run interrupted (Ctrl-C) in this folder from its checkpoint",
+		},
+		&cli.StringFlag{
+			Name:        "rules",
+			Destination: &rulesFile,
+			Usage:       "JSON rules file routing matching files (screenshots, messaging apps, drones...) before event grouping",
+		},
+		&cli.BoolFlag{
+			Name:        "classify",
+			Destination: &classify,
+			Usage:       "Send screenshots to screenshots/ and WhatsApp, Telegram or Signal images to messaging/<app>/ instead of events",
+		},
+		&cli.StringFlag{
+			Name:        "classify-by",
+			Value:       "year",
+			Destination: &classifyBy,
+			Usage:       "Subfolder period of classified files: year or month",
+		},
+		&cli.BoolFlag{
+			Name:        "contact-sheet",
+			Destination: &contactSheet,
+			Usage:       "Write a contact.jpg of photo thumbnails into each event folder created or extended",
+		},
+		&cli.StringSliceFlag{
+			Name:  flagFilenameDatePattern,
+			Usage: "Regex reading dates in file names with (?P<year>) (?P<month>) (?P<day>) and optional (?P<hour>) (?P<minute>) (?P<second>) groups, repeatable",
+		},
+		&cli.StringFlag{
+			Name:        "date-priority",
+			Value:       strings.Join(handler.DefaultDatePriority, ","),
+			Destination: &datePriority,
+			Usage:       "Date sources tried in order, unlisted ones are ignored: exif, video, filename, xmp, mtime, birthtime",
+		},
+		&cli.DurationFlag{
+			Name:        "date-conflict-threshold",
+			Destination: &dateConflictThreshold,
+			Usage:       "Report files whose dates (EXIF, file name, mtime...) are further apart, e.g. 720h (0 disables)",
+		},
+		&cli.StringFlag{
+			Name:        "date-conflict-policy",
+			Value:       "priority",
+			Destination: &dateConflictPolicy,
+			Usage:       "Date kept on conflict: priority (--date-priority order), oldest, newest, or skip (leave the file at root)",
+		},
+		&cli.IntFlag{
+			Name:        "min-valid-year",
+			Value:       1990,
+			Destination: &minValidYear,
+			Usage:       "Ignore dates before this year (unset camera clocks write 1970, 1980 or 2000)",
+		},
+		&cli.StringFlag{
+			Name:        "group-by",
+			Value:       "gap",
+			Destination: &groupBy,
+			Usage:       "Grouping strategy: gap (events split by --delta), adaptive (threshold learned per day, --delta as floor), day, week, month, or year (calendar folders such as 2024/06)",
+		},
+		&cli.StringFlag{
+			Name:        "day-boundary",
+			Destination: &dayBoundary,
+			Usage:       "Time the day starts at for --group-by adaptive/day/week/month/year, e.g. 04:00 keeps late-night photos with the evening",
+		},
+		&cli.DurationFlag{
+			Name:        "adaptive-max-gap",
+			Value:       handler.DefaultAdaptiveMaxGap,
+			Destination: &adaptiveMaxGap,
+			Usage:       "Gaps longer than this always start a new event with --group-by adaptive",
+		},
+		&cli.StringFlag{
+			Name:        "gpx",
+			Destination: &gpxPath,
+			Usage:       "GPX, KML or FIT track (or folder of tracks) positioning files without GPS, e.g. a watch track for a DSLR",
+		},
+		&cli.DurationFlag{
+			Name:        "gpx-offset",
+			Destination: &gpxOffset,
+			Usage:       "Added to file dates before matching the track, to correct the camera clock (e.g. -2h, 1m30s)",
+		},
+		&cli.DurationFlag{
+			Name:        "gpx-max-gap",
+			Value:       handler.DefaultGPXMaxGap,
+			Destination: &gpxMaxGap,
+			Usage:       "Longest time between a file and the track points it is positioned from",
+		},
+		&cli.BoolFlag{
+			Name:        "gpx-write-xmp",
+			Destination: &gpxWriteXMP,
+			Usage:       "Write interpolated positions to new XMP sidecars (files with a sidecar are left untouched)",
+		},
+		&cli.BoolFlag{
+			Name:        "trips",
+			Destination: &trips,
+			Usage:       "Group runs of consecutive days away from --home into trip folders (\"2024-06 Italy trip\") with one subfolder per day",
+		},
+		&cli.IntFlag{
+			Name:        "trip-min-days",
+			Value:       handler.DefaultTripMinDays,
+			Destination: &tripMinDays,
+			Usage:       "Shortest trip in days",
+		},
+		&cli.StringFlag{
+			Name:        "home",
+			Destination: &home,
+			Usage:       "Home coordinates as latitude,longitude (e.g. 48.8566,2.3522), required by --trips: days spent at home end trips",
+		},
+		&cli.Float64Flag{
+			Name:        "home-radius",
+			Value:       handler.DefaultHomeRadius,
+			Destination: &homeRadius,
+			Usage:       "Home radius in meters (default: 50000m = 50km)",
+		},
+		&cli.StringFlag{
+			Name:        flagReport,
+			Destination: &reportPath,
+			Usage:       "Write the run outcome as JSON to this file (groups, duplicates, errors with suggestions...)",
+		},
+		&cli.StringFlag{
+			Name:        "metrics-addr",
+			Destination: &metricsAddr,
+			Usage:       "Serve Prometheus metrics on this address (e.g. ':9101'), at /metrics",
+		},
+		&cli.StringFlag{
+			Name:        "metrics-file",
+			Destination: &metricsFile,
+			Usage:       "Write Prometheus metrics to this file after each run (node-exporter textfile collector, e.g. picsplit.prom)",
+		},
 		&cli.BoolFlag{
 			Name:        flagForce,
 			Aliases:     []string{"f"},
@@ -549,100 +1217,32 @@ func main() {
 			return fmt.Errorf("wrong count of argument %d, a unique path is required", c.NArg())
 		}
 
-		// Parse custom extensions
-		photoExts, err := parseExtensions(customPhotoExts)
-		if err != nil {
-			return fmt.Errorf("invalid photo extensions: %w", err)
-		}
-
-		videoExts, err := parseExtensions(customVideoExts)
-		if err != nil {
-			return fmt.Errorf("invalid video extensions: %w", err)
-		}
-
-		rawExts, err := parseExtensions(customRawExts)
-		if err != nil {
-			return fmt.Errorf("invalid RAW extensions: %w", err)
-		}
-
-		// Parse cleanup ignore files
-		cleanupIgnoreFiles := []string{}
-		if cleanupIgnore != "" {
-			cleanupIgnoreFiles = strings.Split(cleanupIgnore, ",")
-			for i, file := range cleanupIgnoreFiles {
-				cleanupIgnoreFiles[i] = strings.TrimSpace(file)
-			}
-		}
-
-		slog.Debug("configuration",
-			"path", path,
-			"delta_minutes", durationDelta.Minutes(),
-			"mode", executionMode,
-			"no_move_movies", noMoveMovie,
-			"no_move_raw", noMoveRaw,
-			"use_exif", useEXIF,
-			"use_gps", useGPS,
-			"gps_radius_meters", gpsRadius,
-			"separate_orphan_raw", separateOrphanRaw)
-		if len(photoExts) > 0 {
-			slog.Debug("custom photo extensions", "extensions", strings.Join(photoExts, ", "))
-		}
-		if len(videoExts) > 0 {
-			slog.Debug("custom video extensions", "extensions", strings.Join(videoExts, ", "))
-		}
-		if len(rawExts) > 0 {
-			slog.Debug("custom raw extensions", "extensions", strings.Join(rawExts, ", "))
-		}
-
-		// check path exists
-		fi, err := os.Stat(path)
+		cfg, err := buildSplitConfig(c, path)
 		if err != nil {
 			return err
 		}
+		cfg.Metrics = startMetrics()
 
-		if !fi.IsDir() {
-			return fmt.Errorf("provided path %s is not a directory", path)
-		}
+		runCtx, stop := interruptContext()
+		defer stop()
 
-		// Handle execution mode
-		mode := handler.ExecutionMode(executionMode)
-
-		// Validate execution mode
-		validModes := map[handler.ExecutionMode]bool{
-			handler.ModeValidate: true,
-			handler.ModeDryRun:   true,
-			handler.ModeRun:      true,
-		}
-		if !validModes[mode] {
-			return fmt.Errorf("invalid --mode value: %s (must be: validate, dryrun, or run)", mode)
+		result, err := handler.Split(runCtx, cfg)
+		if result != nil {
+			if result.Validation != nil {
+				result.Validation.Print()
+			}
+			if result.Stats != nil {
+				result.Stats.PrintSummary(cfg.Mode == handler.ModeDryRun)
+			}
 		}
-
-		cfg := &handler.Config{
-			BasePath:          path,
-			Delta:             durationDelta,
-			NoMoveMovie:       noMoveMovie,
-			NoMoveRaw:         noMoveRaw,
-			UseEXIF:           useEXIF,
-			UseGPS:            useGPS,
-			GPSRadius:         gpsRadius,
-			GPSUseGeocoding:   gpsUseGeocoding,
-			CustomPhotoExts:   photoExts,
-			CustomVideoExts:   videoExts,
-			CustomRawExts:     rawExts,
-			SeparateOrphanRaw: separateOrphanRaw,
-			ContinueOnError:   continueOnError,
-			Mode:              mode,
-			CleanupEmptyDirs:  cleanupEmptyDirs,
-			CleanupIgnore:     cleanupIgnoreFiles,
-			Force:             force,
-			DetectDuplicates:  detectDuplicates,
-			SkipDuplicates:    skipDuplicates,
-			MoveDuplicates:    moveDuplicates,
-			MinGroupSize:      minGroupSize,
-			LogLevel:          c.String(flagLogLevel),
-			LogFormat:         c.String(flagLogFormat),
+		if reportPath != "" {
+			if reportErr := handler.NewSplitReport(cfg.Mode, result, err).WriteFile(reportPath); reportErr != nil {
+				slog.Error("failed to write report", "path", reportPath, "error", reportErr)
+			} else {
+				slog.Info("report written", "path", reportPath)
+			}
 		}
-		return handler.Split(cfg)
+		return err
 	}
 
 	// run the app